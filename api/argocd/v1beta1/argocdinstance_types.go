@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// nolint:lll
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CABundleKind is the kind of the object which stores a CA bundle
+// +kubebuilder:validation:Enum=ConfigMap;Secret
+type CABundleKind string
+
+const (
+	// CABundleKindConfigMap stores the CA bundle in a ConfigMap
+	CABundleKindConfigMap CABundleKind = "ConfigMap"
+	// CABundleKindSecret stores the CA bundle in a Secret
+	CABundleKindSecret CABundleKind = "Secret"
+)
+
+// CABundleReference references the ConfigMap or Secret, in the namespace of ArgoCD, which stores the
+// PEM encoded CA bundle under the ca.crt key
+type CABundleReference struct {
+	// Kind of the object which stores the CA bundle
+	// +kubebuilder:default=ConfigMap
+	// +optional
+	Kind CABundleKind `json:"kind,omitempty"`
+
+	// Name of the ConfigMap or Secret
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ArgoCDInstanceSpec defines how the operator connects to an ArgoCD instance
+// +kubebuilder:validation:XValidation:rule="!self.endpoint.startsWith('http://') || (has(self.allowInsecureEndpoint) && self.allowInsecureEndpoint)",message="endpoint must be an https:// URL unless allowInsecureEndpoint is set"
+type ArgoCDInstanceSpec struct {
+	// Endpoint of the ArgoCD API, i.e. https://argocd-server.argocd.svc
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Endpoint string `json:"endpoint"`
+
+	// Namespace where ArgoCD is deployed. The Secrets and ConfigMaps referenced by the ArgoCDInstance
+	// are read from it and the cluster Secrets are created in it in Declarative mode.
+	// +kubebuilder:default="argocd"
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// CredentialsSecretRef references the Secret, in the namespace of ArgoCD, which stores the credentials
+	// of the ArgoCD account used by the operator: an API token under the token key, or a password under
+	// the password key and optionally a username. When it is not informed argocd-initial-admin-secret is used.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
+	// CABundleRef references the CA bundle trusted, in addition to the system certificates, to verify
+	// the certificate of the ArgoCD API.
+	// +optional
+	CABundleRef *CABundleReference `json:"caBundleRef,omitempty"`
+
+	// ClientCertSecretRef references the Secret, in the namespace of ArgoCD, of type kubernetes.io/tls
+	// with the client certificate presented to the ArgoCD API when it requires mutual TLS authentication.
+	// +optional
+	ClientCertSecretRef *corev1.LocalObjectReference `json:"clientCertSecretRef,omitempty"`
+
+	// InsecureSkipVerify disables the verification of the certificate of the ArgoCD API.
+	// It is only meant for development and test environments.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// AllowInsecureEndpoint allows an http:// Endpoint. The clusters registered are sent with their
+	// credentials, therefore, the endpoints which are not HTTPS are rejected unless it is set.
+	// It is only meant for development and test environments.
+	// +optional
+	AllowInsecureEndpoint bool `json:"allowInsecureEndpoint,omitempty"`
+
+	// DefaultProject is the name of the ArgoCD AppProject of the clusters registered whose
+	// Register does not inform one.
+	// +optional
+	DefaultProject string `json:"defaultProject,omitempty"`
+
+	// DefaultShard is the shard of the ArgoCD application controller which manages the clusters
+	// registered. When it is not informed ArgoCD assigns the shard.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DefaultShard *int64 `json:"defaultShard,omitempty"`
+}
+
+// ArgoCDInstanceStatus defines the observed state of ArgoCDInstance
+type ArgoCDInstanceStatus struct {
+
+	// Represents the observations of a ArgoCDInstance's current state.
+	// ArgoCDInstance.status.conditions.type are: "Available" and "Degraded"
+	// ArgoCDInstance.status.conditions.status are one of True, False, Unknown.
+	// For further information see: https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
+
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// Version of ArgoCD reported by its API
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// LastCheckedTime is when the operator last checked that the ArgoCD API is reachable with the
+	// credentials configured
+	// +optional
+	LastCheckedTime *metav1.Time `json:"lastCheckedTime,omitempty"`
+
+	// ObservedGeneration is the generation of the ArgoCDInstance observed by the last reconciliation.
+	// The status reflects the latest spec only when it is equal to the metadata.generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,categories=workload
+//+kubebuilder:printcolumn:name="Endpoint",type="string",JSONPath=".spec.endpoint",description="Endpoint of the ArgoCD API"
+//+kubebuilder:printcolumn:name="Available",type="string",JSONPath=".status.conditions[?(@.type==\"Available\")].status",description="Whether the ArgoCD API is reachable with the credentials configured"
+//+kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version",description="Version of ArgoCD"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ArgoCDInstance is the Schema for the argocdinstances API. It configures declaratively how the operator
+// connects to ArgoCD, instead of the Manager ENV VARs, and reports whether its API is reachable.
+type ArgoCDInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ArgoCDInstanceSpec   `json:"spec,omitempty"`
+	Status ArgoCDInstanceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ArgoCDInstanceList contains a list of ArgoCDInstance
+type ArgoCDInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDInstance{}, &ArgoCDInstanceList{})
+}
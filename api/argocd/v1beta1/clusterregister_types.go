@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// nolint:lll
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterReference references a Cluster by its namespace and name
+type ClusterReference struct {
+	// Namespace of the Cluster
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Name of the Cluster
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ClusterRegisterSpec defines the desired state of ClusterRegister
+type ClusterRegisterSpec struct {
+	// ClusterRef references the Cluster registered within ArgoCD. It cannot be changed, a new
+	// ClusterRegister is required to register another Cluster.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterRef is immutable"
+	ClusterRef ClusterReference `json:"clusterRef"`
+
+	// RegisterSpec defines the registration of the Cluster, as the spec of a Register. The Secrets
+	// referenced without a namespace are read from the namespace of the Cluster.
+	RegisterSpec `json:",inline"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=creg,categories=workload
+//+kubebuilder:printcolumn:name="Cluster Namespace",type="string",JSONPath=".spec.clusterRef.namespace",description="Namespace of the Cluster"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterRef.name",description="Name of the Cluster"
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Summary of the conditions"
+//+kubebuilder:printcolumn:name="Registered",type="string",JSONPath=".status.conditions[?(@.type==\"Registered\")].status",description="Whether the cluster entry exists within ArgoCD"
+//+kubebuilder:printcolumn:name="Available",type="string",JSONPath=".status.conditions[?(@.type==\"Available\")].status",description="Whether ArgoCD is connected to the Cluster"
+//+kubebuilder:printcolumn:name="Server",type="string",JSONPath=".status.server",description="Server of the Cluster registered within ArgoCD",priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterRegister is the Schema for the clusterregisters API. It is the cluster-scoped variant of the
+// Register, which references the Cluster by its namespace and name, so that the namespaces of the tenants
+// never contain a resource related to ArgoCD. The Cluster referenced is not given a Register, and when
+// a Register already exists for it, the Register keeps being used and the ClusterRegister is ignored.
+type ClusterRegister struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterRegisterSpec `json:"spec,omitempty"`
+	Status RegisterStatus      `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterRegisterList contains a list of ClusterRegister
+type ClusterRegisterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterRegister `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterRegister{}, &ClusterRegisterList{})
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// nolint:lll
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExternalClusterSpec defines the desired state of ExternalCluster
+type ExternalClusterSpec struct {
+	// Name of the cluster within ArgoCD. When it is not informed the name of the ExternalCluster is used.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// KubeConfigSecretRef references the Secret which stores the kubeconfig of the cluster. ArgoCD
+	// connects to the server of its current context with its credentials.
+	KubeConfigSecretRef KubeConfigSecretReference `json:"kubeconfigSecretRef"`
+
+	// RegistrationMode defines how the cluster is registered within ArgoCD.
+	// When it is not informed, the mode defined via the Manager ENV VAR
+	// ARGOCD_REGISTRATION_MODE is used, which defaults to API.
+	// +optional
+	RegistrationMode RegistrationMode `json:"registrationMode,omitempty"`
+
+	// Namespaces when informed, ArgoCD can only deploy into these namespaces of the cluster,
+	// i.e. for multi-tenant clusters. Otherwise, all namespaces are allowed.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ClusterResources defines if ArgoCD can manage cluster-scoped resources of the cluster when
+	// the Namespaces are informed. ArgoCD can always manage them when the Namespaces are not informed.
+	// +optional
+	ClusterResources bool `json:"clusterResources,omitempty"`
+
+	// Project is the name of the ArgoCD AppProject which the cluster belongs to. When it is
+	// informed the cluster can only be used as destination by the Applications of this project.
+	// +optional
+	Project string `json:"project,omitempty"`
+}
+
+// ExternalClusterStatus defines the observed state of ExternalCluster
+type ExternalClusterStatus struct {
+
+	// Represents the observations of a ExternalCluster's current state.
+	// ExternalCluster.status.conditions.type are: "Available", "Progressing", and "Degraded"
+	// ExternalCluster.status.conditions.status are one of True, False, Unknown.
+	// For further information see: https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
+
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// ClusterName is the name of the cluster within ArgoCD
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// Server is the server of the cluster registered within ArgoCD. It allows to remove the
+	// registration of the previous server when the kubeconfig points to a new one.
+	// +optional
+	Server string `json:"server,omitempty"`
+
+	// ObservedGeneration is the generation of the ExternalCluster observed by the last reconciliation.
+	// The status reflects the latest spec only when it is equal to the metadata.generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ExternalCluster is the Schema for the externalclusters API. It registers within ArgoCD the clusters
+// which are not managed by Cluster API.
+type ExternalCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ExternalClusterSpec   `json:"spec,omitempty"`
+	Status ExternalClusterStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ExternalClusterList contains a list of ExternalCluster
+type ExternalClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ExternalCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ExternalCluster{}, &ExternalClusterList{})
+}
@@ -20,11 +20,369 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// RegistrationMode defines how the Cluster is registered within ArgoCD
+// +kubebuilder:validation:Enum=API;Declarative
+type RegistrationMode string
+
+const (
+	// RegistrationModeAPI registers the Cluster by calling the ArgoCD API
+	RegistrationModeAPI RegistrationMode = "API"
+
+	// RegistrationModeDeclarative registers the Cluster by creating a Secret labeled with
+	// argocd.argoproj.io/secret-type: cluster in the namespace where ArgoCD is deployed
+	RegistrationModeDeclarative RegistrationMode = "Declarative"
+)
+
+// AuthStrategy defines how ArgoCD authenticates to the Cluster
+// +kubebuilder:validation:Enum=ServiceAccountToken;EmbeddedTLSConfig;AWSAuth;ExecProvider
+type AuthStrategy string
+
+const (
+	// AuthStrategyServiceAccountToken authenticates with the token of the ServiceAccount created
+	// in the Cluster, in the same way that it is done by `argocd cluster add`
+	AuthStrategyServiceAccountToken AuthStrategy = "ServiceAccountToken"
+
+	// AuthStrategyEmbeddedTLSConfig authenticates with the credentials of the kubeconfig
+	AuthStrategyEmbeddedTLSConfig AuthStrategy = "EmbeddedTLSConfig"
+
+	// AuthStrategyAWSAuth authenticates against EKS Clusters via IAM
+	AuthStrategyAWSAuth AuthStrategy = "AWSAuth"
+
+	// AuthStrategyExecProvider authenticates with the credentials obtained by executing a credential plugin
+	AuthStrategyExecProvider AuthStrategy = "ExecProvider"
+)
+
+// ServiceAccountSpec defines the ServiceAccount created in the workload Cluster whose token
+// is used by ArgoCD to connect to it, in the same way that it is done by `argocd cluster add`
+// +kubebuilder:validation:XValidation:rule="!(has(self.clusterRoleName) && has(self.rules))",message="clusterRoleName and rules are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.tokenExpiration) || duration(self.tokenExpiration) >= duration('10m')",message="tokenExpiration must be at least 10m"
+type ServiceAccountSpec struct {
+	// Namespace where the ServiceAccount is created in the workload Cluster
+	// +kubebuilder:default=kube-system
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the ServiceAccount created in the workload Cluster
+	// +kubebuilder:default=argocd-manager
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// ClusterRoleName is the name of an existing ClusterRole in the workload Cluster bound to
+	// the ServiceAccount (i.e. cluster-admin). It cannot be informed with Rules.
+	// +optional
+	ClusterRoleName string `json:"clusterRoleName,omitempty"`
+
+	// Rules of the ClusterRole created for the ServiceAccount. When neither Rules nor
+	// ClusterRoleName are informed the ServiceAccount has full access to the workload Cluster,
+	// in the same way that it is done by `argocd cluster add`.
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+
+	// Namespaces when informed, the ClusterRole is bound to the ServiceAccount only in these
+	// namespaces of the workload Cluster instead of cluster-wide.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// TokenExpiration when informed, tokens bound to this duration are requested for the
+	// ServiceAccount (i.e. 24h) and they are rotated before they expire. Otherwise, a
+	// long-lived token is used. The minimum duration is 10m.
+	// +optional
+	TokenExpiration *metav1.Duration `json:"tokenExpiration,omitempty"`
+}
+
+// AWSAuthSpec defines how ArgoCD authenticates against EKS Clusters via IAM, i.e. the ones
+// created with CAPA, so that no static credentials are shared with ArgoCD.
+type AWSAuthSpec struct {
+	// ClusterName is the name of the EKS Cluster in AWS
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// RoleARN of the IAM role assumed by ArgoCD to connect to the EKS Cluster. When it is not
+	// informed the IAM identity of ArgoCD (i.e. via IRSA) is used.
+	// +optional
+	RoleARN string `json:"roleARN,omitempty"`
+}
+
+// AzureLoginMode defines how kubelogin authenticates against Azure AD
+// +kubebuilder:validation:Enum=workloadidentity;msi;spn;azurecli
+type AzureLoginMode string
+
+const (
+	// AzureLoginModeWorkloadIdentity authenticates with the federated token of the Azure workload identity
+	AzureLoginModeWorkloadIdentity AzureLoginMode = "workloadidentity"
+
+	// AzureLoginModeMSI authenticates with the managed identity of the node
+	AzureLoginModeMSI AzureLoginMode = "msi"
+
+	// AzureLoginModeSPN authenticates with the secret of a service principal
+	AzureLoginModeSPN AzureLoginMode = "spn"
+
+	// AzureLoginModeAzureCLI authenticates with the credentials of the Azure CLI
+	AzureLoginModeAzureCLI AzureLoginMode = "azurecli"
+)
+
+// AzureKubeloginSpec defines the kubelogin credential plugin executed by ArgoCD to obtain the
+// credentials of AKS Clusters, i.e. the ones created with CAPZ.
+type AzureKubeloginSpec struct {
+	// LoginMode defines how kubelogin authenticates against Azure AD
+	// +kubebuilder:default=workloadidentity
+	// +optional
+	LoginMode AzureLoginMode `json:"loginMode,omitempty"`
+
+	// ServerID is the application ID of the Azure AD server of AKS
+	// +kubebuilder:default="6dae42f8-4368-4678-94ff-3960e28e3630"
+	// +optional
+	ServerID string `json:"serverID,omitempty"`
+
+	// ClientID of the identity used to authenticate. When it is not informed the AZURE_CLIENT_ID
+	// env var of ArgoCD, injected by the Azure workload identity webhook, is used.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+
+	// TenantID of the identity used to authenticate. When it is not informed the AZURE_TENANT_ID
+	// env var of ArgoCD, injected by the Azure workload identity webhook, is used.
+	// +optional
+	TenantID string `json:"tenantID,omitempty"`
+}
+
+// ExecProviderSpec defines the client-go credential plugin executed by ArgoCD to obtain the
+// credentials of the Cluster, i.e. the gke-gcloud-auth-plugin for GKE Clusters.
+// +kubebuilder:validation:XValidation:rule="has(self.command) != has(self.azure)",message="exactly one of command and azure must be informed"
+type ExecProviderSpec struct {
+	// Command executed by ArgoCD to obtain the credentials (i.e. gke-gcloud-auth-plugin).
+	// It must be available in the ArgoCD application controller and server images.
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	Command string `json:"command,omitempty"`
+
+	// Azure when informed, kubelogin is executed with the arguments required to obtain the
+	// credentials of the AKS Cluster instead of the Command and Args.
+	// +optional
+	Azure *AzureKubeloginSpec `json:"azure,omitempty"`
+
+	// Args passed to the command
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env variables set when executing the command in addition to the ones of ArgoCD
+	// +optional
+	Env map[string]string `json:"env,omitempty"`
+
+	// APIVersion of the ExecCredential returned by the command
+	// +kubebuilder:default="client.authentication.k8s.io/v1beta1"
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// InstallHint is shown by ArgoCD when the command is not found
+	// +optional
+	InstallHint string `json:"installHint,omitempty"`
+}
+
+// DecryptionProvider defines how the kubeconfig of the Cluster is decrypted
+// +kubebuilder:validation:Enum=sops
+type DecryptionProvider string
+
+const (
+	// DecryptionProviderSOPS decrypts the kubeconfig encrypted with SOPS using age keys
+	DecryptionProviderSOPS DecryptionProvider = "sops"
+)
+
+// DecryptionSpec defines how the kubeconfig of the Cluster is decrypted before it is used
+type DecryptionSpec struct {
+	// Provider used to decrypt the kubeconfig
+	// +kubebuilder:default=sops
+	// +optional
+	Provider DecryptionProvider `json:"provider,omitempty"`
+
+	// SecretRef references the Secret, in the namespace of the Register, which stores the age
+	// private keys used to decrypt the kubeconfig under keys with the .agekey suffix.
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
+// DeletionPolicy defines what happens to the registration within ArgoCD when the Register is deleted
+// +kubebuilder:validation:Enum=Delete;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete removes the registration of the Cluster from ArgoCD
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps the registration of the Cluster within ArgoCD, i.e. to migrate it to
+	// another management cluster or to keep ArgoCD managing it after it is detached from Cluster API
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+// FinalizerFailurePolicy defines what happens once the retry budget to remove the registration of the
+// Cluster from ArgoCD is exhausted while the Register is deleted
+// +kubebuilder:validation:Enum=Retry;Release
+type FinalizerFailurePolicy string
+
+const (
+	// FinalizerFailurePolicyRetry keeps the finalizer and retries to remove the registration until it succeeds
+	FinalizerFailurePolicyRetry FinalizerFailurePolicy = "Retry"
+	// FinalizerFailurePolicyRelease removes the finalizer, leaving the registration behind within ArgoCD
+	FinalizerFailurePolicyRelease FinalizerFailurePolicy = "Release"
+)
+
+// RegisterPhase is a summary of the conditions of the Register, for the consumers which do not want to
+// evaluate them
+// +kubebuilder:validation:Enum=Pending;Registering;Registered;Failed;Deleting
+type RegisterPhase string
+
+const (
+	// RegisterPhasePending is used before the registration of the Cluster is attempted
+	RegisterPhasePending RegisterPhase = "Pending"
+	// RegisterPhaseRegistering is used while the Cluster is being registered within ArgoCD
+	RegisterPhaseRegistering RegisterPhase = "Registering"
+	// RegisterPhaseRegistered is used when the cluster entry exists within ArgoCD
+	RegisterPhaseRegistered RegisterPhase = "Registered"
+	// RegisterPhaseFailed is used when the Register is Degraded
+	RegisterPhaseFailed RegisterPhase = "Failed"
+	// RegisterPhaseDeleting is used while the registration is removed before the Register is deleted
+	RegisterPhaseDeleting RegisterPhase = "Deleting"
+)
+
+// ForceUnregisterAnnotation when set on a Register being deleted with the number of failed attempts
+// tolerated to remove its registration, i.e. "3", the finalizer stops calling ArgoCD once they are
+// reached and lets the Register be deleted, leaving the registration behind. It is the escape hatch for
+// when ArgoCD is unreachable or no longer exists. "0" skips the ArgoCD call right away.
+const ForceUnregisterAnnotation = "argocd.workload.com/force-unregister-after"
+
+// RefreshAnnotation when set on a Register whose registration Failed restarts its reconciliation, i.e. once
+// the permissions of the ArgoCD account were fixed. It is removed once the reconciliation is restarted.
+const RefreshAnnotation = "argocd.workload.com/refresh"
+
+// KubeConfigSecretReference references the Secret which stores the kubeconfig of the Cluster
+type KubeConfigSecretReference struct {
+	// Name of the Secret
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the Secret. When it is not informed the namespace of the resource which references it is used.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the Secret which stores the kubeconfig
+	// +kubebuilder:default="value"
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
 // RegisterSpec defines the desired state of Register
+// +kubebuilder:validation:XValidation:rule="[has(self.awsAuth), has(self.serviceAccount), has(self.execProvider)].filter(x, x).size() <= 1",message="only one of awsAuth, serviceAccount and execProvider can be informed"
+// +kubebuilder:validation:XValidation:rule="!has(self.authStrategy) || has(self.awsAuth) == (self.authStrategy == 'AWSAuth')",message="awsAuth must be informed only when authStrategy is AWSAuth"
+// +kubebuilder:validation:XValidation:rule="!has(self.authStrategy) || has(self.execProvider) == (self.authStrategy == 'ExecProvider')",message="execProvider must be informed only when authStrategy is ExecProvider"
+// +kubebuilder:validation:XValidation:rule="!has(self.authStrategy) || !has(self.serviceAccount) || self.authStrategy == 'ServiceAccountToken'",message="serviceAccount must be informed only when authStrategy is ServiceAccountToken"
+// +kubebuilder:validation:XValidation:rule="!has(self.verifyInterval) || duration(self.verifyInterval) >= duration('1m')",message="verifyInterval must be at least 1m"
 type RegisterSpec struct {
+	// RegistrationMode defines how the Cluster is registered within ArgoCD.
+	// When it is not informed, the mode defined via the Manager ENV VAR
+	// ARGOCD_REGISTRATION_MODE is used, which defaults to API.
+	// +optional
+	RegistrationMode RegistrationMode `json:"registrationMode,omitempty"`
+
+	// InstanceRef references the ArgoCDInstance where the Cluster is registered, i.e. to register the
+	// Clusters of each environment within its own ArgoCD. When it is not informed the ArgoCDInstance
+	// selected via the --argocd-instance flag of the Manager is used, or otherwise the ArgoCD instance
+	// configured via Manager ENV VAR.
+	// +optional
+	InstanceRef *corev1.LocalObjectReference `json:"instanceRef,omitempty"`
+
+	// Suspend when true, the controller stops making any calls to ArgoCD for the Cluster, i.e. to freeze
+	// its registration during an incident response, while still reporting the status. The registration
+	// is still removed when the Register is deleted, unless the deletionPolicy is Retain.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy defines whether the registration of the Cluster is removed from ArgoCD or retained
+	// when the Register, or the Cluster which owns it, is deleted.
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// FinalizerFailurePolicy defines whether the finalizer keeps retrying or is released, leaving the
+	// registration behind within ArgoCD, once the retry budget of the Manager to remove it is exhausted.
+	// +kubebuilder:default=Retry
+	// +optional
+	FinalizerFailurePolicy FinalizerFailurePolicy `json:"finalizerFailurePolicy,omitempty"`
+
+	// Name of the Cluster within ArgoCD. When it is not informed, it is rendered from the template provided
+	// via the Manager ENV VAR ARGOCD_CLUSTER_NAME_TEMPLATE, which defaults to the name of the Cluster.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// KubeConfigSecretRef when informed, the kubeconfig of the Cluster is read from this Secret
+	// instead of the one found by the naming conventions, i.e. the <cluster-name>-kubeconfig
+	// Secret created by Cluster API.
+	// +optional
+	KubeConfigSecretRef *KubeConfigSecretReference `json:"kubeconfigSecretRef,omitempty"`
+
+	// Decryption when informed, the kubeconfig of the Cluster is decrypted before it is used,
+	// i.e. when it is stored encrypted with SOPS.
+	// +optional
+	Decryption *DecryptionSpec `json:"decryption,omitempty"`
+
+	// ValidateConnectivity when true, the Cluster is reached with its kubeconfig, by requesting the
+	// version of its API server, before it is registered within ArgoCD. Otherwise, the kubeconfig
+	// is only checked to be well-formed.
+	// +optional
+	ValidateConnectivity bool `json:"validateConnectivity,omitempty"`
+
+	// VerifyInterval when informed, the registration is verified again at this interval (i.e. 10m), so that
+	// it is repaired when it was removed or edited out-of-band, or its connection state is no longer healthy,
+	// without waiting for a change of the Cluster or the Register. The minimum interval is 1m.
+	// +optional
+	VerifyInterval *metav1.Duration `json:"verifyInterval,omitempty"`
+
+	// AuthStrategy defines how ArgoCD authenticates to the Cluster. When it is not informed it is
+	// defaulted from the credentials informed: ServiceAccountToken when the ServiceAccount is
+	// informed, AWSAuth when the AWSAuth is informed, ExecProvider when the ExecProvider is
+	// informed, and otherwise, EmbeddedTLSConfig which uses the credentials of the kubeconfig.
+	// +optional
+	AuthStrategy AuthStrategy `json:"authStrategy,omitempty"`
+
+	// ServiceAccount when informed, a ServiceAccount bound to a ClusterRole is created in the
+	// workload Cluster and its token is used by ArgoCD to connect to the Cluster instead of the
+	// credentials of the kubeconfig. When the ServiceAccountToken AuthStrategy is informed without it,
+	// the default ServiceAccount is used.
+	// +optional
+	ServiceAccount *ServiceAccountSpec `json:"serviceAccount,omitempty"`
+
+	// AWSAuth when informed, ArgoCD authenticates against the EKS Cluster via IAM instead of the
+	// credentials of the kubeconfig which would no longer work after the token expires.
+	// +optional
+	AWSAuth *AWSAuthSpec `json:"awsAuth,omitempty"`
+
+	// ExecProvider when informed, ArgoCD obtains the credentials of the Cluster by executing the
+	// credential plugin instead of using the credentials of the kubeconfig, i.e. for GKE Clusters.
+	// +optional
+	ExecProvider *ExecProviderSpec `json:"execProvider,omitempty"`
+
+	// ProxyURL of the HTTP proxy used by ArgoCD to connect to the Cluster (i.e. http://proxy:3128),
+	// for Clusters only reachable through a proxy. When it is not informed the proxy-url of the
+	// kubeconfig is used, if any.
+	// +kubebuilder:validation:Pattern=`^(http|https|socks5)://`
+	// +optional
+	ProxyURL string `json:"proxyUrl,omitempty"`
+
+	// Namespaces when informed, ArgoCD can only deploy into these namespaces of the Cluster,
+	// i.e. for multi-tenant Clusters. Otherwise, all namespaces are allowed.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ClusterResources defines if ArgoCD can manage cluster-scoped resources of the Cluster when
+	// the Namespaces are informed. ArgoCD can always manage them when the Namespaces are not informed.
+	// +optional
+	ClusterResources bool `json:"clusterResources,omitempty"`
+
+	// Project is the name of the ArgoCD AppProject which the Cluster belongs to. When it is
+	// informed the Cluster can only be used as destination by the Applications of this project.
+	// +optional
+	Project string `json:"project,omitempty"`
 }
 
 // RegisterStatus defines the observed state of Register
@@ -39,10 +397,104 @@ type RegisterStatus struct {
 	// For further information see: https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
 
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// ClusterName is the effective name of the cluster within ArgoCD, i.e. the one rendered from the
+	// name template
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// Server is the control plane endpoint of the Cluster registered within ArgoCD. It allows to remove
+	// the registration of the previous endpoint when it changes.
+	// +optional
+	Server string `json:"server,omitempty"`
+
+	// Instance is the name of the ArgoCDInstance where the Cluster is registered, empty when it is
+	// registered within the ArgoCD instance configured via Manager ENV VAR. It allows to remove the
+	// registration from the previous instance when the instanceRef changes.
+	// +optional
+	Instance string `json:"instance,omitempty"`
+
+	// RegistrationMode is the mode used to register the Cluster within ArgoCD. It allows to migrate the
+	// registration to the other mode when the one of the Register or the Manager ENV VAR changes.
+	// +optional
+	RegistrationMode RegistrationMode `json:"registrationMode,omitempty"`
+
+	// KubeConfigHash is the hash of the kubeconfig of the Cluster used to register it within ArgoCD.
+	// It allows to push the new credentials to ArgoCD when the kubeconfig is rotated.
+	// +optional
+	KubeConfigHash string `json:"kubeConfigHash,omitempty"`
+
+	// ServiceAccountHash is the hash of the spec.serviceAccount whose ServiceAccount and RBAC were created in the
+	// workload Cluster. They are only reconciled again when it changes, so that the workload Cluster is not
+	// written by every reconciliation.
+	// +optional
+	ServiceAccountHash string `json:"serviceAccountHash,omitempty"`
+
+	// KubernetesVersion is the version of the API server of the Cluster reached with its kubeconfig.
+	// It is only informed when the spec.validateConnectivity is true.
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// TokenExpiry is when the token of the ServiceAccount used by ArgoCD to connect to the Cluster
+	// expires. It is only informed when the spec.serviceAccount.tokenExpiration is informed.
+	// +optional
+	TokenExpiry *metav1.Time `json:"tokenExpiry,omitempty"`
+
+	// UnregisterFailures is the number of failed attempts to remove the registration from ArgoCD while
+	// the Register is deleted. It is compared with the argocd.workload.com/force-unregister-after annotation.
+	// +optional
+	UnregisterFailures int32 `json:"unregisterFailures,omitempty"`
+
+	// TransientFailures is the number of consecutive reconciliations which failed since ArgoCD was unavailable.
+	// The reconciliation is requeued with a backoff which increases with them, and they are reset once it succeeds.
+	// +optional
+	TransientFailures int32 `json:"transientFailures,omitempty"`
+
+	// PermanentFailures is the number of consecutive reconciliations which failed with an error which is not
+	// solved by retrying, i.e. when ArgoCD denies the operation. Once the budget of the Manager is reached the
+	// Register is reported as Failed and it is no longer reconciled until its spec changes or it is refreshed.
+	// +optional
+	PermanentFailures int32 `json:"permanentFailures,omitempty"`
+
+	// ObservedGeneration is the generation of the Register observed by the last reconciliation.
+	// The status reflects the latest spec only when it is equal to the metadata.generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastRegistrationTime is when the Cluster was last registered within ArgoCD, including when its
+	// registration was restored after it was edited or removed out-of-band.
+	// +optional
+	LastRegistrationTime *metav1.Time `json:"lastRegistrationTime,omitempty"`
+
+	// LastVerifiedTime is when the registration of the Cluster was last verified within ArgoCD.
+	// +optional
+	LastVerifiedTime *metav1.Time `json:"lastVerifiedTime,omitempty"`
+
+	// ArgoCDServerVersion is the version of ArgoCD reported by its API when the registration was last
+	// verified. It is not informed in Declarative mode.
+	// +optional
+	ArgoCDServerVersion string `json:"argoCDServerVersion,omitempty"`
+
+	// ArgoCDClusterID identifies the cluster entry within ArgoCD: the server in API mode and the name
+	// of the cluster Secret in Declarative mode.
+	// +optional
+	ArgoCDClusterID string `json:"argoCDClusterID,omitempty"`
+
+	// Phase is a summary of the conditions: Pending, Registering, Registered, Failed or Deleting.
+	// The conditions describe the state in detail.
+	// +optional
+	Phase RegisterPhase `json:"phase,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=reg,categories=workload
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Summary of the conditions"
+//+kubebuilder:printcolumn:name="Registered",type="string",JSONPath=".status.conditions[?(@.type==\"Registered\")].status",description="Whether the cluster entry exists within ArgoCD"
+//+kubebuilder:printcolumn:name="Available",type="string",JSONPath=".status.conditions[?(@.type==\"Available\")].status",description="Whether ArgoCD is connected to the Cluster"
+//+kubebuilder:printcolumn:name="Server",type="string",JSONPath=".status.server",description="Server of the Cluster registered within ArgoCD"
+//+kubebuilder:printcolumn:name="Instance",type="string",JSONPath=".status.instance",description="ArgoCDInstance where the Cluster is registered",priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Register is the Schema for the registers API
 type Register struct {
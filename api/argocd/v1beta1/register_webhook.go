@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the webhook which validates the Registers within the Manager.
+// The kubeconfig Secret of a Register must be in its namespace or in one of the allowedSecretNamespaces.
+func (r *Register) SetupWebhookWithManager(mgr ctrl.Manager, allowedSecretNamespaces []string) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&RegisterValidator{AllowedSecretNamespaces: allowedSecretNamespaces}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-argocd-workload-com-v1beta1-register,mutating=false,failurePolicy=fail,sideEffects=None,groups=argocd.workload.com,resources=registers,verbs=create;update,versions=v1beta1,name=vregister.kb.io,admissionReviewVersions=v1
+
+// RegisterValidator rejects the invalid Registers at admission, instead of letting them fail later
+// when they are reconciled
+// +kubebuilder:object:generate=false
+type RegisterValidator struct {
+	// AllowedSecretNamespaces are the namespaces, besides the one of the Register, where the
+	// kubeconfig Secret referenced by it can be
+	AllowedSecretNamespaces []string
+}
+
+var _ webhook.CustomValidator = &RegisterValidator{}
+
+// ValidateCreate validates the spec of the Register created
+func (v *RegisterValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	register, ok := obj.(*Register)
+	if !ok {
+		return nil, fmt.Errorf("expected a Register but got a %T", obj)
+	}
+	return nil, v.validate(register)
+}
+
+// ValidateUpdate validates the spec of the Register updated. The Registers whose spec did not change,
+// or which are being deleted, are not validated so that their finalizer can always be removed.
+func (v *RegisterValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings,
+	error) {
+	oldRegister, ok := oldObj.(*Register)
+	if !ok {
+		return nil, fmt.Errorf("expected a Register but got a %T", oldObj)
+	}
+	register, ok := newObj.(*Register)
+	if !ok {
+		return nil, fmt.Errorf("expected a Register but got a %T", newObj)
+	}
+	if register.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldRegister.Spec, register.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(register)
+}
+
+// ValidateDelete allows the Registers to be deleted
+func (v *RegisterValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns an Invalid error with all the fields of the spec which are not valid
+func (v *RegisterValidator) validate(register *Register) error {
+	specPath := field.NewPath("spec")
+	allErrs := v.validateKubeConfigSecretRef(register, specPath.Child("kubeconfigSecretRef"))
+	allErrs = append(allErrs, validateAuthStrategy(&register.Spec, specPath)...)
+	if register.Spec.ProxyURL != "" {
+		if proxyURL, err := url.Parse(register.Spec.ProxyURL); err != nil || proxyURL.Host == "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("proxyUrl"), register.Spec.ProxyURL,
+				"must be a URL with a host, i.e. http://proxy:3128"))
+		}
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Register").GroupKind(), register.Name, allErrs)
+}
+
+// validateKubeConfigSecretRef checks that the kubeconfig Secret is in the namespace of the Register or
+// in one of the namespaces allowed, so that a Register cannot read the kubeconfig of any namespace
+func (v *RegisterValidator) validateKubeConfigSecretRef(register *Register, fldPath *field.Path) field.ErrorList {
+	ref := register.Spec.KubeConfigSecretRef
+	if ref == nil || ref.Namespace == "" || ref.Namespace == register.Namespace {
+		return nil
+	}
+	for _, namespace := range v.AllowedSecretNamespaces {
+		if ref.Namespace == namespace {
+			return nil
+		}
+	}
+	return field.ErrorList{field.Forbidden(fldPath.Child("namespace"),
+		fmt.Sprintf("the Secret must be in the namespace %s of the Register or in one of the allowed namespaces %v",
+			register.Namespace, v.AllowedSecretNamespaces))}
+}
+
+// validateAuthStrategy checks that only the credentials of the AuthStrategy are informed. The CRD
+// enforces it too, it is checked again for the API servers which do not evaluate its rules.
+func validateAuthStrategy(spec *RegisterSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	informed := 0
+	for _, ok := range []bool{spec.AWSAuth != nil, spec.ServiceAccount != nil, spec.ExecProvider != nil} {
+		if ok {
+			informed++
+		}
+	}
+	if informed > 1 {
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			"only one of awsAuth, serviceAccount and execProvider can be informed"))
+	}
+	if spec.AuthStrategy == "" {
+		return allErrs
+	}
+
+	strategyPath := fldPath.Child("authStrategy")
+	switch {
+	case spec.AuthStrategy == AuthStrategyAWSAuth && spec.AWSAuth == nil:
+		allErrs = append(allErrs, field.Required(fldPath.Child("awsAuth"),
+			"must be informed when authStrategy is AWSAuth"))
+	case spec.AuthStrategy == AuthStrategyExecProvider && spec.ExecProvider == nil:
+		allErrs = append(allErrs, field.Required(fldPath.Child("execProvider"),
+			"must be informed when authStrategy is ExecProvider"))
+	}
+	if spec.AuthStrategy != AuthStrategyAWSAuth && spec.AWSAuth != nil {
+		allErrs = append(allErrs, field.Invalid(strategyPath, spec.AuthStrategy,
+			"must be AWSAuth when awsAuth is informed"))
+	}
+	if spec.AuthStrategy != AuthStrategyExecProvider && spec.ExecProvider != nil {
+		allErrs = append(allErrs, field.Invalid(strategyPath, spec.AuthStrategy,
+			"must be ExecProvider when execProvider is informed"))
+	}
+	if spec.AuthStrategy != AuthStrategyServiceAccountToken && spec.ServiceAccount != nil {
+		allErrs = append(allErrs, field.Invalid(strategyPath, spec.AuthStrategy,
+			"must be ServiceAccountToken when serviceAccount is informed"))
+	}
+	return allErrs
+}
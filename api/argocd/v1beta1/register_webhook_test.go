@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Register webhook", func() {
+	ctx := context.Background()
+	validator := &RegisterValidator{AllowedSecretNamespaces: []string{"kubeconfigs"}}
+
+	newRegister := func(spec RegisterSpec) *Register {
+		return &Register{
+			ObjectMeta: metav1.ObjectMeta{Name: "workload", Namespace: "clusters"},
+			Spec:       spec,
+		}
+	}
+
+	DescribeTable("validating the Register created",
+		func(spec RegisterSpec, message string) {
+			_, err := validator.ValidateCreate(ctx, newRegister(spec))
+			if message == "" {
+				Expect(err).To(Not(HaveOccurred()))
+				return
+			}
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		Entry("allows an empty spec", RegisterSpec{}, ""),
+		Entry("allows the kubeconfig Secret in the namespace of the Register",
+			RegisterSpec{KubeConfigSecretRef: &KubeConfigSecretReference{Name: "kubeconfig", Namespace: "clusters"}}, ""),
+		Entry("allows the kubeconfig Secret in an allowed namespace",
+			RegisterSpec{KubeConfigSecretRef: &KubeConfigSecretReference{Name: "kubeconfig", Namespace: "kubeconfigs"}},
+			""),
+		Entry("rejects the kubeconfig Secret in another namespace",
+			RegisterSpec{KubeConfigSecretRef: &KubeConfigSecretReference{Name: "kubeconfig", Namespace: "kube-system"}},
+			"spec.kubeconfigSecretRef.namespace"),
+		Entry("allows a proxy URL with a host", RegisterSpec{ProxyURL: "http://proxy:3128"}, ""),
+		Entry("rejects a proxy URL without a host", RegisterSpec{ProxyURL: "http://"}, "spec.proxyUrl"),
+		Entry("allows the credentials of the AuthStrategy",
+			RegisterSpec{AuthStrategy: AuthStrategyAWSAuth, AWSAuth: &AWSAuthSpec{ClusterName: "eks"}}, ""),
+		Entry("allows the ServiceAccountToken AuthStrategy without a ServiceAccount",
+			RegisterSpec{AuthStrategy: AuthStrategyServiceAccountToken}, ""),
+		Entry("rejects the AWSAuth AuthStrategy without its credentials",
+			RegisterSpec{AuthStrategy: AuthStrategyAWSAuth}, "spec.awsAuth"),
+		Entry("rejects the credentials of another AuthStrategy",
+			RegisterSpec{AuthStrategy: AuthStrategyEmbeddedTLSConfig, ServiceAccount: &ServiceAccountSpec{}},
+			"must be ServiceAccountToken when serviceAccount is informed"),
+		Entry("rejects the credentials of many AuthStrategies",
+			RegisterSpec{AWSAuth: &AWSAuthSpec{ClusterName: "eks"}, ServiceAccount: &ServiceAccountSpec{}},
+			"only one of awsAuth, serviceAccount and execProvider can be informed"),
+	)
+
+	It("should only validate the Registers updated whose spec changed", func() {
+		invalid := RegisterSpec{KubeConfigSecretRef: &KubeConfigSecretReference{Name: "kubeconfig", Namespace: "kube-system"}}
+		oldRegister := newRegister(invalid)
+		register := newRegister(invalid)
+		register.Finalizers = []string{"argocd.register.workload.com/finalizer"}
+		_, err := validator.ValidateUpdate(ctx, oldRegister, register)
+		Expect(err).To(Not(HaveOccurred()))
+
+		By("Rejecting the update of the spec")
+		register.Spec.ProxyURL = "http://proxy:3128"
+		_, err = validator.ValidateUpdate(ctx, oldRegister, register)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+		By("Allowing the update of a Register being deleted")
+		register.DeletionTimestamp = &metav1.Time{}
+		_, err = validator.ValidateUpdate(ctx, oldRegister, register)
+		Expect(err).To(Not(HaveOccurred()))
+	})
+})
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RegistrationPolicySpec defines which Clusters are registered within ArgoCD and the defaults of their Register
+type RegistrationPolicySpec struct {
+	// NamespaceSelector selects the namespaces whose Clusters are registered. When it is not
+	// informed the Clusters of all namespaces are selected.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// ClusterSelector selects the Clusters which are registered by their labels. When it is not
+	// informed all Clusters of the namespaces selected are selected.
+	// +optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// RegistrationMode defines how the Clusters selected are registered within ArgoCD. When it is
+	// not informed, the mode defined via the Manager ENV VAR ARGOCD_REGISTRATION_MODE is used.
+	// +optional
+	RegistrationMode RegistrationMode `json:"registrationMode,omitempty"`
+
+	// InstanceRef references the ArgoCDInstance where the Clusters selected are registered. When it is
+	// not informed the ArgoCDInstance selected via the --argocd-instance flag of the Manager is used.
+	// +optional
+	InstanceRef *corev1.LocalObjectReference `json:"instanceRef,omitempty"`
+
+	// Project is the name of the ArgoCD AppProject which the Clusters selected belong to.
+	// +optional
+	Project string `json:"project,omitempty"`
+
+	// NameTemplate is the Go template which renders the name of the Clusters selected within ArgoCD,
+	// i.e. "{{ .Namespace }}-{{ .Name }}". When it is not informed, the template provided via the
+	// Manager ENV VAR ARGOCD_CLUSTER_NAME_TEMPLATE is used.
+	// +optional
+	NameTemplate string `json:"nameTemplate,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// RegistrationPolicy is the Schema for the registrationpolicies API. When at least one RegistrationPolicy
+// exists, only the Clusters selected by one of them get a Register created, with the defaults of the
+// first one which selects them in alphabetical order. Otherwise, all Clusters get a Register created.
+type RegistrationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RegistrationPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RegistrationPolicyList contains a list of RegistrationPolicy
+type RegistrationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RegistrationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RegistrationPolicy{}, &RegistrationPolicyList{})
+}
@@ -18,26 +18,47 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/config"
 	argocdcontroller "github.com/workload-operator/internal/controller/argocd"
+	"github.com/workload-operator/internal/tracing"
 	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	//+kubebuilder:scaffold:imports
 )
 
+const (
+	// watchNamespaceEnvVar store the name of the envvar used to provide the default of the namespaces watched
+	watchNamespaceEnvVar = "WATCH_NAMESPACE"
+	// watchNamespaceSelectorEnvVar store the name of the envvar used to provide the default of the label
+	// selector of the namespaces watched
+	watchNamespaceSelectorEnvVar = "WATCH_NAMESPACE_SELECTOR"
+	// podNamespaceEnvVar store the name of the envvar used to provide the namespace where the Manager runs
+	podNamespaceEnvVar = "POD_NAMESPACE"
+	// serviceAccountNamespaceFile is the file which holds the namespace of the ServiceAccount of the Manager
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -54,11 +75,138 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var argocdTokenFile string
+	var registerManagementCluster bool
+	var managementClusterName string
+	var finalizerRetryBudget int
+	var permanentFailureBudget int
+	var maxConcurrentReconciles int
+	var kubeAPIQPS float64
+	var kubeAPIBurst int
+	var rateLimiterBaseDelay time.Duration
+	var rateLimiterMaxDelay time.Duration
+	var allowedSecretNamespaces string
+	var argoCDInstance string
+	var configFile string
+	var enableTracing bool
+	var eventBurst int
+	var eventInterval time.Duration
+	var argoCDReadinessCheck bool
+	var enablePprof bool
+	var pprofAddr string
+	var watchNamespaces string
+	var watchNamespaceSelector string
+	var secretLabelSelector string
+	var argoCDCacheTTL time.Duration
+	var argoCDClusterListTTL time.Duration
+	var bulkRegistrationWorkers int
+	var registrationWorkers int
+	var circuitBreakerThreshold int
+	var circuitBreakerCooldown time.Duration
+	var orphanSweepInterval time.Duration
+	var argoCDOrphanSweepInterval time.Duration
+	var adoptArgoCDClusters bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&argocdTokenFile, "argocd-token-file", "",
+		"Path of the file with the ArgoCD API token, i.e. mounted from a projected Secret or a CSI secrets-store volume. "+
+			"When it is set the token is not read from the Secret in the ArgoCD namespace.")
+	flag.BoolVar(&registerManagementCluster, "register-management-cluster", false,
+		"Register the management cluster itself within ArgoCD, which connects to it with its own ServiceAccount.")
+	flag.StringVar(&managementClusterName, "management-cluster-name", argocdcontroller.DefaultManagementClusterName,
+		"The name of the management cluster within ArgoCD when it is registered.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The maximum number of Clusters and ExternalClusters reconciled concurrently by each controller.")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", 20,
+		"The maximum queries per second from the Manager to the kube-apiserver.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", 30,
+		"The maximum burst of queries from the Manager to the kube-apiserver.")
+	flag.DurationVar(&rateLimiterBaseDelay, "rate-limiter-base-delay", 0,
+		"The delay to requeue a failed reconciliation, doubled on every failure. "+
+			"When neither the base nor the max delay are set the default rate limiter of controller-runtime is used.")
+	flag.DurationVar(&rateLimiterMaxDelay, "rate-limiter-max-delay", 0,
+		"The maximum delay to requeue a reconciliation which keeps failing.")
+	flag.IntVar(&finalizerRetryBudget, "finalizer-retry-budget", argocdcontroller.DefaultFinalizerRetryBudget,
+		"The number of failed attempts to remove the registration of a Cluster from ArgoCD before the "+
+			"finalization of its Register is reported as failed.")
+	flag.IntVar(&permanentFailureBudget, "permanent-failure-budget", argocdcontroller.DefaultPermanentFailureBudget,
+		"The number of consecutive reconciliations of a Register failed with an error which is not solved by "+
+			"retrying, i.e. when ArgoCD denies the operation, before it is reported as Failed and no longer "+
+			"reconciled until its spec changes or it is refreshed.")
+	flag.StringVar(&allowedSecretNamespaces, "allowed-secret-namespaces", "",
+		"Comma-separated namespaces, besides the one of the Register, where the kubeconfig Secret referenced "+
+			"by a Register can be. The Registers which reference a Secret in any other namespace are rejected.")
+	flag.StringVar(&argoCDInstance, "argocd-instance", "",
+		"The name of the ArgoCDInstance where the clusters are registered. When it is not informed the "+
+			"ArgoCD instance is configured via the ARGOAPI_ENDPOINT, ARGOCD_NAMESPACE and related env vars.")
+	flag.StringVar(&configFile, "config", "",
+		"Path of the OperatorConfig file. Its settings replace the flags which are not set in the command line "+
+			"and the env vars of ArgoCD, which are reloaded when the file changes.")
+	flag.BoolVar(&enableTracing, "enable-tracing", false,
+		"Export the OpenTelemetry spans of the reconciliations and of the calls to ArgoCD via OTLP. The exporter "+
+			"is configured with the standard env vars, i.e. OTEL_EXPORTER_OTLP_ENDPOINT.")
+	flag.IntVar(&eventBurst, "event-burst", argocdcontroller.DefaultEventBurst,
+		"The number of events of the same type and reason each object can raise before they are throttled.")
+	flag.DurationVar(&eventInterval, "event-interval", argocdcontroller.DefaultEventInterval,
+		"The interval at which each object can raise again an event of the same type and reason once its "+
+			"burst is exhausted. The identical events are correlated into a single one with its count.")
+	flag.BoolVar(&argoCDReadinessCheck, "argocd-readiness-check", true,
+		"Report the Manager as not ready while the API of any ArgoCD instance configured is not reachable.")
+	flag.BoolVar(&enablePprof, "enable-pprof", false,
+		"Serve the net/http/pprof profiles of the Manager, i.e. to profile its memory and CPU usage when it manages "+
+			"thousands of Registers. They are only served on the loopback interface.")
+	flag.StringVar(&pprofAddr, "pprof-bind-address", config.DefaultPprofBindAddress,
+		"The loopback address the pprof endpoint binds to when it is enabled.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv(watchNamespaceEnvVar),
+		"Comma-separated namespaces where the Clusters, the Registers and the Secrets are watched, so that the "+
+			"Manager does not need to list and watch them cluster-wide. Defaults to the WATCH_NAMESPACE env var, "+
+			"all the namespaces are watched when it is empty.")
+	flag.StringVar(&watchNamespaceSelector, "watch-namespace-selector", os.Getenv(watchNamespaceSelectorEnvVar),
+		"Label selector of the namespaces watched in addition to the --watch-namespaces, i.e. "+
+			"workload.com/managed=true. The cache is rebuilt once the namespaces gain or lose the labels. "+
+			"Defaults to the WATCH_NAMESPACE_SELECTOR env var.")
+	flag.StringVar(&secretLabelSelector, "secret-label-selector", "",
+		"Label selector of the Secrets cached and watched, i.e. cluster.x-k8s.io/cluster-name, so that the memory "+
+			"of the Manager does not grow with all the Secrets of the cluster. The Secrets which do not match it are "+
+			"read from the kube-apiserver, but their changes are only noticed on the next reconciliation.")
+	flag.DurationVar(&argoCDCacheTTL, "argocd-cache-ttl", argocd.DefaultAPIManagerCacheTTL,
+		"How long the endpoint, the credentials and the TLS configuration of each ArgoCD instance are reused by the "+
+			"reconciliations before they are read again. They are read again at once when the credentials change, "+
+			"and on every reconciliation when it is 0.")
+	flag.DurationVar(&argoCDClusterListTTL, "argocd-cluster-list-ttl", argocd.DefaultClusterListTTL,
+		"How long the snapshot of the clusters registered within each ArgoCD instance is used to check the "+
+			"registration of the clusters before they are listed again. Each cluster is fetched from ArgoCD "+
+			"when it is 0.")
+	flag.IntVar(&bulkRegistrationWorkers, "bulk-registration-workers", argocdcontroller.DefaultBulkRegistrationWorkers,
+		"Number of Clusters registered concurrently once the Manager starts, diffing all the Clusters against the "+
+			"clusters registered within ArgoCD rather than waiting for each one to be reconciled, i.e. when the "+
+			"operator is first deployed into a fleet. The Clusters are only registered by the controller when it is 0.")
+	flag.IntVar(&registrationWorkers, "registration-workers", 0,
+		"Number of workers which perform the calls to ArgoCD of the reconciliations of the Clusters, so that the "+
+			"controller keeps reconciling the events of the Clusters while ArgoCD is slow to respond. The results "+
+			"are reported in the status of the Registers. The calls are performed by the controller when it is 0.")
+	flag.IntVar(&circuitBreakerThreshold, "argocd-circuit-breaker-threshold", argocd.DefaultCircuitBreakerThreshold,
+		"Number of consecutive requests to an ArgoCD endpoint which fail because it is unavailable before the "+
+			"requests to it are failed fast, rather than each reconciliation waiting for them to time out. "+
+			"The requests are always sent when it is 0.")
+	flag.DurationVar(&circuitBreakerCooldown, "argocd-circuit-breaker-cooldown", argocd.DefaultCircuitBreakerCooldown,
+		"How long the requests to an unavailable ArgoCD endpoint are failed fast before a single request is sent "+
+			"to probe whether it is available again.")
+	flag.DurationVar(&orphanSweepInterval, "orphan-sweep-interval", argocdcontroller.DefaultOrphanSweepInterval,
+		"Interval between the sweeps which finalize and delete the Registers whose Cluster no longer exists, i.e. "+
+			"when the Cluster was deleted while the operator was down. The sweeps are disabled when it is 0.")
+	flag.DurationVar(&argoCDOrphanSweepInterval, "argocd-orphan-sweep-interval", 0,
+		"Interval between the sweeps which remove from ArgoCD the clusters labeled as managed by the operator whose "+
+			"Register, ExternalCluster or Cluster no longer exists. The clusters found orphaned by two sweeps in a row "+
+			"are removed. It must only be enabled when the ArgoCD instances are not shared with another operator. "+
+			"The sweeps are disabled when it is 0.")
+	flag.BoolVar(&adoptArgoCDClusters, "adopt-argocd-clusters", false,
+		"Adopt the clusters already registered within ArgoCD, i.e. via the ArgoCD CLI, whose server is the control "+
+			"plane endpoint of a Cluster. Their Register keeps the name, the project and the namespaces of the "+
+			"cluster entry and updates it rather than registering the Cluster again.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -67,52 +215,344 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "b1698346.workload.com",
-		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-		// when the Manager ends. This requires the binary to immediately end when the
-		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-		// speeds up voluntary leader transitions as the new leader don't have to wait
-		// LeaseDuration time first.
-		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
-	})
-	if err != nil {
-		setupLog.Error(err, "unable to start manager")
-		os.Exit(1)
+	enableWebhooks := os.Getenv("ENABLE_WEBHOOKS") != "false"
+	var operatorConfig *config.OperatorConfig
+	if configFile != "" {
+		var err error
+		if operatorConfig, err = config.Load(configFile); err != nil {
+			setupLog.Error(err, "unable to load the configuration file")
+			os.Exit(1)
+		}
+		if err := applyConfig(operatorConfig); err != nil {
+			setupLog.Error(err, "unable to apply the configuration file")
+			os.Exit(1)
+		}
+		if _, exists := operatorConfig.FeatureGates[config.FeatureWebhooks]; exists {
+			enableWebhooks = operatorConfig.FeatureEnabled(config.FeatureWebhooks)
+		}
 	}
+	argocd.SetTokenFile(argocdTokenFile)
+	argocd.EnableAPIManagerCache(argoCDCacheTTL)
+	argocd.EnableClusterListCache(argoCDClusterListTTL)
+	argocd.EnableCircuitBreaker(circuitBreakerThreshold, circuitBreakerCooldown)
 
-	if err = (&argocdcontroller.RegisterReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("argocd-register-controller"),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Register")
-		os.Exit(1)
+	shutdownTracing := func(context.Context) error { return nil }
+	if enableTracing {
+		var err error
+		if shutdownTracing, err = tracing.Setup(context.Background()); err != nil {
+			setupLog.Error(err, "unable to set up the tracing")
+			os.Exit(1)
+		}
 	}
-	//+kubebuilder:scaffold:builder
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up health check")
-		os.Exit(1)
+	// The pprof endpoint is disabled when no address is informed to the Manager
+	var pprofBindAddress string
+	if enablePprof {
+		if err := config.ValidateLoopbackAddress(pprofAddr); err != nil {
+			setupLog.Error(err, "invalid pprof bind address", "address", pprofAddr)
+			os.Exit(1)
+		}
+		pprofBindAddress = pprofAddr
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
-		os.Exit(1)
+
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.QPS = float32(kubeAPIQPS)
+	restConfig.Burst = kubeAPIBurst
+
+	// The namespaces selected by label are watched in addition to the ones informed, the Namespaces are
+	// listed with a client which does not use the cache since it depends on them
+	var namespaceSelector labels.Selector
+	var namespaceReader client.Reader
+	if watchNamespaceSelector != "" {
+		var err error
+		if namespaceSelector, err = labels.Parse(watchNamespaceSelector); err != nil {
+			setupLog.Error(err, "invalid watch namespace selector", "selector", watchNamespaceSelector)
+			os.Exit(1)
+		}
+		if os.Getenv(argocd.NamespaceEnvVar) == "" {
+			setupLog.Error(nil, "the namespace of ArgoCD must be provided via "+argocd.NamespaceEnvVar+
+				" when the namespaces watched are selected by label")
+			os.Exit(1)
+		}
+		if namespaceReader, err = client.New(restConfig, client.Options{Scheme: scheme}); err != nil {
+			setupLog.Error(err, "unable to create the client to list the namespaces")
+			os.Exit(1)
+		}
+	}
+
+	var secretSelector labels.Selector
+	if secretLabelSelector != "" {
+		var err error
+		if secretSelector, err = labels.Parse(secretLabelSelector); err != nil {
+			setupLog.Error(err, "invalid secret label selector", "selector", secretLabelSelector)
+			os.Exit(1)
+		}
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	var err error
+	for {
+		var selectedNamespaces []string
+		if namespaceSelector != nil {
+			if selectedNamespaces, err = argocdcontroller.SelectedNamespaces(ctx, namespaceReader,
+				namespaceSelector); err != nil {
+				setupLog.Error(err, "unable to list the namespaces selected", "selector", watchNamespaceSelector)
+				os.Exit(1)
+			}
+		}
+		cacheOptions := argocdcontroller.NewCacheOptions(cacheNamespaces(watchNamespaces, selectedNamespaces),
+			secretSelector)
+		if len(cacheOptions.Namespaces) > 0 {
+			setupLog.Info("Watching only the namespaces informed", "namespaces", cacheOptions.Namespaces)
+		}
+
+		// The broadcaster is informed so that the events, i.e. the ones raised on every failed reconciliation
+		// while ArgoCD is down, are throttled as defined by the flags rather than by the defaults of client-go
+		eventBroadcaster := argocdcontroller.NewEventBroadcaster(eventBurst, eventInterval)
+		var mgr ctrl.Manager
+		mgr, err = ctrl.NewManager(restConfig, ctrl.Options{
+			Scheme:                 scheme,
+			EventBroadcaster:       eventBroadcaster, //nolint:staticcheck // it is shut down once the Manager ends
+			MetricsBindAddress:     metricsAddr,
+			Port:                   9443,
+			HealthProbeBindAddress: probeAddr,
+			PprofBindAddress:       pprofBindAddress,
+			Cache:                  cacheOptions,
+			NewClient:              argocdcontroller.NewClient(secretSelector),
+			LeaderElection:         enableLeaderElection,
+			LeaderElectionID:       "b1698346.workload.com",
+			// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
+			// when the Manager ends. This requires the binary to immediately end when the
+			// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
+			// speeds up voluntary leader transitions as the new leader don't have to wait
+			// LeaseDuration time first.
+			//
+			// In the default scaffold provided, the program ends immediately after
+			// the manager stops, so would be fine to enable this option. However,
+			// if you are doing or is intended to do any operation such as perform cleanups
+			// after the manager stops then its usage might be unsafe.
+			// LeaderElectionReleaseOnCancel: true,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to start manager")
+			os.Exit(1)
+		}
+		// The namespace where ArgoCD is deployed, and whether it runs in core mode, are detected when they are
+		// not provided via ARGOCD_NAMESPACE and ARGOCD_CORE_MODE
+		argocd.EnableNamespaceDetection(mgr.GetAPIReader())
+		argocd.EnableCoreModeDetection(mgr.GetAPIReader())
+		// The clusters registered are labeled with the namespace of the Manager, so that the installations which
+		// share an ArgoCD instance do not remove the clusters of each other
+		if namespace := managerNamespace(); namespace != "" {
+			argocd.SetInstallation(namespace)
+		} else {
+			setupLog.Info("the namespace of the Manager is unknown, the orphaned clusters are not removed from "+
+				"ArgoCD", "envvar", podNamespaceEnvVar)
+		}
+
+		registerReconciler := &argocdcontroller.RegisterReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("argocd-register-controller"),
+
+			MaxConcurrentReconciles: maxConcurrentReconciles,
+			RateLimiter:             argocdcontroller.NewRateLimiter(rateLimiterBaseDelay, rateLimiterMaxDelay),
+			FinalizerRetryBudget:    int32(finalizerRetryBudget),
+			PermanentFailureBudget:  int32(permanentFailureBudget),
+			ArgoCDInstance:          argoCDInstance,
+			RegistrationWorkers:     registrationWorkers,
+			AdoptClusters:           adoptArgoCDClusters,
+		}
+		if err = registerReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Register")
+			os.Exit(1)
+		}
+		if bulkRegistrationWorkers > 0 {
+			if err = mgr.Add(&argocdcontroller.BulkRegistrar{
+				Reconciler: registerReconciler,
+				Log:        ctrl.Log.WithName("bulk-registration"),
+				Workers:    bulkRegistrationWorkers,
+			}); err != nil {
+				setupLog.Error(err, "unable to add the bulk registration of the Clusters")
+				os.Exit(1)
+			}
+		}
+		if orphanSweepInterval > 0 {
+			if err = mgr.Add(&argocdcontroller.OrphanCollector{
+				Reconciler: registerReconciler,
+				Log:        ctrl.Log.WithName("orphan-collector"),
+				Interval:   orphanSweepInterval,
+			}); err != nil {
+				setupLog.Error(err, "unable to add the collector of the orphaned Registers")
+				os.Exit(1)
+			}
+		}
+		if argoCDOrphanSweepInterval > 0 {
+			if err = mgr.Add(&argocdcontroller.ArgoCDClusterCollector{
+				Reconciler: registerReconciler,
+				Log:        ctrl.Log.WithName("argocd-cluster-collector"),
+				Interval:   argoCDOrphanSweepInterval,
+			}); err != nil {
+				setupLog.Error(err, "unable to add the collector of the orphaned clusters within ArgoCD")
+				os.Exit(1)
+			}
+		}
+		if err = (&argocdcontroller.ExternalClusterReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("argocd-externalcluster-controller"),
+
+			MaxConcurrentReconciles: maxConcurrentReconciles,
+			RateLimiter:             argocdcontroller.NewRateLimiter(rateLimiterBaseDelay, rateLimiterMaxDelay),
+			ArgoCDInstance:          argoCDInstance,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ExternalCluster")
+			os.Exit(1)
+		}
+		if err = (&argocdcontroller.ArgoCDInstanceReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("argocd-argocdinstance-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ArgoCDInstance")
+			os.Exit(1)
+		}
+		if registerManagementCluster {
+			if err = mgr.Add(&argocdcontroller.ManagementClusterRegistrar{
+				Client: mgr.GetClient(),
+				Log:    ctrl.Log.WithName("management-cluster"),
+				Name:   managementClusterName,
+
+				ArgoCDInstance: argoCDInstance,
+			}); err != nil {
+				setupLog.Error(err, "unable to add the registration of the management cluster")
+				os.Exit(1)
+			}
+		}
+		if operatorConfig != nil {
+			if err = mgr.Add(config.NewWatcher(configFile, operatorConfig, ctrl.Log.WithName("config"))); err != nil {
+				setupLog.Error(err, "unable to add the reload of the configuration file")
+				os.Exit(1)
+			}
+		}
+		if enableWebhooks {
+			var namespaces []string
+			if allowedSecretNamespaces != "" {
+				namespaces = strings.Split(allowedSecretNamespaces, ",")
+			}
+			if err = (&argocdv1beta1.Register{}).SetupWebhookWithManager(mgr, namespaces); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", "Register")
+				os.Exit(1)
+			}
+		}
+		//+kubebuilder:scaffold:builder
+
+		if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+			setupLog.Error(err, "unable to set up health check")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+			setupLog.Error(err, "unable to set up ready check")
+			os.Exit(1)
+		}
+		if argoCDReadinessCheck {
+			readinessChecker := &argocdcontroller.ArgoCDReadinessChecker{
+				Client:         mgr.GetClient(),
+				Log:            ctrl.Log.WithName("readiness"),
+				ArgoCDInstance: argoCDInstance,
+			}
+			if err := mgr.Add(readinessChecker); err != nil {
+				setupLog.Error(err, "unable to add the ping of the ArgoCD instances")
+				os.Exit(1)
+			}
+			if err := mgr.AddReadyzCheck("argocd", readinessChecker.Check); err != nil {
+				setupLog.Error(err, "unable to set up the ArgoCD ready check")
+				os.Exit(1)
+			}
+		}
+
+		// The Manager is created again, with a cache which watches the namespaces selected, once they change
+		managerCtx, stopManager := context.WithCancel(ctx)
+		if namespaceSelector != nil {
+			if err = (&argocdcontroller.NamespaceSelectionReconciler{
+				Client:     mgr.GetClient(),
+				Selector:   namespaceSelector,
+				Namespaces: selectedNamespaces,
+				OnChange:   stopManager,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "NamespaceSelection")
+				os.Exit(1)
+			}
+		}
+
+		setupLog.Info("starting manager")
+		err = mgr.Start(managerCtx)
+		eventBroadcaster.Shutdown()
+		rebuild := ctx.Err() == nil && managerCtx.Err() != nil
+		stopManager()
+		if err != nil || !rebuild {
+			break
+		}
+		setupLog.Info("restarting manager since the namespaces selected changed")
 	}
 
-	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	// The pending spans are flushed before the Manager ends
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		setupLog.Error(err, "unable to flush the spans")
+	}
+	cancel()
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// cacheNamespaces returns the namespaces informed, comma-separated, and the ones selected by label where
+// the objects are watched. The namespace of ArgoCD provided via ARGOCD_NAMESPACE is watched as well, since
+// its Secrets are read from the cache of the Manager, i.e. the credentials of the ArgoCD account. It
+// returns nil to watch all namespaces, which is when neither namespaces nor a selector are informed.
+func cacheNamespaces(watchNamespaces string, selectedNamespaces []string) []string {
+	namespaces := sets.New(selectedNamespaces...)
+	for _, namespace := range strings.Split(watchNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces.Insert(namespace)
+		}
+	}
+	if namespaces.Len() == 0 && selectedNamespaces == nil {
+		return nil
+	}
+	if argocdNamespace := os.Getenv(argocd.NamespaceEnvVar); argocdNamespace != "" {
+		namespaces.Insert(argocdNamespace)
+	}
+	return sets.List(namespaces)
+}
+
+// managerNamespace returns the namespace where the Manager runs, provided via POD_NAMESPACE or read from the
+// ServiceAccount mounted. It returns empty when it is unknown, i.e. when the Manager runs out of the cluster.
+func managerNamespace() string {
+	if namespace := os.Getenv(podNamespaceEnvVar); namespace != "" {
+		return namespace
+	}
+	namespace, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(namespace))
+}
+
+// applyConfig sets the flags which were not set in the command line with the settings of the Manager of
+// the configuration file, and overrides the env vars of ArgoCD with its settings
+func applyConfig(operatorConfig *config.OperatorConfig) error {
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	for name, value := range operatorConfig.Manager.Flags() {
+		if setFlags[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return err
+		}
+	}
+	return config.ApplyArgoCDSettings(&operatorConfig.ArgoCD)
+}
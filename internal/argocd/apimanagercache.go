@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAPIManagerCacheTTL is how long the configuration of the connection to an ArgoCD instance is reused
+// before its Secrets are read again
+const DefaultAPIManagerCacheTTL = 5 * time.Minute
+
+// apiManagerEnvVarPrefixes are the prefixes of the env vars which configure the connection to the ArgoCD
+// instance configured via Manager ENV VAR
+var apiManagerEnvVarPrefixes = []string{"ARGOCD_", "ARGOAPI_", "VAULT_"}
+
+// apiManagerEntry stores the APIManager configured to connect to an ArgoCD instance, without any cluster
+type apiManagerEntry struct {
+	apiManager APIManager
+	expiresAt  time.Time
+}
+
+// apiManagerStore caches the APIManagers by ArgoCD instance, so that the endpoint, the credentials and the
+// TLS configuration are not gathered again from the Secrets on the reconciliation of every cluster. It is
+// disabled until a TTL is informed. It is safe for concurrent use.
+type apiManagerStore struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	apiManagers map[string]apiManagerEntry
+}
+
+// apiManagers is shared by all the reconciliations
+var apiManagers = &apiManagerStore{apiManagers: map[string]apiManagerEntry{}}
+
+// EnableAPIManagerCache reuses the configuration of the connection to each ArgoCD instance for the TTL
+// informed, it is disabled when the TTL is not positive
+func EnableAPIManagerCache(ttl time.Duration) {
+	apiManagers.mu.Lock()
+	defer apiManagers.mu.Unlock()
+	apiManagers.ttl = ttl
+	apiManagers.apiManagers = map[string]apiManagerEntry{}
+}
+
+// InvalidateAPIManagers drops the cached APIManagers so that the configuration of the connections is gathered
+// again, i.e. when the Secret with the credentials of an ArgoCD account changes.
+func InvalidateAPIManagers() {
+	apiManagers.mu.Lock()
+	defer apiManagers.mu.Unlock()
+	apiManagers.apiManagers = map[string]apiManagerEntry{}
+}
+
+// get returns the APIManager cached for the key when it did not expire
+func (s *apiManagerStore) get(key string, now time.Time) (APIManager, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.apiManagers[key]
+	if !ok || s.ttl <= 0 || !now.Before(cached.expiresAt) {
+		return APIManager{}, false
+	}
+	return cached.apiManager, true
+}
+
+// set caches a copy of the configuration of the connection of the APIManager
+func (s *apiManagerStore) set(key string, apiManager *APIManager, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ttl <= 0 {
+		return
+	}
+	cached := APIManager{}
+	cached.copyConnection(apiManager)
+	s.apiManagers[key] = apiManagerEntry{apiManager: cached, expiresAt: now.Add(s.ttl)}
+}
+
+// copyConnection copies the configuration of the connection to the ArgoCD instance of the APIManager informed
+func (a *APIManager) copyConnection(from *APIManager) {
+	a.Token = from.Token
+	a.Endpoint = from.Endpoint
+	a.Namespace = from.Namespace
+	a.TLSConfig = from.TLSConfig
+	a.ProxyURL = from.ProxyURL
+	a.Timeout = from.Timeout
+	a.RetryPolicy = from.RetryPolicy
+	a.username = from.username
+	a.password = from.password
+	a.caBundle = from.caBundle
+}
+
+// apiManagerKey returns the key of the APIManager of the ArgoCD instance informed. The instance configured
+// via Manager ENV VAR is identified by its env vars, so that a configuration reloaded is applied at once. The
+// key is hashed since the env vars may hold credentials, i.e. VAULT_TOKEN.
+func apiManagerKey(instance *Instance) string {
+	var settings []string
+	for _, env := range os.Environ() {
+		for _, prefix := range apiManagerEnvVarPrefixes {
+			if strings.HasPrefix(env, prefix) {
+				settings = append(settings, env)
+				break
+			}
+		}
+	}
+	sort.Strings(settings)
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "env=%s;tokenFile=%s;", strings.Join(settings, ","), tokenFile)
+	if instance != nil {
+		_, _ = fmt.Fprintf(hash, "instance=%s/%s,%s,%s,%s,%s,%s,%t,%t;", instance.Namespace, instance.Name,
+			instance.Endpoint, instance.credentialsSecretName(), instance.CAConfigMapName, instance.CASecretName,
+			instance.ClientCertSecretName, instance.InsecureSkipVerify, instance.AllowInsecureEndpoint)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
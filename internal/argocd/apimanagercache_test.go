@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("APIManager cache", func() {
+	ctx := context.Background()
+	instance := &Instance{Name: "cached", Endpoint: "https://argocd-server.cached.svc", Namespace: "argocd-cached",
+		CredentialsSecretName: "cached-credentials"}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cached-credentials", Namespace: "argocd-cached"},
+		Data:       map[string][]byte{PasswordSecretKey: []byte("first")},
+	}
+	newCluster := func(name string) *clusterapiv1.Cluster {
+		return &clusterapiv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tenant"},
+			Spec: clusterapiv1.ClusterSpec{
+				ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: name, Port: 6443},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "argocd-cached"}}
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, namespace))).To(Succeed())
+		Expect(k8sClient.Create(ctx, secret.DeepCopy())).To(Succeed())
+		EnableAPIManagerCache(time.Minute)
+	})
+
+	AfterEach(func() {
+		EnableAPIManagerCache(0)
+		Expect(k8sClient.Delete(ctx, secret.DeepCopy())).To(Succeed())
+	})
+
+	It("should reuse the connection to the ArgoCD instance until it is invalidated", func() {
+		apiManager, err := newAPIManager(ctx, k8sClient, logr.Discard(), newCluster("first"), nil, instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apiManager.password).To(Equal("first"))
+
+		By("rotating the credentials of the ArgoCD account")
+		rotated := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), rotated)).To(Succeed())
+		rotated.Data[PasswordSecretKey] = []byte("second")
+		Expect(k8sClient.Update(ctx, rotated)).To(Succeed())
+
+		By("checking that the cached connection is used for another cluster")
+		apiManager, err = newAPIManager(ctx, k8sClient, logr.Discard(), newCluster("second"), nil, instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apiManager.password).To(Equal("first"))
+		Expect(apiManager.Name).To(Equal("second"))
+		Expect(apiManager.Server).To(Equal("second:6443"))
+
+		By("checking that the credentials are read again once the cache is invalidated")
+		InvalidateAPIManagers()
+		apiManager, err = newAPIManager(ctx, k8sClient, logr.Discard(), newCluster("second"), nil, instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apiManager.password).To(Equal("second"))
+	})
+
+	It("should configure again the connection of an ArgoCD instance which changed", func() {
+		_, err := newAPIManager(ctx, k8sClient, logr.Discard(), newCluster("first"), nil, instance)
+		Expect(err).NotTo(HaveOccurred())
+
+		changed := *instance
+		changed.Endpoint = "http://argocd-server.cached.svc"
+		_, err = newAPIManager(ctx, k8sClient, logr.Discard(), newCluster("first"), nil, &changed)
+		Expect(err).To(MatchError(ContainSubstring("allowInsecureEndpoint")))
+	})
+
+	It("should not cache the connections which fail to be configured", func() {
+		Expect(k8sClient.Delete(ctx, secret.DeepCopy())).To(Succeed())
+		_, err := newAPIManager(ctx, k8sClient, logr.Discard(), newCluster("first"), nil, instance)
+		Expect(err).To(HaveOccurred())
+
+		Expect(k8sClient.Create(ctx, secret.DeepCopy())).To(Succeed())
+		apiManager, err := newAPIManager(ctx, k8sClient, logr.Discard(), newCluster("first"), nil, instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apiManager.password).To(Equal("first"))
+	})
+})
@@ -25,21 +25,23 @@ package argocd
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
-	v1 "k8s.io/api/core/v1"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/client-go/tools/clientcmd"
 
 	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/workload-operator/internal/tracing"
 )
 
 const (
@@ -48,100 +50,204 @@ const (
 	NamespaceEnvVar = "ARGOCD_NAMESPACE"
 
 	// SecretNameEnvVar store the name of the envvar used to provide the SecretName used to get
-	// the token to authenticate within to Argo API
+	// the credentials to authenticate within to Argo API
 	SecretNameEnvVar = "ARGOCD_SECRET_NAME"
 
 	// APIEndpointEnvVar store the name of the envvar used to provide the API Endpoint
 	APIEndpointEnvVar = "ARGOAPI_ENDPOINT"
 
-	defaultSecretName      = "argocd-secret"
+	// RegistrationModeEnvVar store the name of the envvar used to provide the default mode
+	// used to register the clusters within ArgoCD (API or Declarative)
+	RegistrationModeEnvVar = "ARGOCD_REGISTRATION_MODE"
+
+	// UsernameSecretKey is the key of the credentials secret which stores the username of the
+	// ArgoCD account. When it is not present the defaultUsername is used.
+	UsernameSecretKey = "username"
+
+	// PasswordSecretKey is the key of the credentials secret which stores the password of the
+	// ArgoCD account.
+	PasswordSecretKey = "password"
+
+	// TokenSecretKey is the key of the credentials secret which stores an API token generated for
+	// an ArgoCD local account. When it is present the token is used instead of creating a session.
+	TokenSecretKey = "token"
+
+	// defaultSecretName is the secret created by ArgoCD to store the initial admin password
+	defaultSecretName      = "argocd-initial-admin-secret"
 	defaultNamespace       = "argocd"
 	defaultArgoAPIEndpoint = "https://argocd-api.example.com"
+	defaultUsername        = "admin"
 )
 
 // APIManager stores the required information to interact with the ArgoCD API.
 type APIManager struct {
-	Token      string          // The ArgoCD API token
-	Client     client.Client   // Kubernetes client
-	Ctx        context.Context // Context for the operations
-	Log        logr.Logger     // Logger for the manager
-	Server     string          // Server endpoint for ArgoCD
-	Name       string          // Name of the cluster
-	KubeConfig []byte          // Kubeconfig content in bytes
-	Endpoint   string          // ArgoCD API endpoint
+	Token       string            // The ArgoCD session token, obtained via login
+	Client      client.Client     // Kubernetes client
+	Ctx         context.Context   // Context used to gather the configuration, the requests use the one informed
+	Log         logr.Logger       // Logger for the manager
+	Server      string            // Server endpoint for ArgoCD
+	Name        string            // Name of the cluster
+	Labels      map[string]string // Labels of the Cluster propagated to the ArgoCD cluster entry
+	Annotations map[string]string // Annotations of the Cluster propagated to the ArgoCD cluster entry
+	KubeConfig  []byte            // Kubeconfig content in bytes
+	Endpoint    string            // ArgoCD API endpoint
+	Namespace   string            // Namespace where ArgoCD is deployed
+	TLSConfig   *tls.Config       // TLS configuration used to connect to the ArgoCD API
+	ProxyURL    *url.URL          // Proxy used to connect to the ArgoCD API, when not informed the environment is used
+	Timeout     time.Duration     // Timeout of the requests to the ArgoCD API
+	Instance    *Instance         // ArgoCD instance, when not informed it is configured via Manager ENV VAR
+
+	RetryPolicy RetryPolicy // Defines how the requests which fail due to transient errors are retried
+
+	Options ClusterOptions // Options to connect to the cluster
+
+	username string // ArgoCD account used to create the session
+	password string // Password of the ArgoCD account
+	caBundle []byte // CA bundle trusted to connect to the ArgoCD API
 }
 
 // NewAPIManagerWithCluster returns the Manager to allow to perform operations against the ArgoCD API.
 func NewAPIManagerWithCluster(ctx context.Context, client client.Client, log logr.Logger,
 	clusterAPI *clusterapiv1.Cluster, kubeConfig []byte) (*APIManager, error) {
+	return newAPIManager(ctx, client, log, clusterAPI, kubeConfig, nil)
+}
 
-	argoAPIEndpoint, exists := os.LookupEnv(APIEndpointEnvVar)
-	if !exists {
-		log.Info(fmt.Sprintf("Argo API Endpoint is not provided via Manager ENV VAR, "+
-			"using default value (%s)", defaultArgoAPIEndpoint))
-		argoAPIEndpoint = defaultArgoAPIEndpoint
-	}
-
+// newAPIManager returns the Manager to perform operations against the API of the ArgoCD instance informed,
+// or of the one configured via Manager ENV VAR when it is nil.
+func newAPIManager(ctx context.Context, client client.Client, log logr.Logger,
+	clusterAPI *clusterapiv1.Cluster, kubeConfig []byte, instance *Instance) (*APIManager, error) {
 	newArgo := &APIManager{
-		Client: client,
-		Ctx:    ctx,
-		Log:    log,
-		Server: clusterAPI.Spec.ControlPlaneEndpoint.Host + ":" +
-			strconv.Itoa(int(clusterAPI.Spec.ControlPlaneEndpoint.Port)),
-		Name:       clusterAPI.Name,
-		KubeConfig: kubeConfig,
-		Endpoint:   argoAPIEndpoint,
+		Client:      client,
+		Ctx:         ctx,
+		Log:         log,
+		Server:      ServerURL(clusterAPI),
+		Name:        clusterAPI.Name,
+		Labels:      propagatedLabels(clusterAPI.Labels),
+		Annotations: propagatedAnnotations(clusterAPI.Annotations),
+		KubeConfig:  kubeConfig,
+		Instance:    instance,
 	}
-	err := newArgo.setBareToken()
-
-	return newArgo, err
+	// The connection to the ArgoCD instance is the same for all the clusters, it is only configured again
+	// once the one cached expires or is invalidated
+	key := apiManagerKey(instance)
+	if cached, ok := apiManagers.get(key, time.Now()); ok {
+		newArgo.copyConnection(&cached)
+		return newArgo, nil
+	}
+	if err := newArgo.setConnection(); err != nil {
+		return newArgo, err
+	}
+	apiManagers.set(key, newArgo, time.Now())
+	return newArgo, nil
 }
 
-// setBareToken retrieves the ArgoCD API token from its namespace and sets it in the struct.
-func (a *APIManager) setBareToken() error {
-
-	argocdNamespace, exists := os.LookupEnv(NamespaceEnvVar)
-	if !exists {
-		a.Log.Info(fmt.Sprintf("Argo Instance Namespace is not provided via Manager ENV VAR, "+
-			"using default value (%s)", defaultNamespace))
-		argocdNamespace = defaultNamespace
+// setConnection sets the endpoint, the credentials and the configuration of the transport used to connect
+// to the ArgoCD instance
+func (a *APIManager) setConnection() error {
+	if err := a.setEndpoint(); err != nil {
+		return err
 	}
-
-	argocdSecretName, exists := os.LookupEnv(SecretNameEnvVar)
-	if !exists {
-		a.Log.Info(fmt.Sprintf("Argo Instance Secret Name is not provided via Manager ENV VAR, "+
-			"using default value (%s)", defaultSecretName))
-		argocdSecretName = defaultSecretName
+	if err := a.setCredentials(); err != nil {
+		return err
 	}
-
-	secret := &v1.Secret{}
-	if err := a.Client.Get(a.Ctx, client.ObjectKey{
-		Namespace: argocdNamespace,
-		Name:      argocdSecretName,
-	}, secret); err != nil {
-		return fmt.Errorf("error fetching secret: %w", err)
+	if err := a.setTLSConfig(); err != nil {
+		return err
+	}
+	if err := a.setProxy(); err != nil {
+		return err
+	}
+	timeout, err := getRequestTimeout()
+	if err != nil {
+		return err
 	}
+	a.Timeout = timeout
+	retryPolicy, err := getRetryPolicy()
+	a.RetryPolicy = retryPolicy
+	return err
+}
 
-	// Decode the token
-	tokenBase64, ok := secret.Data["admin.password"]
-	if !ok {
-		return fmt.Errorf("admin.password not found in secret")
+// setEndpoint sets the namespace and the endpoint of the ArgoCD instance, or of the one configured via
+// Manager ENV VAR when no instance is informed, and validates the endpoint.
+func (a *APIManager) setEndpoint() error {
+	if a.Instance != nil {
+		a.Endpoint, a.Namespace = a.Instance.Endpoint, a.Instance.Namespace
+	} else {
+		namespace, err := getNamespace(a.Ctx, a.Log)
+		if err != nil {
+			return err
+		}
+		a.Namespace = namespace
+		endpoint, err := getAPIEndpoint(a.Ctx, a.Client, a.Log, a.Namespace)
+		if err != nil {
+			return err
+		}
+		a.Endpoint = endpoint
 	}
+	return a.validateEndpoint()
+}
 
-	token, err := base64.StdEncoding.DecodeString(string(tokenBase64))
+// setCredentials retrieves the credentials of the ArgoCD account from the CredentialsProvider and sets
+// it in the struct. When an API token is provided it is used directly, otherwise, the session token is
+// obtained with the username and password before the first request to the ArgoCD API.
+func (a *APIManager) setCredentials() error {
+	provider, err := a.newCredentialsProvider()
 	if err != nil {
 		return err
 	}
+	ctx, span := tracing.Start(a.Ctx, "Fetch ArgoCD credentials")
+	defer span.End()
+	credentials, err := provider.Credentials(ctx)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
 
-	a.Token = string(token)
+	if credentials.Token != "" {
+		a.Token = credentials.Token
+		return nil
+	}
+	a.username = credentials.Username
+	a.password = credentials.Password
 	return nil
 }
 
+// newCredentialsProvider returns the CredentialsProvider of the ArgoCD instance, which sources the
+// credentials from its Secret, or the one defined via Manager ENV VAR when no instance is informed.
+func (a *APIManager) newCredentialsProvider() (CredentialsProvider, error) {
+	if a.Instance != nil {
+		return &SecretCredentialsProvider{Client: a.Client, Namespace: a.Namespace,
+			Name: a.Instance.credentialsSecretName()}, nil
+	}
+	return NewCredentialsProvider(a.Client, a.Namespace, a.Log)
+}
+
+// IsCredentialsSecret returns true when the object informed is the Secret which stores the
+// credentials of the ArgoCD account used by the operator.
+func IsCredentialsSecret(obj client.Object) bool {
+	if credentialsProvider() != CredentialsProviderSecret {
+		return false
+	}
+	namespace := currentNamespace()
+	name, exists := os.LookupEnv(SecretNameEnvVar)
+	if !exists {
+		name = defaultSecretName
+	}
+	return obj.GetNamespace() == namespace && obj.GetName() == name
+}
+
+// getNamespace returns the namespace where ArgoCD is deployed provided via Manager ENV VAR or, when it
+// is not provided, the one detected
+func getNamespace(ctx context.Context, log logr.Logger) (string, error) {
+	if argocdNamespace, exists := os.LookupEnv(NamespaceEnvVar); exists {
+		return argocdNamespace, nil
+	}
+	return detectNamespace(ctx, log)
+}
+
 // ValidateKubeConfigForClusterAPI checks if the kubeconfig retrieved is valid for the cluster.
 func (a *APIManager) ValidateKubeConfigForClusterAPI() error {
 	_, err := clientcmd.Load(a.KubeConfig)
 	if err != nil {
-		return fmt.Errorf("error loading kubeconfig: %w", err)
+		return fmt.Errorf("error loading kubeconfig: %w", RedactError(err))
 	}
 
 	// TODO: Add further checks
@@ -149,72 +255,381 @@ func (a *APIManager) ValidateKubeConfigForClusterAPI() error {
 	return nil
 }
 
-// RegisterCluster registers the Cluster to the ArgoCD.
-func (a *APIManager) RegisterCluster() error {
+// RegisterCluster registers the Cluster to the ArgoCD. The cluster is created with upsert semantics,
+// therefore, when it is already registered its entry is updated instead.
+func (a *APIManager) RegisterCluster(ctx context.Context) error {
 	if err := a.ValidateKubeConfigForClusterAPI(); err != nil {
 		return err
 	}
 
-	argocdCluster := map[string]interface{}{
-		"server":     a.Server,
-		"name":       a.Name,
-		"kubeconfig": a.KubeConfig,
-		"config": map[string]interface{}{
-			"bearerToken": a.Token,
-		},
+	desired, err := a.desiredCluster(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := clusterPayload(desired)
+	if err != nil {
+		return err
 	}
 
-	payload, err := json.Marshal(argocdCluster)
+	resp, err := a.doRequest(ctx, http.MethodPost, "/api/v1/clusters?upsert=true", payload)
 	if err != nil {
-		return fmt.Errorf("error marshalling payload: %w", err)
+		return err
 	}
+	defer a.closeResponse(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		a.forgetCluster()
+		return nil
+	case http.StatusConflict:
+		// Older ArgoCD versions or ArgoCD accounts without the update permission might reject
+		// the upsert when the cluster exists with a different configuration.
+		return a.updateExistingCluster(ctx, payload)
+	default:
+		return newAPIError("registering cluster", resp)
+	}
+}
 
-	url := a.Endpoint + "/api/v1/clusters"
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+// desiredCluster returns the cluster entry which is expected to be registered in ArgoCD, adapted to
+// its version. ArgoCD connects to the cluster with the credentials of the current context of the
+// kubeconfig unless the options informed define otherwise.
+func (a *APIManager) desiredCluster(ctx context.Context) (*Cluster, error) {
+	config, err := a.Options.clusterConfig(a.KubeConfig)
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
+	labels := map[string]string{}
+	for key, value := range a.Labels {
+		labels[key] = value
+	}
+	setManagedLabels(labels)
+	desired := &Cluster{
+		Server:           a.Server,
+		Name:             a.Name,
+		Labels:           labels,
+		Annotations:      a.Annotations,
+		Namespaces:       a.Options.Namespaces,
+		ClusterResources: a.Options.ClusterResources,
+		Project:          a.Options.Project,
+		Shard:            a.Options.Shard,
+		Config:           *config,
+	}
+	if err := a.adaptCluster(ctx, desired); err != nil {
+		return nil, err
+	}
+	return desired, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.Token)
+// clusterPayload returns the cluster entry which is sent to the ArgoCD API
+func clusterPayload(desired *Cluster) ([]byte, error) {
+	// Only the credentials extracted from the kubeconfig are sent, ArgoCD does not accept the kubeconfig
+	payload, err := json.Marshal(&clusterRequest{
+		Server:           desired.Server,
+		Name:             desired.Name,
+		Labels:           desired.Labels,
+		Annotations:      desired.Annotations,
+		Namespaces:       desired.Namespaces,
+		ClusterResources: desired.ClusterResources,
+		Project:          desired.Project,
+		Shard:            desired.Shard,
+		Config:           desired.Config,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling payload: %w", err)
+	}
+	return payload, nil
+}
 
-	client := &http.Client{
-		Timeout: time.Second * 30,
+// updateExistingCluster fetches the cluster entry which conflicted with the registration and updates it
+func (a *APIManager) updateExistingCluster(ctx context.Context, payload []byte) error {
+	existing, err := a.getCluster(ctx)
+	if err != nil {
+		return err
 	}
+	if existing == nil {
+		return fmt.Errorf("error registering cluster, ArgoCD reported a conflict but cluster %s was not found",
+			a.Server)
+	}
+	if existing.Name != a.Name {
+		a.Log.Info("Cluster is registered in ArgoCD with another name, updating it",
+			"server", a.Server, "name", existing.Name, "expectedName", a.Name)
+	}
+	return a.updateCluster(ctx, payload)
+}
 
-	resp, err := client.Do(req)
+// updateCluster updates the cluster entry registered in ArgoCD
+func (a *APIManager) updateCluster(ctx context.Context, payload []byte) error {
+	resp, err := a.doRequest(ctx, http.MethodPut, "/api/v1/clusters/"+url.PathEscape(a.Server), payload)
 	if err != nil {
-		return fmt.Errorf("error sending request: %w", err)
+		return err
 	}
-	defer func() {
-		_, err = io.Copy(io.Discard, resp.Body)
-		if err != nil {
-			a.Log.Error(err, "Error reading response body")
-		}
-		_ = resp.Body.Close()
-	}()
+	defer a.closeResponse(resp)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("error registering cluster, status: %s", resp.Status)
+		return newAPIError("updating cluster", resp)
 	}
-
+	a.forgetCluster()
 	return nil
 }
 
+// SyncCluster compares the cluster entry registered in ArgoCD with the desired one and updates it
+// when they differ, i.e. when it was edited out-of-band. It returns true when a drift was corrected.
+func (a *APIManager) SyncCluster(ctx context.Context) (bool, error) {
+	registered, cached, err := a.lookupCluster(ctx)
+	if err != nil {
+		return false, err
+	}
+	if registered == nil {
+		return false, fmt.Errorf("cluster %s is not registered in ArgoCD", a.Server)
+	}
+
+	desired, err := a.desiredCluster(ctx)
+	if err != nil {
+		return false, err
+	}
+	drift := clusterDrift(desired, registered)
+	// The snapshot of the clusters registered might be stale, therefore, the drift is confirmed with the
+	// cluster entry fetched before it is corrected
+	if len(drift) > 0 && cached {
+		if registered, err = a.getCluster(ctx); err != nil {
+			return false, err
+		}
+		if registered == nil {
+			return false, fmt.Errorf("cluster %s is not registered in ArgoCD", a.Server)
+		}
+		drift = clusterDrift(desired, registered)
+	}
+	if len(drift) == 0 {
+		return false, nil
+	}
+
+	a.Log.Info("Cluster entry in ArgoCD drifted from the desired state, updating it", "fields", drift)
+	payload, err := clusterPayload(desired)
+	if err != nil {
+		return false, err
+	}
+	if err := a.updateCluster(ctx, payload); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// doRequest sends an authenticated request to the given path of the ArgoCD API.
+// A session is created first when no valid session token is cached. If ArgoCD rejects
+// the session token then, a new session is created and the request is sent again.
+// The caller is responsible for closing the response body.
+func (a *APIManager) doRequest(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	if err := a.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := a.sendThroughCircuit(ctx, method, path, payload, a.Token)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized || a.password == "" {
+		return resp, nil
+	}
+
+	a.closeResponse(resp)
+	a.resetSession()
+	if err := a.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	return a.sendThroughCircuit(ctx, method, path, payload, a.Token)
+}
+
+// sendOnce sends the request to the given path of the ArgoCD API using the token informed, if any.
+// The caller is responsible for closing the response body.
+func (a *APIManager) sendOnce(ctx context.Context, method, path string, payload []byte,
+	token string) (*http.Response, error) {
+	ctx, span := tracing.Start(ctx, "ArgoCD "+method, attribute.String("http.method", method),
+		attribute.String("http.target", path), attribute.String("argocd.cluster", a.Name))
+	defer span.End()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewBuffer(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.Endpoint+path, body)
+	if err != nil {
+		return nil, tracing.RecordError(span, fmt.Errorf("error creating request: %w", err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	tracing.Inject(ctx, req.Header)
+
+	// The payload, i.e. the cluster entry, has the credentials of the cluster, therefore, it is redacted
+	a.Log.V(2).Info("Sending request to the ArgoCD API", "method", method, "path", path,
+		"payload", Redact(string(payload)))
+	resp, err := a.httpClient().Do(req)
+	if err != nil {
+		return nil, tracing.RecordError(span, fmt.Errorf("error sending request: %w", err))
+	}
+	a.Log.V(2).Info("Received response from the ArgoCD API", "method", method, "path", path,
+		"status", resp.Status)
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	return resp, nil
+}
+
+// closeResponse drains and closes the response body so that the connection can be reused.
+func (a *APIManager) closeResponse(resp *http.Response) {
+	_, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		a.Log.Error(err, "Error reading response body")
+	}
+	_ = resp.Body.Close()
+}
+
+// getCluster fetches the cluster entry from ArgoCD. It returns nil when the cluster is not found.
+func (a *APIManager) getCluster(ctx context.Context) (*Cluster, error) {
+	resp, err := a.doRequest(ctx, http.MethodGet, "/api/v1/clusters/"+url.PathEscape(a.Server), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer a.closeResponse(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, newAPIError("fetching cluster", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	cluster := &Cluster{}
+	if err := json.Unmarshal(body, cluster); err != nil {
+		return nil, fmt.Errorf("error decoding cluster: %w", err)
+	}
+	return cluster, nil
+}
+
 // IsClusterRegistered returns true when registered or an error if face issues to do the check.
-func (a *APIManager) IsClusterRegistered() (bool, error) {
-	// TODO: Implement check
-	return false, nil
+func (a *APIManager) IsClusterRegistered(ctx context.Context) (bool, error) {
+	cluster, _, err := a.lookupCluster(ctx)
+	if err != nil {
+		return false, err
+	}
+	return cluster != nil, nil
 }
 
-// CheckRegistration returns an error when issues were found into the registration.
-func (a *APIManager) CheckRegistration() error {
-	// TODO: Implement check
+// Verify returns an error when issues were found into the registration.
+// A *ConnectionError is returned when the cluster is registered but ArgoCD reports that
+// it is unable to connect to it or that the connection was not established yet. The versions
+// of ArgoCD which do not report the connection state are not gated on it.
+func (a *APIManager) Verify(ctx context.Context) error {
+	cluster, _, err := a.lookupCluster(ctx)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("cluster %s is not registered in ArgoCD", a.Server)
+	}
+
+	if state := cluster.GetConnectionState(); state.Status != "" && state.Status != ConnectionStatusSuccessful {
+		return &ConnectionError{Status: state.Status, Message: state.Message}
+	}
 	return nil
 }
 
+// Describe returns the server which identifies the cluster within the ArgoCD API and the version of ArgoCD.
+func (a *APIManager) Describe(ctx context.Context) (*RegistrationInfo, error) {
+	serverVersion, err := a.fetchServerVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.recordServerVersion(serverVersion)
+	return &RegistrationInfo{ClusterID: a.Server, ServerVersion: serverVersion}, nil
+}
+
+// fetchServerVersion returns the version of ArgoCD reported by its API
+func (a *APIManager) fetchServerVersion(ctx context.Context) (string, error) {
+	resp, err := a.doRequest(ctx, http.MethodGet, "/api/version", nil)
+	if err != nil {
+		return "", err
+	}
+	defer a.closeResponse(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newAPIError("fetching version", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body: %w", err)
+	}
+
+	version := struct {
+		Version string `json:"Version"`
+	}{}
+	if err := json.Unmarshal(body, &version); err != nil {
+		return "", fmt.Errorf("error decoding version: %w", err)
+	}
+	return version.Version, nil
+}
+
 // UnRegisterCluster unregisters a cluster from the ArgoCD instance or returns an error for failure scenarios.
-func (a *APIManager) UnRegisterCluster() error {
-	// TODO: Implement request to unregisterCluster
+// It does not return an error when the cluster is not registered.
+func (a *APIManager) UnRegisterCluster(ctx context.Context) error {
+	resp, err := a.doRequest(ctx, http.MethodDelete, "/api/v1/clusters/"+url.PathEscape(a.Server), nil)
+	if err != nil {
+		return err
+	}
+	defer a.closeResponse(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+		a.forgetCluster()
+		return nil
+	default:
+		return newAPIError("deleting cluster", resp)
+	}
+}
+
+// ReleaseCluster removes the ManagedByLabel and the InstallationLabel from the cluster entry registered within
+// ArgoCD. Only its labels are updated, therefore, the credentials are not required. It does not return an error
+// when the cluster is not registered.
+func (a *APIManager) ReleaseCluster(ctx context.Context) error {
+	registered, err := a.getCluster(ctx)
+	if err != nil {
+		return err
+	}
+	if registered == nil {
+		return nil
+	}
+	if _, managed := registered.Labels[ManagedByLabel]; !managed {
+		return nil
+	}
+
+	labels := map[string]string{}
+	for key, value := range registered.Labels {
+		if key != ManagedByLabel && key != InstallationLabel {
+			labels[key] = value
+		}
+	}
+	payload, err := json.Marshal(&clusterRequest{Server: registered.Server, Name: registered.Name, Labels: labels})
+	if err != nil {
+		return fmt.Errorf("error marshalling cluster: %w", err)
+	}
+	resp, err := a.doRequest(ctx, http.MethodPut,
+		"/api/v1/clusters/"+url.PathEscape(a.Server)+"?updatedFields=labels", payload)
+	if err != nil {
+		return err
+	}
+	defer a.closeResponse(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("releasing cluster", resp)
+	}
+	a.forgetCluster()
 	return nil
 }
@@ -18,7 +18,23 @@ package argocd
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2"
@@ -28,9 +44,12 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("ArgoCD APIManager", func() {
+	ctx := context.Background()
+
 	Context("APIManager creation", func() {
 		argoNs := &corev1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
@@ -41,21 +60,21 @@ var _ = Describe("ArgoCD APIManager", func() {
 
 		ctx := context.Background()
 		var testLog logr.Logger
-		var secret corev1.Secret
+		var secret *corev1.Secret
 
 		BeforeEach(func() {
 			By("creating Argo namespace")
 			err := k8sClient.Create(ctx, argoNs)
-			Expect(err).To(Not(HaveOccurred()))
+			Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
 
 			By(" creating Argo the secret")
-			secret := &corev1.Secret{
+			secret = &corev1.Secret{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      defaultSecretName, // or "argocd-secret"
+					Name:      defaultSecretName, // or "argocd-initial-admin-secret"
 					Namespace: defaultNamespace,  // or "argocd"
 				},
 				Data: map[string][]byte{
-					"admin.password": []byte(base64.StdEncoding.EncodeToString([]byte("token-test"))),
+					PasswordSecretKey: []byte("password-test"),
 				},
 			}
 			err = k8sClient.Create(ctx, secret)
@@ -64,10 +83,7 @@ var _ = Describe("ArgoCD APIManager", func() {
 
 		AfterEach(func() {
 			By("cleaning up Argo Mock secret")
-			_ = k8sClient.Delete(ctx, &secret)
-
-			By("deleting the Namespace to perform the tests")
-			_ = k8sClient.Delete(ctx, argoNs)
+			_ = k8sClient.Delete(ctx, secret)
 		})
 
 		It("should create a new APIManager with the expected values", func() {
@@ -89,10 +105,797 @@ var _ = Describe("ArgoCD APIManager", func() {
 
 			By("checking expected results")
 			Expect(apiManager.Endpoint).To(Equal(defaultArgoAPIEndpoint))
-			Expect(apiManager.Token).To(Not(BeNil()))
+			Expect(apiManager.username).To(Equal(defaultUsername))
+			Expect(apiManager.password).To(Equal("password-test"))
 			Expect(apiManager.Name).To(Equal("test"))
 			Expect(apiManager.KubeConfig).To(Equal([]byte(mocks.MockKubeConfig)))
 			Expect(apiManager.Server).To(Equal("Host:80"))
 		})
+
+		It("should reject the ArgoCD API endpoints which are not HTTPS unless they are allowed", func() {
+			Expect(os.Setenv(APIEndpointEnvVar, "http://argocd-server.argocd.svc")).To(Succeed())
+			defer func() { _ = os.Unsetenv(APIEndpointEnvVar) }()
+			cluster := &clusterapiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+				Spec: clusterapiv1.ClusterSpec{
+					ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "Host", Port: 80},
+				},
+			}
+
+			By("checking that the credentials of the cluster are not sent in plain text")
+			_, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(MatchError(ContainSubstring("is not HTTPS")))
+			Expect(err).To(MatchError(ContainSubstring(AllowInsecureEndpointEnvVar)))
+
+			By("checking that an http endpoint is accepted when it is explicitly allowed")
+			Expect(os.Setenv(AllowInsecureEndpointEnvVar, "true")).To(Succeed())
+			defer func() { _ = os.Unsetenv(AllowInsecureEndpointEnvVar) }()
+			apiManager, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apiManager.Endpoint).To(Equal("http://argocd-server.argocd.svc"))
+
+			By("checking that an endpoint of the ArgoCDInstance is only allowed by its own option")
+			instance := &Instance{Endpoint: "http://argocd-server.gitops.svc", Namespace: defaultNamespace}
+			_, err = newAPIManager(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig), instance)
+			Expect(err).To(MatchError(ContainSubstring("allowInsecureEndpoint")))
+			instance.AllowInsecureEndpoint = true
+			_, err = newAPIManager(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig), instance)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should use the API token of the local account when it is provided", func() {
+			By("adding the API token to the secret")
+			tokenSecret := &corev1.Secret{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: defaultSecretName, Namespace: defaultNamespace}, tokenSecret)
+			Expect(err).NotTo(HaveOccurred())
+			tokenSecret.Data[TokenSecretKey] = []byte("api-token")
+			Expect(k8sClient.Update(ctx, tokenSecret)).To(Succeed())
+
+			By("creating a new APIManager instance with the cluster")
+			cluster := &clusterapiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+				Spec: clusterapiv1.ClusterSpec{
+					ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "Host", Port: 80},
+				},
+			}
+			apiManager, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(Not(HaveOccurred()))
+
+			By("checking that no session will be created")
+			Expect(apiManager.Token).To(Equal("api-token"))
+			Expect(apiManager.password).To(BeEmpty())
+		})
+
+		It("should trust the CA bundle provided to connect to the ArgoCD API", func() {
+			By("starting an ArgoCD API with a certificate signed by an unknown CA")
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v1/session" {
+					_, _ = fmt.Fprint(w, `{"token":"session-token"}`)
+					return
+				}
+				_, _ = fmt.Fprint(w, `{"server":"Host:80","name":"test"}`)
+			}))
+			defer server.Close()
+			Expect(os.Setenv(APIEndpointEnvVar, server.URL)).To(Succeed())
+			defer func() { _ = os.Unsetenv(APIEndpointEnvVar) }()
+
+			cluster := &clusterapiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+				Spec: clusterapiv1.ClusterSpec{
+					ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "Host", Port: 80},
+				},
+			}
+
+			By("checking that the certificate is not trusted by default")
+			apiManager, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(apiManager.Verify(ctx)).NotTo(Succeed())
+
+			By("creating the ConfigMap with the CA bundle")
+			caBundle := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "argocd-ca", Namespace: defaultNamespace},
+				Data: map[string]string{
+					CABundleKey: string(pem.EncodeToMemory(&pem.Block{
+						Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+				},
+			}
+			Expect(k8sClient.Create(ctx, caBundle)).To(Succeed())
+			defer func() { _ = k8sClient.Delete(ctx, caBundle) }()
+			Expect(os.Setenv(CAConfigMapNameEnvVar, caBundle.Name)).To(Succeed())
+			defer func() { _ = os.Unsetenv(CAConfigMapNameEnvVar) }()
+
+			By("checking that the certificate is trusted with the CA bundle")
+			apiManager, err = NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+		})
+
+		It("should skip the verification of the certificate only when it is explicitly enabled", func() {
+			By("starting an ArgoCD API with a self-signed certificate")
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v1/session" {
+					_, _ = fmt.Fprint(w, `{"token":"session-token"}`)
+					return
+				}
+				_, _ = fmt.Fprint(w, `{"server":"Host:80","name":"test"}`)
+			}))
+			defer server.Close()
+			Expect(os.Setenv(APIEndpointEnvVar, server.URL)).To(Succeed())
+			defer func() { _ = os.Unsetenv(APIEndpointEnvVar) }()
+
+			cluster := &clusterapiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+				Spec: clusterapiv1.ClusterSpec{
+					ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "Host", Port: 80},
+				},
+			}
+
+			By("checking that an invalid value is not accepted")
+			Expect(os.Setenv(InsecureSkipVerifyEnvVar, "maybe")).To(Succeed())
+			defer func() { _ = os.Unsetenv(InsecureSkipVerifyEnvVar) }()
+			_, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(HaveOccurred())
+
+			By("checking that the certificate is not verified when enabled")
+			Expect(os.Setenv(InsecureSkipVerifyEnvVar, "true")).To(Succeed())
+			apiManager, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(apiManager.TLSConfig.InsecureSkipVerify).To(BeTrue())
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+		})
+
+		It("should present the client certificate provided to the ArgoCD API", func() {
+			By("starting an ArgoCD API which requires a client certificate")
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v1/session" {
+					_, _ = fmt.Fprint(w, `{"token":"session-token"}`)
+					return
+				}
+				_, _ = fmt.Fprint(w, `{"server":"Host:80","name":"test"}`)
+			}))
+			server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+			server.StartTLS()
+			defer server.Close()
+			Expect(os.Setenv(APIEndpointEnvVar, server.URL)).To(Succeed())
+			defer func() { _ = os.Unsetenv(APIEndpointEnvVar) }()
+			Expect(os.Setenv(InsecureSkipVerifyEnvVar, "true")).To(Succeed())
+			defer func() { _ = os.Unsetenv(InsecureSkipVerifyEnvVar) }()
+
+			cluster := &clusterapiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+				Spec: clusterapiv1.ClusterSpec{
+					ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "Host", Port: 80},
+				},
+			}
+
+			By("checking that the request is rejected without the client certificate")
+			apiManager, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(apiManager.Verify(ctx)).NotTo(Succeed())
+
+			By("creating the Secret with the client certificate")
+			certPEM, keyPEM := newSelfSignedCertificate()
+			clientCert := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "argocd-client-cert", Namespace: defaultNamespace},
+				Type:       corev1.SecretTypeTLS,
+				Data: map[string][]byte{
+					corev1.TLSCertKey:       certPEM,
+					corev1.TLSPrivateKeyKey: keyPEM,
+				},
+			}
+			Expect(k8sClient.Create(ctx, clientCert)).To(Succeed())
+			defer func() { _ = k8sClient.Delete(ctx, clientCert) }()
+			Expect(os.Setenv(ClientCertSecretNameEnvVar, clientCert.Name)).To(Succeed())
+			defer func() { _ = os.Unsetenv(ClientCertSecretNameEnvVar) }()
+
+			By("checking that the request is accepted with the client certificate")
+			apiManager, err = NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(apiManager.TLSConfig.Certificates).To(HaveLen(1))
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+		})
+
+		It("should send the requests to the ArgoCD API through the proxy provided", func() {
+			By("starting a proxy which answers as the ArgoCD API")
+			var proxiedHost string
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proxiedHost = r.Host
+				if r.URL.Path == "/api/v1/session" {
+					_, _ = fmt.Fprint(w, `{"token":"session-token"}`)
+					return
+				}
+				_, _ = fmt.Fprint(w, `{"server":"Host:80","name":"test"}`)
+			}))
+			defer proxy.Close()
+			Expect(os.Setenv(APIEndpointEnvVar, "http://argocd.invalid")).To(Succeed())
+			defer func() { _ = os.Unsetenv(APIEndpointEnvVar) }()
+			Expect(os.Setenv(AllowInsecureEndpointEnvVar, "true")).To(Succeed())
+			defer func() { _ = os.Unsetenv(AllowInsecureEndpointEnvVar) }()
+
+			cluster := &clusterapiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+				Spec: clusterapiv1.ClusterSpec{
+					ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "Host", Port: 80},
+				},
+			}
+
+			By("checking that an invalid proxy is not accepted")
+			Expect(os.Setenv(ProxyURLEnvVar, "ftp://proxy.invalid")).To(Succeed())
+			defer func() { _ = os.Unsetenv(ProxyURLEnvVar) }()
+			_, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(HaveOccurred())
+
+			By("checking that the requests are sent through the proxy")
+			Expect(os.Setenv(ProxyURLEnvVar, proxy.URL)).To(Succeed())
+			apiManager, err := NewAPIManagerWithCluster(ctx, k8sClient, testLog, cluster, []byte(mocks.MockKubeConfig))
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+			Expect(proxiedHost).To(Equal("argocd.invalid"))
+		})
+	})
+
+	Context("Ping", func() {
+		It("should ping the ArgoCD API without authenticating", func() {
+			var authorization string
+			versionStatus := http.StatusOK
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/version" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				authorization = r.Header.Get("Authorization")
+				w.WriteHeader(versionStatus)
+				_, _ = fmt.Fprint(w, `{"Version":"v2.8.4+c279299"}`)
+			}))
+			defer server.Close()
+			instance := &Instance{Name: "ping", Endpoint: server.URL, Namespace: defaultNamespace,
+				CredentialsSecretName: "missing", InsecureSkipVerify: true}
+
+			By("checking that the credentials of the ArgoCD account are not required")
+			Expect(Ping(ctx, k8sClient, logr.Discard(), instance)).To(Succeed())
+			Expect(authorization).To(BeEmpty())
+
+			By("checking that an ArgoCD API which is not available is reported")
+			versionStatus = http.StatusServiceUnavailable
+			Expect(Ping(ctx, k8sClient, logr.Discard(), instance)).NotTo(Succeed())
+		})
+	})
+
+	Context("Registration verification", func() {
+		var server *httptest.Server
+		var connectionStatus string
+
+		BeforeEach(func() {
+			connectionStatus = ConnectionStatusSuccessful
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/version" {
+					_, _ = fmt.Fprint(w, `{"Version":"v2.8.4+c279299"}`)
+					return
+				}
+				if r.URL.Path != "/api/v1/clusters/Host:80" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = fmt.Fprintf(w, `{"server":"Host:80","name":"test",`+
+					`"info":{"connectionState":{"status":%q,"message":"dial tcp: i/o timeout"}}}`, connectionStatus)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		newAPIManager := func(clusterServer string) *APIManager {
+			return &APIManager{
+				Token:    "token-test",
+				Log:      logr.Discard(),
+				Server:   clusterServer,
+				Name:     "test",
+				Endpoint: server.URL,
+			}
+		}
+
+		It("should not return an error when ArgoCD is able to connect to the cluster", func() {
+			Expect(newAPIManager("Host:80").Verify(ctx)).To(Succeed())
+		})
+
+		It("should return a ConnectionError when ArgoCD is unable to connect to the cluster", func() {
+			connectionStatus = ConnectionStatusFailed
+			err := newAPIManager("Host:80").Verify(ctx)
+			Expect(err).To(HaveOccurred())
+
+			var connErr *ConnectionError
+			Expect(errors.As(err, &connErr)).To(BeTrue())
+			Expect(connErr.Status).To(Equal(ConnectionStatusFailed))
+			Expect(connErr.Message).To(Equal("dial tcp: i/o timeout"))
+		})
+
+		It("should return a ConnectionError when ArgoCD is not connected to the cluster yet", func() {
+			connectionStatus = ConnectionStatusUnknown
+			err := newAPIManager("Host:80").Verify(ctx)
+
+			var connErr *ConnectionError
+			Expect(errors.As(err, &connErr)).To(BeTrue())
+			Expect(connErr.Status).To(Equal(ConnectionStatusUnknown))
+		})
+
+		It("should return an error when the cluster is not registered", func() {
+			err := newAPIManager("Other:80").Verify(ctx)
+			Expect(err).To(HaveOccurred())
+
+			var connErr *ConnectionError
+			Expect(errors.As(err, &connErr)).To(BeFalse())
+		})
+
+		It("should describe the registration with the version of ArgoCD", func() {
+			info, err := newAPIManager("Host:80").Describe(ctx)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(info.ClusterID).To(Equal("Host:80"))
+			Expect(info.ServerVersion).To(Equal("v2.8.4+c279299"))
+		})
+	})
+
+	Context("Session", func() {
+		var server *httptest.Server
+		var logins int
+
+		BeforeEach(func() {
+			logins = 0
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v1/session":
+					logins++
+					body := &sessionRequest{}
+					if err := json.NewDecoder(r.Body).Decode(body); err != nil ||
+						body.Username != defaultUsername || body.Password != "password-test" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = fmt.Fprint(w, `{"token":"session-token"}`)
+				default:
+					if r.Header.Get("Authorization") != "Bearer session-token" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = fmt.Fprint(w, `{"server":"Host:80","name":"test"}`)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should create a session before calling the ArgoCD API", func() {
+			apiManager := &APIManager{
+				Log:      logr.Discard(),
+				Server:   "Host:80",
+				Endpoint: server.URL,
+				username: defaultUsername,
+				password: "password-test",
+			}
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+			Expect(apiManager.Token).To(Equal("session-token"))
+		})
+
+		It("should return an error when the credentials are not valid", func() {
+			apiManager := &APIManager{
+				Log:      logr.Discard(),
+				Server:   "Host:80",
+				Endpoint: server.URL,
+				username: defaultUsername,
+				password: "invalid",
+			}
+			Expect(apiManager.Verify(ctx)).NotTo(Succeed())
+			Expect(apiManager.Token).To(BeEmpty())
+		})
+
+		It("should reuse the cached session across APIManagers", func() {
+			for i := 0; i < 2; i++ {
+				apiManager := &APIManager{
+					Log:      logr.Discard(),
+					Server:   "Host:80",
+					Endpoint: server.URL,
+					username: defaultUsername,
+					password: "password-test",
+				}
+				Expect(apiManager.Verify(ctx)).To(Succeed())
+			}
+			Expect(logins).To(Equal(1))
+		})
+
+		It("should create a single session for the concurrent APIManagers", func() {
+			const concurrency = 10
+			errs := make(chan error, concurrency)
+			var wg sync.WaitGroup
+			for i := 0; i < concurrency; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					apiManager := &APIManager{
+						Log:      logr.Discard(),
+						Server:   "Host:80",
+						Endpoint: server.URL,
+						username: defaultUsername,
+						password: "password-test",
+					}
+					errs <- apiManager.Verify(ctx)
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(logins).To(Equal(1))
+		})
+
+		It("should create a new session when the credentials are rotated", func() {
+			apiManager := &APIManager{
+				Log:      logr.Discard(),
+				Server:   "Host:80",
+				Endpoint: server.URL,
+				username: defaultUsername,
+				password: "password-test",
+			}
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+
+			apiManager.password = "invalid"
+			Expect(apiManager.Verify(ctx)).NotTo(Succeed())
+			Expect(logins).To(Equal(2))
+		})
+
+		It("should create a new session when the cached sessions are invalidated", func() {
+			apiManager := &APIManager{
+				Log:      logr.Discard(),
+				Server:   "Host:80",
+				Endpoint: server.URL,
+				username: defaultUsername,
+				password: "password-test",
+			}
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+			Expect(logins).To(Equal(1))
+
+			InvalidateSessions()
+			Expect(apiManager.Verify(ctx)).To(Succeed())
+			Expect(logins).To(Equal(2))
+		})
+	})
+
+	Context("Cluster registration", func() {
+		var server *httptest.Server
+		var createStatus int
+		var upserts, updates, deletes int
+		var payload map[string]interface{}
+
+		BeforeEach(func() {
+			createStatus = http.StatusOK
+			upserts, updates, deletes = 0, 0, 0
+			payload = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/api/v1/clusters":
+					if r.URL.Query().Get("upsert") == "true" {
+						upserts++
+					}
+					_ = json.NewDecoder(r.Body).Decode(&payload)
+					w.WriteHeader(createStatus)
+				case r.Method == http.MethodGet && r.URL.Path == "/api/v1/clusters/Host:80":
+					_, _ = fmt.Fprint(w, `{"server":"Host:80","name":"previous"}`)
+				case r.Method == http.MethodPut && r.URL.Path == "/api/v1/clusters/Host:80":
+					updates++
+				case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/clusters/Host:80":
+					deletes++
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		newAPIManager := func() *APIManager {
+			return &APIManager{
+				Token:      "token-test",
+				Log:        logr.Discard(),
+				Server:     "Host:80",
+				Name:       "test",
+				KubeConfig: []byte(mocks.MockKubeConfig),
+				Endpoint:   server.URL,
+			}
+		}
+
+		It("should register the cluster with upsert semantics", func() {
+			Expect(newAPIManager().RegisterCluster(ctx)).To(Succeed())
+			Expect(upserts).To(Equal(1))
+			Expect(updates).To(BeZero())
+		})
+
+		It("should send the TLS client configuration instead of the kubeconfig", func() {
+			Expect(newAPIManager().RegisterCluster(ctx)).To(Succeed())
+			Expect(payload).NotTo(HaveKey("kubeconfig"))
+			Expect(payload).To(HaveKeyWithValue("server", "Host:80"))
+			Expect(payload).To(HaveKey("config"))
+
+			config := payload["config"].(map[string]interface{})
+			Expect(config).To(HaveKey("tlsClientConfig"))
+			tlsClientConfig := config["tlsClientConfig"].(map[string]interface{})
+			Expect(tlsClientConfig).To(HaveKey("caData"))
+			Expect(tlsClientConfig).To(HaveKey("certData"))
+			Expect(tlsClientConfig).To(HaveKey("keyData"))
+		})
+
+		It("should authenticate via IAM when the AWS auth config is informed", func() {
+			apiManager := newAPIManager()
+			apiManager.Options = ClusterOptions{AWSAuthConfig: &AWSAuthConfig{ClusterName: "eks-cluster",
+				RoleARN: "arn:aws:iam::123456789012:role/argocd"}}
+			Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+
+			config := payload["config"].(map[string]interface{})
+			Expect(config).To(HaveKeyWithValue("awsAuthConfig", map[string]interface{}{
+				"clusterName": "eks-cluster", "roleARN": "arn:aws:iam::123456789012:role/argocd"}))
+			Expect(config).NotTo(HaveKey("bearerToken"))
+			tlsClientConfig := config["tlsClientConfig"].(map[string]interface{})
+			Expect(tlsClientConfig).To(HaveKey("caData"))
+			Expect(tlsClientConfig).NotTo(HaveKey("certData"))
+			Expect(tlsClientConfig).NotTo(HaveKey("keyData"))
+		})
+
+		It("should connect to the cluster through the proxy informed", func() {
+			apiManager := newAPIManager()
+			apiManager.Options = ClusterOptions{ProxyURL: "http://proxy.example.com:3128"}
+			Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+
+			config := payload["config"].(map[string]interface{})
+			Expect(config).To(HaveKeyWithValue("proxyUrl", "http://proxy.example.com:3128"))
+		})
+
+		It("should restrict the namespaces allowed to be used by ArgoCD", func() {
+			apiManager := newAPIManager()
+			apiManager.Options = ClusterOptions{Namespaces: []string{"tenant-a", "tenant-b"}}
+			Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+			Expect(payload).To(HaveKeyWithValue("namespaces", []interface{}{"tenant-a", "tenant-b"}))
+			Expect(payload).NotTo(HaveKey("clusterResources"))
+
+			By("allowing ArgoCD to manage the cluster-scoped resources")
+			apiManager.Options.ClusterResources = true
+			Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+			Expect(payload).To(HaveKeyWithValue("clusterResources", true))
+		})
+
+		It("should propagate the labels and annotations of the Cluster", func() {
+			apiManager := newAPIManager()
+			apiManager.Labels = map[string]string{"region": "eu-west-1"}
+			apiManager.Annotations = map[string]string{"description.example.com/maintainer": "team-a"}
+			Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+			Expect(payload).To(HaveKeyWithValue("labels", map[string]interface{}{
+				"region": "eu-west-1", ManagedByLabel: ManagedByValue}))
+			Expect(payload).To(HaveKeyWithValue("annotations", map[string]interface{}{
+				"description.example.com/maintainer": "team-a"}))
+		})
+
+		It("should register the cluster where ArgoCD is running without credentials", func() {
+			apiManager := newAPIManager()
+			apiManager.KubeConfig = nil
+			apiManager.Options = ClusterOptions{InCluster: true}
+			Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+
+			config := payload["config"].(map[string]interface{})
+			Expect(config).NotTo(HaveKey("bearerToken"))
+			Expect(config).To(HaveKeyWithValue("tlsClientConfig", map[string]interface{}{"insecure": false}))
+		})
+
+		It("should register the cluster within the project informed", func() {
+			apiManager := newAPIManager()
+			apiManager.Options = ClusterOptions{Project: "tenant-a"}
+			Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+			Expect(payload).To(HaveKeyWithValue("project", "tenant-a"))
+		})
+
+		It("should return an error when the kubeconfig has no supported credentials", func() {
+			apiManager := newAPIManager()
+			apiManager.KubeConfig = []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://Host:80
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+`)
+			err := apiManager.RegisterCluster(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("has no token, client certificate data"))
+			Expect(upserts).To(BeZero())
+		})
+
+		It("should update the existing cluster when ArgoCD reports a conflict", func() {
+			createStatus = http.StatusConflict
+			Expect(newAPIManager().RegisterCluster(ctx)).To(Succeed())
+			Expect(updates).To(Equal(1))
+		})
+
+		It("should return an error when the registration fails", func() {
+			createStatus = http.StatusBadRequest
+			Expect(newAPIManager().RegisterCluster(ctx)).NotTo(Succeed())
+			Expect(updates).To(BeZero())
+		})
+
+		It("should delete the cluster entry when the cluster is unregistered", func() {
+			Expect(newAPIManager().UnRegisterCluster(ctx)).To(Succeed())
+			Expect(deletes).To(Equal(1))
+
+			By("checking that no error is returned when the cluster is not registered")
+			apiManager := newAPIManager()
+			apiManager.Server = "Other:80"
+			Expect(apiManager.UnRegisterCluster(ctx)).To(Succeed())
+			Expect(deletes).To(Equal(1))
+		})
+
+		It("should only update the labels of the cluster entry when it is released", func() {
+			var updatedFields string
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					updates++
+					updatedFields = r.URL.Query().Get("updatedFields")
+					_ = json.NewDecoder(r.Body).Decode(&payload)
+					return
+				}
+				_, _ = fmt.Fprintf(w, `{"server":"Host:80","name":"test","labels":{%q:%q,"region":"eu-west-1"}}`,
+					ManagedByLabel, ManagedByValue)
+			})
+
+			Expect(newAPIManager().ReleaseCluster(ctx)).To(Succeed())
+			Expect(updates).To(Equal(1))
+			Expect(updatedFields).To(Equal("labels"))
+			Expect(payload).To(HaveKeyWithValue("labels", map[string]interface{}{"region": "eu-west-1"}))
+
+			By("checking that the cluster entry is not updated when it is not managed")
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					updates++
+				}
+				_, _ = fmt.Fprint(w, `{"server":"Host:80","name":"test"}`)
+			})
+			Expect(newAPIManager().ReleaseCluster(ctx)).To(Succeed())
+			Expect(updates).To(Equal(1))
+		})
+
+		It("should update the cluster entry when it drifted from the desired state", func() {
+			apiManager := newAPIManager()
+			registered, err := apiManager.IsClusterRegistered(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registered).To(BeTrue())
+
+			drifted, err := apiManager.SyncCluster(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifted).To(BeTrue())
+			Expect(updates).To(Equal(1))
+		})
+
+		It("should not update the cluster entry when it matches the desired state", func() {
+			apiManager := newAPIManager()
+			apiManager.Name = "previous"
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					updates++
+				}
+				_, _ = fmt.Fprintf(w, `{"server":"Host:80","name":"previous","labels":{%q:%q}}`,
+					ManagedByLabel, ManagedByValue)
+			})
+
+			drifted, err := apiManager.SyncCluster(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifted).To(BeFalse())
+			Expect(updates).To(BeZero())
+		})
+	})
+
+	Context("HTTP client", func() {
+		It("should share the HTTP client between the APIManagers of the same endpoint", func() {
+			endpoint := "https://shared.argocd.invalid"
+			first := &APIManager{Log: logr.Discard(), Endpoint: endpoint, TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12}}
+			second := &APIManager{Log: logr.Discard(), Endpoint: endpoint, TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12}}
+			Expect(first.httpClient()).To(BeIdenticalTo(second.httpClient()))
+
+			By("checking that another client is used when the configuration changes")
+			previous := first.httpClient()
+			second.Timeout = time.Minute
+			Expect(second.httpClient()).NotTo(BeIdenticalTo(previous))
+			Expect(second.httpClient().Timeout).To(Equal(time.Minute))
+
+			By("checking that the clients of the configurations of the same endpoint are kept side by side")
+			Expect(first.httpClient()).To(BeIdenticalTo(previous))
+
+			By("checking that another client is used for other endpoints")
+			other := &APIManager{Log: logr.Discard(), Endpoint: "https://other.argocd.invalid"}
+			Expect(other.httpClient()).NotTo(BeIdenticalTo(second.httpClient()))
+		})
+
+		It("should release the HTTP clients which are no longer used", func() {
+			store := &httpClientStore{clients: map[httpClientKey]*httpClientEntry{}}
+			now := time.Now()
+			newClient := func() *http.Client { return &http.Client{} }
+			rotated := store.get("https://argocd.invalid", "previous", newClient, now)
+			current := store.get("https://argocd.invalid", "current", newClient, now)
+			Expect(current).NotTo(BeIdenticalTo(rotated))
+
+			By("keeping the client used within the TTL")
+			Expect(store.get("https://argocd.invalid", "current", newClient,
+				now.Add(httpClientIdleTTL))).To(BeIdenticalTo(current))
+			Expect(store.clients).To(HaveLen(2))
+
+			By("releasing the client unused for longer than the TTL")
+			Expect(store.get("https://argocd.invalid", "current", newClient,
+				now.Add(httpClientIdleTTL+time.Minute))).To(BeIdenticalTo(current))
+			Expect(store.clients).To(HaveLen(1))
+			Expect(store.clients).To(HaveKey(httpClientKey{endpoint: "https://argocd.invalid", config: "current"}))
+		})
+	})
+
+	Context("Session token expiry", func() {
+		newToken := func(claims string) string {
+			return "header." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+		}
+
+		It("should return the expiration of the token", func() {
+			expiresAt, err := tokenExpiry(newToken(`{"exp":1700000000}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expiresAt).To(Equal(time.Unix(1700000000, 0)))
+		})
+
+		It("should return a zero time when the token does not expire", func() {
+			expiresAt, err := tokenExpiry(newToken(`{"sub":"admin"}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expiresAt.IsZero()).To(BeTrue())
+		})
+
+		It("should not reuse a session which is about to expire", func() {
+			store := &sessionStore{sessions: map[string]session{}}
+			now := time.Now()
+			store.set("key", session{token: "token", expiresAt: now.Add(time.Hour), credentials: "hash"})
+
+			_, ok := store.get("key", "hash", now)
+			Expect(ok).To(BeTrue())
+			_, ok = store.get("key", "hash", now.Add(time.Hour-sessionRefreshWindow/2))
+			Expect(ok).To(BeFalse())
+			_, ok = store.get("key", "other", now)
+			Expect(ok).To(BeFalse())
+		})
 	})
 })
+
+// newSelfSignedCertificate returns a PEM encoded self-signed certificate and its key
+func newSelfSignedCertificate() ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).To(Not(HaveOccurred()))
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "workload-operator"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).To(Not(HaveOccurred()))
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).To(Not(HaveOccurred()))
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCircuitBreakerThreshold is the number of consecutive requests to an ArgoCD endpoint which fail
+	// because it is unavailable before the requests to it are failed fast
+	DefaultCircuitBreakerThreshold = 5
+
+	// DefaultCircuitBreakerCooldown is how long the requests to an unavailable ArgoCD endpoint are failed fast
+	// before a single request is sent to probe whether it is available again
+	DefaultCircuitBreakerCooldown = 30 * time.Second
+)
+
+// CircuitOpenError is returned, without sending the request, while the ArgoCD endpoint is unavailable.
+// RetryAfter informs when the endpoint is probed again.
+type CircuitOpenError struct {
+	Endpoint   string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("ArgoCD API %s is unavailable, the requests are failed fast until it is probed again in %s",
+		e.Endpoint, e.RetryAfter)
+}
+
+// circuitState is the state of the circuit of an ArgoCD endpoint
+type circuitState int
+
+const (
+	// circuitClosed sends the requests
+	circuitClosed circuitState = iota
+	// circuitOpen fails the requests fast until the cooldown expires
+	circuitOpen
+	// circuitHalfOpen sends a single request to probe the endpoint, failing the others fast
+	circuitHalfOpen
+)
+
+// circuit tracks the availability of an ArgoCD endpoint
+type circuit struct {
+	state    circuitState
+	failures int
+	retryAt  time.Time
+}
+
+// circuitBreakerStore tracks the circuits by ArgoCD endpoint, so that once an endpoint is down the requests
+// of all the reconciliations are failed fast rather than each one waiting for the request to time out. It is
+// disabled until a threshold is informed. It is safe for concurrent use.
+type circuitBreakerStore struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+}
+
+// circuitBreakers is shared by all APIManagers
+var circuitBreakers = &circuitBreakerStore{circuits: map[string]*circuit{}}
+
+// EnableCircuitBreaker fails fast the requests to an ArgoCD endpoint once the threshold informed of consecutive
+// requests failed because it is unavailable, and probes it again after the cooldown. It is disabled when the
+// threshold is not positive.
+func EnableCircuitBreaker(threshold int, cooldown time.Duration) {
+	circuitBreakers.mu.Lock()
+	defer circuitBreakers.mu.Unlock()
+	circuitBreakers.threshold = threshold
+	circuitBreakers.cooldown = cooldown
+	circuitBreakers.circuits = map[string]*circuit{}
+}
+
+// allow returns a *CircuitOpenError when the request to the endpoint must be failed fast. Once the cooldown
+// expires the request allowed is the probe, and the others are failed fast until its outcome is recorded.
+func (s *circuitBreakerStore) allow(endpoint string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.circuits[endpoint]
+	if s.threshold <= 0 || !ok {
+		return nil
+	}
+	switch c.state {
+	case circuitOpen:
+		if now.Before(c.retryAt) {
+			return &CircuitOpenError{Endpoint: endpoint, RetryAfter: c.retryAt.Sub(now)}
+		}
+		c.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		return &CircuitOpenError{Endpoint: endpoint, RetryAfter: s.cooldown}
+	}
+	return nil
+}
+
+// record records the outcome of the request to the endpoint. The circuit is opened once the threshold of
+// consecutive outages is reached or when the probe fails, and closed when any request succeeds.
+func (s *circuitBreakerStore) record(endpoint string, outage bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.threshold <= 0 {
+		return
+	}
+	c, ok := s.circuits[endpoint]
+	if !outage {
+		delete(s.circuits, endpoint)
+		return
+	}
+	if !ok {
+		c = &circuit{}
+		s.circuits[endpoint] = c
+	}
+	c.failures++
+	if c.state == circuitHalfOpen || c.failures >= s.threshold {
+		c.state = circuitOpen
+		c.retryAt = now.Add(s.cooldown)
+	}
+}
+
+// abort releases the probe of the endpoint without an outcome, i.e. when its reconciliation was cancelled,
+// so that the next request probes it
+func (s *circuitBreakerStore) abort(endpoint string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.circuits[endpoint]; ok && c.state == circuitHalfOpen {
+		c.state = circuitOpen
+		c.retryAt = now
+	}
+}
+
+// isOutage returns true when the request failed because the ArgoCD endpoint is unavailable, that is, when it
+// could not be sent or the gateway in front of ArgoCD reports that it is not available
+func isOutage(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// sendThroughCircuit sends the request, according to the RetryPolicy, unless the endpoint is unavailable,
+// and records its outcome in the circuit of the endpoint
+func (a *APIManager) sendThroughCircuit(ctx context.Context, method, path string, payload []byte,
+	token string) (*http.Response, error) {
+	if err := circuitBreakers.allow(a.Endpoint, time.Now()); err != nil {
+		return nil, err
+	}
+	resp, err := a.send(ctx, method, path, payload, token)
+	if ctx.Err() != nil {
+		circuitBreakers.abort(a.Endpoint, time.Now())
+		return resp, err
+	}
+	// The rate limited requests were answered by ArgoCD, therefore, it is available
+	var rateLimitedErr *RateLimitedError
+	circuitBreakers.record(a.Endpoint, !errors.As(err, &rateLimitedErr) && isOutage(resp, err), time.Now())
+	return resp, err
+}
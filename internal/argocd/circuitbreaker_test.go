@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Circuit breaker", func() {
+	ctx := context.Background()
+	var server *httptest.Server
+	var requests int
+	var available bool
+
+	BeforeEach(func() {
+		requests, available = 0, false
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if !available {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"server":"registered:443","name":"registered"}`)
+		}))
+		EnableCircuitBreaker(2, time.Minute)
+	})
+
+	AfterEach(func() {
+		EnableCircuitBreaker(0, 0)
+		server.Close()
+	})
+
+	It("should fail fast the requests while ArgoCD is down and probe it once the cooldown expires", func() {
+		apiManager := &APIManager{Log: logr.Discard(), Endpoint: server.URL, Token: "token", Server: "registered:443"}
+		for i := 0; i < 2; i++ {
+			_, err := apiManager.IsClusterRegistered(ctx)
+			Expect(err).To(HaveOccurred())
+		}
+		Expect(requests).To(Equal(2))
+
+		By("failing fast once the threshold is reached")
+		_, err := apiManager.IsClusterRegistered(ctx)
+		Expect(err).To(BeAssignableToTypeOf(&CircuitOpenError{}))
+		Expect(ErrorReason(err)).To(Equal(ReasonUnavailable))
+		Expect(requests).To(Equal(2))
+
+		By("opening the circuit again when the probe fails")
+		Expect(circuitBreakers.allow(server.URL, time.Now().Add(2*time.Minute))).To(Succeed())
+		Expect(circuitBreakers.allow(server.URL, time.Now().Add(2*time.Minute))).
+			To(BeAssignableToTypeOf(&CircuitOpenError{}))
+		circuitBreakers.record(server.URL, true, time.Now())
+		_, err = apiManager.IsClusterRegistered(ctx)
+		Expect(err).To(BeAssignableToTypeOf(&CircuitOpenError{}))
+
+		By("closing the circuit once the probe succeeds")
+		available = true
+		circuitBreakers.mu.Lock()
+		circuitBreakers.circuits[server.URL].retryAt = time.Now()
+		circuitBreakers.mu.Unlock()
+		Expect(apiManager.IsClusterRegistered(ctx)).To(BeTrue())
+		Expect(apiManager.IsClusterRegistered(ctx)).To(BeTrue())
+		Expect(requests).To(Equal(4))
+	})
+})
@@ -0,0 +1,338 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// ConnectionStatusSuccessful is reported by ArgoCD when it is able to connect to the cluster
+	ConnectionStatusSuccessful = "Successful"
+
+	// ConnectionStatusFailed is reported by ArgoCD when it is unable to connect to the cluster
+	ConnectionStatusFailed = "Failed"
+
+	// ConnectionStatusUnknown is reported by ArgoCD when the connection was not attempted yet.
+	// ArgoCD only connects to a cluster when it is used as destination of an Application.
+	ConnectionStatusUnknown = "Unknown"
+)
+
+// TLSClientConfig contains the settings used by ArgoCD to enable transport layer security
+// when connecting to the cluster.
+type TLSClientConfig struct {
+	Insecure   bool   `json:"insecure"`
+	ServerName string `json:"serverName,omitempty"`
+	CertData   []byte `json:"certData,omitempty"`
+	KeyData    []byte `json:"keyData,omitempty"`
+	CAData     []byte `json:"caData,omitempty"`
+}
+
+// AWSAuthConfig is the configuration used by ArgoCD to authenticate against EKS clusters via IAM
+// with the aws-iam-authenticator instead of static credentials.
+type AWSAuthConfig struct {
+	ClusterName string `json:"clusterName,omitempty"`
+	RoleARN     string `json:"roleARN,omitempty"`
+}
+
+// ExecProviderConfig is the configuration used by ArgoCD to obtain the credentials of the cluster
+// by executing a client-go credential plugin (i.e. gke-gcloud-auth-plugin).
+type ExecProviderConfig struct {
+	Command     string            `json:"command,omitempty"`
+	Args        []string          `json:"args,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	APIVersion  string            `json:"apiVersion,omitempty"`
+	InstallHint string            `json:"installHint,omitempty"`
+}
+
+// ClusterConfig is the configuration used by ArgoCD to connect to the cluster.
+type ClusterConfig struct {
+	Username           string              `json:"username,omitempty"`
+	Password           string              `json:"password,omitempty"`
+	BearerToken        string              `json:"bearerToken,omitempty"`
+	TLSClientConfig    TLSClientConfig     `json:"tlsClientConfig"`
+	AWSAuthConfig      *AWSAuthConfig      `json:"awsAuthConfig,omitempty"`
+	ExecProviderConfig *ExecProviderConfig `json:"execProviderConfig,omitempty"`
+	ProxyURL           string              `json:"proxyUrl,omitempty"`
+}
+
+// ClusterOptions defines how ArgoCD connects to the cluster when it should not use the
+// settings of the kubeconfig, the settings of the cluster entry and the ArgoCD instance where it is registered.
+type ClusterOptions struct {
+	// Name when informed is used as the name of the cluster within ArgoCD instead of the name of the Cluster
+	Name string
+	// Server when informed is used as the server of the cluster within ArgoCD instead of the control
+	// plane endpoint of the Cluster, i.e. to remove the registration of a previous endpoint
+	Server string
+	// Namespaces when informed ArgoCD can only deploy into these namespaces of the cluster
+	Namespaces []string
+	// ClusterResources when true ArgoCD can manage cluster-scoped resources when the namespaces
+	// are informed
+	ClusterResources bool
+	// Project when informed the cluster can only be used by the Applications of this AppProject
+	Project string
+	// AWSAuthConfig when informed ArgoCD authenticates against the EKS cluster via IAM
+	AWSAuthConfig *AWSAuthConfig
+	// ExecProviderConfig when informed ArgoCD obtains the credentials by executing the plugin
+	ExecProviderConfig *ExecProviderConfig
+	// ProxyURL when informed ArgoCD connects to the cluster through this proxy instead of the
+	// one defined in the kubeconfig, if any
+	ProxyURL string
+	// InCluster when true ArgoCD connects to the cluster where it is running with its own
+	// ServiceAccount, therefore, no credentials are registered and the kubeconfig is not required
+	InCluster bool
+	// Shard when informed is the shard of the ArgoCD application controller which manages the cluster
+	Shard *int64
+	// Instance when informed is the ArgoCD instance where the cluster is registered, otherwise, the
+	// one configured via Manager ENV VAR is used. Its defaults apply when Project and Shard are not informed.
+	Instance *Instance
+}
+
+// withInstanceDefaults returns the options with the Project and the Shard of the ArgoCD instance
+// when they are not informed
+func (o ClusterOptions) withInstanceDefaults() ClusterOptions {
+	if o.Instance == nil {
+		return o
+	}
+	if o.Project == "" {
+		o.Project = o.Instance.Project
+	}
+	if o.Shard == nil {
+		o.Shard = o.Instance.Shard
+	}
+	return o
+}
+
+// InClusterServer is the server used by ArgoCD to connect to the cluster where it is running
+const InClusterServer = "https://kubernetes.default.svc"
+
+// clusterConfig builds the configuration used by ArgoCD to connect to the cluster. The TLS settings
+// are always taken from the kubeconfig while the credentials can be replaced by the options informed.
+func (o ClusterOptions) clusterConfig(kubeConfig []byte) (*ClusterConfig, error) {
+	if o.InCluster {
+		return &ClusterConfig{ProxyURL: o.ProxyURL}, nil
+	}
+
+	var config *ClusterConfig
+	if o.AWSAuthConfig == nil && o.ExecProviderConfig == nil {
+		var err error
+		if config, err = clusterConfigFromKubeConfig(kubeConfig); err != nil {
+			return nil, err
+		}
+	} else {
+		cluster, _, err := currentContextFromKubeConfig(kubeConfig)
+		if err != nil {
+			return nil, err
+		}
+		config = &ClusterConfig{
+			TLSClientConfig: TLSClientConfig{
+				Insecure:   cluster.InsecureSkipTLSVerify,
+				ServerName: cluster.TLSServerName,
+				CAData:     cluster.CertificateAuthorityData,
+			},
+			AWSAuthConfig:      o.AWSAuthConfig,
+			ExecProviderConfig: o.ExecProviderConfig,
+			ProxyURL:           cluster.ProxyURL,
+		}
+	}
+
+	if o.ProxyURL != "" {
+		config.ProxyURL = o.ProxyURL
+	}
+	return config, nil
+}
+
+// currentContextFromKubeConfig returns the cluster and the user of the current context of the kubeconfig.
+func currentContextFromKubeConfig(kubeConfig []byte) (*clientcmdapi.Cluster, *clientcmdapi.AuthInfo, error) {
+	config, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading kubeconfig: %w", RedactError(err))
+	}
+
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, nil, fmt.Errorf("current context %q not found in kubeconfig", config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[kubeContext.Cluster]
+	if !ok {
+		return nil, nil, fmt.Errorf("cluster %q not found in kubeconfig", kubeContext.Cluster)
+	}
+	authInfo, ok := config.AuthInfos[kubeContext.AuthInfo]
+	if !ok {
+		return nil, nil, fmt.Errorf("user %q not found in kubeconfig", kubeContext.AuthInfo)
+	}
+	return cluster, authInfo, nil
+}
+
+// KubeConfigServer returns the server of the cluster of the current context of the kubeconfig.
+func KubeConfigServer(kubeConfig []byte) (string, error) {
+	cluster, _, err := currentContextFromKubeConfig(kubeConfig)
+	if err != nil {
+		return "", err
+	}
+	if cluster.Server == "" {
+		return "", fmt.Errorf("cluster of the current context of the kubeconfig has no server")
+	}
+	return cluster.Server, nil
+}
+
+// clusterConfigFromKubeConfig builds the configuration used by ArgoCD to connect to the cluster
+// from the credentials of the current context of the kubeconfig.
+func clusterConfigFromKubeConfig(kubeConfig []byte) (*ClusterConfig, error) {
+	cluster, authInfo, err := currentContextFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if authInfo.Token == "" && len(authInfo.ClientCertificateData) == 0 && authInfo.Username == "" {
+		return nil, fmt.Errorf("user of the current context of the kubeconfig has no token, " +
+			"client certificate data or basic authentication credentials")
+	}
+
+	return &ClusterConfig{
+		Username:    authInfo.Username,
+		Password:    authInfo.Password,
+		BearerToken: authInfo.Token,
+		TLSClientConfig: TLSClientConfig{
+			Insecure:   cluster.InsecureSkipTLSVerify,
+			ServerName: cluster.TLSServerName,
+			CAData:     cluster.CertificateAuthorityData,
+			CertData:   authInfo.ClientCertificateData,
+			KeyData:    authInfo.ClientKeyData,
+		},
+		ProxyURL: cluster.ProxyURL,
+	}, nil
+}
+
+// ConnectionState represents the connection state of a cluster as reported by ArgoCD.
+type ConnectionState struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ClusterInfo represents the information cached by ArgoCD about the cluster.
+type ClusterInfo struct {
+	ConnectionState ConnectionState `json:"connectionState,omitempty"`
+}
+
+// Cluster represents the cluster entry returned by the ArgoCD API.
+// Only the fields used by this project are mapped.
+type Cluster struct {
+	Server string            `json:"server"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations of the cluster, i.e. used by ApplicationSet cluster generators
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Namespaces allowed to be used by ArgoCD, all namespaces are allowed when it is empty
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ClusterResources defines if ArgoCD can manage cluster-scoped resources when the namespaces
+	// are informed
+	ClusterResources bool `json:"clusterResources,omitempty"`
+	// Project is the AppProject which the cluster belongs to, if any
+	Project string `json:"project,omitempty"`
+	// Shard of the ArgoCD application controller which manages the cluster, assigned by ArgoCD when it is nil
+	Shard *int64 `json:"shard,omitempty"`
+	// Config is returned by ArgoCD without the sensitive data (i.e. bearer token and keys)
+	Config ClusterConfig `json:"config"`
+	// ConnectionState is deprecated in ArgoCD in favor of Info.ConnectionState
+	// however, it is still returned by the API and used by older versions.
+	ConnectionState ConnectionState `json:"connectionState,omitempty"`
+	Info            ClusterInfo     `json:"info,omitempty"`
+}
+
+// clusterRequest is the cluster entry sent to the ArgoCD API to register or update the cluster.
+type clusterRequest struct {
+	Server           string            `json:"server"`
+	Name             string            `json:"name"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Annotations      map[string]string `json:"annotations,omitempty"`
+	Namespaces       []string          `json:"namespaces,omitempty"`
+	ClusterResources bool              `json:"clusterResources,omitempty"`
+	Project          string            `json:"project,omitempty"`
+	Shard            *int64            `json:"shard,omitempty"`
+	Config           ClusterConfig     `json:"config"`
+}
+
+// GetConnectionState returns the connection state of the cluster checking first the info, which is the
+// only one returned by ArgoCD 3.x, and then the deprecated field to support older versions of ArgoCD.
+func (c *Cluster) GetConnectionState() ConnectionState {
+	if c.Info.ConnectionState.Status != "" {
+		return c.Info.ConnectionState
+	}
+	return c.ConnectionState
+}
+
+// clusterDrift returns the fields of the cluster entry registered in ArgoCD which differ from the
+// desired ones. The sensitive data is not compared since it is not returned by ArgoCD, neither
+// the exec provider config since it might carry credentials via its env.
+func clusterDrift(desired, registered *Cluster) []string {
+	var drift []string
+	if desired.Server != registered.Server {
+		drift = append(drift, "server")
+	}
+	if desired.Name != registered.Name {
+		drift = append(drift, "name")
+	}
+	if strings.Join(desired.Namespaces, ",") != strings.Join(registered.Namespaces, ",") {
+		drift = append(drift, "namespaces")
+	}
+	if desired.ClusterResources != registered.ClusterResources {
+		drift = append(drift, "clusterResources")
+	}
+	if desired.Project != registered.Project {
+		drift = append(drift, "project")
+	}
+	if desired.Shard != nil && (registered.Shard == nil || *desired.Shard != *registered.Shard) {
+		drift = append(drift, "shard")
+	}
+	for key, value := range desired.Labels {
+		if registered.Labels[key] != value {
+			drift = append(drift, "labels")
+			break
+		}
+	}
+	for key, value := range desired.Annotations {
+		if registered.Annotations[key] != value {
+			drift = append(drift, "annotations")
+			break
+		}
+	}
+	if desired.Config.TLSClientConfig.Insecure != registered.Config.TLSClientConfig.Insecure ||
+		desired.Config.TLSClientConfig.ServerName != registered.Config.TLSClientConfig.ServerName {
+		drift = append(drift, "config")
+	} else if !reflect.DeepEqual(desired.Config.AWSAuthConfig, registered.Config.AWSAuthConfig) ||
+		desired.Config.ProxyURL != registered.Config.ProxyURL {
+		drift = append(drift, "config")
+	}
+	return drift
+}
+
+// ConnectionError is returned when the cluster is registered but ArgoCD reports
+// that it is unable to connect to it.
+type ConnectionError struct {
+	Status  string
+	Message string
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("ArgoCD is unable to connect to the cluster (status: %s): %s", e.Status, e.Message)
+}
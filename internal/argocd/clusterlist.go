@@ -0,0 +1,284 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/json"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultClusterListTTL is how long the snapshot of the clusters registered within an ArgoCD instance is
+// used to check the registration of the clusters before it is listed again
+const DefaultClusterListTTL = 30 * time.Second
+
+// clusterListResponse is the payload returned by the ArgoCD API when the clusters are listed
+type clusterListResponse struct {
+	Items []Cluster `json:"items"`
+}
+
+// clusterList is the snapshot of the clusters registered within an ArgoCD instance, by server
+type clusterList struct {
+	clusters  map[string]Cluster
+	expiresAt time.Time
+}
+
+// clusterListStore caches the snapshot of the clusters registered by ArgoCD endpoint and account, so that
+// the registration of each cluster is checked without a request to the ArgoCD API. It is disabled until a
+// TTL is informed. It is safe for concurrent use.
+type clusterListStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	lists map[string]clusterList
+
+	// refreshes serializes the listing of the clusters per key, so that the concurrent reconciliations
+	// share the snapshot listed by the first one
+	refreshes map[string]*sync.Mutex
+}
+
+// clusterLists is shared by all APIManagers
+var clusterLists = &clusterListStore{lists: map[string]clusterList{}}
+
+// EnableClusterListCache checks the registration of the clusters in a snapshot of the clusters registered
+// within ArgoCD which is listed again once the TTL informed expires, it is disabled when the TTL is not positive
+func EnableClusterListCache(ttl time.Duration) {
+	clusterLists.mu.Lock()
+	defer clusterLists.mu.Unlock()
+	clusterLists.ttl = ttl
+	clusterLists.lists = map[string]clusterList{}
+}
+
+func (s *clusterListStore) enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ttl > 0
+}
+
+// get returns the cluster of the snapshot cached for the key, and whether the snapshot did not expire
+func (s *clusterListStore) get(key, server string, now time.Time) (*Cluster, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.lists[key]
+	if !ok || !now.Before(cached.expiresAt) {
+		return nil, false
+	}
+	if cluster, found := cached.clusters[server]; found {
+		return &cluster, true
+	}
+	return nil, true
+}
+
+// refreshLock returns the lock which serializes the listing of the clusters for the key
+func (s *clusterListStore) refreshLock(key string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshes == nil {
+		s.refreshes = map[string]*sync.Mutex{}
+	}
+	lock, ok := s.refreshes[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.refreshes[key] = lock
+	}
+	return lock
+}
+
+func (s *clusterListStore) set(key string, clusters []Cluster, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := clusterList{clusters: make(map[string]Cluster, len(clusters)), expiresAt: now.Add(s.ttl)}
+	for _, cluster := range clusters {
+		snapshot.clusters[cluster.Server] = cluster
+	}
+	s.lists[key] = snapshot
+}
+
+// forget removes the cluster from the snapshot cached for the key, so that its registration is fetched
+// until the snapshot is listed again, i.e. once it was changed by the operator
+func (s *clusterListStore) forget(key, server string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.lists[key]; ok {
+		delete(cached.clusters, server)
+	}
+}
+
+// lookupCluster returns the cluster entry from the snapshot of the clusters registered within ArgoCD, which
+// is listed again once it expires. The cluster is fetched from the ArgoCD API when it is not in the snapshot,
+// therefore, a cluster which was just registered is never reported as missing. It returns whether the cluster
+// was found in the snapshot.
+func (a *APIManager) lookupCluster(ctx context.Context) (*Cluster, bool, error) {
+	if !clusterLists.enabled() {
+		cluster, err := a.getCluster(ctx)
+		return cluster, false, err
+	}
+
+	key := a.sessionKey()
+	cluster, ok := clusterLists.get(key, a.Server, time.Now())
+	if !ok {
+		lock := clusterLists.refreshLock(key)
+		lock.Lock()
+		if cluster, ok = clusterLists.get(key, a.Server, time.Now()); !ok {
+			// When the clusters cannot be listed an empty snapshot is cached, so that the clusters are fetched
+			// one by one rather than listed again on every check until it expires
+			clusters, err := a.listClusters(ctx)
+			if err != nil {
+				a.Log.V(1).Info("Unable to list the clusters registered within ArgoCD, fetching the cluster",
+					"reason", err.Error())
+			}
+			clusterLists.set(key, clusters, time.Now())
+			cluster, _ = clusterLists.get(key, a.Server, time.Now())
+		}
+		lock.Unlock()
+	}
+	if cluster != nil {
+		return cluster, true, nil
+	}
+	cluster, err := a.getCluster(ctx)
+	return cluster, false, err
+}
+
+// RegisteredServers returns the servers of the clusters registered within the API of the ArgoCD instance
+// informed, or of the one configured via Manager ENV VAR when it is nil, listed with a single request. The
+// snapshot of the clusters registered is replaced with them, so that their registration is not checked again.
+func RegisteredServers(ctx context.Context, client client.Client, log logr.Logger,
+	instance *Instance) ([]string, error) {
+	clusters, err := registeredClusters(ctx, client, log, instance)
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]string, 0, len(clusters))
+	for i := range clusters {
+		servers = append(servers, clusters[i].Server)
+	}
+	return servers, nil
+}
+
+// ManagedServers returns the servers of the clusters registered within the API of the ArgoCD instance informed,
+// or of the one configured via Manager ENV VAR when it is nil, which are labeled as managed by this installation
+// of the project. The clusters registered by the other installations which share the ArgoCD instance are skipped.
+func ManagedServers(ctx context.Context, client client.Client, log logr.Logger,
+	instance *Instance) ([]string, error) {
+	clusters, err := registeredClusters(ctx, client, log, instance)
+	if err != nil {
+		return nil, err
+	}
+	var servers []string
+	for i := range clusters {
+		if managedByInstallation(clusters[i].Labels) {
+			servers = append(servers, clusters[i].Server)
+		}
+	}
+	return servers, nil
+}
+
+// UnmanagedCluster returns the cluster registered within the API of the ArgoCD instance informed, or of the one
+// configured via Manager ENV VAR when it is nil, which has the same server as the one informed but is not labeled
+// as managed by this project, i.e. when it was registered via the ArgoCD CLI. It returns nil when there is none.
+func UnmanagedCluster(ctx context.Context, client client.Client, log logr.Logger, instance *Instance,
+	server string) (*Cluster, error) {
+	clusters, err := registeredClusters(ctx, client, log, instance)
+	if err != nil {
+		return nil, err
+	}
+	for i := range clusters {
+		if SameServer(clusters[i].Server, server) && clusters[i].Labels[ManagedByLabel] != ManagedByValue {
+			return &clusters[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// HasAPI returns true when the ArgoCD instance informed, or the one configured via Manager ENV VAR when it is nil,
+// serves its API. The ArgoCDInstances are always reached via their API, whereas the one configured via Manager
+// ENV VAR has no API when it runs in core mode.
+func HasAPI(ctx context.Context, log logr.Logger, instance *Instance) (bool, error) {
+	if instance != nil {
+		return true, nil
+	}
+	namespace, err := getNamespace(ctx, log)
+	if err != nil {
+		return false, err
+	}
+	coreMode, err := CoreMode(ctx, namespace)
+	if err != nil {
+		return false, err
+	}
+	return !coreMode, nil
+}
+
+// registeredClusters lists the clusters registered within the API of the ArgoCD instance informed, and replaces
+// the snapshot of the clusters registered with them
+func registeredClusters(ctx context.Context, client client.Client, log logr.Logger,
+	instance *Instance) ([]Cluster, error) {
+	hasAPI, err := HasAPI(ctx, log, instance)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAPI {
+		return nil, fmt.Errorf("ArgoCD runs in core mode in the namespace %s, it has no API", currentNamespace())
+	}
+	apiManager, err := newAPIManager(ctx, client, log, &clusterapiv1.Cluster{}, nil, instance)
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := apiManager.listClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if clusterLists.enabled() {
+		clusterLists.set(apiManager.sessionKey(), clusters, time.Now())
+	}
+	return clusters, nil
+}
+
+// forgetCluster removes the cluster from the snapshot of the clusters registered, once it is changed
+func (a *APIManager) forgetCluster() {
+	clusterLists.forget(a.sessionKey(), a.Server)
+}
+
+// listClusters lists the clusters registered within ArgoCD
+func (a *APIManager) listClusters(ctx context.Context) ([]Cluster, error) {
+	resp, err := a.doRequest(ctx, http.MethodGet, "/api/v1/clusters", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer a.closeResponse(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("listing clusters", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	list := &clusterListResponse{}
+	if err := json.Unmarshal(body, list); err != nil {
+		return nil, fmt.Errorf("error decoding clusters: %w", err)
+	}
+	return list.Items, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster list cache", func() {
+	ctx := context.Background()
+	var server *httptest.Server
+	var lists, gets int
+
+	BeforeEach(func() {
+		lists, gets = 0, 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/api/v1/clusters":
+				lists++
+				_, _ = fmt.Fprint(w, `{"items":[{"server":"registered:443","name":"registered",`+
+					`"connectionState":{"status":"Successful"}}]}`)
+			case r.Method == http.MethodGet && r.URL.Path == "/api/v1/clusters/registered:443":
+				gets++
+				_, _ = fmt.Fprint(w, `{"server":"registered:443","name":"registered"}`)
+			case r.Method == http.MethodDelete:
+				w.WriteHeader(http.StatusOK)
+			default:
+				gets++
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		EnableClusterListCache(time.Minute)
+	})
+
+	AfterEach(func() {
+		EnableClusterListCache(0)
+		server.Close()
+	})
+
+	newAPIManager := func(clusterServer string) *APIManager {
+		return &APIManager{Log: logr.Discard(), Endpoint: server.URL, Token: "token", Server: clusterServer}
+	}
+
+	It("should check the registration of the clusters in the snapshot listed", func() {
+		registered := newAPIManager("registered:443")
+		for i := 0; i < 3; i++ {
+			Expect(registered.IsClusterRegistered(ctx)).To(BeTrue())
+			Expect(registered.Verify(ctx)).To(Succeed())
+		}
+		Expect(lists).To(Equal(1))
+		Expect(gets).To(Equal(0))
+
+		By("fetching the clusters which are not in the snapshot")
+		Expect(newAPIManager("missing:443").IsClusterRegistered(ctx)).To(BeFalse())
+		Expect(lists).To(Equal(1))
+		Expect(gets).To(Equal(1))
+	})
+
+	It("should fetch the clusters changed by the operator until the snapshot is listed again", func() {
+		registered := newAPIManager("registered:443")
+		Expect(registered.IsClusterRegistered(ctx)).To(BeTrue())
+		Expect(registered.UnRegisterCluster(ctx)).To(Succeed())
+
+		Expect(registered.IsClusterRegistered(ctx)).To(BeTrue())
+		Expect(lists).To(Equal(1))
+		Expect(gets).To(Equal(1))
+	})
+
+	It("should fetch each cluster when the cache is disabled", func() {
+		EnableClusterListCache(0)
+		registered := newAPIManager("registered:443")
+		Expect(registered.IsClusterRegistered(ctx)).To(BeTrue())
+		Expect(registered.IsClusterRegistered(ctx)).To(BeTrue())
+		Expect(lists).To(Equal(0))
+		Expect(gets).To(Equal(2))
+	})
+})
+
+var _ = Describe("Cluster servers", func() {
+	It("should identify the same cluster regardless of the https scheme and the trailing slash", func() {
+		Expect(SameServer("https://cluster.example.com:6443/", "cluster.example.com:6443")).To(BeTrue())
+		Expect(SameServer("cluster.example.com:6443", "https://cluster.example.com:6443")).To(BeTrue())
+		Expect(SameServer("http://cluster.example.com:6443", "cluster.example.com:6443")).To(BeFalse())
+		Expect(SameServer("https://other.example.com:6443", "cluster.example.com:6443")).To(BeFalse())
+		Expect(SameServer("", "")).To(BeFalse())
+	})
+})
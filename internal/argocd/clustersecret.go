@@ -0,0 +1,242 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/json"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// SecretTypeLabel is the label used by ArgoCD to identify the type of the Secret
+	SecretTypeLabel = "argocd.argoproj.io/secret-type"
+
+	// SecretTypeCluster is the value of the SecretTypeLabel for Secrets which represent a cluster
+	SecretTypeCluster = "cluster"
+
+	// ManagedByLabel is the label added to identify the Secrets managed by this project
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+
+	// ManagedByValue is the value of the ManagedByLabel for Secrets managed by this project
+	ManagedByValue = "workload-operator"
+)
+
+// invalidSecretNameChars matches the characters which are not allowed in the name of the Secret
+var invalidSecretNameChars = regexp.MustCompile(`[^a-z0-9.-]`)
+
+// SecretManager stores the required information to register clusters declaratively
+// within ArgoCD by managing its cluster Secrets.
+// More info: https://argo-cd.readthedocs.io/en/stable/operator-manual/declarative-setup/#clusters
+type SecretManager struct {
+	Client      client.Client     // Kubernetes client
+	Log         logr.Logger       // Logger for the manager
+	Server      string            // Server endpoint of the cluster
+	Name        string            // Name of the cluster
+	Labels      map[string]string // Labels of the Cluster propagated to the cluster Secret
+	Annotations map[string]string // Annotations of the Cluster propagated to the cluster Secret
+	KubeConfig  []byte            // Kubeconfig content in bytes
+	Namespace   string            // Namespace where ArgoCD is deployed
+	Options     ClusterOptions    // Options to connect to the cluster
+}
+
+// NewSecretManagerWithCluster returns the Manager to allow to register the cluster declaratively within ArgoCD.
+// It fails when the namespace where ArgoCD is deployed is not provided and cannot be detected.
+func NewSecretManagerWithCluster(ctx context.Context, client client.Client, log logr.Logger,
+	clusterAPI *clusterapiv1.Cluster, kubeConfig []byte) (*SecretManager, error) {
+	namespace, err := getNamespace(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	return newSecretManager(ctx, client, log, clusterAPI, kubeConfig, namespace), nil
+}
+
+// newSecretManager returns the Manager to register the cluster declaratively within the ArgoCD deployed
+// in the namespace informed.
+func newSecretManager(_ context.Context, client client.Client, log logr.Logger,
+	clusterAPI *clusterapiv1.Cluster, kubeConfig []byte, namespace string) *SecretManager {
+	return &SecretManager{
+		Client:      client,
+		Log:         log,
+		Server:      ServerURL(clusterAPI),
+		Name:        clusterAPI.Name,
+		Labels:      propagatedLabels(clusterAPI.Labels),
+		Annotations: propagatedAnnotations(clusterAPI.Annotations),
+		KubeConfig:  kubeConfig,
+		Namespace:   namespace,
+	}
+}
+
+// secretName returns the name of the cluster Secret following the convention used by ArgoCD
+// so that the Secret is the same one which would be created by the ArgoCD CLI or API.
+func (s *SecretManager) secretName() string {
+	host := strings.TrimPrefix(strings.TrimPrefix(s.Server, "https://"), "http://")
+	host, _, _ = strings.Cut(host, ":")
+	host, _, _ = strings.Cut(host, "/")
+	host = strings.Trim(invalidSecretNameChars.ReplaceAllString(strings.ToLower(host), "-"), "-.")
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s.Server))
+	return fmt.Sprintf("cluster-%s-%v", host, h.Sum32())
+}
+
+// RegisterCluster creates or updates the cluster Secret in the ArgoCD namespace.
+func (s *SecretManager) RegisterCluster(ctx context.Context) error {
+	_, err := s.applySecret(ctx)
+	return err
+}
+
+// SyncCluster updates the cluster Secret when it differs from the desired one, i.e. when it was
+// edited out-of-band. It returns true when a drift was corrected.
+func (s *SecretManager) SyncCluster(ctx context.Context) (bool, error) {
+	result, err := s.applySecret(ctx)
+	if err != nil {
+		return false, err
+	}
+	if result == controllerutil.OperationResultUpdated {
+		s.Log.Info("Cluster Secret drifted from the desired state, it was updated",
+			"secret", s.secretName(), "namespace", s.Namespace)
+		return true, nil
+	}
+	return false, nil
+}
+
+// applySecret creates or updates the cluster Secret with the desired labels and data.
+func (s *SecretManager) applySecret(ctx context.Context) (controllerutil.OperationResult, error) {
+	config, err := s.Options.clusterConfig(s.KubeConfig)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return controllerutil.OperationResultNone, fmt.Errorf("error marshalling cluster config: %w", err)
+	}
+
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: s.secretName(), Namespace: s.Namespace}}
+	result, err := controllerutil.CreateOrUpdate(ctx, s.Client, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		for key, value := range s.Labels {
+			secret.Labels[key] = value
+		}
+		if len(s.Annotations) > 0 && secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		for key, value := range s.Annotations {
+			secret.Annotations[key] = value
+		}
+		secret.Labels[SecretTypeLabel] = SecretTypeCluster
+		setManagedLabels(secret.Labels)
+		secret.Data = map[string][]byte{
+			"name":   []byte(s.Name),
+			"server": []byte(s.Server),
+			"config": configData,
+		}
+		if len(s.Options.Namespaces) > 0 {
+			secret.Data["namespaces"] = []byte(strings.Join(s.Options.Namespaces, ","))
+		}
+		if s.Options.ClusterResources {
+			secret.Data["clusterResources"] = []byte("true")
+		}
+		if s.Options.Project != "" {
+			secret.Data["project"] = []byte(s.Options.Project)
+		}
+		if s.Options.Shard != nil {
+			secret.Data["shard"] = []byte(strconv.FormatInt(*s.Options.Shard, 10))
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("error creating or updating cluster secret: %w", err)
+	}
+	return result, nil
+}
+
+// IsClusterRegistered returns true when the cluster Secret exists in the ArgoCD namespace.
+func (s *SecretManager) IsClusterRegistered(ctx context.Context) (bool, error) {
+	secret := &v1.Secret{}
+	err := s.Client.Get(ctx, client.ObjectKey{Name: s.secretName(), Namespace: s.Namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error fetching cluster secret: %w", err)
+	}
+	return true, nil
+}
+
+// Verify returns an error when the cluster Secret does not exist.
+// Note that the connection state is not verified since it is only available via the ArgoCD API.
+func (s *SecretManager) Verify(ctx context.Context) error {
+	registered, err := s.IsClusterRegistered(ctx)
+	if err != nil {
+		return err
+	}
+	if !registered {
+		return fmt.Errorf("cluster secret %s/%s not found", s.Namespace, s.secretName())
+	}
+	return nil
+}
+
+// Describe returns the name of the cluster Secret. The version of ArgoCD is not informed since it is
+// only available via the ArgoCD API.
+func (s *SecretManager) Describe(_ context.Context) (*RegistrationInfo, error) {
+	return &RegistrationInfo{ClusterID: s.secretName()}, nil
+}
+
+// UnRegisterCluster deletes the cluster Secret from the ArgoCD namespace.
+func (s *SecretManager) UnRegisterCluster(ctx context.Context) error {
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: s.secretName(), Namespace: s.Namespace}}
+	if err := s.Client.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("error deleting cluster secret: %w", err)
+	}
+	return nil
+}
+
+// ReleaseCluster removes the ManagedByLabel and the InstallationLabel from the cluster Secret, which is kept in the ArgoCD namespace.
+func (s *SecretManager) ReleaseCluster(ctx context.Context) error {
+	secret := &v1.Secret{}
+	if err := s.Client.Get(ctx, client.ObjectKey{Name: s.secretName(), Namespace: s.Namespace}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("error fetching cluster secret: %w", err)
+	}
+	if _, managed := secret.Labels[ManagedByLabel]; !managed {
+		return nil
+	}
+	delete(secret.Labels, ManagedByLabel)
+	delete(secret.Labels, InstallationLabel)
+	if err := s.Client.Update(ctx, secret); err != nil {
+		return fmt.Errorf("error releasing cluster secret: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/workload-operator/internal/argocd/mocks"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/json"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ArgoCD SecretManager", func() {
+	Context("Declarative registration", func() {
+		ctx := context.Background()
+
+		cluster := &clusterapiv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "declarative",
+				Namespace: "test",
+			},
+			Spec: clusterapiv1.ClusterSpec{
+				ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "Host.Example.com", Port: 6443},
+			},
+		}
+
+		BeforeEach(func() {
+			By("creating Argo namespace")
+			err := k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: defaultNamespace}})
+			Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
+		})
+
+		It("should manage the cluster Secret within the ArgoCD namespace", func() {
+			secretManager, err := NewSecretManagerWithCluster(ctx, k8sClient, logr.Discard(), cluster,
+				[]byte(mocks.MockKubeConfig))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secretManager.Namespace).To(Equal(defaultNamespace))
+			Expect(secretManager.secretName()).To(HavePrefix("cluster-host.example.com-"))
+
+			By("checking that the cluster is not registered")
+			registered, err := secretManager.IsClusterRegistered(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registered).To(BeFalse())
+			Expect(secretManager.Verify(ctx)).NotTo(Succeed())
+
+			By("registering the cluster")
+			Expect(secretManager.RegisterCluster(ctx)).To(Succeed())
+			Expect(secretManager.Verify(ctx)).To(Succeed())
+
+			By("checking the cluster Secret")
+			secret := &corev1.Secret{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: secretManager.secretName(), Namespace: defaultNamespace}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secret.Labels).To(HaveKeyWithValue(SecretTypeLabel, SecretTypeCluster))
+			Expect(string(secret.Data["name"])).To(Equal("declarative"))
+			Expect(string(secret.Data["server"])).To(Equal("Host.Example.com:6443"))
+
+			By("describing the registration")
+			info, err := secretManager.Describe(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ClusterID).To(Equal(secret.Name))
+			Expect(info.ServerVersion).To(BeEmpty())
+
+			config := &ClusterConfig{}
+			Expect(json.Unmarshal(secret.Data["config"], config)).To(Succeed())
+			Expect(config.TLSClientConfig.CAData).NotTo(BeEmpty())
+			Expect(config.TLSClientConfig.CertData).NotTo(BeEmpty())
+
+			By("checking that no drift is reported when the Secret matches the desired state")
+			drifted, err := secretManager.SyncCluster(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifted).To(BeFalse())
+
+			By("checking that the drift is corrected when the Secret is edited out-of-band")
+			secret.Data["server"] = []byte("https://edited:443")
+			Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+			drifted, err = secretManager.SyncCluster(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifted).To(BeTrue())
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: secretManager.secretName(), Namespace: defaultNamespace}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(secret.Data["server"])).To(Equal("Host.Example.com:6443"))
+
+			By("registering the cluster again")
+			Expect(secretManager.RegisterCluster(ctx)).To(Succeed())
+
+			By("releasing the cluster")
+			Expect(secretManager.ReleaseCluster(ctx)).To(Succeed())
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: secretManager.secretName(), Namespace: defaultNamespace}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secret.Labels).NotTo(HaveKey(ManagedByLabel))
+			Expect(secret.Labels).To(HaveKeyWithValue(SecretTypeLabel, SecretTypeCluster))
+
+			By("unregistering the cluster")
+			Expect(secretManager.UnRegisterCluster(ctx)).To(Succeed())
+			registered, err = secretManager.IsClusterRegistered(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registered).To(BeFalse())
+		})
+
+		It("should obtain the credentials via the exec provider when it is informed", func() {
+			secretManager, err := NewSecretManagerWithCluster(ctx, k8sClient, logr.Discard(), cluster,
+				[]byte(mocks.MockKubeConfig))
+			Expect(err).NotTo(HaveOccurred())
+			secretManager.Options = ClusterOptions{ExecProviderConfig: &ExecProviderConfig{
+				Command:    "gke-gcloud-auth-plugin",
+				Args:       []string{"--use_application_default_credentials"},
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+			}}
+			Expect(secretManager.RegisterCluster(ctx)).To(Succeed())
+			defer func() { Expect(secretManager.UnRegisterCluster(ctx)).To(Succeed()) }()
+
+			secret := &corev1.Secret{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: secretManager.secretName(), Namespace: defaultNamespace}, secret)
+			Expect(err).NotTo(HaveOccurred())
+
+			config := &ClusterConfig{}
+			Expect(json.Unmarshal(secret.Data["config"], config)).To(Succeed())
+			Expect(config.ExecProviderConfig).To(Equal(secretManager.Options.ExecProviderConfig))
+			Expect(config.TLSClientConfig.CAData).NotTo(BeEmpty())
+			Expect(config.TLSClientConfig.CertData).To(BeEmpty())
+			Expect(config.TLSClientConfig.KeyData).To(BeEmpty())
+		})
+
+		It("should restrict the namespaces allowed to be used by ArgoCD", func() {
+			secretManager, err := NewSecretManagerWithCluster(ctx, k8sClient, logr.Discard(), cluster,
+				[]byte(mocks.MockKubeConfig))
+			Expect(err).NotTo(HaveOccurred())
+			secretManager.Options = ClusterOptions{Namespaces: []string{"tenant-a", "tenant-b"}}
+			Expect(secretManager.RegisterCluster(ctx)).To(Succeed())
+			defer func() { Expect(secretManager.UnRegisterCluster(ctx)).To(Succeed()) }()
+
+			secret := &corev1.Secret{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: secretManager.secretName(), Namespace: defaultNamespace}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(secret.Data["namespaces"])).To(Equal("tenant-a,tenant-b"))
+			Expect(secret.Data).NotTo(HaveKey("clusterResources"))
+
+			By("allowing ArgoCD to manage the cluster-scoped resources")
+			secretManager.Options.ClusterResources = true
+			Expect(secretManager.RegisterCluster(ctx)).To(Succeed())
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: secretManager.secretName(), Namespace: defaultNamespace}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(secret.Data["clusterResources"])).To(Equal("true"))
+		})
+
+		It("should register the cluster within the project informed", func() {
+			secretManager, err := NewSecretManagerWithCluster(ctx, k8sClient, logr.Discard(), cluster,
+				[]byte(mocks.MockKubeConfig))
+			Expect(err).NotTo(HaveOccurred())
+			secretManager.Options = ClusterOptions{Project: "tenant-a"}
+			Expect(secretManager.RegisterCluster(ctx)).To(Succeed())
+			defer func() { Expect(secretManager.UnRegisterCluster(ctx)).To(Succeed()) }()
+
+			secret := &corev1.Secret{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: secretManager.secretName(), Namespace: defaultNamespace}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(secret.Data["project"])).To(Equal("tenant-a"))
+		})
+
+		It("should register the cluster with the defaults of the ArgoCD instance informed", func() {
+			shard := int64(3)
+			instance := &Instance{Namespace: defaultNamespace, Project: "fleet", Shard: &shard}
+			registrar, err := NewRegistrar(ctx, k8sClient, logr.Discard(), "Declarative", cluster,
+				[]byte(mocks.MockKubeConfig), ClusterOptions{Instance: instance})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registrar.RegisterCluster(ctx)).To(Succeed())
+			secretManager := registrar.(*SecretManager)
+			defer func() { Expect(secretManager.UnRegisterCluster(ctx)).To(Succeed()) }()
+
+			secret := &corev1.Secret{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: secretManager.secretName(), Namespace: defaultNamespace}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(secret.Data["project"])).To(Equal("fleet"))
+			Expect(string(secret.Data["shard"])).To(Equal("3"))
+		})
+	})
+})
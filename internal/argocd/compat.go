@@ -0,0 +1,111 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// versionCacheTTL defines how long the version of ArgoCD is cached before it is queried again, so that
+// the requests are adapted once ArgoCD is upgraded
+const versionCacheTTL = 10 * time.Minute
+
+var (
+	// minVersionClusterMetadata is the first version of ArgoCD which accepts the labels and the
+	// annotations of the cluster entries
+	minVersionClusterMetadata = version.MustParseGeneric("2.1.0")
+
+	// minVersionProjectScopedClusters is the first version of ArgoCD which supports the cluster entries
+	// scoped to an AppProject
+	minVersionProjectScopedClusters = version.MustParseGeneric("2.2.0")
+)
+
+// cachedVersion stores the version of ArgoCD reported by its API
+type cachedVersion struct {
+	version   string
+	checkedAt time.Time
+}
+
+// serverVersions caches the version of ArgoCD by endpoint so that it is not queried before every request.
+// It is safe for concurrent use.
+var serverVersions = struct {
+	mu       sync.Mutex
+	versions map[string]cachedVersion
+}{versions: map[string]cachedVersion{}}
+
+// recordServerVersion caches the version of ArgoCD reported by its API, logging when it changed,
+// i.e. when ArgoCD was upgraded
+func (a *APIManager) recordServerVersion(serverVersion string) {
+	serverVersions.mu.Lock()
+	defer serverVersions.mu.Unlock()
+	if previous, ok := serverVersions.versions[a.Endpoint]; !ok || previous.version != serverVersion {
+		a.Log.Info("Connected to ArgoCD", "endpoint", a.Endpoint, "version", serverVersion)
+	}
+	serverVersions.versions[a.Endpoint] = cachedVersion{version: serverVersion, checkedAt: time.Now()}
+}
+
+// serverVersion returns the version of ArgoCD, querying its API when it is not cached. It returns nil
+// when the version cannot be determined, i.e. for development builds, so that no shim is applied.
+func (a *APIManager) serverVersion(ctx context.Context) *version.Version {
+	serverVersions.mu.Lock()
+	cached, ok := serverVersions.versions[a.Endpoint]
+	serverVersions.mu.Unlock()
+
+	if !ok || time.Since(cached.checkedAt) > versionCacheTTL {
+		serverVersion, err := a.fetchServerVersion(ctx)
+		if err != nil {
+			a.Log.V(1).Info("Unable to determine the version of ArgoCD, assuming the latest one",
+				"reason", err.Error())
+			return nil
+		}
+		a.recordServerVersion(serverVersion)
+		cached.version = serverVersion
+	}
+
+	parsed, err := version.ParseGeneric(cached.version)
+	if err != nil {
+		return nil
+	}
+	return parsed
+}
+
+// adaptCluster adapts the cluster entry sent to the ArgoCD API to the version of ArgoCD, so that the
+// fleets with ArgoCD instances of different versions are supported by the same operator. The settings
+// which are not supported are dropped when they only describe the cluster, while an error is returned
+// when dropping them would grant more permissions than desired.
+func (a *APIManager) adaptCluster(ctx context.Context, cluster *Cluster) error {
+	serverVersion := a.serverVersion(ctx)
+	if serverVersion == nil {
+		return nil
+	}
+	if serverVersion.LessThan(minVersionProjectScopedClusters) && cluster.Project != "" {
+		return fmt.Errorf("the cluster is scoped to the AppProject %s but ArgoCD %s does not support "+
+			"project-scoped clusters, v%s or later is required", cluster.Project, serverVersion,
+			minVersionProjectScopedClusters)
+	}
+	if serverVersion.LessThan(minVersionClusterMetadata) {
+		a.Log.V(1).Info("ArgoCD does not support the labels and annotations of the clusters, they are not sent",
+			"version", serverVersion.String())
+		cluster.Labels, cluster.Annotations = nil, nil
+	}
+	return nil
+}
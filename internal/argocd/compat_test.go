@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/workload-operator/internal/argocd/mocks"
+)
+
+var _ = Describe("ArgoCD API compatibility", func() {
+	ctx := context.Background()
+	var server *httptest.Server
+	var serverVersion string
+	var versionRequests int
+	var payload map[string]interface{}
+
+	BeforeEach(func() {
+		versionRequests = 0
+		payload = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/api/version":
+				versionRequests++
+				_, _ = fmt.Fprintf(w, `{"Version":%q}`, serverVersion)
+			case r.Method == http.MethodPost && r.URL.Path == "/api/v1/clusters":
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newAPIManager := func() *APIManager {
+		return &APIManager{
+			Token:       "token-test",
+			Log:         logr.Discard(),
+			Server:      "Host:80",
+			Name:        "test",
+			Labels:      map[string]string{"region": "eu-west-1"},
+			Annotations: map[string]string{"description.example.com/maintainer": "team-a"},
+			KubeConfig:  []byte(mocks.MockKubeConfig),
+			Endpoint:    server.URL,
+		}
+	}
+
+	It("should query the version of ArgoCD once to register the clusters", func() {
+		serverVersion = "v3.0.6+db93798"
+		apiManager := newAPIManager()
+		apiManager.Options = ClusterOptions{Project: "tenant-a"}
+		Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+		Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+		Expect(versionRequests).To(Equal(1))
+		Expect(payload).To(HaveKeyWithValue("project", "tenant-a"))
+		Expect(payload).To(HaveKey("labels"))
+		Expect(payload).To(HaveKey("annotations"))
+	})
+
+	It("should not send the labels and annotations to the versions which do not support them", func() {
+		serverVersion = "v2.0.5+4c94d88"
+		Expect(newAPIManager().RegisterCluster(ctx)).To(Succeed())
+		Expect(payload).To(HaveKeyWithValue("server", "Host:80"))
+		Expect(payload).NotTo(HaveKey("labels"))
+		Expect(payload).NotTo(HaveKey("annotations"))
+	})
+
+	It("should fail to register a project-scoped cluster within the versions which do not support it", func() {
+		serverVersion = "v2.1.16+7ebf7a0"
+		apiManager := newAPIManager()
+		apiManager.Options = ClusterOptions{Project: "tenant-a"}
+		Expect(apiManager.RegisterCluster(ctx)).To(MatchError(ContainSubstring("v2.2.0 or later is required")))
+		Expect(payload).To(BeNil())
+	})
+
+	It("should not adapt the requests when the version cannot be determined", func() {
+		serverVersion = "unknown"
+		apiManager := newAPIManager()
+		apiManager.Options = ClusterOptions{Project: "tenant-a"}
+		Expect(apiManager.RegisterCluster(ctx)).To(Succeed())
+		Expect(payload).To(HaveKeyWithValue("project", "tenant-a"))
+		Expect(payload).To(HaveKey("labels"))
+	})
+})
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// serverVersionTimeout is the timeout of the request sent to the workload cluster to check that
+// it is reachable with the kubeconfig
+const serverVersionTimeout = 10 * time.Second
+
+// ServerVersion connects to the workload cluster with the kubeconfig and returns the version of its
+// Kubernetes API server. It allows to check that the kubeconfig is not only well-formed but that its
+// endpoint is reachable and its credentials are accepted before the cluster is registered within ArgoCD.
+func ServerVersion(ctx context.Context, kubeConfig []byte) (string, error) {
+	config, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return "", fmt.Errorf("error loading kubeconfig: %w", RedactError(err))
+	}
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return "", fmt.Errorf("error loading kubeconfig: %w", RedactError(err))
+	}
+	restConfig.Timeout = serverVersionTimeout
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return "", fmt.Errorf("error creating client for the workload cluster: %w", err)
+	}
+	body, err := discoveryClient.RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		return "", fmt.Errorf("error connecting to the workload cluster: %w", err)
+	}
+
+	info := version.Info{}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("error decoding the version of the workload cluster: %w", err)
+	}
+	return info.GitVersion, nil
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("Workload cluster connectivity", func() {
+	ctx := context.Background()
+
+	It("should return the version of the workload cluster", func() {
+		serverVersion, err := ServerVersion(ctx, kubeConfigFromRESTConfig(cfg))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(serverVersion).To(HavePrefix("v1."))
+	})
+
+	It("should return an error when the credentials are rejected", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		_, err := ServerVersion(ctx, kubeConfigFromRESTConfig(&rest.Config{Host: server.URL}))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("error connecting to the workload cluster"))
+	})
+
+	It("should return an error when the kubeconfig is not valid", func() {
+		_, err := ServerVersion(ctx, []byte("invalid"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,118 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CoreModeEnvVar store the name of the envvar used to provide whether ArgoCD runs in core mode, which
+	// is without the argocd-server API, therefore, the clusters can only be registered declaratively.
+	// When it is not provided it is detected from the Deployments in the ArgoCD namespace.
+	CoreModeEnvVar = "ARGOCD_CORE_MODE"
+
+	// coreModeCacheTTL defines how long the core mode detected is cached before it is checked again,
+	// i.e. once the argocd-server is installed
+	coreModeCacheTTL = 5 * time.Minute
+)
+
+// cachedCoreMode stores whether ArgoCD was detected running in core mode
+type cachedCoreMode struct {
+	coreMode  bool
+	checkedAt time.Time
+}
+
+// coreModeDetection caches by namespace whether ArgoCD runs in core mode. It is safe for concurrent use.
+var coreModeDetection = struct {
+	mu         sync.Mutex
+	reader     client.Reader
+	namespaces map[string]cachedCoreMode
+}{namespaces: map[string]cachedCoreMode{}}
+
+// EnableCoreModeDetection enables the detection of ArgoCD running in core mode when it is not provided via
+// the ARGOCD_CORE_MODE envvar. The reader informed is used to look up the argocd-server Deployment and the
+// argocd-cm ConfigMap in the ArgoCD namespace, i.e. the API reader of the Manager, so that all the
+// Deployments of the cluster are not cached.
+func EnableCoreModeDetection(reader client.Reader) {
+	coreModeDetection.mu.Lock()
+	defer coreModeDetection.mu.Unlock()
+	coreModeDetection.reader = reader
+	coreModeDetection.namespaces = map[string]cachedCoreMode{}
+}
+
+// CoreMode returns true when ArgoCD, deployed in the namespace informed, runs in core mode as provided
+// via Manager ENV VAR or, when it is not provided, as detected: ArgoCD is installed, since its argocd-cm
+// ConfigMap exists, but the argocd-server Deployment does not. It returns false when the detection is
+// not enabled.
+func CoreMode(ctx context.Context, namespace string) (bool, error) {
+	if value, exists := os.LookupEnv(CoreModeEnvVar); exists && value != "" {
+		coreMode, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid value %q for %s: %w", value, CoreModeEnvVar, err)
+		}
+		return coreMode, nil
+	}
+
+	coreModeDetection.mu.Lock()
+	defer coreModeDetection.mu.Unlock()
+	if coreModeDetection.reader == nil {
+		return false, nil
+	}
+	if cached, ok := coreModeDetection.namespaces[namespace]; ok && time.Since(cached.checkedAt) < coreModeCacheTTL {
+		return cached.coreMode, nil
+	}
+
+	serverExists, err := objectExists(ctx, coreModeDetection.reader,
+		schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, namespace, serverDeploymentName)
+	if err != nil {
+		return false, err
+	}
+	coreMode := false
+	if !serverExists {
+		if coreMode, err = objectExists(ctx, coreModeDetection.reader,
+			schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, namespace, configMapName); err != nil {
+			return false, err
+		}
+	}
+	coreModeDetection.namespaces[namespace] = cachedCoreMode{coreMode: coreMode, checkedAt: time.Now()}
+	return coreMode, nil
+}
+
+// objectExists returns true when the object of the kind informed exists, fetching only its metadata
+func objectExists(ctx context.Context, reader client.Reader, kind schema.GroupVersionKind,
+	namespace, name string) (bool, error) {
+	object := &metav1.PartialObjectMetadata{}
+	object.SetGroupVersionKind(kind)
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, object); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error fetching the %s %s/%s: %w", kind.Kind, namespace, name, err)
+	}
+	return true, nil
+}
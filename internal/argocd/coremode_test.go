@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"os"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd/mocks"
+)
+
+var _ = Describe("ArgoCD core mode", func() {
+	ctx := context.Background()
+	const namespace = "argocd-core"
+
+	cluster := &clusterapiv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "core", Namespace: "test"},
+		Spec: clusterapiv1.ClusterSpec{
+			ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "core.example.com", Port: 6443},
+		},
+	}
+
+	BeforeEach(func() {
+		By("creating the ArgoCD namespace installed in core mode")
+		err := k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+		Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
+		Expect(k8sClient.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: configMapName,
+			Namespace: namespace}})).To(Succeed())
+
+		Expect(os.Setenv(NamespaceEnvVar, namespace)).To(Succeed())
+		EnableCoreModeDetection(k8sClient)
+	})
+
+	AfterEach(func() {
+		_ = os.Unsetenv(NamespaceEnvVar)
+		EnableCoreModeDetection(nil)
+		Expect(k8sClient.Delete(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: configMapName,
+			Namespace: namespace}})).To(Succeed())
+	})
+
+	It("should register the clusters declaratively when ArgoCD runs in core mode", func() {
+		registrar, err := NewRegistrar(ctx, k8sClient, logr.Discard(), argocdv1beta1.RegistrationModeAPI, cluster,
+			[]byte(mocks.MockKubeConfig), ClusterOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registrar).To(BeAssignableToTypeOf(&SecretManager{}))
+		Expect(registrar.(*SecretManager).Namespace).To(Equal(namespace))
+	})
+
+	It("should register the clusters via the API once the argocd-server is deployed", func() {
+		labels := map[string]string{"app.kubernetes.io/name": serverDeploymentName}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: serverDeploymentName, Namespace: namespace},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{
+						{Name: "argocd-server", Image: "quay.io/argoproj/argocd:v2.8.4"}}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		defer func() { _ = k8sClient.Delete(ctx, deployment) }()
+
+		coreMode, err := CoreMode(ctx, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(coreMode).To(BeFalse())
+	})
+
+	It("should use the core mode provided via Manager ENV VAR", func() {
+		Expect(os.Setenv(CoreModeEnvVar, "false")).To(Succeed())
+		defer func() { _ = os.Unsetenv(CoreModeEnvVar) }()
+
+		coreMode, err := CoreMode(ctx, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(coreMode).To(BeFalse())
+
+		Expect(os.Setenv(CoreModeEnvVar, "maybe")).To(Succeed())
+		_, err = CoreMode(ctx, namespace)
+		Expect(err).To(MatchError(ContainSubstring(CoreModeEnvVar)))
+	})
+})
@@ -0,0 +1,480 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CredentialsProviderEnvVar store the name of the envvar used to provide where the credentials
+	// of the ArgoCD account are sourced from (Secret or Vault)
+	CredentialsProviderEnvVar = "ARGOCD_CREDENTIALS_PROVIDER"
+
+	// CredentialsProviderSecret sources the credentials from the Secret in the ArgoCD namespace
+	CredentialsProviderSecret = "Secret"
+
+	// CredentialsProviderVault sources the credentials from HashiCorp Vault
+	CredentialsProviderVault = "Vault"
+
+	// CredentialsProviderFile sources the API token from the file configured via SetTokenFile
+	CredentialsProviderFile = "File"
+
+	// VaultAddressEnvVar store the name of the envvar used to provide the address of Vault
+	VaultAddressEnvVar = "VAULT_ADDR"
+
+	// VaultTokenEnvVar store the name of the envvar used to provide the Vault token. When it is not
+	// provided the operator authenticates within Vault via the Kubernetes auth method.
+	VaultTokenEnvVar = "VAULT_TOKEN"
+
+	// VaultCACertEnvVar store the name of the envvar used to provide the path of the CA certificate
+	// trusted to connect to Vault
+	VaultCACertEnvVar = "VAULT_CACERT"
+
+	// VaultPathEnvVar store the name of the envvar used to provide the path of the Vault secret which
+	// stores the credentials, i.e. secret/data/argocd for the KV version 2 secrets engine
+	VaultPathEnvVar = "ARGOCD_VAULT_PATH"
+
+	// VaultRoleEnvVar store the name of the envvar used to provide the role of the Kubernetes auth method
+	VaultRoleEnvVar = "ARGOCD_VAULT_ROLE"
+
+	// VaultAuthPathEnvVar store the name of the envvar used to provide the path where the Kubernetes
+	// auth method is enabled
+	VaultAuthPathEnvVar = "ARGOCD_VAULT_AUTH_PATH"
+
+	defaultVaultAuthPath = "kubernetes"
+
+	// serviceAccountTokenFile is the token of the ServiceAccount of the Manager used to authenticate within Vault
+	serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token" // #nosec G101
+
+	// vaultRequestTimeout is the timeout of the requests to Vault
+	vaultRequestTimeout = 30 * time.Second
+)
+
+// Credentials of the ArgoCD account used by the operator. Either the Token or the Password is informed.
+type Credentials struct {
+	Token    string // API token of an ArgoCD local account
+	Username string // Username of the ArgoCD account used to create a session
+	Password string // Password of the ArgoCD account used to create a session
+}
+
+// CredentialsProvider is implemented by the sources of the credentials of the ArgoCD account.
+type CredentialsProvider interface {
+	// Credentials returns the current credentials of the ArgoCD account
+	Credentials(ctx context.Context) (*Credentials, error)
+}
+
+var _ CredentialsProvider = &SecretCredentialsProvider{}
+var _ CredentialsProvider = &VaultCredentialsProvider{}
+var _ CredentialsProvider = &FileCredentialsProvider{}
+
+// tokenFile is the path of the file with the API token of the ArgoCD account, i.e. mounted from a
+// projected Secret or a CSI secrets-store volume
+var tokenFile string
+
+// SetTokenFile configures the path of the file with the API token of the ArgoCD account. When it is
+// informed the token is read from the file instead of the Secret in the ArgoCD namespace, therefore,
+// the operator does not require permissions to get Secrets in the ArgoCD namespace.
+func SetTokenFile(path string) {
+	tokenFile = path
+}
+
+// credentialsProvider returns the name of the CredentialsProvider defined via Manager ENV VAR. It defaults
+// to the File when the token file is configured, otherwise, to the Secret in the ArgoCD namespace.
+func credentialsProvider() string {
+	if provider := os.Getenv(CredentialsProviderEnvVar); provider != "" {
+		return provider
+	}
+	if tokenFile != "" {
+		return CredentialsProviderFile
+	}
+	return CredentialsProviderSecret
+}
+
+// NewCredentialsProvider returns the CredentialsProvider defined via Manager ENV VAR or the token file.
+func NewCredentialsProvider(client client.Client, namespace string, log logr.Logger) (CredentialsProvider, error) {
+	switch provider := credentialsProvider(); provider {
+	case CredentialsProviderSecret:
+		name, exists := os.LookupEnv(SecretNameEnvVar)
+		if !exists {
+			log.Info(fmt.Sprintf("Argo Instance Secret Name is not provided via Manager ENV VAR, "+
+				"using default value (%s)", defaultSecretName))
+			name = defaultSecretName
+		}
+		return &SecretCredentialsProvider{Client: client, Namespace: namespace, Name: name}, nil
+	case CredentialsProviderVault:
+		return newVaultCredentialsProvider()
+	case CredentialsProviderFile:
+		if tokenFile == "" {
+			return nil, fmt.Errorf("the token file must be configured to use the %s provider", CredentialsProviderFile)
+		}
+		return &FileCredentialsProvider{Path: tokenFile}, nil
+	default:
+		return nil, fmt.Errorf("invalid value %q for %s: it must be %s, %s or %s", provider,
+			CredentialsProviderEnvVar, CredentialsProviderSecret, CredentialsProviderVault, CredentialsProviderFile)
+	}
+}
+
+// credentialsFromData returns the credentials stored under the TokenSecretKey or, the PasswordSecretKey
+// and UsernameSecretKey keys. The API token of a dedicated local account is preferred since it allows the
+// operator to interact with ArgoCD using a least-privilege identity instead of the admin account.
+func credentialsFromData(data map[string]string) (*Credentials, error) {
+	if token := data[TokenSecretKey]; token != "" {
+		return &Credentials{Token: token}, nil
+	}
+
+	password, ok := data[PasswordSecretKey]
+	if !ok {
+		return nil, fmt.Errorf("neither %s nor %s found", TokenSecretKey, PasswordSecretKey)
+	}
+	username := defaultUsername
+	if value, ok := data[UsernameSecretKey]; ok {
+		username = value
+	}
+	return &Credentials{Username: username, Password: password}, nil
+}
+
+// SecretCredentialsProvider sources the credentials of the ArgoCD account from a Kubernetes Secret.
+type SecretCredentialsProvider struct {
+	Client    client.Client // Kubernetes client
+	Namespace string        // Namespace of the Secret
+	Name      string        // Name of the Secret
+}
+
+// Credentials returns the credentials stored in the Secret.
+func (p *SecretCredentialsProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	secret := &v1.Secret{}
+	if err := p.Client.Get(ctx, client.ObjectKey{Namespace: p.Namespace, Name: p.Name}, secret); err != nil {
+		return nil, fmt.Errorf("error fetching secret: %w", err)
+	}
+
+	data := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		data[key] = string(value)
+	}
+	credentials, err := credentialsFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w in secret", err)
+	}
+	return credentials, nil
+}
+
+// FileCredentialsProvider sources the API token of the ArgoCD account from a file. The file is read on
+// every call, therefore, the token rotated by the kubelet or the CSI driver is picked up without restarts.
+type FileCredentialsProvider struct {
+	Path string // Path of the file with the API token
+}
+
+// Credentials returns the API token stored in the file.
+func (p *FileCredentialsProvider) Credentials(_ context.Context) (*Credentials, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("token file %s is empty", p.Path)
+	}
+	return &Credentials{Token: token}, nil
+}
+
+// VaultCredentialsProvider sources the credentials of the ArgoCD account from a HashiCorp Vault secret,
+// for environments where the Secrets of the ArgoCD namespace are not readable by the workloads.
+// Both versions of the KV secrets engine are supported.
+// More info: https://developer.hashicorp.com/vault/api-docs/secret/kv
+type VaultCredentialsProvider struct {
+	Address    string       // Address of Vault, i.e. https://vault.example.com:8200
+	Path       string       // Path of the secret which stores the credentials, i.e. secret/data/argocd
+	Token      string       // Vault token, when it is not informed the Kubernetes auth method is used
+	Role       string       // Role of the Kubernetes auth method
+	AuthPath   string       // Path where the Kubernetes auth method is enabled
+	TokenFile  string       // Token of the ServiceAccount used to authenticate via the Kubernetes auth method
+	HTTPClient *http.Client // Client used to send the requests to Vault
+}
+
+// vaultProvider stores the VaultCredentialsProvider configured via Manager ENV VAR, so that its HTTP client,
+// therefore its connections to Vault, is shared by all APIManagers until the configuration changes
+var vaultProvider = struct {
+	mu       sync.Mutex
+	config   string
+	provider *VaultCredentialsProvider
+}{}
+
+// newVaultCredentialsProvider returns the VaultCredentialsProvider configured via Manager ENV VAR. It is only
+// created again when the configuration changes, i.e. once the CA certificate is rotated.
+func newVaultCredentialsProvider() (*VaultCredentialsProvider, error) {
+	config := vaultProviderConfig()
+	vaultProvider.mu.Lock()
+	defer vaultProvider.mu.Unlock()
+	if vaultProvider.provider != nil && vaultProvider.config == config {
+		return vaultProvider.provider, nil
+	}
+	provider, err := loadVaultCredentialsProvider()
+	if err != nil {
+		return nil, err
+	}
+	if vaultProvider.provider != nil {
+		vaultProvider.provider.HTTPClient.CloseIdleConnections()
+	}
+	vaultProvider.config, vaultProvider.provider = config, provider
+	return provider, nil
+}
+
+// vaultProviderConfig returns the configuration of Vault provided via Manager ENV VAR, including when the
+// CA certificate was last modified so that the rotated one is loaded
+func vaultProviderConfig() string {
+	config := []string{os.Getenv(VaultAddressEnvVar), os.Getenv(VaultPathEnvVar), os.Getenv(VaultTokenEnvVar),
+		os.Getenv(VaultRoleEnvVar), os.Getenv(VaultAuthPathEnvVar), os.Getenv(VaultCACertEnvVar)}
+	if caCertFile := os.Getenv(VaultCACertEnvVar); caCertFile != "" {
+		if info, err := os.Stat(caCertFile); err == nil {
+			config = append(config, info.ModTime().String())
+		}
+	}
+	return strings.Join(config, "\n")
+}
+
+// loadVaultCredentialsProvider creates the VaultCredentialsProvider configured via Manager ENV VAR.
+func loadVaultCredentialsProvider() (*VaultCredentialsProvider, error) {
+	provider := &VaultCredentialsProvider{
+		Address:   strings.TrimSuffix(os.Getenv(VaultAddressEnvVar), "/"),
+		Path:      strings.Trim(os.Getenv(VaultPathEnvVar), "/"),
+		Token:     os.Getenv(VaultTokenEnvVar),
+		Role:      os.Getenv(VaultRoleEnvVar),
+		AuthPath:  strings.Trim(os.Getenv(VaultAuthPathEnvVar), "/"),
+		TokenFile: serviceAccountTokenFile,
+	}
+	if provider.Address == "" || provider.Path == "" {
+		return nil, fmt.Errorf("%s and %s are required to source the credentials from Vault",
+			VaultAddressEnvVar, VaultPathEnvVar)
+	}
+	if provider.Token == "" && provider.Role == "" {
+		return nil, fmt.Errorf("either %s or %s is required to authenticate within Vault",
+			VaultTokenEnvVar, VaultRoleEnvVar)
+	}
+	if provider.AuthPath == "" {
+		provider.AuthPath = defaultVaultAuthPath
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caCertFile := os.Getenv(VaultCACertEnvVar); caCertFile != "" {
+		caCert, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", VaultCACertEnvVar, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid certificate found in %s", caCertFile)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	provider.HTTPClient = &http.Client{Transport: transport, Timeout: vaultRequestTimeout}
+	return provider, nil
+}
+
+// vaultToken stores a token issued by Vault via the Kubernetes auth method
+type vaultToken struct {
+	token     string
+	expiresAt time.Time // zero when the token does not expire
+}
+
+// vaultTokenStore caches the tokens issued by Vault by address and role so that the operator does not
+// authenticate within Vault on every reconciliation. It is safe for concurrent use.
+type vaultTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]vaultToken
+}
+
+// vaultTokens is shared by all VaultCredentialsProviders
+var vaultTokens = &vaultTokenStore{tokens: map[string]vaultToken{}}
+
+func (s *vaultTokenStore) get(key string, now time.Time) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.tokens[key]
+	if !ok || (!cached.expiresAt.IsZero() && now.Add(sessionRefreshWindow).After(cached.expiresAt)) {
+		return "", false
+	}
+	return cached.token, true
+}
+
+func (s *vaultTokenStore) set(key string, cached vaultToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = cached
+}
+
+func (s *vaultTokenStore) invalidate(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, key)
+}
+
+// Credentials returns the credentials stored in the Vault secret.
+func (p *VaultCredentialsProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	token, err := p.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.send(ctx, http.MethodGet, p.Path, nil, token)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Vault response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// The cached token might have been revoked, therefore, a new one is issued on the next attempt
+		vaultTokens.invalidate(p.tokenKey())
+		return nil, fmt.Errorf("error reading Vault secret %s: status %d: %s", p.Path, resp.StatusCode,
+			Redact(strings.TrimSpace(string(body))))
+	}
+
+	data, err := vaultSecretData(body)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := credentialsFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w in Vault secret %s", err, p.Path)
+	}
+	return credentials, nil
+}
+
+// vaultSecretData returns the data of the secret read from the KV secrets engine. The data of the
+// version 2 is nested with its metadata.
+func vaultSecretData(body []byte) (map[string]string, error) {
+	secret := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("error decoding Vault secret: %w", err)
+	}
+
+	values := secret.Data
+	if nested, ok := secret.Data["data"].(map[string]interface{}); ok && secret.Data["metadata"] != nil {
+		values = nested
+	}
+	data := make(map[string]string, len(values))
+	for key, value := range values {
+		if value, ok := value.(string); ok {
+			data[key] = value
+		}
+	}
+	return data, nil
+}
+
+// tokenKey returns the key used to cache the Vault token of the role
+func (p *VaultCredentialsProvider) tokenKey() string {
+	return p.Address + "|" + p.AuthPath + "|" + p.Role
+}
+
+// token returns the Vault token informed or, the one issued via the Kubernetes auth method
+// reusing the cached one while it is valid.
+// More info: https://developer.hashicorp.com/vault/api-docs/auth/kubernetes#login
+func (p *VaultCredentialsProvider) token(ctx context.Context) (string, error) {
+	if p.Token != "" {
+		return p.Token, nil
+	}
+	if token, ok := vaultTokens.get(p.tokenKey(), time.Now()); ok {
+		return token, nil
+	}
+
+	jwt, err := os.ReadFile(p.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading the ServiceAccount token to authenticate within Vault: %w", err)
+	}
+	payload, err := json.Marshal(map[string]string{"role": p.Role, "jwt": strings.TrimSpace(string(jwt))})
+	if err != nil {
+		return "", fmt.Errorf("error marshalling Vault login payload: %w", err)
+	}
+
+	resp, err := p.send(ctx, http.MethodPost, "auth/"+p.AuthPath+"/login", payload, "")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading Vault login response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error authenticating within Vault: status %d: %s", resp.StatusCode,
+			Redact(strings.TrimSpace(string(body))))
+	}
+
+	login := struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}{}
+	if err := json.Unmarshal(body, &login); err != nil {
+		return "", fmt.Errorf("error decoding Vault login response: %w", err)
+	}
+	if login.Auth.ClientToken == "" {
+		return "", fmt.Errorf("no token returned by Vault for the role %s", p.Role)
+	}
+
+	cached := vaultToken{token: login.Auth.ClientToken}
+	if login.Auth.LeaseDuration > 0 {
+		cached.expiresAt = time.Now().Add(time.Duration(login.Auth.LeaseDuration) * time.Second)
+	}
+	vaultTokens.set(p.tokenKey(), cached)
+	return cached.token, nil
+}
+
+// send sends the request to the Vault API
+func (p *VaultCredentialsProvider) send(ctx context.Context, method, path string, payload []byte,
+	token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.Address+"/v1/"+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating Vault request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request to Vault: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+var _ = Describe("ArgoCD credentials", func() {
+	ctx := context.Background()
+
+	AfterEach(func() {
+		_ = os.Unsetenv(CredentialsProviderEnvVar)
+		_ = os.Unsetenv(VaultAddressEnvVar)
+		_ = os.Unsetenv(VaultPathEnvVar)
+		_ = os.Unsetenv(VaultRoleEnvVar)
+		SetTokenFile("")
+	})
+
+	It("should source the credentials from the Secret by default", func() {
+		provider, err := NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(provider).To(BeAssignableToTypeOf(&SecretCredentialsProvider{}))
+	})
+
+	It("should return an error when the provider is not valid", func() {
+		Expect(os.Setenv(CredentialsProviderEnvVar, "Unknown")).To(Succeed())
+		_, err := NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error when the Vault configuration is incomplete", func() {
+		Expect(os.Setenv(CredentialsProviderEnvVar, CredentialsProviderVault)).To(Succeed())
+		Expect(os.Setenv(VaultAddressEnvVar, "https://vault.example.com:8200")).To(Succeed())
+		_, err := NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).To(HaveOccurred())
+
+		Expect(os.Setenv(VaultPathEnvVar, "secret/data/argocd")).To(Succeed())
+		_, err = NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).To(HaveOccurred())
+
+		Expect(os.Setenv(VaultRoleEnvVar, "workload-operator")).To(Succeed())
+		provider, err := NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(provider.(*VaultCredentialsProvider).AuthPath).To(Equal(defaultVaultAuthPath))
+	})
+
+	It("should share the Vault provider until its configuration changes", func() {
+		Expect(os.Setenv(CredentialsProviderEnvVar, CredentialsProviderVault)).To(Succeed())
+		Expect(os.Setenv(VaultAddressEnvVar, "https://vault.example.com:8200")).To(Succeed())
+		Expect(os.Setenv(VaultPathEnvVar, "secret/data/argocd")).To(Succeed())
+		Expect(os.Setenv(VaultRoleEnvVar, "workload-operator")).To(Succeed())
+		first, err := NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		second, err := NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(second.(*VaultCredentialsProvider).HTTPClient).To(BeIdenticalTo(
+			first.(*VaultCredentialsProvider).HTTPClient))
+
+		By("creating the provider again once the configuration changes")
+		Expect(os.Setenv(VaultRoleEnvVar, "other")).To(Succeed())
+		third, err := NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(third).NotTo(BeIdenticalTo(first))
+		Expect(third.(*VaultCredentialsProvider).Role).To(Equal("other"))
+	})
+
+	It("should source the API token from the token file when it is configured", func() {
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("file-token\n"), 0o600)).To(Succeed())
+		SetTokenFile(tokenFile)
+
+		provider, err := NewCredentialsProvider(k8sClient, defaultNamespace, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		credentials, err := provider.Credentials(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(&Credentials{Token: "file-token"}))
+
+		By("ignoring the credentials Secret")
+		secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: defaultSecretName, Namespace: defaultNamespace}}
+		Expect(IsCredentialsSecret(secret)).To(BeFalse())
+
+		By("picking up the rotated token")
+		Expect(os.WriteFile(tokenFile, []byte("rotated-token"), 0o600)).To(Succeed())
+		credentials, err = provider.Credentials(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials.Token).To(Equal("rotated-token"))
+	})
+
+	It("should return an error when the token file is empty", func() {
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("\n"), 0o600)).To(Succeed())
+		_, err := (&FileCredentialsProvider{Path: tokenFile}).Credentials(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("Vault", func() {
+		var server *httptest.Server
+		var logins int
+		var secretBody string
+
+		BeforeEach(func() {
+			logins = 0
+			secretBody = `{"data":{"data":{"token":"api-token"},"metadata":{"version":1}}}`
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/auth/kubernetes/login":
+					login := map[string]string{}
+					body, _ := io.ReadAll(r.Body)
+					if err := json.Unmarshal(body, &login); err != nil ||
+						login["role"] != "workload-operator" || login["jwt"] != "sa-token" {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					logins++
+					_, _ = fmt.Fprint(w, `{"auth":{"client_token":"vault-token","lease_duration":3600}}`)
+				case "/v1/secret/data/argocd":
+					if r.Header.Get("X-Vault-Token") != "vault-token" {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					_, _ = fmt.Fprint(w, secretBody)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		newProvider := func() *VaultCredentialsProvider {
+			tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+			Expect(os.WriteFile(tokenFile, []byte("sa-token\n"), 0o600)).To(Succeed())
+			return &VaultCredentialsProvider{
+				Address:   server.URL,
+				Path:      "secret/data/argocd",
+				Role:      "workload-operator",
+				AuthPath:  defaultVaultAuthPath,
+				TokenFile: tokenFile,
+			}
+		}
+
+		It("should authenticate via the Kubernetes auth method and read the KV version 2 secret", func() {
+			credentials, err := newProvider().Credentials(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials).To(Equal(&Credentials{Token: "api-token"}))
+
+			By("reusing the cached Vault token")
+			_, err = newProvider().Credentials(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logins).To(Equal(1))
+		})
+
+		It("should read the KV version 1 secret with the username and password", func() {
+			secretBody = `{"data":{"username":"workload-operator","password":"password-test"}}`
+			provider := newProvider()
+			provider.Role = ""
+			provider.Token = "vault-token"
+			credentials, err := provider.Credentials(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials).To(Equal(&Credentials{Username: "workload-operator", Password: "password-test"}))
+			Expect(logins).To(BeZero())
+		})
+
+		It("should return an error when the secret has no credentials", func() {
+			secretBody = `{"data":{"data":{"other":"value"},"metadata":{"version":1}}}`
+			_, err := newProvider().Credentials(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("neither token nor password found"))
+		})
+
+		It("should return an error when Vault rejects the login", func() {
+			provider := newProvider()
+			provider.Role = "other"
+			_, err := provider.Credentials(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error authenticating within Vault"))
+		})
+	})
+})
@@ -0,0 +1,57 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// AgeKeySuffix is the suffix of the keys of the Secret which store the age private keys
+	// used to decrypt the kubeconfig encrypted with SOPS
+	AgeKeySuffix = ".agekey"
+
+	// sopsAgeKeyEnvVar is the env var used by sops to read the age private keys
+	sopsAgeKeyEnvVar = "SOPS_AGE_KEY"
+)
+
+// sopsCommand is the sops binary executed to decrypt the kubeconfig. It must be available in
+// the Manager image.
+var sopsCommand = "sops"
+
+// DecryptSOPS returns the YAML document encrypted with SOPS decrypted with the age private keys informed.
+// More info: https://github.com/getsops/sops#encrypting-using-age
+func DecryptSOPS(ctx context.Context, data []byte, ageKeys []string) ([]byte, error) {
+	// #nosec G204 -- the command is not provided by the users
+	cmd := exec.CommandContext(ctx, sopsCommand, "--decrypt", "--input-type", "yaml", "--output-type", "yaml",
+		"/dev/stdin")
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(), sopsAgeKeyEnvVar+"="+strings.Join(ageKeys, "\n"))
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	decrypted, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting with sops: %w: %s", err, Redact(strings.TrimSpace(stderr.String())))
+	}
+	return decrypted, nil
+}
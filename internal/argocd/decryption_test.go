@@ -0,0 +1,61 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeSOPS emulates sops by replacing the encrypted values when the expected age key is informed
+const fakeSOPS = `#!/bin/sh
+if [ "$SOPS_AGE_KEY" != "AGE-SECRET-KEY-1TEST" ]; then
+  echo "no matching age key found" >&2
+  exit 1
+fi
+sed 's/ENC\[token\]/decrypted-token/'
+`
+
+var _ = Describe("SOPS decryption", func() {
+	ctx := context.Background()
+	previousCommand := sopsCommand
+
+	BeforeEach(func() {
+		sopsCommand = filepath.Join(GinkgoT().TempDir(), "sops")
+		Expect(os.WriteFile(sopsCommand, []byte(fakeSOPS), 0o700)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		sopsCommand = previousCommand
+	})
+
+	It("should decrypt the kubeconfig with the age keys informed", func() {
+		decrypted, err := DecryptSOPS(ctx, []byte("token: ENC[token]\n"), []string{"AGE-SECRET-KEY-1TEST"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(decrypted)).To(Equal("token: decrypted-token\n"))
+	})
+
+	It("should return the error reported by sops", func() {
+		_, err := DecryptSOPS(ctx, []byte("token: ENC[token]\n"), []string{"AGE-SECRET-KEY-1OTHER"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("no matching age key found"))
+	})
+})
@@ -0,0 +1,223 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// EndpointDiscoveryEnvVar store the name of the envvar used to provide how the ArgoCD API endpoint
+	// is discovered from the argocd-server Service, in the ArgoCD namespace, when it is not provided via
+	// the ARGOAPI_ENDPOINT envvar (Service, LoadBalancer, Ingress or Route)
+	EndpointDiscoveryEnvVar = "ARGOAPI_ENDPOINT_DISCOVERY"
+
+	// EndpointDiscoveryService uses the in-cluster DNS name of the argocd-server Service
+	EndpointDiscoveryService = "Service"
+
+	// EndpointDiscoveryLoadBalancer uses the address assigned to the argocd-server Service of type LoadBalancer
+	EndpointDiscoveryLoadBalancer = "LoadBalancer"
+
+	// EndpointDiscoveryIngress uses the host of the Ingress which routes to the argocd-server Service
+	EndpointDiscoveryIngress = "Ingress"
+
+	// EndpointDiscoveryRoute uses the host of the OpenShift Route which routes to the argocd-server Service
+	EndpointDiscoveryRoute = "Route"
+
+	// serverServiceName is the Service created by ArgoCD to expose its API
+	serverServiceName = "argocd-server"
+
+	// serverServicePortName is the port of the argocd-server Service which serves the API over TLS
+	serverServicePortName = "https"
+)
+
+// routeListGVK is the kind of the list of the OpenShift Routes, which are not part of the scheme of the Manager
+var routeListGVK = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "RouteList"}
+
+// discoveredEndpoints records the last ArgoCD API endpoint discovered per namespace, so that its changes,
+// i.e. when the address of the LoadBalancer is re-assigned, are reported
+var discoveredEndpoints = struct {
+	mu        sync.Mutex
+	endpoints map[string]string
+}{endpoints: map[string]string{}}
+
+// EndpointDiscovery returns how the ArgoCD API endpoint is discovered, as defined via Manager ENV VAR.
+// It returns an empty string when the discovery is not enabled.
+func EndpointDiscovery() (string, error) {
+	switch mode := os.Getenv(EndpointDiscoveryEnvVar); mode {
+	case "", EndpointDiscoveryService, EndpointDiscoveryLoadBalancer, EndpointDiscoveryIngress, EndpointDiscoveryRoute:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid value %q for %s, the supported values are %s, %s, %s and %s", mode,
+			EndpointDiscoveryEnvVar, EndpointDiscoveryService, EndpointDiscoveryLoadBalancer,
+			EndpointDiscoveryIngress, EndpointDiscoveryRoute)
+	}
+}
+
+// getAPIEndpoint returns the ArgoCD API endpoint provided via Manager ENV VAR or, when its discovery is
+// enabled, the one derived from the argocd-server Service in the namespace informed. The objects are read
+// from the cache of the Manager, therefore, the endpoint follows the changes of the Service.
+func getAPIEndpoint(ctx context.Context, c client.Client, log logr.Logger, namespace string) (string, error) {
+	if endpoint, exists := os.LookupEnv(APIEndpointEnvVar); exists {
+		return endpoint, nil
+	}
+	mode, err := EndpointDiscovery()
+	if err != nil {
+		return "", err
+	}
+	if mode == "" {
+		log.Info(fmt.Sprintf("Argo API Endpoint is not provided via Manager ENV VAR, "+
+			"using default value (%s)", defaultArgoAPIEndpoint))
+		return defaultArgoAPIEndpoint, nil
+	}
+
+	endpoint, err := discoverAPIEndpoint(ctx, c, namespace, mode)
+	if err != nil {
+		return "", fmt.Errorf("error discovering the ArgoCD API endpoint (%s): %w", mode, err)
+	}
+
+	discoveredEndpoints.mu.Lock()
+	defer discoveredEndpoints.mu.Unlock()
+	if previous := discoveredEndpoints.endpoints[namespace]; previous != endpoint {
+		log.Info("ArgoCD API endpoint discovered", "mode", mode, "endpoint", endpoint, "previous", previous)
+		discoveredEndpoints.endpoints[namespace] = endpoint
+	}
+	return endpoint, nil
+}
+
+// discoverAPIEndpoint derives the ArgoCD API endpoint from the argocd-server Service in the namespace
+// informed, or from the Ingress or Route which expose it, as defined by the mode informed
+func discoverAPIEndpoint(ctx context.Context, c client.Client, namespace, mode string) (string, error) {
+	switch mode {
+	case EndpointDiscoveryIngress:
+		return ingressEndpoint(ctx, c, namespace)
+	case EndpointDiscoveryRoute:
+		return routeEndpoint(ctx, c, namespace)
+	}
+
+	service := &corev1.Service{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: serverServiceName}, service); err != nil {
+		return "", fmt.Errorf("error fetching the Service %s/%s: %w", namespace, serverServiceName, err)
+	}
+	host := fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace)
+	if mode == EndpointDiscoveryLoadBalancer {
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			return "", fmt.Errorf("the Service %s/%s is not of type %s", namespace, serverServiceName,
+				corev1.ServiceTypeLoadBalancer)
+		}
+		if len(service.Status.LoadBalancer.Ingress) == 0 {
+			return "", fmt.Errorf("no address was assigned to the Service %s/%s yet", namespace, serverServiceName)
+		}
+		host = service.Status.LoadBalancer.Ingress[0].Hostname
+		if host == "" {
+			host = service.Status.LoadBalancer.Ingress[0].IP
+		}
+	}
+
+	port := int32(443)
+	for _, servicePort := range service.Spec.Ports {
+		if servicePort.Name == serverServicePortName {
+			port = servicePort.Port
+			break
+		}
+	}
+	if port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return "https://" + host, nil
+}
+
+// ingressEndpoint returns the endpoint of the first Ingress, by name, in the namespace informed with a
+// rule which routes to the argocd-server Service. It uses https when the TLS of the Ingress covers its host.
+func ingressEndpoint(ctx context.Context, c client.Client, namespace string) (string, error) {
+	ingresses := &networkingv1.IngressList{}
+	if err := c.List(ctx, ingresses, client.InNamespace(namespace)); err != nil {
+		return "", fmt.Errorf("error listing the Ingresses in the namespace %s: %w", namespace, err)
+	}
+	sort.Slice(ingresses.Items, func(i, j int) bool { return ingresses.Items[i].Name < ingresses.Items[j].Name })
+
+	for _, ingress := range ingresses.Items {
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host == "" || rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service == nil || path.Backend.Service.Name != serverServiceName {
+					continue
+				}
+				scheme := "http"
+				for _, tls := range ingress.Spec.TLS {
+					if len(tls.Hosts) == 0 || containsString(tls.Hosts, rule.Host) {
+						scheme = "https"
+					}
+				}
+				return scheme + "://" + rule.Host, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no Ingress in the namespace %s routes to the Service %s", namespace, serverServiceName)
+}
+
+// routeEndpoint returns the endpoint of the first OpenShift Route, by name, in the namespace informed which
+// routes to the argocd-server Service. It uses https when the Route terminates TLS.
+func routeEndpoint(ctx context.Context, c client.Client, namespace string) (string, error) {
+	routes := &unstructured.UnstructuredList{}
+	routes.SetGroupVersionKind(routeListGVK)
+	if err := c.List(ctx, routes, client.InNamespace(namespace)); err != nil {
+		return "", fmt.Errorf("error listing the Routes in the namespace %s: %w", namespace, err)
+	}
+	sort.Slice(routes.Items, func(i, j int) bool { return routes.Items[i].GetName() < routes.Items[j].GetName() })
+
+	for _, route := range routes.Items {
+		target, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name")
+		host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+		if target != serverServiceName || host == "" {
+			continue
+		}
+		if _, tls, _ := unstructured.NestedMap(route.Object, "spec", "tls"); tls {
+			return "https://" + host, nil
+		}
+		return "http://" + host, nil
+	}
+	return "", fmt.Errorf("no Route in the namespace %s routes to the Service %s", namespace, serverServiceName)
+}
+
+// containsString returns true when the value informed is in the list
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"os"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ArgoCD API endpoint discovery", func() {
+	ctx := context.Background()
+	const namespace = "argocd-discovery"
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: serverServiceName, Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80},
+				{Name: serverServicePortName, Port: 8443},
+			},
+		},
+	}
+
+	BeforeEach(func() {
+		By("creating the namespace and the argocd-server Service")
+		err := k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+		Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
+		err = k8sClient.Create(ctx, service.DeepCopy())
+		Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
+	})
+
+	It("should use the ArgoCD API endpoint provided via Manager ENV VAR", func() {
+		Expect(os.Setenv(APIEndpointEnvVar, "https://argocd.example.com")).To(Succeed())
+		defer func() { _ = os.Unsetenv(APIEndpointEnvVar) }()
+		Expect(os.Setenv(EndpointDiscoveryEnvVar, EndpointDiscoveryService)).To(Succeed())
+		defer func() { _ = os.Unsetenv(EndpointDiscoveryEnvVar) }()
+
+		endpoint, err := getAPIEndpoint(ctx, k8sClient, logr.Discard(), namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoint).To(Equal("https://argocd.example.com"))
+	})
+
+	It("should reject an invalid discovery mode", func() {
+		Expect(os.Setenv(EndpointDiscoveryEnvVar, "NodePort")).To(Succeed())
+		defer func() { _ = os.Unsetenv(EndpointDiscoveryEnvVar) }()
+
+		_, err := getAPIEndpoint(ctx, k8sClient, logr.Discard(), namespace)
+		Expect(err).To(MatchError(ContainSubstring(EndpointDiscoveryEnvVar)))
+	})
+
+	It("should discover the endpoint from the argocd-server Service", func() {
+		Expect(os.Setenv(EndpointDiscoveryEnvVar, EndpointDiscoveryService)).To(Succeed())
+		defer func() { _ = os.Unsetenv(EndpointDiscoveryEnvVar) }()
+
+		endpoint, err := getAPIEndpoint(ctx, k8sClient, logr.Discard(), namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoint).To(Equal("https://argocd-server.argocd-discovery.svc:8443"))
+
+		By("failing when the Service does not exist")
+		_, err = getAPIEndpoint(ctx, k8sClient, logr.Discard(), defaultNamespace)
+		Expect(err).To(MatchError(ContainSubstring("error fetching the Service argocd/argocd-server")))
+	})
+
+	It("should discover the endpoint from the address of the LoadBalancer", func() {
+		_, err := discoverAPIEndpoint(ctx, k8sClient, namespace, EndpointDiscoveryLoadBalancer)
+		Expect(err).To(MatchError(ContainSubstring("no address was assigned")))
+
+		By("assigning an address to the LoadBalancer")
+		existing := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(service), existing)).To(Succeed())
+		existing.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "203.0.113.10"}}
+		Expect(k8sClient.Status().Update(ctx, existing)).To(Succeed())
+
+		endpoint, err := discoverAPIEndpoint(ctx, k8sClient, namespace, EndpointDiscoveryLoadBalancer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoint).To(Equal("https://203.0.113.10:8443"))
+	})
+
+	It("should discover the endpoint from the Ingress which routes to the argocd-server Service", func() {
+		_, err := discoverAPIEndpoint(ctx, k8sClient, namespace, EndpointDiscoveryIngress)
+		Expect(err).To(MatchError(ContainSubstring("no Ingress in the namespace argocd-discovery")))
+
+		By("creating the Ingress")
+		pathType := networkingv1.PathTypePrefix
+		ingress := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "argocd-server", Namespace: namespace},
+			Spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{{Hosts: []string{"argocd.example.com"}, SecretName: "argocd-tls"}},
+				Rules: []networkingv1.IngressRule{{
+					Host: "argocd.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     "/",
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+								Name: serverServiceName, Port: networkingv1.ServiceBackendPort{Name: "http"}}},
+						}},
+					}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+		defer func() { _ = k8sClient.Delete(ctx, ingress) }()
+
+		endpoint, err := discoverAPIEndpoint(ctx, k8sClient, namespace, EndpointDiscoveryIngress)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoint).To(Equal("https://argocd.example.com"))
+	})
+})
@@ -0,0 +1,159 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+const (
+	// ReasonUnauthorized is used when ArgoCD rejects the credentials of the account
+	ReasonUnauthorized = "Unauthorized"
+
+	// ReasonPermissionDenied is used when the account is not allowed to perform the operation
+	ReasonPermissionDenied = "PermissionDenied"
+
+	// ReasonInvalidSpec is used when ArgoCD rejects the cluster entry sent
+	ReasonInvalidSpec = "InvalidSpec"
+
+	// ReasonNotFound is used when the resource was not found in ArgoCD
+	ReasonNotFound = "NotFound"
+
+	// ReasonUnreachable is used when the request could not be sent to ArgoCD
+	ReasonUnreachable = "ArgoCDUnreachable"
+
+	// ReasonUnavailable is used while the requests to ArgoCD are failed fast since it is unavailable
+	ReasonUnavailable = "ArgoCDUnavailable"
+
+	// ReasonError is used for the errors which are not mapped to a specific reason
+	ReasonError = "Error"
+)
+
+// maxErrorBodySize limits how much of the error body is read from the ArgoCD API
+const maxErrorBodySize = 64 * 1024
+
+// gRPC status codes returned by ArgoCD in the error body
+// (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md)
+const (
+	grpcCodeInvalidArgument  = 3
+	grpcCodeNotFound         = 5
+	grpcCodePermissionDenied = 7
+	grpcCodeUnauthenticated  = 16
+)
+
+// apiErrorBody is the payload returned by the ArgoCD API when a request fails.
+type apiErrorBody struct {
+	Error   string `json:"error"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIError is returned when the ArgoCD API answers a request with an unexpected status.
+type APIError struct {
+	Operation  string // Operation which was performed, i.e. registering cluster
+	StatusCode int    // HTTP status code
+	Code       int    // gRPC status code informed by ArgoCD, if any
+	Message    string // Message informed by ArgoCD, if any
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("error %s, status: %d %s", e.Operation, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("error %s, status: %d %s: %s", e.Operation, e.StatusCode,
+		http.StatusText(e.StatusCode), e.Message)
+}
+
+// Reason returns the reason, in CamelCase, which can be used in the status conditions
+func (e *APIError) Reason() string {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.Code == grpcCodeUnauthenticated:
+		return ReasonUnauthorized
+	case e.StatusCode == http.StatusForbidden || e.Code == grpcCodePermissionDenied:
+		return ReasonPermissionDenied
+	case e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity ||
+		e.Code == grpcCodeInvalidArgument:
+		return ReasonInvalidSpec
+	case e.StatusCode == http.StatusNotFound || e.Code == grpcCodeNotFound:
+		return ReasonNotFound
+	default:
+		return ReasonError
+	}
+}
+
+// newAPIError returns the APIError with the details decoded from the body of the response.
+// When the body is not the ArgoCD error payload its content is used as message. The sensitive values
+// are redacted from the message since ArgoCD might echo the cluster entry, and its credentials, sent.
+func newAPIError(operation string, resp *http.Response) *APIError {
+	apiErr := &APIError{Operation: operation, StatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	errorBody := &apiErrorBody{}
+	if err := json.Unmarshal(body, errorBody); err != nil {
+		apiErr.Message = Redact(strings.TrimSpace(string(body)))
+		return apiErr
+	}
+	apiErr.Code = errorBody.Code
+	apiErr.Message = errorBody.Message
+	if apiErr.Message == "" {
+		apiErr.Message = errorBody.Error
+	}
+	apiErr.Message = Redact(apiErr.Message)
+	return apiErr
+}
+
+// IsTransient returns true when the error is expected to be solved by retrying later, i.e. while ArgoCD is
+// briefly unavailable, which is when the request could not be sent or ArgoCD answered with a server error
+// which is not mapped to a specific reason, i.e. an invalid argument informed only by the gRPC code.
+func IsTransient(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= http.StatusInternalServerError &&
+		apiErr.Reason() == ReasonError
+}
+
+// ErrorReason returns the reason, in CamelCase, which can be used in the status conditions
+// to describe the error.
+func ErrorReason(err error) string {
+	var circuitOpenErr *CircuitOpenError
+	if errors.As(err, &circuitOpenErr) {
+		return ReasonUnavailable
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Reason()
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return ReasonUnreachable
+	}
+	return ReasonError
+}
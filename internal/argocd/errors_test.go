@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ArgoCD API errors", func() {
+	newResponse := func(statusCode int, body string) *http.Response {
+		return &http.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	It("should decode the error payload returned by ArgoCD", func() {
+		apiErr := newAPIError("registering cluster", newResponse(http.StatusForbidden,
+			`{"error":"permission denied","code":7,"message":"permission denied: clusters, create"}`))
+		Expect(apiErr.Code).To(Equal(7))
+		Expect(apiErr.Message).To(Equal("permission denied: clusters, create"))
+		Expect(apiErr.Reason()).To(Equal(ReasonPermissionDenied))
+		Expect(apiErr.Error()).To(Equal(
+			"error registering cluster, status: 403 Forbidden: permission denied: clusters, create"))
+	})
+
+	It("should use the body as message when it is not the ArgoCD error payload", func() {
+		apiErr := newAPIError("fetching cluster", newResponse(http.StatusBadGateway, "upstream unavailable\n"))
+		Expect(apiErr.Message).To(Equal("upstream unavailable"))
+		Expect(apiErr.Reason()).To(Equal(ReasonError))
+	})
+
+	DescribeTable("should map the error into the condition reason",
+		func(statusCode int, code int, reason string) {
+			apiErr := newAPIError("registering cluster", newResponse(statusCode, fmt.Sprintf(`{"code":%d}`, code)))
+			Expect(ErrorReason(fmt.Errorf("wrapped: %w", apiErr))).To(Equal(reason))
+		},
+		Entry("unauthorized", http.StatusUnauthorized, 16, ReasonUnauthorized),
+		Entry("permission denied", http.StatusForbidden, 7, ReasonPermissionDenied),
+		Entry("invalid argument", http.StatusBadRequest, 3, ReasonInvalidSpec),
+		Entry("invalid argument informed only by the gRPC code", http.StatusInternalServerError, 3, ReasonInvalidSpec),
+		Entry("not found", http.StatusNotFound, 5, ReasonNotFound),
+		Entry("unknown", http.StatusInternalServerError, 2, ReasonError),
+	)
+
+	It("should use the default reason for other errors", func() {
+		Expect(ErrorReason(errors.New("connection refused"))).To(Equal(ReasonError))
+	})
+
+	It("should report ArgoCD as unreachable when the request could not be sent", func() {
+		err := &url.Error{Op: "Get", URL: "https://argocd-server/api/version", Err: errors.New("connection refused")}
+		Expect(ErrorReason(fmt.Errorf("wrapped: %w", err))).To(Equal(ReasonUnreachable))
+	})
+
+	DescribeTable("should classify the errors which are solved by retrying later",
+		func(err error, transient bool) {
+			Expect(IsTransient(fmt.Errorf("wrapped: %w", err))).To(Equal(transient))
+		},
+		Entry("request not sent", &url.Error{Op: "Post", URL: "https://argocd", Err: errors.New("connection refused")},
+			true),
+		Entry("service unavailable", newAPIError("registering cluster", newResponse(http.StatusServiceUnavailable, "")),
+			true),
+		Entry("invalid argument informed only by the gRPC code", newAPIError("registering cluster",
+			newResponse(http.StatusInternalServerError, `{"code":3}`)), false),
+		Entry("permission denied", newAPIError("registering cluster", newResponse(http.StatusForbidden, "")), false),
+		Entry("other errors", errors.New("invalid kubeconfig"), false),
+	)
+})
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+const (
+	// ExecCredentialAPIVersion is the version of the ExecCredential returned by the credential plugins
+	ExecCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+	// AzureKubeloginCommand is the credential plugin executed to obtain the credentials of AKS clusters
+	AzureKubeloginCommand = "kubelogin"
+
+	// AzureDefaultServerID is the application ID of the Azure AD server of AKS
+	AzureDefaultServerID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+
+	// azureKubeloginInstallHint is shown by ArgoCD when kubelogin is not found
+	azureKubeloginInstallHint = "kubelogin must be available in the ArgoCD images. " +
+		"More info: https://azure.github.io/kubelogin/install.html"
+)
+
+// NewExecProviderConfig returns the configuration used by ArgoCD to execute the credential plugin
+// informed. When the Azure spec is informed kubelogin is executed instead of the Command.
+func NewExecProviderConfig(spec *argocdv1beta1.ExecProviderSpec) *ExecProviderConfig {
+	config := &ExecProviderConfig{
+		Command:     spec.Command,
+		Args:        spec.Args,
+		Env:         spec.Env,
+		APIVersion:  spec.APIVersion,
+		InstallHint: spec.InstallHint,
+	}
+	if spec.Azure != nil {
+		config.Command = AzureKubeloginCommand
+		config.Args = azureKubeloginArgs(spec.Azure)
+		if config.InstallHint == "" {
+			config.InstallHint = azureKubeloginInstallHint
+		}
+	}
+	if config.APIVersion == "" {
+		config.APIVersion = ExecCredentialAPIVersion
+	}
+	return config
+}
+
+// azureKubeloginArgs returns the arguments of kubelogin to obtain the token of the AKS cluster.
+// The identity not informed is taken by kubelogin from the AZURE_* env vars, i.e. the ones
+// injected by the Azure workload identity webhook into the ArgoCD pods.
+func azureKubeloginArgs(spec *argocdv1beta1.AzureKubeloginSpec) []string {
+	loginMode := spec.LoginMode
+	if loginMode == "" {
+		loginMode = argocdv1beta1.AzureLoginModeWorkloadIdentity
+	}
+	serverID := spec.ServerID
+	if serverID == "" {
+		serverID = AzureDefaultServerID
+	}
+
+	args := []string{"get-token", "--login", string(loginMode), "--server-id", serverID}
+	if spec.ClientID != "" {
+		args = append(args, "--client-id", spec.ClientID)
+	}
+	if spec.TenantID != "" {
+		args = append(args, "--tenant-id", spec.TenantID)
+	}
+	return args
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+var _ = Describe("ArgoCD exec provider", func() {
+	It("should execute the command informed", func() {
+		config := NewExecProviderConfig(&argocdv1beta1.ExecProviderSpec{
+			Command: "gke-gcloud-auth-plugin",
+			Args:    []string{"--use_application_default_credentials"},
+		})
+		Expect(config.Command).To(Equal("gke-gcloud-auth-plugin"))
+		Expect(config.Args).To(Equal([]string{"--use_application_default_credentials"}))
+		Expect(config.APIVersion).To(Equal(ExecCredentialAPIVersion))
+		Expect(config.InstallHint).To(BeEmpty())
+	})
+
+	It("should execute kubelogin with the workload identity by default for AKS clusters", func() {
+		config := NewExecProviderConfig(&argocdv1beta1.ExecProviderSpec{
+			Azure: &argocdv1beta1.AzureKubeloginSpec{},
+			Env:   map[string]string{"AZURE_AUTHORITY_HOST": "https://login.microsoftonline.com/"},
+		})
+		Expect(config.Command).To(Equal(AzureKubeloginCommand))
+		Expect(config.Args).To(Equal([]string{"get-token", "--login", "workloadidentity",
+			"--server-id", AzureDefaultServerID}))
+		Expect(config.Env).To(HaveKeyWithValue("AZURE_AUTHORITY_HOST", "https://login.microsoftonline.com/"))
+		Expect(config.InstallHint).NotTo(BeEmpty())
+	})
+
+	It("should execute kubelogin with the identity informed for AKS clusters", func() {
+		config := NewExecProviderConfig(&argocdv1beta1.ExecProviderSpec{
+			Azure: &argocdv1beta1.AzureKubeloginSpec{
+				LoginMode: argocdv1beta1.AzureLoginModeMSI,
+				ServerID:  "server-id",
+				ClientID:  "client-id",
+				TenantID:  "tenant-id",
+			},
+		})
+		Expect(config.Args).To(Equal([]string{"get-token", "--login", "msi", "--server-id", "server-id",
+			"--client-id", "client-id", "--tenant-id", "tenant-id"}))
+	})
+})
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"sync"
+)
+
+// InstallationLabel is the label added to the cluster entries to identify the installation of this project
+// which registered them, so that the installations which share an ArgoCD instance only collect their own clusters
+const InstallationLabel = "argocd.workload.com/installation"
+
+// installation records the identifier of the installation of this project, i.e. the namespace of the Manager
+var installation = struct {
+	mu sync.Mutex
+	id string
+}{}
+
+// SetInstallation sets the identifier of the installation of this project which is stamped on the cluster
+// entries registered. It must be unique by ArgoCD instance, i.e. the namespace where the Manager runs.
+func SetInstallation(id string) {
+	installation.mu.Lock()
+	defer installation.mu.Unlock()
+	installation.id = id
+}
+
+// Installation returns the identifier of the installation of this project, empty when it was not set
+func Installation() string {
+	installation.mu.Lock()
+	defer installation.mu.Unlock()
+	return installation.id
+}
+
+// setManagedLabels labels the cluster entry as managed by this project and by its installation, when it is known
+func setManagedLabels(labels map[string]string) {
+	labels[ManagedByLabel] = ManagedByValue
+	if id := Installation(); id != "" {
+		labels[InstallationLabel] = id
+	}
+}
+
+// managedByInstallation returns true when the labels identify the cluster entry as managed by this installation.
+// It returns false when the installation is unknown, since then the entries of the others cannot be told apart.
+func managedByInstallation(labels map[string]string) bool {
+	id := Installation()
+	return id != "" && labels[ManagedByLabel] == ManagedByValue && labels[InstallationLabel] == id
+}
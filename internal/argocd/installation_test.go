@@ -0,0 +1,57 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Installation", func() {
+	AfterEach(func() {
+		SetInstallation("")
+	})
+
+	It("should label the cluster entries with the installation which registered them", func() {
+		labels := map[string]string{}
+		setManagedLabels(labels)
+		Expect(labels).To(Equal(map[string]string{ManagedByLabel: ManagedByValue}))
+
+		SetInstallation("workload-operator-system")
+		setManagedLabels(labels)
+		Expect(labels).To(Equal(map[string]string{ManagedByLabel: ManagedByValue,
+			InstallationLabel: "workload-operator-system"}))
+		Expect(propagatedLabels(labels)).To(BeEmpty())
+	})
+
+	It("should only identify the cluster entries of this installation as managed", func() {
+		SetInstallation("workload-operator-system")
+		Expect(managedByInstallation(map[string]string{ManagedByLabel: ManagedByValue,
+			InstallationLabel: "workload-operator-system"})).To(BeTrue())
+
+		By("skipping the cluster entries of the other installations and the ones registered before")
+		Expect(managedByInstallation(map[string]string{ManagedByLabel: ManagedByValue,
+			InstallationLabel: "other-system"})).To(BeFalse())
+		Expect(managedByInstallation(map[string]string{ManagedByLabel: ManagedByValue})).To(BeFalse())
+		Expect(managedByInstallation(map[string]string{InstallationLabel: "workload-operator-system"})).To(BeFalse())
+
+		By("skipping all the cluster entries when the installation is unknown")
+		SetInstallation("")
+		Expect(managedByInstallation(map[string]string{ManagedByLabel: ManagedByValue,
+			InstallationLabel: ""})).To(BeFalse())
+	})
+})
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Instance defines how to connect to the ArgoCD instance where the clusters are registered. When it is
+// informed it replaces the configuration provided via Manager ENV VAR. The proxy, the timeout and the
+// retries of the requests are still configured via Manager ENV VAR.
+type Instance struct {
+	Name                  string // Name of the ArgoCDInstance
+	Endpoint              string // ArgoCD API endpoint
+	Namespace             string // Namespace where ArgoCD is deployed
+	CredentialsSecretName string // Secret, in the Namespace, with the credentials of the ArgoCD account
+	CAConfigMapName       string // ConfigMap, in the Namespace, with the CA bundle of the ArgoCD API
+	CASecretName          string // Secret, in the Namespace, with the CA bundle of the ArgoCD API
+	ClientCertSecretName  string // Secret, in the Namespace, with the client certificate presented to ArgoCD
+	InsecureSkipVerify    bool   // Disables the verification of the certificate of the ArgoCD API
+	AllowInsecureEndpoint bool   // Allows an http:// Endpoint, the credentials of the clusters are sent in plain text
+
+	Project string // AppProject of the clusters registered when their options do not inform one
+	Shard   *int64 // Shard of the ArgoCD application controller which manages the clusters registered
+}
+
+// credentialsSecretName returns the Secret with the credentials of the ArgoCD account, the one created
+// by ArgoCD to store the initial admin password when it is not informed
+func (i *Instance) credentialsSecretName() string {
+	if i.CredentialsSecretName == "" {
+		return defaultSecretName
+	}
+	return i.CredentialsSecretName
+}
+
+// IsCredentialsSecret returns true when the object informed is the Secret which stores the credentials
+// of the ArgoCD account of the instance.
+func (i *Instance) IsCredentialsSecret(obj client.Object) bool {
+	return obj.GetNamespace() == i.Namespace && obj.GetName() == i.credentialsSecretName()
+}
+
+// CheckInstance returns the version of the ArgoCD instance. It fails when the ArgoCD API is not
+// reachable or rejects the credentials configured.
+func CheckInstance(ctx context.Context, client client.Client, log logr.Logger, instance *Instance) (string, error) {
+	apiManager, err := newAPIManager(ctx, client, log, &clusterapiv1.Cluster{}, nil, instance)
+	if err != nil {
+		return "", err
+	}
+	info, err := apiManager.Describe(ctx)
+	if err != nil {
+		return "", err
+	}
+	return info.ServerVersion, nil
+}
+
+// Ping checks that the API of the ArgoCD instance informed, or of the one configured via Manager ENV VAR
+// when it is nil, is reachable. Unlike CheckInstance it is lightweight: the credentials of the ArgoCD
+// account are not fetched and a single unauthenticated request is sent to the version endpoint. It does
+// not fail when the ArgoCD configured via Manager ENV VAR runs in core mode, since then there is no API.
+func Ping(ctx context.Context, client client.Client, log logr.Logger, instance *Instance) error {
+	if instance == nil {
+		namespace, err := getNamespace(ctx, log)
+		if err != nil {
+			return err
+		}
+		coreMode, err := CoreMode(ctx, namespace)
+		if err != nil || coreMode {
+			return err
+		}
+	}
+	apiManager := &APIManager{Client: client, Ctx: ctx, Log: log, Instance: instance}
+	if err := apiManager.setEndpoint(); err != nil {
+		return err
+	}
+	if err := apiManager.setTLSConfig(); err != nil {
+		return err
+	}
+	if err := apiManager.setProxy(); err != nil {
+		return err
+	}
+	timeout, err := getRequestTimeout()
+	if err != nil {
+		return err
+	}
+	apiManager.Timeout = timeout
+
+	resp, err := apiManager.sendOnce(ctx, http.MethodGet, "/api/version", nil, "")
+	if err != nil {
+		return err
+	}
+	defer apiManager.closeResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("fetching version", resp)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// KubeConfigConventionsEnvVar store the name of the envvar used to provide the comma-separated conventions
+// of the Secrets which store the kubeconfig of the Clusters per control plane provider, in the format
+// <ControlPlaneKind>=<Secret name template>:<key>, i.e. "MyControlPlane={{ .Name }}-admin:kubeconfig".
+// The conventions informed are added to the default ones, overriding them for the same kind.
+const KubeConfigConventionsEnvVar = "KUBECONFIG_SECRET_CONVENTIONS"
+
+// KubeConfigSecretConvention defines the Secret which stores the kubeconfig of the Clusters whose control
+// plane is managed by a provider which does not follow the Cluster API convention.
+type KubeConfigSecretConvention struct {
+	// NameTemplate is the Go template which renders the name of the Secret from the Cluster
+	NameTemplate string
+	// Key of the Secret which stores the kubeconfig
+	Key string
+}
+
+// defaultKubeConfigSecretConventions are the conventions of the hosted control plane providers
+var defaultKubeConfigSecretConventions = map[string]KubeConfigSecretConvention{
+	// Kamaji stores the kubeconfig of the TenantControlPlane, named as the KamajiControlPlane
+	"KamajiControlPlane": {NameTemplate: "{{ .Spec.ControlPlaneRef.Name }}-admin-kubeconfig", Key: "admin.conf"},
+	// k0smotron stores the kubeconfig of the control plane, named as the K0smotronControlPlane
+	"K0smotronControlPlane": {NameTemplate: "{{ .Spec.ControlPlaneRef.Name }}-kubeconfig", Key: "value"},
+	// HyperShift stores the kubeconfig in the namespace of the HostedControlPlane
+	"HostedControlPlane": {NameTemplate: "admin-kubeconfig", Key: "kubeconfig"},
+}
+
+// kubeConfigSecretConventions returns the default conventions merged with the ones informed via the envvar
+func kubeConfigSecretConventions() (map[string]KubeConfigSecretConvention, error) {
+	conventions := make(map[string]KubeConfigSecretConvention, len(defaultKubeConfigSecretConventions))
+	for kind, convention := range defaultKubeConfigSecretConventions {
+		conventions[kind] = convention
+	}
+
+	for _, entry := range strings.Split(os.Getenv(KubeConfigConventionsEnvVar), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, value, found := strings.Cut(entry, "=")
+		separator := strings.LastIndex(value, ":")
+		if !found || kind == "" || separator <= 0 || separator == len(value)-1 {
+			return nil, fmt.Errorf("invalid kubeconfig secret convention %q in %s: it must be "+
+				"<ControlPlaneKind>=<Secret name template>:<key>", entry, KubeConfigConventionsEnvVar)
+		}
+		conventions[strings.TrimSpace(kind)] = KubeConfigSecretConvention{
+			NameTemplate: value[:separator],
+			Key:          value[separator+1:],
+		}
+	}
+	return conventions, nil
+}
+
+// ProviderKubeConfigSecret returns the name and the key of the Secret which stores the kubeconfig of the
+// Cluster by the convention of its control plane provider. It returns false when the Cluster has no
+// control plane reference or no convention is defined for its kind.
+func ProviderKubeConfigSecret(clusterAPI *clusterapiv1.Cluster) (string, string, bool, error) {
+	if clusterAPI.Spec.ControlPlaneRef == nil {
+		return "", "", false, nil
+	}
+	conventions, err := kubeConfigSecretConventions()
+	if err != nil {
+		return "", "", false, err
+	}
+	convention, exists := conventions[clusterAPI.Spec.ControlPlaneRef.Kind]
+	if !exists {
+		return "", "", false, nil
+	}
+
+	tmpl, err := template.New("secret").Option("missingkey=error").Parse(convention.NameTemplate)
+	if err != nil {
+		return "", "", false, fmt.Errorf("error parsing the kubeconfig secret name template %q: %w",
+			convention.NameTemplate, err)
+	}
+	var name bytes.Buffer
+	if err := tmpl.Execute(&name, clusterAPI); err != nil {
+		return "", "", false, fmt.Errorf("error rendering the kubeconfig secret name template %q: %w",
+			convention.NameTemplate, err)
+	}
+	return strings.TrimSpace(name.String()), convention.Key, true, nil
+}
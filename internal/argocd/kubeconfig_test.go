@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var _ = Describe("Kubeconfig secret conventions", func() {
+	newCluster := func(controlPlaneKind string) *clusterapiv1.Cluster {
+		return &clusterapiv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "workload", Namespace: "default"},
+			Spec: clusterapiv1.ClusterSpec{
+				ControlPlaneRef: &corev1.ObjectReference{Kind: controlPlaneKind, Name: "workload-cp"},
+			},
+		}
+	}
+
+	AfterEach(func() {
+		_ = os.Unsetenv(KubeConfigConventionsEnvVar)
+	})
+
+	DescribeTable("should return the secret of the hosted control plane providers",
+		func(controlPlaneKind, name, key string) {
+			secretName, secretKey, found, err := ProviderKubeConfigSecret(newCluster(controlPlaneKind))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(secretName).To(Equal(name))
+			Expect(secretKey).To(Equal(key))
+		},
+		Entry("Kamaji", "KamajiControlPlane", "workload-cp-admin-kubeconfig", "admin.conf"),
+		Entry("k0smotron", "K0smotronControlPlane", "workload-cp-kubeconfig", "value"),
+		Entry("HyperShift", "HostedControlPlane", "admin-kubeconfig", "kubeconfig"),
+	)
+
+	It("should not return a secret when no convention is defined for the control plane", func() {
+		_, _, found, err := ProviderKubeConfigSecret(newCluster("KubeadmControlPlane"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+
+		_, _, found, err = ProviderKubeConfigSecret(&clusterapiv1.Cluster{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("should use the conventions informed via the Manager ENV VAR", func() {
+		Expect(os.Setenv(KubeConfigConventionsEnvVar,
+			"KubeadmControlPlane={{ .Namespace }}-{{ .Name }}:config, KamajiControlPlane=kamaji-{{ .Name }}:kubeconfig")).
+			To(Succeed())
+
+		secretName, secretKey, found, err := ProviderKubeConfigSecret(newCluster("KubeadmControlPlane"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(secretName).To(Equal("default-workload"))
+		Expect(secretKey).To(Equal("config"))
+
+		secretName, secretKey, _, err = ProviderKubeConfigSecret(newCluster("KamajiControlPlane"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secretName).To(Equal("kamaji-workload"))
+		Expect(secretKey).To(Equal("kubeconfig"))
+	})
+
+	It("should return an error when the conventions informed are not valid", func() {
+		Expect(os.Setenv(KubeConfigConventionsEnvVar, "KamajiControlPlane={{ .Name }}")).To(Succeed())
+		_, _, _, err := ProviderKubeConfigSecret(newCluster("KamajiControlPlane"))
+		Expect(err).To(HaveOccurred())
+
+		Expect(os.Setenv(KubeConfigConventionsEnvVar, "KamajiControlPlane={{ .Unknown }}:value")).To(Succeed())
+		_, _, _, err = ProviderKubeConfigSecret(newCluster("KamajiControlPlane"))
+		Expect(err).To(HaveOccurred())
+	})
+})
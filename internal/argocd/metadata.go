@@ -0,0 +1,130 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// PropagatedLabelsEnvVar store the name of the envvar used to provide the comma-separated
+	// prefixes of the labels of the Cluster propagated to the ArgoCD cluster entry, i.e.
+	// "region,env.example.com/". All labels are propagated when it is not informed.
+	PropagatedLabelsEnvVar = "ARGOCD_PROPAGATED_LABELS"
+
+	// PropagatedAnnotationsEnvVar store the name of the envvar used to provide the comma-separated
+	// prefixes of the annotations of the Cluster propagated to the ArgoCD cluster entry.
+	// No annotations are propagated when it is not informed.
+	PropagatedAnnotationsEnvVar = "ARGOCD_PROPAGATED_ANNOTATIONS"
+
+	// ClusterNameTemplateEnvVar store the name of the envvar used to provide the Go template which
+	// renders the name of the cluster within ArgoCD from the Cluster, i.e. "{{ .Namespace }}-{{ .Name }}"
+	// so that Clusters with the same name in different namespaces do not collide.
+	ClusterNameTemplateEnvVar = "ARGOCD_CLUSTER_NAME_TEMPLATE"
+
+	// propagateAll is the prefix used to propagate all labels or annotations
+	propagateAll = "*"
+)
+
+// ClusterName returns the name of the cluster within ArgoCD rendered with the template informed via
+// the envvar. The name of the Cluster is used when the template is not informed or renders an empty name.
+func ClusterName(clusterAPI *clusterapiv1.Cluster) (string, error) {
+	return RenderClusterName(os.Getenv(ClusterNameTemplateEnvVar), clusterAPI)
+}
+
+// RenderClusterName returns the name of the cluster within ArgoCD rendered with the template informed.
+// The name of the Cluster is used when the template is empty or renders an empty name.
+func RenderClusterName(nameTemplate string, clusterAPI *clusterapiv1.Cluster) (string, error) {
+	if nameTemplate == "" {
+		return clusterAPI.Name, nil
+	}
+
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing the cluster name template %q: %w", nameTemplate, err)
+	}
+	var name bytes.Buffer
+	if err := tmpl.Execute(&name, clusterAPI); err != nil {
+		return "", fmt.Errorf("error rendering the cluster name template %q: %w", nameTemplate, err)
+	}
+	if strings.TrimSpace(name.String()) == "" {
+		return clusterAPI.Name, nil
+	}
+	return strings.TrimSpace(name.String()), nil
+}
+
+// reservedMetadataPrefixes are the prefixes of the labels and annotations which are never propagated
+// since they are managed by Cluster API, kubectl or ArgoCD itself
+var reservedMetadataPrefixes = []string{
+	"cluster.x-k8s.io/",
+	"kubectl.kubernetes.io/",
+	"argocd.argoproj.io/",
+	ManagedByLabel,
+	InstallationLabel,
+}
+
+// propagatedLabels returns the labels of the Cluster which are propagated to the ArgoCD cluster entry
+func propagatedLabels(labels map[string]string) map[string]string {
+	return propagatedMetadata(labels, PropagatedLabelsEnvVar, propagateAll)
+}
+
+// propagatedAnnotations returns the annotations of the Cluster which are propagated to the ArgoCD cluster entry
+func propagatedAnnotations(annotations map[string]string) map[string]string {
+	return propagatedMetadata(annotations, PropagatedAnnotationsEnvVar, "")
+}
+
+// propagatedMetadata returns the entries whose keys match the prefixes informed via the envvar
+func propagatedMetadata(metadata map[string]string, envVar, defaultPrefixes string) map[string]string {
+	prefixes, exists := os.LookupEnv(envVar)
+	if !exists {
+		prefixes = defaultPrefixes
+	}
+
+	propagated := map[string]string{}
+	for _, prefix := range strings.Split(prefixes, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		for key, value := range metadata {
+			if isReservedMetadata(key) {
+				continue
+			}
+			if prefix == propagateAll || strings.HasPrefix(key, prefix) {
+				propagated[key] = value
+			}
+		}
+	}
+	return propagated
+}
+
+// isReservedMetadata returns true when the label or annotation must not be propagated
+func isReservedMetadata(key string) bool {
+	for _, prefix := range reservedMetadataPrefixes {
+		// The Cluster API labels are also set with subdomains, i.e. topology.cluster.x-k8s.io/
+		if strings.HasPrefix(key, prefix) || strings.Contains(key, "."+prefix) {
+			return true
+		}
+	}
+	return false
+}
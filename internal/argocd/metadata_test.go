@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var _ = Describe("Cluster metadata propagation", func() {
+	metadata := map[string]string{
+		"region":                               "eu-west-1",
+		"env.example.com/stage":                "prod",
+		"cluster.x-k8s.io/cluster-name":        "workload",
+		"topology.cluster.x-k8s.io/owned":      "",
+		"kubectl.kubernetes.io/last-applied":   "{}",
+		"argocd.argoproj.io/secret-type":       "cluster",
+		ManagedByLabel:                         "other",
+		"description.example.com/maintainer":   "team-a",
+		"description.example.com/on-call-team": "team-b",
+	}
+
+	AfterEach(func() {
+		_ = os.Unsetenv(PropagatedLabelsEnvVar)
+		_ = os.Unsetenv(PropagatedAnnotationsEnvVar)
+		_ = os.Unsetenv(ClusterNameTemplateEnvVar)
+	})
+
+	It("should propagate all labels except the reserved ones by default", func() {
+		Expect(propagatedLabels(metadata)).To(Equal(map[string]string{
+			"region":                               "eu-west-1",
+			"env.example.com/stage":                "prod",
+			"description.example.com/maintainer":   "team-a",
+			"description.example.com/on-call-team": "team-b",
+		}))
+	})
+
+	It("should not propagate annotations by default", func() {
+		Expect(propagatedAnnotations(metadata)).To(BeEmpty())
+	})
+
+	It("should propagate only the labels and annotations matching the prefixes informed", func() {
+		Expect(os.Setenv(PropagatedLabelsEnvVar, "region, env.example.com/")).To(Succeed())
+		Expect(os.Setenv(PropagatedAnnotationsEnvVar, "description.example.com/,cluster.x-k8s.io/")).To(Succeed())
+
+		Expect(propagatedLabels(metadata)).To(Equal(map[string]string{
+			"region":                "eu-west-1",
+			"env.example.com/stage": "prod",
+		}))
+		Expect(propagatedAnnotations(metadata)).To(Equal(map[string]string{
+			"description.example.com/maintainer":   "team-a",
+			"description.example.com/on-call-team": "team-b",
+		}))
+	})
+
+	Context("Cluster name", func() {
+		cluster := &clusterapiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "prod", Namespace: "tenant-a",
+			Labels: map[string]string{"region": "eu-west-1"}}}
+
+		It("should use the name of the Cluster by default", func() {
+			Expect(ClusterName(cluster)).To(Equal("prod"))
+		})
+
+		It("should render the name with the template informed", func() {
+			Expect(os.Setenv(ClusterNameTemplateEnvVar, "{{ .Namespace }}-{{ .Name }}")).To(Succeed())
+			Expect(ClusterName(cluster)).To(Equal("tenant-a-prod"))
+
+			Expect(os.Setenv(ClusterNameTemplateEnvVar, `{{ index .Labels "region" }}-{{ .Name }}`)).To(Succeed())
+			Expect(ClusterName(cluster)).To(Equal("eu-west-1-prod"))
+		})
+
+		It("should return an error when the template is invalid", func() {
+			Expect(os.Setenv(ClusterNameTemplateEnvVar, "{{ .Namespace ")).To(Succeed())
+			_, err := ClusterName(cluster)
+			Expect(err).To(HaveOccurred())
+
+			Expect(os.Setenv(ClusterNameTemplateEnvVar, "{{ .Unknown }}")).To(Succeed())
+			_, err = ClusterName(cluster)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -22,7 +22,7 @@ const MockKubeConfig = `
 apiVersion: v1
 clusters:
 - cluster:
-    certificate-authority-data: mocks
+    certificate-authority-data: bW9ja3M= # mocks
     server: https://your-cluster-server-here
   name: Test
 contexts:
@@ -36,6 +36,6 @@ preferences: {}
 users:
 - name: mocks
   user:
-    client-certificate-data: mocks
-    client-key-data: mocks
+    client-certificate-data: bW9ja3M= # mocks
+    client-key-data: bW9ja3M= # mocks
 `
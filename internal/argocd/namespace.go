@@ -0,0 +1,130 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// serverDeploymentName is the Deployment of the ArgoCD API server
+	serverDeploymentName = "argocd-server"
+
+	// configMapName is the ConfigMap created by ArgoCD to store its settings
+	configMapName = "argocd-cm"
+)
+
+// namespaceDetection records the namespace where ArgoCD is deployed once it is detected, so that it is
+// only looked up again when its detection fails, i.e. when ArgoCD was not installed yet
+var namespaceDetection = struct {
+	mu        sync.Mutex
+	reader    client.Reader
+	namespace string
+}{}
+
+// EnableNamespaceDetection enables the detection of the namespace where ArgoCD is deployed when it is
+// not provided via the ARGOCD_NAMESPACE envvar, instead of assuming the argocd namespace. The reader
+// informed is used to look up, across the namespaces, the argocd-server Deployment and the argocd-cm
+// ConfigMap. It is recommended to inform a reader which is not backed by the cache, i.e. the API reader
+// of the Manager, so that all the Deployments of the cluster are not cached.
+func EnableNamespaceDetection(reader client.Reader) {
+	namespaceDetection.mu.Lock()
+	defer namespaceDetection.mu.Unlock()
+	namespaceDetection.reader = reader
+	namespaceDetection.namespace = ""
+}
+
+// currentNamespace returns the namespace where ArgoCD is deployed provided via Manager ENV VAR or, when
+// it is not provided, the last one detected, without looking it up
+func currentNamespace() string {
+	if namespace, exists := os.LookupEnv(NamespaceEnvVar); exists {
+		return namespace
+	}
+	namespaceDetection.mu.Lock()
+	defer namespaceDetection.mu.Unlock()
+	if namespaceDetection.namespace != "" {
+		return namespaceDetection.namespace
+	}
+	return defaultNamespace
+}
+
+// detectNamespace returns the namespace where ArgoCD is deployed, which is the only one with the
+// argocd-server Deployment or the argocd-cm ConfigMap. It fails when ArgoCD is not found or when it is
+// found in several namespaces, since then the one where the clusters are registered cannot be chosen.
+// The default namespace is returned when the detection is not enabled.
+func detectNamespace(ctx context.Context, log logr.Logger) (string, error) {
+	namespaceDetection.mu.Lock()
+	defer namespaceDetection.mu.Unlock()
+	if namespaceDetection.reader == nil {
+		log.Info(fmt.Sprintf("Argo Instance Namespace is not provided via Manager ENV VAR, "+
+			"using default value (%s)", defaultNamespace))
+		return defaultNamespace, nil
+	}
+	if namespaceDetection.namespace != "" {
+		return namespaceDetection.namespace, nil
+	}
+
+	found := map[string]bool{}
+	for _, lookup := range []struct {
+		kind schema.GroupVersionKind
+		name string
+	}{
+		{kind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DeploymentList"}, name: serverDeploymentName},
+		{kind: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMapList"}, name: configMapName},
+	} {
+		objects := &metav1.PartialObjectMetadataList{}
+		objects.SetGroupVersionKind(lookup.kind)
+		if err := namespaceDetection.reader.List(ctx, objects, client.MatchingFieldsSelector{
+			Selector: fields.OneTermEqualSelector("metadata.name", lookup.name)}); err != nil {
+			return "", fmt.Errorf("error detecting the namespace where ArgoCD is deployed: %w", err)
+		}
+		for _, object := range objects.Items {
+			found[object.Namespace] = true
+		}
+	}
+
+	namespaces := make([]string, 0, len(found))
+	for namespace := range found {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	switch len(namespaces) {
+	case 0:
+		return "", fmt.Errorf("unable to detect the namespace where ArgoCD is deployed, neither the %s "+
+			"Deployment nor the %s ConfigMap was found, it can be provided via the %s Manager ENV VAR",
+			serverDeploymentName, configMapName, NamespaceEnvVar)
+	case 1:
+		log.Info("Argo Instance Namespace is not provided via Manager ENV VAR, using the one detected",
+			"namespace", namespaces[0])
+		namespaceDetection.namespace = namespaces[0]
+		return namespaces[0], nil
+	default:
+		return "", fmt.Errorf("ArgoCD is deployed in several namespaces (%s), the one where the clusters are "+
+			"registered must be provided via the %s Manager ENV VAR", strings.Join(namespaces, ", "), NamespaceEnvVar)
+	}
+}
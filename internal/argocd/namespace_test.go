@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"os"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ArgoCD namespace detection", func() {
+	ctx := context.Background()
+
+	createNamespace := func(name string) {
+		err := k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
+	}
+
+	BeforeEach(func() {
+		EnableNamespaceDetection(k8sClient)
+	})
+
+	AfterEach(func() {
+		EnableNamespaceDetection(nil)
+	})
+
+	It("should use the namespace provided via Manager ENV VAR", func() {
+		Expect(os.Setenv(NamespaceEnvVar, "gitops")).To(Succeed())
+		defer func() { _ = os.Unsetenv(NamespaceEnvVar) }()
+
+		namespace, err := getNamespace(ctx, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namespace).To(Equal("gitops"))
+	})
+
+	It("should detect the namespace where ArgoCD is deployed", func() {
+		By("failing when ArgoCD is not found")
+		_, err := getNamespace(ctx, logr.Discard())
+		Expect(err).To(MatchError(ContainSubstring("unable to detect the namespace where ArgoCD is deployed")))
+		Expect(currentNamespace()).To(Equal(defaultNamespace))
+
+		By("detecting the namespace of the argocd-cm ConfigMap")
+		createNamespace("argocd-detection-a")
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: configMapName,
+			Namespace: "argocd-detection-a"}}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+		defer func() { _ = k8sClient.Delete(ctx, configMap) }()
+
+		namespace, err := getNamespace(ctx, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namespace).To(Equal("argocd-detection-a"))
+		Expect(currentNamespace()).To(Equal("argocd-detection-a"))
+
+		By("failing when ArgoCD is found in several namespaces")
+		createNamespace("argocd-detection-b")
+		labels := map[string]string{"app.kubernetes.io/name": serverDeploymentName}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: serverDeploymentName, Namespace: "argocd-detection-b"},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{
+						{Name: "argocd-server", Image: "quay.io/argoproj/argocd:v2.8.4"}}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+		defer func() { _ = k8sClient.Delete(ctx, deployment) }()
+
+		EnableNamespaceDetection(k8sClient)
+		_, err = getNamespace(ctx, logr.Discard())
+		Expect(err).To(MatchError(ContainSubstring(
+			"ArgoCD is deployed in several namespaces (argocd-detection-a, argocd-detection-b)")))
+	})
+})
@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import "regexp"
+
+// Redacted replaces the sensitive values, i.e. the tokens and the credentials of the kubeconfigs, in the
+// messages which are logged or reported in the status conditions
+const Redacted = "[REDACTED]"
+
+var (
+	// sensitiveFieldPattern matches the values of the sensitive fields of the JSON payloads, i.e. the cluster
+	// entries sent to ArgoCD and the responses of Vault, and of the kubeconfigs in YAML
+	sensitiveFieldPattern = regexp.MustCompile(
+		`(?i)((?:bearerToken|password|token|keyData|client-key-data)"?\s*:\s*)("(?:[^"\\]|\\.)*"|[^\s,}\]]+)`)
+
+	// bearerPattern matches the tokens informed in the Authorization headers
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`)
+
+	// jwtPattern matches the JWTs, i.e. the ArgoCD session tokens and the ServiceAccount tokens, wherever
+	// they are informed
+	jwtPattern = regexp.MustCompile(`eyJ[\w-]*\.[\w-]+\.[\w-]*`)
+
+	// privateKeyPattern matches the PEM-encoded private keys
+	privateKeyPattern = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// Redact returns the message with its sensitive values replaced, so that the ArgoCD tokens and the
+// credentials of the kubeconfigs are never logged or reported in the status conditions, i.e. when ArgoCD
+// or Vault echo the payload in their errors. It must be used for any payload or response body logged.
+func Redact(message string) string {
+	message = privateKeyPattern.ReplaceAllString(message, Redacted)
+	message = sensitiveFieldPattern.ReplaceAllString(message, "${1}"+Redacted)
+	message = bearerPattern.ReplaceAllString(message, "${1}"+Redacted)
+	return jwtPattern.ReplaceAllString(message, Redacted)
+}
+
+// redactedError redacts the message of the error it wraps, which is kept available to errors.As and errors.Is
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return Redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError returns the error with the sensitive values of its message redacted, i.e. when it is raised
+// while parsing a kubeconfig. It returns nil when no error is informed.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{err: err}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+// Registrar is implemented by the backends able to register clusters within ArgoCD.
+type Registrar interface {
+	// RegisterCluster registers the cluster within ArgoCD
+	RegisterCluster(ctx context.Context) error
+	// UnRegisterCluster removes the cluster from ArgoCD
+	UnRegisterCluster(ctx context.Context) error
+	// ReleaseCluster keeps the cluster registered within ArgoCD but no longer labeled as managed by this
+	// project, so that it is not removed as orphaned once it is retained
+	ReleaseCluster(ctx context.Context) error
+	// IsClusterRegistered returns true when the cluster is registered within ArgoCD
+	IsClusterRegistered(ctx context.Context) (bool, error)
+	// SyncCluster updates the registration when it drifted from the desired state, i.e. when it
+	// was edited out-of-band. It returns true when a drift was corrected.
+	SyncCluster(ctx context.Context) (bool, error)
+	// Verify returns an error when issues were found into the registration. A *ConnectionError
+	// is returned when the cluster is registered but ArgoCD is unable to connect to it.
+	Verify(ctx context.Context) error
+	// Describe returns how the cluster is registered within ArgoCD
+	Describe(ctx context.Context) (*RegistrationInfo, error)
+}
+
+// RegistrationInfo describes the registration of the cluster within ArgoCD.
+type RegistrationInfo struct {
+	// ClusterID identifies the cluster entry within ArgoCD: the server in API mode, since the ArgoCD API
+	// identifies the clusters by it, and the name of the cluster Secret in Declarative mode
+	ClusterID string
+	// ServerVersion is the version of ArgoCD. It is only available via the ArgoCD API.
+	ServerVersion string
+}
+
+var _ Registrar = &APIManager{}
+var _ Registrar = &SecretManager{}
+
+// RegistrarFactory returns the Registrar used to register the cluster within ArgoCD.
+type RegistrarFactory func(ctx context.Context, client client.Client, log logr.Logger,
+	mode argocdv1beta1.RegistrationMode, clusterAPI *clusterapiv1.Cluster, kubeConfig []byte,
+	options ClusterOptions) (Registrar, error)
+
+// ServerURL returns the server of the cluster within ArgoCD from the control plane endpoint of the Cluster.
+func ServerURL(clusterAPI *clusterapiv1.Cluster) string {
+	return clusterAPI.Spec.ControlPlaneEndpoint.Host + ":" +
+		strconv.Itoa(int(clusterAPI.Spec.ControlPlaneEndpoint.Port))
+}
+
+// SameServer returns true when both servers identify the same cluster within ArgoCD. The https scheme and the
+// trailing slash are ignored, since the clusters registered via the ArgoCD CLI have them unlike ServerURL.
+func SameServer(server, other string) bool {
+	normalize := func(server string) string {
+		return strings.TrimSuffix(strings.TrimPrefix(server, "https://"), "/")
+	}
+	return server != "" && normalize(server) == normalize(other)
+}
+
+// NewRegistrar returns the Registrar which implements the registration mode informed, within the
+// ArgoCD instance of the options or the one configured via Manager ENV VAR. The clusters are registered
+// declaratively when the ArgoCD configured via Manager ENV VAR runs in core mode, since then there is
+// no API to register them.
+func NewRegistrar(ctx context.Context, client client.Client, log logr.Logger,
+	mode argocdv1beta1.RegistrationMode, clusterAPI *clusterapiv1.Cluster, kubeConfig []byte,
+	options ClusterOptions) (Registrar, error) {
+	options = options.withInstanceDefaults()
+	if (mode == argocdv1beta1.RegistrationModeAPI || mode == "") && options.Instance == nil {
+		namespace, err := getNamespace(ctx, log)
+		if err != nil {
+			return nil, err
+		}
+		coreMode, err := CoreMode(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if coreMode {
+			log.V(1).Info("ArgoCD runs in core mode, registering the cluster declaratively", "namespace", namespace)
+			mode = argocdv1beta1.RegistrationModeDeclarative
+		}
+	}
+	switch mode {
+	case argocdv1beta1.RegistrationModeDeclarative:
+		var secretManager *SecretManager
+		if options.Instance != nil {
+			secretManager = newSecretManager(ctx, client, log, clusterAPI, kubeConfig, options.Instance.Namespace)
+		} else {
+			var err error
+			if secretManager, err = NewSecretManagerWithCluster(ctx, client, log, clusterAPI, kubeConfig); err != nil {
+				return nil, err
+			}
+		}
+		secretManager.Options = options
+		if options.Name != "" {
+			secretManager.Name = options.Name
+		}
+		if options.Server != "" {
+			secretManager.Server = options.Server
+		}
+		return secretManager, nil
+	case argocdv1beta1.RegistrationModeAPI, "":
+		apiManager, err := newAPIManager(ctx, client, log, clusterAPI, kubeConfig, options.Instance)
+		if err != nil {
+			return nil, err
+		}
+		apiManager.Options = options
+		if options.Name != "" {
+			apiManager.Name = options.Name
+		}
+		if options.Server != "" {
+			apiManager.Server = options.Server
+		}
+		return apiManager, nil
+	default:
+		return nil, fmt.Errorf("unknown registration mode %q", mode)
+	}
+}
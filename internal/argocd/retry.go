@@ -0,0 +1,235 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// RetryMaxAttemptsEnvVar store the name of the envvar used to provide the maximum number of
+	// attempts to send a request to the ArgoCD API. Use 1 to disable the retries.
+	RetryMaxAttemptsEnvVar = "ARGOCD_RETRY_MAX_ATTEMPTS"
+
+	// RetryInitialBackoffEnvVar store the name of the envvar used to provide the duration to wait
+	// before the first retry (i.e. 500ms). The duration is doubled after each retry.
+	RetryInitialBackoffEnvVar = "ARGOCD_RETRY_INITIAL_BACKOFF"
+
+	// RetryMaxBackoffEnvVar store the name of the envvar used to provide the maximum duration
+	// to wait between the retries (i.e. 5s)
+	RetryMaxBackoffEnvVar = "ARGOCD_RETRY_MAX_BACKOFF"
+
+	// RetryJitterEnvVar store the name of the envvar used to provide the jitter factor (i.e. 0.2)
+	// applied to the duration to wait between the retries
+	RetryJitterEnvVar = "ARGOCD_RETRY_JITTER"
+
+	// RetryStatusCodesEnvVar store the name of the envvar used to provide the comma separated
+	// list of HTTP status codes returned by the ArgoCD API which are retried (i.e. 502,503,504)
+	RetryStatusCodesEnvVar = "ARGOCD_RETRY_STATUS_CODES"
+
+	// defaultRetryAfter is used when ArgoCD rate limits the requests without informing when they can be retried
+	defaultRetryAfter = 10 * time.Second
+)
+
+// RateLimitedError is returned when ArgoCD rate limits the requests (429 Too Many Requests).
+// RetryAfter informs how long to wait before sending new requests.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("ArgoCD API is rate limiting the requests, retry after %s", e.RetryAfter)
+}
+
+// parseRetryAfter returns the duration informed by the Retry-After header, which can be either
+// a number of seconds or an HTTP date. The defaultRetryAfter is returned when it is not valid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if retryAfter := date.Sub(now); retryAfter > 0 {
+			return retryAfter
+		}
+		return 0
+	}
+	return defaultRetryAfter
+}
+
+// RetryPolicy defines how the requests to the ArgoCD API are retried when they fail due
+// to transient errors. The zero value does not retry the requests.
+type RetryPolicy struct {
+	MaxAttempts          int           // Maximum number of attempts, including the first one
+	InitialBackoff       time.Duration // Duration to wait before the first retry
+	MaxBackoff           time.Duration // Maximum duration to wait between the retries
+	Jitter               float64       // Jitter factor applied to the duration to wait
+	RetryableStatusCodes []int         // HTTP status codes which are retried
+}
+
+// defaultRetryPolicy returns the policy used when it is not customized via Manager ENV VAR.
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// getRetryPolicy returns the retry policy customized via Manager ENV VAR.
+func getRetryPolicy() (RetryPolicy, error) {
+	policy := defaultRetryPolicy()
+
+	if value, exists := os.LookupEnv(RetryMaxAttemptsEnvVar); exists {
+		maxAttempts, err := strconv.Atoi(value)
+		if err != nil || maxAttempts < 1 {
+			return policy, fmt.Errorf("invalid value %q for %s: it must be a positive integer",
+				value, RetryMaxAttemptsEnvVar)
+		}
+		policy.MaxAttempts = maxAttempts
+	}
+
+	if value, exists := os.LookupEnv(RetryInitialBackoffEnvVar); exists {
+		initialBackoff, err := time.ParseDuration(value)
+		if err != nil || initialBackoff < 0 {
+			return policy, fmt.Errorf("invalid value %q for %s: it must be a valid duration",
+				value, RetryInitialBackoffEnvVar)
+		}
+		policy.InitialBackoff = initialBackoff
+	}
+
+	if value, exists := os.LookupEnv(RetryMaxBackoffEnvVar); exists {
+		maxBackoff, err := time.ParseDuration(value)
+		if err != nil || maxBackoff < 0 {
+			return policy, fmt.Errorf("invalid value %q for %s: it must be a valid duration",
+				value, RetryMaxBackoffEnvVar)
+		}
+		policy.MaxBackoff = maxBackoff
+	}
+
+	if value, exists := os.LookupEnv(RetryJitterEnvVar); exists {
+		jitter, err := strconv.ParseFloat(value, 64)
+		if err != nil || jitter < 0 {
+			return policy, fmt.Errorf("invalid value %q for %s: it must be a positive number",
+				value, RetryJitterEnvVar)
+		}
+		policy.Jitter = jitter
+	}
+
+	if value, exists := os.LookupEnv(RetryStatusCodesEnvVar); exists {
+		policy.RetryableStatusCodes = nil
+		for _, code := range strings.Split(value, ",") {
+			if code = strings.TrimSpace(code); code == "" {
+				continue
+			}
+			statusCode, err := strconv.Atoi(code)
+			if err != nil || http.StatusText(statusCode) == "" {
+				return policy, fmt.Errorf("invalid value %q for %s: %q is not a valid HTTP status code",
+					value, RetryStatusCodesEnvVar, code)
+			}
+			policy.RetryableStatusCodes = append(policy.RetryableStatusCodes, statusCode)
+		}
+	}
+
+	return policy, nil
+}
+
+// backoff returns the exponential backoff used to wait between the retries
+func (p RetryPolicy) backoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: p.InitialBackoff,
+		Factor:   2,
+		Jitter:   p.Jitter,
+		Steps:    p.MaxAttempts,
+		Cap:      p.MaxBackoff,
+	}
+}
+
+// shouldRetry returns true when the request failed due to a transient error
+func (p RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	for _, statusCode := range p.RetryableStatusCodes {
+		if resp.StatusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// send sends the request to the given path of the ArgoCD API using the token informed, if any.
+// The request is sent again, according to the RetryPolicy, when it fails due to transient errors.
+// A *RateLimitedError is returned when ArgoCD keeps rate limiting the requests.
+// The caller is responsible for closing the response body.
+func (a *APIManager) send(ctx context.Context, method, path string, payload []byte,
+	token string) (*http.Response, error) {
+	backoff := a.RetryPolicy.backoff()
+	for attempt := 1; ; attempt++ {
+		resp, err := a.sendOnce(ctx, method, path, payload, token)
+		if attempt >= a.RetryPolicy.MaxAttempts || !a.RetryPolicy.shouldRetry(resp, err) {
+			if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+				retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+				a.closeResponse(resp)
+				return nil, &RateLimitedError{RetryAfter: retryAfter}
+			}
+			return resp, err
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			a.closeResponse(resp)
+		}
+
+		delay := backoff.Step()
+		a.Log.V(1).Info("Retrying the request to the ArgoCD API", "method", method, "path", path,
+			"attempt", attempt, "delay", delay.String(), "reason", reason)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ArgoCD API retries", func() {
+	ctx := context.Background()
+	var server *httptest.Server
+	var requests int
+	var failures int
+	var failureStatus int
+
+	BeforeEach(func() {
+		requests = 0
+		failures = 2
+		failureStatus = http.StatusServiceUnavailable
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests <= failures {
+				w.WriteHeader(failureStatus)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"server":"Host:80","name":"test"}`)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newAPIManager := func(policy RetryPolicy) *APIManager {
+		return &APIManager{
+			Token:       "token-test",
+			Log:         logr.Discard(),
+			Server:      "Host:80",
+			Name:        "test",
+			Endpoint:    server.URL,
+			RetryPolicy: policy,
+		}
+	}
+
+	retryPolicy := RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		MaxBackoff:           10 * time.Millisecond,
+		Jitter:               0.2,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	}
+
+	It("should retry the requests which fail due to transient errors", func() {
+		Expect(newAPIManager(retryPolicy).Verify(ctx)).To(Succeed())
+		Expect(requests).To(Equal(3))
+	})
+
+	It("should give up when the maximum number of attempts is reached", func() {
+		failures = 3
+		Expect(newAPIManager(retryPolicy).Verify(ctx)).NotTo(Succeed())
+		Expect(requests).To(Equal(3))
+	})
+
+	It("should not retry the requests which fail with status codes that are not retryable", func() {
+		failureStatus = http.StatusBadRequest
+		Expect(newAPIManager(retryPolicy).Verify(ctx)).NotTo(Succeed())
+		Expect(requests).To(Equal(1))
+	})
+
+	It("should not retry the requests when no policy is defined", func() {
+		Expect(newAPIManager(RetryPolicy{}).Verify(ctx)).NotTo(Succeed())
+		Expect(requests).To(Equal(1))
+	})
+
+	It("should return a RateLimitedError when ArgoCD rate limits the requests", func() {
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		err := newAPIManager(retryPolicy).Verify(ctx)
+		var rateLimitedErr *RateLimitedError
+		Expect(errors.As(err, &rateLimitedErr)).To(BeTrue())
+		Expect(rateLimitedErr.RetryAfter).To(Equal(7 * time.Second))
+		Expect(requests).To(Equal(1))
+	})
+
+	It("should abort the requests when the context is done", func() {
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		Expect(errors.Is(newAPIManager(retryPolicy).Verify(cancelledCtx), context.Canceled)).To(BeTrue())
+		Expect(requests).To(BeZero())
+
+		By("checking that the retries are aborted when the context is done")
+		failures = 3
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		slowPolicy := retryPolicy
+		slowPolicy.InitialBackoff = time.Minute
+		slowPolicy.MaxBackoff = time.Minute
+		Expect(errors.Is(newAPIManager(slowPolicy).Verify(timeoutCtx), context.DeadlineExceeded)).To(BeTrue())
+		Expect(requests).To(Equal(1))
+	})
+
+	It("should parse the Retry-After header", func() {
+		now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+		Expect(parseRetryAfter("120", now)).To(Equal(2 * time.Minute))
+		Expect(parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now)).To(Equal(30 * time.Second))
+		Expect(parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now)).To(BeZero())
+		Expect(parseRetryAfter("", now)).To(Equal(defaultRetryAfter))
+		Expect(parseRetryAfter("soon", now)).To(Equal(defaultRetryAfter))
+	})
+
+	Context("Policy customized via Manager ENV VAR", func() {
+		AfterEach(func() {
+			for _, envVar := range []string{RetryMaxAttemptsEnvVar, RetryInitialBackoffEnvVar,
+				RetryMaxBackoffEnvVar, RetryJitterEnvVar, RetryStatusCodesEnvVar} {
+				_ = os.Unsetenv(envVar)
+			}
+		})
+
+		It("should return the default policy when it is not customized", func() {
+			Expect(getRetryPolicy()).To(Equal(defaultRetryPolicy()))
+		})
+
+		It("should return the policy customized", func() {
+			Expect(os.Setenv(RetryMaxAttemptsEnvVar, "5")).To(Succeed())
+			Expect(os.Setenv(RetryInitialBackoffEnvVar, "1s")).To(Succeed())
+			Expect(os.Setenv(RetryMaxBackoffEnvVar, "30s")).To(Succeed())
+			Expect(os.Setenv(RetryJitterEnvVar, "0.5")).To(Succeed())
+			Expect(os.Setenv(RetryStatusCodesEnvVar, "502, 503")).To(Succeed())
+
+			Expect(getRetryPolicy()).To(Equal(RetryPolicy{
+				MaxAttempts:          5,
+				InitialBackoff:       time.Second,
+				MaxBackoff:           30 * time.Second,
+				Jitter:               0.5,
+				RetryableStatusCodes: []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+			}))
+		})
+
+		It("should return an error when the policy is invalid", func() {
+			Expect(os.Setenv(RetryMaxAttemptsEnvVar, "0")).To(Succeed())
+			_, err := getRetryPolicy()
+			Expect(err).To(HaveOccurred())
+
+			Expect(os.Setenv(RetryMaxAttemptsEnvVar, "3")).To(Succeed())
+			Expect(os.Setenv(RetryStatusCodesEnvVar, "503,unavailable")).To(Succeed())
+			_, err = getRetryPolicy()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,327 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+const (
+	// DefaultServiceAccountNamespace is the namespace where the ServiceAccount used by ArgoCD
+	// is created in the workload cluster. It is the same used by `argocd cluster add`.
+	DefaultServiceAccountNamespace = "kube-system"
+
+	// DefaultServiceAccountName is the name of the ServiceAccount used by ArgoCD.
+	// It is the same used by `argocd cluster add`.
+	DefaultServiceAccountName = "argocd-manager"
+
+	// ServiceAccountLabel is added to the resources created in the workload cluster for the ServiceAccount
+	ServiceAccountLabel = "argocd.workload.com/service-account"
+)
+
+// ServiceAccountCredentials stores the kubeconfig which authenticates with the token of the
+// ServiceAccount used by ArgoCD and when the token expires.
+type ServiceAccountCredentials struct {
+	KubeConfig []byte
+	// ExpiresAt is zero when the token is long-lived
+	ExpiresAt time.Time
+}
+
+// GetServiceAccountCredentials returns a kubeconfig which authenticates with the token of the ServiceAccount
+// used by ArgoCD. When reconcileRBAC is true it first ensures that the ServiceAccount, bound to a ClusterRole,
+// exists in the workload cluster, which is only required when the spec changed.
+// When the TokenExpiration is informed a new token bound to it is requested, otherwise, the
+// long-lived token is used. The kubeconfig informed is only used to connect to the workload
+// cluster and its credentials are not part of the kubeconfig returned.
+func GetServiceAccountCredentials(ctx context.Context, kubeConfig []byte, spec *argocdv1beta1.ServiceAccountSpec,
+	reconcileRBAC bool) (*ServiceAccountCredentials, error) {
+	config, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error loading kubeconfig: %w", RedactError(err))
+	}
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading kubeconfig: %w", RedactError(err))
+	}
+	workloadClient, err := client.New(restConfig, client.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating client for the workload cluster: %w", err)
+	}
+
+	namespace, name := DefaultServiceAccountNamespace, DefaultServiceAccountName
+	if spec != nil && spec.Namespace != "" {
+		namespace = spec.Namespace
+	}
+	if spec != nil && spec.Name != "" {
+		name = spec.Name
+	}
+
+	if reconcileRBAC {
+		if err := ensureServiceAccount(ctx, workloadClient, namespace, name, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	credentials := &ServiceAccountCredentials{}
+	var token, caData []byte
+	if spec != nil && spec.TokenExpiration != nil {
+		token, credentials.ExpiresAt, err = requestToken(ctx, workloadClient, namespace, name,
+			spec.TokenExpiration.Duration)
+	} else {
+		token, caData, err = longLivedToken(ctx, workloadClient, namespace, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("current context %q not found in kubeconfig", config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[kubeContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", kubeContext.Cluster)
+	}
+	cluster = cluster.DeepCopy()
+	if len(cluster.CertificateAuthorityData) == 0 && !cluster.InsecureSkipTLSVerify {
+		cluster.CertificateAuthorityData = caData
+	}
+
+	serviceAccountConfig := clientcmdapi.NewConfig()
+	serviceAccountConfig.Clusters[kubeContext.Cluster] = cluster
+	serviceAccountConfig.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: string(token)}
+	serviceAccountConfig.Contexts[config.CurrentContext] = &clientcmdapi.Context{
+		Cluster:  kubeContext.Cluster,
+		AuthInfo: name,
+	}
+	serviceAccountConfig.CurrentContext = config.CurrentContext
+
+	credentials.KubeConfig, err = clientcmd.Write(*serviceAccountConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error writing kubeconfig: %w", err)
+	}
+	return credentials, nil
+}
+
+// ensureServiceAccount creates or updates the ServiceAccount and its RBAC. The long-lived token is revoked, if
+// any, when the tokens are bound to the TokenExpiration.
+func ensureServiceAccount(ctx context.Context, workloadClient client.Client, namespace, name string,
+	spec *argocdv1beta1.ServiceAccountSpec) error {
+	serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, workloadClient, serviceAccount, func() error {
+		serviceAccount.Labels = serviceAccountLabels(name)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("error creating or updating ServiceAccount: %w", err)
+	}
+	if spec != nil && spec.TokenExpiration != nil {
+		tokenSecret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name + "-long-lived-token", Namespace: namespace}}
+		if err := workloadClient.Delete(ctx, tokenSecret); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("error deleting ServiceAccount token Secret: %w", err)
+		}
+	}
+
+	clusterRoleName, err := ensureClusterRole(ctx, workloadClient, name, spec)
+	if err != nil {
+		return err
+	}
+	return ensureRoleBindings(ctx, workloadClient, namespace, name, clusterRoleName, spec)
+}
+
+// longLivedToken returns the long-lived token of the ServiceAccount and the CA data of the cluster.
+// Since Kubernetes 1.24 the tokens are no longer created automatically for the ServiceAccounts,
+// therefore the Secret is created to request it.
+func longLivedToken(ctx context.Context, workloadClient client.Client, namespace, name string) ([]byte, []byte, error) {
+	tokenSecret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name + "-long-lived-token", Namespace: namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, workloadClient, tokenSecret, func() error {
+		tokenSecret.Labels = serviceAccountLabels(name)
+		if tokenSecret.Annotations == nil {
+			tokenSecret.Annotations = map[string]string{}
+		}
+		tokenSecret.Annotations[v1.ServiceAccountNameKey] = name
+		tokenSecret.Type = v1.SecretTypeServiceAccountToken
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("error creating or updating ServiceAccount token Secret: %w", err)
+	}
+
+	token := tokenSecret.Data[v1.ServiceAccountTokenKey]
+	if len(token) == 0 {
+		return nil, nil, fmt.Errorf("token of the ServiceAccount %s/%s was not issued yet", namespace, name)
+	}
+	return token, tokenSecret.Data[v1.ServiceAccountRootCAKey], nil
+}
+
+// requestToken requests a new token of the ServiceAccount bound to the expiration informed via the
+// TokenRequest API and returns it with its expiration.
+func requestToken(ctx context.Context, workloadClient client.Client, namespace, name string,
+	expiration time.Duration) ([]byte, time.Time, error) {
+	expirationSeconds := int64(expiration.Seconds())
+	serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSeconds},
+	}
+	if err := workloadClient.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+		return nil, time.Time{}, fmt.Errorf("error requesting token of the ServiceAccount %s/%s: %w",
+			namespace, name, err)
+	}
+	return []byte(tokenRequest.Status.Token), tokenRequest.Status.ExpirationTimestamp.Time, nil
+}
+
+// serviceAccountLabels returns the labels of the resources created for the ServiceAccount
+func serviceAccountLabels(name string) map[string]string {
+	return map[string]string{ManagedByLabel: ManagedByValue, ServiceAccountLabel: name}
+}
+
+// ensureClusterRole creates or updates the ClusterRole with the rules informed, or the default ones,
+// and returns its name. When an existing ClusterRole is informed its name is returned instead.
+func ensureClusterRole(ctx context.Context, workloadClient client.Client, name string,
+	spec *argocdv1beta1.ServiceAccountSpec) (string, error) {
+	clusterRole := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name + "-role"}}
+
+	if spec != nil && spec.ClusterRoleName != "" {
+		if len(spec.Rules) > 0 {
+			return "", fmt.Errorf("clusterRoleName and rules of the ServiceAccount are mutually exclusive")
+		}
+		// The ClusterRole previously created for the ServiceAccount is no longer used
+		if spec.ClusterRoleName != clusterRole.Name {
+			if err := workloadClient.Delete(ctx, clusterRole); client.IgnoreNotFound(err) != nil {
+				return "", fmt.Errorf("error deleting ClusterRole: %w", err)
+			}
+		}
+		return spec.ClusterRoleName, nil
+	}
+
+	rules := []rbacv1.PolicyRule{
+		{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+		{NonResourceURLs: []string{"*"}, Verbs: []string{"*"}},
+	}
+	if spec != nil && len(spec.Rules) > 0 {
+		rules = spec.Rules
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, workloadClient, clusterRole, func() error {
+		clusterRole.Labels = serviceAccountLabels(name)
+		clusterRole.Rules = rules
+		return nil
+	}); err != nil {
+		return "", fmt.Errorf("error creating or updating ClusterRole: %w", err)
+	}
+	return clusterRole.Name, nil
+}
+
+// ensureRoleBindings binds the ClusterRole to the ServiceAccount cluster-wide or, when namespaces
+// are informed, only in these namespaces. The bindings which are no longer desired are deleted.
+func ensureRoleBindings(ctx context.Context, workloadClient client.Client, namespace, name, clusterRoleName string,
+	spec *argocdv1beta1.ServiceAccountSpec) error {
+	labels := serviceAccountLabels(name)
+	bindingName := name + "-role-binding"
+	roleRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRoleName}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: namespace}}
+
+	var namespaces []string
+	if spec != nil {
+		namespaces = spec.Namespaces
+	}
+
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: bindingName}}
+	if len(namespaces) > 0 {
+		if err := workloadClient.Delete(ctx, clusterRoleBinding); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("error deleting ClusterRoleBinding: %w", err)
+		}
+	} else {
+		if err := deleteIfRoleRefChanged(ctx, workloadClient, clusterRoleBinding, roleRef); err != nil {
+			return err
+		}
+		if _, err := controllerutil.CreateOrUpdate(ctx, workloadClient, clusterRoleBinding, func() error {
+			clusterRoleBinding.Labels = labels
+			clusterRoleBinding.RoleRef = roleRef
+			clusterRoleBinding.Subjects = subjects
+			return nil
+		}); err != nil {
+			return fmt.Errorf("error creating or updating ClusterRoleBinding: %w", err)
+		}
+	}
+
+	desired := map[string]bool{}
+	for _, ns := range namespaces {
+		desired[ns] = true
+		roleBinding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: bindingName, Namespace: ns}}
+		if err := deleteIfRoleRefChanged(ctx, workloadClient, roleBinding, roleRef); err != nil {
+			return err
+		}
+		if _, err := controllerutil.CreateOrUpdate(ctx, workloadClient, roleBinding, func() error {
+			roleBinding.Labels = labels
+			roleBinding.RoleRef = roleRef
+			roleBinding.Subjects = subjects
+			return nil
+		}); err != nil {
+			return fmt.Errorf("error creating or updating RoleBinding in the namespace %s: %w", ns, err)
+		}
+	}
+
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := workloadClient.List(ctx, roleBindings, client.MatchingLabels(labels)); err != nil {
+		return fmt.Errorf("error listing RoleBindings: %w", err)
+	}
+	for i := range roleBindings.Items {
+		if roleBinding := &roleBindings.Items[i]; !desired[roleBinding.Namespace] {
+			if err := workloadClient.Delete(ctx, roleBinding); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("error deleting RoleBinding in the namespace %s: %w", roleBinding.Namespace, err)
+			}
+		}
+	}
+	return nil
+}
+
+// deleteIfRoleRefChanged deletes the binding when it refers to another role since the RoleRef
+// cannot be changed after the binding is created
+func deleteIfRoleRefChanged(ctx context.Context, workloadClient client.Client, binding client.Object,
+	roleRef rbacv1.RoleRef) error {
+	if err := workloadClient.Get(ctx, client.ObjectKeyFromObject(binding), binding); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	var currentRoleRef rbacv1.RoleRef
+	switch b := binding.(type) {
+	case *rbacv1.ClusterRoleBinding:
+		currentRoleRef = b.RoleRef
+	case *rbacv1.RoleBinding:
+		currentRoleRef = b.RoleRef
+	}
+	if currentRoleRef == roleRef {
+		return nil
+	}
+
+	if err := workloadClient.Delete(ctx, binding); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("error deleting %s with outdated role: %w", binding.GetName(), err)
+	}
+	binding.SetResourceVersion("")
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+var _ = Describe("ArgoCD ServiceAccount", func() {
+	ctx := context.Background()
+
+	It("should create the ServiceAccount used by ArgoCD in the workload cluster", func() {
+		// The envtest cluster is used as workload cluster
+		kubeConfig := kubeConfigFromRESTConfig(cfg)
+		spec := &argocdv1beta1.ServiceAccountSpec{Namespace: "default", Name: DefaultServiceAccountName}
+
+		By("checking that an error is returned while the token is not issued")
+		_, err := GetServiceAccountCredentials(ctx, kubeConfig, spec, true)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("was not issued yet"))
+
+		By("checking the ServiceAccount and its permissions")
+		serviceAccount := &corev1.ServiceAccount{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: DefaultServiceAccountName},
+			serviceAccount)).To(Succeed())
+		clusterRoleBinding := &rbacv1.ClusterRoleBinding{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: DefaultServiceAccountName + "-role-binding"},
+			clusterRoleBinding)).To(Succeed())
+		Expect(clusterRoleBinding.RoleRef.Name).To(Equal(DefaultServiceAccountName + "-role"))
+		Expect(clusterRoleBinding.Subjects).To(ConsistOf(rbacv1.Subject{Kind: rbacv1.ServiceAccountKind,
+			Name: DefaultServiceAccountName, Namespace: "default"}))
+
+		By("issuing the token since the token controller does not run in the test environment")
+		tokenSecret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default",
+			Name: DefaultServiceAccountName + "-long-lived-token"}, tokenSecret)).To(Succeed())
+		Expect(tokenSecret.Type).To(Equal(corev1.SecretTypeServiceAccountToken))
+		tokenSecret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte("service-account-token")}
+		Expect(k8sClient.Update(ctx, tokenSecret)).To(Succeed())
+
+		By("checking that the kubeconfig returned authenticates with the token")
+		credentials, err := GetServiceAccountCredentials(ctx, kubeConfig, spec, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials.ExpiresAt.IsZero()).To(BeTrue())
+		config, err := clusterConfigFromKubeConfig(credentials.KubeConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.BearerToken).To(Equal("service-account-token"))
+		Expect(config.TLSClientConfig.CAData).To(Equal(cfg.CAData))
+		Expect(config.TLSClientConfig.CertData).To(BeEmpty())
+		Expect(config.TLSClientConfig.KeyData).To(BeEmpty())
+	})
+
+	It("should request tokens bound to the expiration informed", func() {
+		const name = "argocd-expiring"
+		kubeConfig := kubeConfigFromRESTConfig(cfg)
+		spec := &argocdv1beta1.ServiceAccountSpec{Namespace: "default", Name: name,
+			TokenExpiration: &metav1.Duration{Duration: time.Hour}}
+
+		By("creating a long-lived token which must be revoked")
+		Expect(ensureServiceAccount(ctx, k8sClient, "default", name, spec)).To(Succeed())
+		_, _, err := longLivedToken(ctx, k8sClient, "default", name)
+		Expect(err).To(HaveOccurred())
+
+		By("requesting a token bound to the expiration")
+		before := time.Now()
+		credentials, err := GetServiceAccountCredentials(ctx, kubeConfig, spec, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials.ExpiresAt).To(BeTemporally("~", before.Add(time.Hour), time.Minute))
+		config, err := clusterConfigFromKubeConfig(credentials.KubeConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.BearerToken).NotTo(BeEmpty())
+
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name + "-long-lived-token"},
+			&corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should only reconcile the ServiceAccount and its RBAC when it is requested", func() {
+		const name = "argocd-steady"
+		kubeConfig := kubeConfigFromRESTConfig(cfg)
+		spec := &argocdv1beta1.ServiceAccountSpec{Namespace: "default", Name: name,
+			TokenExpiration: &metav1.Duration{Duration: time.Hour}}
+
+		By("failing to request a token of the ServiceAccount which was not created")
+		_, err := GetServiceAccountCredentials(ctx, kubeConfig, spec, false)
+		Expect(err).To(HaveOccurred())
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, &corev1.ServiceAccount{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("requesting tokens without writing the RBAC once it was reconciled")
+		_, err = GetServiceAccountCredentials(ctx, kubeConfig, spec, true)
+		Expect(err).NotTo(HaveOccurred())
+		clusterRoleBinding := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name + "-role-binding"}}
+		Expect(k8sClient.Delete(ctx, clusterRoleBinding)).To(Succeed())
+		_, err = GetServiceAccountCredentials(ctx, kubeConfig, spec, false)
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRoleBinding), &rbacv1.ClusterRoleBinding{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should bind only the permissions informed to the ServiceAccount", func() {
+		const name = "argocd-restricted"
+		rules := []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"deployments"},
+			Verbs: []string{"get", "list", "watch"}}}
+
+		By("binding the rules informed only in the namespaces allowed")
+		err := ensureServiceAccount(ctx, k8sClient, "default", name,
+			&argocdv1beta1.ServiceAccountSpec{Rules: rules, Namespaces: []string{"default"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		clusterRole := &rbacv1.ClusterRole{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: name + "-role"}, clusterRole)).To(Succeed())
+		Expect(clusterRole.Rules).To(Equal(rules))
+		roleBinding := &rbacv1.RoleBinding{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name + "-role-binding"},
+			roleBinding)).To(Succeed())
+		Expect(roleBinding.RoleRef.Name).To(Equal(name + "-role"))
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: name + "-role-binding"}, &rbacv1.ClusterRoleBinding{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("binding an existing ClusterRole cluster-wide")
+		err = ensureServiceAccount(ctx, k8sClient, "default", name,
+			&argocdv1beta1.ServiceAccountSpec{ClusterRoleName: "view"})
+		Expect(err).NotTo(HaveOccurred())
+
+		clusterRoleBinding := &rbacv1.ClusterRoleBinding{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: name + "-role-binding"}, clusterRoleBinding)).To(Succeed())
+		Expect(clusterRoleBinding.RoleRef.Name).To(Equal("view"))
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name + "-role-binding"},
+			&rbacv1.RoleBinding{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: name + "-role"}, &rbacv1.ClusterRole{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("checking that an existing ClusterRole and rules cannot be informed together")
+		err = ensureServiceAccount(ctx, k8sClient, "default", name,
+			&argocdv1beta1.ServiceAccountSpec{ClusterRoleName: "view", Rules: rules})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+// kubeConfigFromRESTConfig returns the kubeconfig which allows to connect with the cluster of the config
+func kubeConfigFromRESTConfig(restConfig *rest.Config) []byte {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["envtest"] = &clientcmdapi.Cluster{
+		Server:                   restConfig.Host,
+		CertificateAuthorityData: restConfig.CAData,
+	}
+	config.AuthInfos["envtest"] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: restConfig.CertData,
+		ClientKeyData:         restConfig.KeyData,
+	}
+	config.Contexts["envtest"] = &clientcmdapi.Context{Cluster: "envtest", AuthInfo: "envtest"}
+	config.CurrentContext = "envtest"
+
+	kubeConfig, err := clientcmd.Write(*config)
+	Expect(err).NotTo(HaveOccurred())
+	return kubeConfig
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"github.com/workload-operator/internal/tracing"
+)
+
+// sessionRefreshWindow defines how long before its expiration the session token is refreshed
+const sessionRefreshWindow = 5 * time.Minute
+
+// sessionRequest is the payload used to create a session within the ArgoCD API.
+type sessionRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// sessionResponse is the payload returned by the ArgoCD API when a session is created.
+type sessionResponse struct {
+	Token string `json:"token"`
+}
+
+// session stores a session token created within the ArgoCD API
+type session struct {
+	token       string
+	expiresAt   time.Time // zero when the expiration is unknown
+	credentials string    // hash of the credentials used to create the session
+}
+
+// sessionStore caches the session tokens by ArgoCD endpoint and account so that a new
+// session is not created on every reconciliation. It is safe for concurrent use.
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]session
+
+	// logins serializes the creation of the sessions per key, so that the concurrent reconciliations
+	// share the session created by the first one instead of all logging in at the same time
+	logins map[string]*sync.Mutex
+}
+
+// sessions is shared by all APIManagers
+var sessions = &sessionStore{sessions: map[string]session{}}
+
+// get returns the cached token when it was created with the same credentials and
+// it will not expire within the sessionRefreshWindow.
+func (s *sessionStore) get(key, credentials string, now time.Time) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.sessions[key]
+	if !ok || cached.credentials != credentials {
+		return "", false
+	}
+	if !cached.expiresAt.IsZero() && now.Add(sessionRefreshWindow).After(cached.expiresAt) {
+		return "", false
+	}
+	return cached.token, true
+}
+
+// loginLock returns the lock which serializes the creation of the sessions for the key
+func (s *sessionStore) loginLock(key string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.logins == nil {
+		s.logins = map[string]*sync.Mutex{}
+	}
+	lock, ok := s.logins[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.logins[key] = lock
+	}
+	return lock
+}
+
+func (s *sessionStore) set(key string, cached session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[key] = cached
+}
+
+func (s *sessionStore) invalidate(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, key)
+}
+
+func (s *sessionStore) invalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = map[string]session{}
+}
+
+// InvalidateSessions drops all the cached session tokens so that new sessions are created with the
+// current credentials, i.e. when the Secret with the credentials of the ArgoCD account changes.
+func InvalidateSessions() {
+	sessions.invalidateAll()
+}
+
+// tokenExpiry returns the expiration time of the JWT token informed.
+// A zero time is returned when the token has no expiration.
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("token is not a valid JWT")
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error decoding token claims: %w", err)
+	}
+
+	var expiry struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(claims, &expiry); err != nil {
+		return time.Time{}, fmt.Errorf("error unmarshalling token claims: %w", err)
+	}
+	if expiry.Exp == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(expiry.Exp, 0), nil
+}
+
+// sessionKey returns the key used to cache the session of the account for the ArgoCD endpoint
+func (a *APIManager) sessionKey() string {
+	return a.Endpoint + "|" + a.username
+}
+
+// credentialsHash returns a hash of the credentials so that a cached session is not reused
+// after the credentials are rotated
+func (a *APIManager) credentialsHash() string {
+	sum := sha256.Sum256([]byte(a.username + ":" + a.password))
+	return hex.EncodeToString(sum[:])
+}
+
+// ensureSession sets the session token, reusing the cached one while it is valid.
+func (a *APIManager) ensureSession(ctx context.Context) error {
+	// The token was provided directly, therefore there are no credentials to create a session
+	if a.password == "" && a.Token != "" {
+		return nil
+	}
+
+	if token, ok := sessions.get(a.sessionKey(), a.credentialsHash(), time.Now()); ok {
+		a.Token = token
+		return nil
+	}
+
+	ctx, span := tracing.Start(ctx, "Acquire ArgoCD session token")
+	defer span.End()
+
+	// Only one session is created at a time for the account, the concurrent reconciliations waiting for
+	// the lock reuse it once it is cached
+	lock := sessions.loginLock(a.sessionKey())
+	lock.Lock()
+	defer lock.Unlock()
+	if token, ok := sessions.get(a.sessionKey(), a.credentialsHash(), time.Now()); ok {
+		a.Token = token
+		return nil
+	}
+	return tracing.RecordError(span, a.login(ctx))
+}
+
+// resetSession drops the session token so that a new one is created on the next request.
+func (a *APIManager) resetSession() {
+	sessions.invalidate(a.sessionKey())
+	a.Token = ""
+}
+
+// login creates a session within the ArgoCD API using the credentials of the account
+// and stores the session token to authenticate the subsequent requests.
+// More info: https://argo-cd.readthedocs.io/en/stable/developer-guide/api-docs/#authorization
+func (a *APIManager) login(ctx context.Context) error {
+	if a.password == "" {
+		return fmt.Errorf("no credentials found to create a session within ArgoCD")
+	}
+
+	payload, err := json.Marshal(sessionRequest{Username: a.username, Password: a.password})
+	if err != nil {
+		return fmt.Errorf("error marshalling session payload: %w", err)
+	}
+
+	resp, err := a.sendThroughCircuit(ctx, http.MethodPost, "/api/v1/session", payload, "")
+	if err != nil {
+		return err
+	}
+	defer a.closeResponse(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("creating session within ArgoCD", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading session response body: %w", err)
+	}
+
+	created := &sessionResponse{}
+	if err := json.Unmarshal(body, created); err != nil {
+		return fmt.Errorf("error decoding session response: %w", err)
+	}
+	if created.Token == "" {
+		return fmt.Errorf("no token returned by ArgoCD for the session")
+	}
+
+	// When the expiration cannot be determined the token is kept until ArgoCD rejects it
+	expiresAt, err := tokenExpiry(created.Token)
+	if err != nil {
+		a.Log.V(1).Info("Unable to determine the expiration of the ArgoCD session token", "reason", err.Error())
+	}
+	sessions.set(a.sessionKey(), session{
+		token:       created.Token,
+		expiresAt:   expiresAt,
+		credentials: a.credentialsHash(),
+	})
+
+	a.Token = created.Token
+	return nil
+}
@@ -0,0 +1,404 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CAConfigMapNameEnvVar store the name of the envvar used to provide the name of the ConfigMap,
+	// in the ArgoCD namespace, with the CA bundle used to verify the certificate of the ArgoCD API
+	CAConfigMapNameEnvVar = "ARGOCD_CA_CONFIGMAP_NAME"
+
+	// CASecretNameEnvVar store the name of the envvar used to provide the name of the Secret,
+	// in the ArgoCD namespace, with the CA bundle used to verify the certificate of the ArgoCD API
+	CASecretNameEnvVar = "ARGOCD_CA_SECRET_NAME"
+
+	// ClientCertSecretNameEnvVar store the name of the envvar used to provide the name of the Secret,
+	// in the ArgoCD namespace, of type kubernetes.io/tls with the client certificate and key presented
+	// to the ArgoCD API when it requires mutual TLS authentication
+	ClientCertSecretNameEnvVar = "ARGOCD_CLIENT_CERT_SECRET_NAME"
+
+	// InsecureSkipVerifyEnvVar store the name of the envvar used to disable the verification of the
+	// certificate of the ArgoCD API. It is only meant for development and test environments.
+	InsecureSkipVerifyEnvVar = "ARGOCD_INSECURE_SKIP_VERIFY"
+
+	// AllowInsecureEndpointEnvVar store the name of the envvar used to allow an http:// ArgoCD API endpoint.
+	// The cluster entries sent to ArgoCD have the credentials of the clusters, therefore, the endpoints which
+	// are not HTTPS are rejected unless it is set. It is only meant for development and test environments.
+	AllowInsecureEndpointEnvVar = "ARGOCD_ALLOW_INSECURE_ENDPOINT"
+
+	// ProxyURLEnvVar store the name of the envvar used to provide the URL of the proxy used to connect
+	// to the ArgoCD API. When it is not provided the HTTP_PROXY, HTTPS_PROXY and NO_PROXY envvars are used.
+	ProxyURLEnvVar = "ARGOCD_PROXY_URL"
+
+	// CABundleKey is the key of the ConfigMap or Secret which stores the PEM encoded CA bundle
+	CABundleKey = "ca.crt"
+
+	// RequestTimeoutEnvVar store the name of the envvar used to provide the timeout of the
+	// requests to the ArgoCD API (i.e. 30s)
+	RequestTimeoutEnvVar = "ARGOCD_REQUEST_TIMEOUT"
+
+	defaultRequestTimeout = 30 * time.Second
+
+	// defaultMaxIdleConnsPerHost allows to keep alive more connections than the http package
+	// default (2) since the requests of all Registers are sent to the same ArgoCD endpoint
+	defaultMaxIdleConnsPerHost = 10
+
+	// httpClientIdleTTL is how long an HTTP client is kept once it is no longer used, i.e. once the CA bundle
+	// was rotated or the ArgoCDInstance was removed, before its idle connections are closed
+	httpClientIdleTTL = 10 * time.Minute
+)
+
+// setTLSConfig sets the TLS configuration used to connect to the ArgoCD API. When a CA bundle
+// is provided it is trusted in addition to the system certificates and when a client certificate
+// is provided it is presented to the ArgoCD API.
+func (a *APIManager) setTLSConfig() error {
+	a.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	clientCert, err := a.getClientCertificate()
+	if err != nil {
+		return err
+	}
+	if clientCert != nil {
+		a.TLSConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+
+	insecureSkipVerify, err := a.insecureSkipVerify()
+	if err != nil {
+		return err
+	}
+	if insecureSkipVerify {
+		a.Log.Info("WARNING: The verification of the ArgoCD API certificate is disabled. "+
+			"This option must not be used in production", "endpoint", a.Endpoint)
+		// #nosec G402 -- explicit opt-in for development and test environments
+		a.TLSConfig.InsecureSkipVerify = true
+		return nil
+	}
+
+	caBundle, err := a.getCABundle()
+	if err != nil {
+		return err
+	}
+	if caBundle == nil {
+		return nil
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		a.Log.Info("Unable to load the system certificates, only the CA bundle provided will be trusted",
+			"reason", err.Error())
+		rootCAs = x509.NewCertPool()
+	}
+	if !rootCAs.AppendCertsFromPEM(caBundle) {
+		return fmt.Errorf("no valid PEM certificates found in the CA bundle")
+	}
+	a.TLSConfig.RootCAs = rootCAs
+	a.caBundle = caBundle
+	return nil
+}
+
+// InsecureSkipVerify returns true when the verification of the ArgoCD API certificate
+// was disabled via Manager ENV VAR.
+func InsecureSkipVerify() (bool, error) {
+	value, exists := os.LookupEnv(InsecureSkipVerifyEnvVar)
+	if !exists || value == "" {
+		return false, nil
+	}
+	insecureSkipVerify, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q for %s: %w", value, InsecureSkipVerifyEnvVar, err)
+	}
+	return insecureSkipVerify, nil
+}
+
+// AllowInsecureEndpoint returns true when the http:// ArgoCD API endpoints were allowed via Manager ENV VAR.
+func AllowInsecureEndpoint() (bool, error) {
+	value, exists := os.LookupEnv(AllowInsecureEndpointEnvVar)
+	if !exists || value == "" {
+		return false, nil
+	}
+	allowInsecureEndpoint, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q for %s: %w", value, AllowInsecureEndpointEnvVar, err)
+	}
+	return allowInsecureEndpoint, nil
+}
+
+// validateEndpoint returns an error when the ArgoCD API endpoint is not HTTPS, since the credentials of the
+// clusters registered would be sent in plain text, unless the http:// endpoints were allowed in the ArgoCD
+// instance or via Manager ENV VAR.
+func (a *APIManager) validateEndpoint() error {
+	endpoint, err := url.Parse(a.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return fmt.Errorf("invalid ArgoCD API endpoint %q: it must be an https:// URL", a.Endpoint)
+	}
+	switch endpoint.Scheme {
+	case "https":
+		return nil
+	case "http":
+	default:
+		return fmt.Errorf("invalid ArgoCD API endpoint %q: it must be an https:// URL", a.Endpoint)
+	}
+
+	allowInsecureEndpoint, err := a.allowInsecureEndpoint()
+	if err != nil {
+		return err
+	}
+	if !allowInsecureEndpoint {
+		return fmt.Errorf("the ArgoCD API endpoint %s is not HTTPS and the credentials of the clusters would be "+
+			"sent in plain text: use an https:// endpoint or set allowInsecureEndpoint in the ArgoCDInstance "+
+			"(%s for the default instance) to allow it", a.Endpoint, AllowInsecureEndpointEnvVar)
+	}
+	a.Log.V(1).Info("WARNING: The ArgoCD API endpoint is not HTTPS. "+
+		"This option must not be used in production", "endpoint", a.Endpoint)
+	return nil
+}
+
+// allowInsecureEndpoint returns true when the http:// endpoints were allowed in the ArgoCD instance or,
+// when no instance is informed, via Manager ENV VAR.
+func (a *APIManager) allowInsecureEndpoint() (bool, error) {
+	if a.Instance != nil {
+		return a.Instance.AllowInsecureEndpoint, nil
+	}
+	return AllowInsecureEndpoint()
+}
+
+// insecureSkipVerify returns true when the verification of the certificate of the ArgoCD API was
+// disabled in the ArgoCD instance or, when no instance is informed, via Manager ENV VAR.
+func (a *APIManager) insecureSkipVerify() (bool, error) {
+	if a.Instance != nil {
+		return a.Instance.InsecureSkipVerify, nil
+	}
+	return InsecureSkipVerify()
+}
+
+// lookupSetting returns the value of the setting of the ArgoCD instance or, when no instance is
+// informed, the one provided via the Manager ENV VAR. It returns false when it is not provided.
+func (a *APIManager) lookupSetting(envVar string, instanceValue func(*Instance) string) (string, bool) {
+	if a.Instance != nil {
+		value := instanceValue(a.Instance)
+		return value, value != ""
+	}
+	return os.LookupEnv(envVar)
+}
+
+// getCABundle returns the CA bundle from the ConfigMap or Secret of the ArgoCD instance, or provided
+// via Manager ENV VAR. It returns nil when none is provided.
+func (a *APIManager) getCABundle() ([]byte, error) {
+	if name, exists := a.lookupSetting(CAConfigMapNameEnvVar,
+		func(i *Instance) string { return i.CAConfigMapName }); exists {
+		configMap := &v1.ConfigMap{}
+		if err := a.Client.Get(a.Ctx, client.ObjectKey{Namespace: a.Namespace, Name: name}, configMap); err != nil {
+			return nil, fmt.Errorf("error fetching CA bundle configmap: %w", err)
+		}
+		if caBundle, ok := configMap.Data[CABundleKey]; ok {
+			return []byte(caBundle), nil
+		}
+		if caBundle, ok := configMap.BinaryData[CABundleKey]; ok {
+			return caBundle, nil
+		}
+		return nil, fmt.Errorf("%s not found in CA bundle configmap", CABundleKey)
+	}
+
+	if name, exists := a.lookupSetting(CASecretNameEnvVar,
+		func(i *Instance) string { return i.CASecretName }); exists {
+		secret := &v1.Secret{}
+		if err := a.Client.Get(a.Ctx, client.ObjectKey{Namespace: a.Namespace, Name: name}, secret); err != nil {
+			return nil, fmt.Errorf("error fetching CA bundle secret: %w", err)
+		}
+		caBundle, ok := secret.Data[CABundleKey]
+		if !ok {
+			return nil, fmt.Errorf("%s not found in CA bundle secret", CABundleKey)
+		}
+		return caBundle, nil
+	}
+
+	return nil, nil
+}
+
+// getClientCertificate returns the client certificate from the Secret of the ArgoCD instance, or
+// provided via Manager ENV VAR. It returns nil when none is provided.
+func (a *APIManager) getClientCertificate() (*tls.Certificate, error) {
+	name, exists := a.lookupSetting(ClientCertSecretNameEnvVar,
+		func(i *Instance) string { return i.ClientCertSecretName })
+	if !exists {
+		return nil, nil
+	}
+
+	secret := &v1.Secret{}
+	if err := a.Client.Get(a.Ctx, client.ObjectKey{Namespace: a.Namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("error fetching client certificate secret: %w", err)
+	}
+	certPEM, ok := secret.Data[v1.TLSCertKey]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in client certificate secret", v1.TLSCertKey)
+	}
+	keyPEM, ok := secret.Data[v1.TLSPrivateKeyKey]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in client certificate secret", v1.TLSPrivateKeyKey)
+	}
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error loading client certificate: %w", err)
+	}
+	return &clientCert, nil
+}
+
+// setProxy sets the proxy provided via Manager ENV VAR to connect to the ArgoCD API
+func (a *APIManager) setProxy() error {
+	proxy, exists := os.LookupEnv(ProxyURLEnvVar)
+	if !exists || proxy == "" {
+		return nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return fmt.Errorf("invalid value %q for %s: %w", proxy, ProxyURLEnvVar, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("invalid value %q for %s: the scheme must be http, https or socks5",
+			proxy, ProxyURLEnvVar)
+	}
+	if proxyURL.Host == "" {
+		return fmt.Errorf("invalid value %q for %s: the host is required", proxy, ProxyURLEnvVar)
+	}
+	a.ProxyURL = proxyURL
+	return nil
+}
+
+// getRequestTimeout returns the timeout of the requests to the ArgoCD API provided via Manager ENV VAR.
+func getRequestTimeout() (time.Duration, error) {
+	value, exists := os.LookupEnv(RequestTimeoutEnvVar)
+	if !exists || value == "" {
+		return defaultRequestTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid value %q for %s: it must be a positive duration", value, RequestTimeoutEnvVar)
+	}
+	return timeout, nil
+}
+
+// httpClientKey identifies the HTTP client of an ArgoCD endpoint by the hash of the configuration used to
+// create it, so that the ArgoCDInstances of the same endpoint with other certificates or proxies do not
+// share their connections
+type httpClientKey struct {
+	endpoint string
+	config   string
+}
+
+// httpClientEntry stores the HTTP client created for an ArgoCD endpoint and when it was last used
+type httpClientEntry struct {
+	client   *http.Client
+	lastUsed time.Time
+}
+
+// httpClientStore caches the HTTP clients by ArgoCD endpoint and configuration so that the connections are
+// reused across the reconciliations. It is safe for concurrent use.
+type httpClientStore struct {
+	mu      sync.Mutex
+	clients map[httpClientKey]*httpClientEntry
+}
+
+// httpClients is the store shared by all APIManagers
+var httpClients = &httpClientStore{clients: map[httpClientKey]*httpClientEntry{}}
+
+// get returns the HTTP client cached for the endpoint and the configuration. When the configuration changed,
+// for example because the CA bundle was rotated, a new client is created and the previous one is released
+// once it is no longer used for the httpClientIdleTTL.
+func (s *httpClientStore) get(endpoint, config string, newClient func() *http.Client, now time.Time) *http.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.clients {
+		if now.Sub(entry.lastUsed) > httpClientIdleTTL {
+			entry.client.CloseIdleConnections()
+			delete(s.clients, key)
+		}
+	}
+	key := httpClientKey{endpoint: endpoint, config: config}
+	entry, ok := s.clients[key]
+	if !ok {
+		entry = &httpClientEntry{client: newClient()}
+		s.clients[key] = entry
+	}
+	entry.lastUsed = now
+	return entry.client
+}
+
+// transportConfigHash returns a hash of the configuration used to create the HTTP client
+func (a *APIManager) transportConfigHash() string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "timeout=%s;", a.Timeout)
+	if a.ProxyURL != nil {
+		_, _ = fmt.Fprintf(hash, "proxy=%s;", a.ProxyURL.String())
+	}
+	if a.TLSConfig != nil {
+		_, _ = fmt.Fprintf(hash, "insecure=%t;", a.TLSConfig.InsecureSkipVerify)
+		for _, certificate := range a.TLSConfig.Certificates {
+			for _, der := range certificate.Certificate {
+				_, _ = hash.Write(der)
+			}
+		}
+	}
+	_, _ = hash.Write(a.caBundle)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// httpClient returns the client used to send the requests to the ArgoCD API. The client is shared by the
+// APIManagers of the same endpoint and configuration so that the connections are kept alive and reused.
+func (a *APIManager) httpClient() *http.Client {
+	return httpClients.get(a.Endpoint, a.transportConfigHash(), a.newHTTPClient, time.Now())
+}
+
+// newHTTPClient returns a new client to send the requests to the ArgoCD API
+func (a *APIManager) newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = a.TLSConfig
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	transport.Proxy = http.ProxyFromEnvironment
+	if a.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(a.ProxyURL)
+	}
+
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+}
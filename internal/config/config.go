@@ -0,0 +1,501 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the configuration of the Manager from a file, as an alternative to its flags
+// and env vars
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+const (
+	// APIVersion is the version of the configuration file
+	APIVersion = "config.workload.com/v1beta1"
+	// Kind is the kind of the configuration file
+	Kind = "OperatorConfig"
+
+	// FeatureWebhooks enables the admission webhooks. It replaces the ENABLE_WEBHOOKS env var.
+	FeatureWebhooks = "Webhooks"
+
+	// DefaultPprofBindAddress is the address the pprof endpoint binds to when it is enabled
+	DefaultPprofBindAddress = "127.0.0.1:6060"
+)
+
+// featureGates are the feature gates supported and whether they are enabled by default
+var featureGates = map[string]bool{
+	FeatureWebhooks: true,
+}
+
+// OperatorConfig is the configuration of the Manager. The flags set in the command line take precedence
+// over the settings of the Manager, while the settings of ArgoCD take precedence over the env vars.
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Manager defines the settings of the Manager, they are only applied when it starts
+	Manager ManagerConfig `json:"manager,omitempty"`
+
+	// ArgoCD defines how to connect to ArgoCD and the defaults of the clusters registered. They are
+	// applied without restarting the Manager when the file changes.
+	ArgoCD ArgoCDConfig `json:"argocd,omitempty"`
+
+	// FeatureGates enables or disables the optional features, i.e. Webhooks
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// ManagerConfig defines the settings of the Manager, each one replaces the flag with the same name
+type ManagerConfig struct {
+	MetricsBindAddress        string           `json:"metricsBindAddress,omitempty"`
+	HealthProbeBindAddress    string           `json:"healthProbeBindAddress,omitempty"`
+	LeaderElect               *bool            `json:"leaderElect,omitempty"`
+	MaxConcurrentReconciles   *int32           `json:"maxConcurrentReconciles,omitempty"`
+	KubeAPIQPS                *float64         `json:"kubeAPIQPS,omitempty"`
+	KubeAPIBurst              *int32           `json:"kubeAPIBurst,omitempty"`
+	RateLimiterBaseDelay      *metav1.Duration `json:"rateLimiterBaseDelay,omitempty"`
+	RateLimiterMaxDelay       *metav1.Duration `json:"rateLimiterMaxDelay,omitempty"`
+	FinalizerRetryBudget      *int32           `json:"finalizerRetryBudget,omitempty"`
+	PermanentFailureBudget    *int32           `json:"permanentFailureBudget,omitempty"`
+	AllowedSecretNamespaces   []string         `json:"allowedSecretNamespaces,omitempty"`
+	ArgoCDInstance            string           `json:"argoCDInstance,omitempty"`
+	ArgoCDTokenFile           string           `json:"argoCDTokenFile,omitempty"`
+	RegisterManagementCluster *bool            `json:"registerManagementCluster,omitempty"`
+	ManagementClusterName     string           `json:"managementClusterName,omitempty"`
+	EnableTracing             *bool            `json:"enableTracing,omitempty"`
+	EventBurst                *int32           `json:"eventBurst,omitempty"`
+	EventInterval             *metav1.Duration `json:"eventInterval,omitempty"`
+	ArgoCDReadinessCheck      *bool            `json:"argoCDReadinessCheck,omitempty"`
+	EnablePprof               *bool            `json:"enablePprof,omitempty"`
+	PprofBindAddress          string           `json:"pprofBindAddress,omitempty"`
+	WatchNamespaces           []string         `json:"watchNamespaces,omitempty"`
+	WatchNamespaceSelector    string           `json:"watchNamespaceSelector,omitempty"`
+	SecretLabelSelector       string           `json:"secretLabelSelector,omitempty"`
+	ArgoCDCacheTTL            *metav1.Duration `json:"argoCDCacheTTL,omitempty"`
+	ArgoCDClusterListTTL      *metav1.Duration `json:"argoCDClusterListTTL,omitempty"`
+	BulkRegistrationWorkers   *int32           `json:"bulkRegistrationWorkers,omitempty"`
+	RegistrationWorkers       *int32           `json:"registrationWorkers,omitempty"`
+
+	ArgoCDCircuitBreakerThreshold *int32           `json:"argoCDCircuitBreakerThreshold,omitempty"`
+	ArgoCDCircuitBreakerCooldown  *metav1.Duration `json:"argoCDCircuitBreakerCooldown,omitempty"`
+	OrphanSweepInterval           *metav1.Duration `json:"orphanSweepInterval,omitempty"`
+	ArgoCDOrphanSweepInterval     *metav1.Duration `json:"argoCDOrphanSweepInterval,omitempty"`
+	AdoptArgoCDClusters           *bool            `json:"adoptArgoCDClusters,omitempty"`
+}
+
+// ArgoCDConfig defines how to connect to ArgoCD, each setting replaces the env var documented
+type ArgoCDConfig struct {
+	Endpoint              string           `json:"endpoint,omitempty"`              // ARGOAPI_ENDPOINT
+	EndpointDiscovery     string           `json:"endpointDiscovery,omitempty"`     // ARGOAPI_ENDPOINT_DISCOVERY
+	Namespace             string           `json:"namespace,omitempty"`             // ARGOCD_NAMESPACE
+	SecretName            string           `json:"secretName,omitempty"`            // ARGOCD_SECRET_NAME
+	CredentialsProvider   string           `json:"credentialsProvider,omitempty"`   // ARGOCD_CREDENTIALS_PROVIDER
+	RegistrationMode      string           `json:"registrationMode,omitempty"`      // ARGOCD_REGISTRATION_MODE
+	CoreMode              *bool            `json:"coreMode,omitempty"`              // ARGOCD_CORE_MODE
+	CAConfigMapName       string           `json:"caConfigMapName,omitempty"`       // ARGOCD_CA_CONFIGMAP_NAME
+	CASecretName          string           `json:"caSecretName,omitempty"`          // ARGOCD_CA_SECRET_NAME
+	ClientCertSecretName  string           `json:"clientCertSecretName,omitempty"`  // ARGOCD_CLIENT_CERT_SECRET_NAME
+	ProxyURL              string           `json:"proxyURL,omitempty"`              // ARGOCD_PROXY_URL
+	InsecureSkipVerify    *bool            `json:"insecureSkipVerify,omitempty"`    // ARGOCD_INSECURE_SKIP_VERIFY
+	AllowInsecureEndpoint *bool            `json:"allowInsecureEndpoint,omitempty"` // ARGOCD_ALLOW_INSECURE_ENDPOINT
+	RequestTimeout        *metav1.Duration `json:"requestTimeout,omitempty"`        // ARGOCD_REQUEST_TIMEOUT
+	Retry                 RetryConfig      `json:"retry,omitempty"`
+
+	ClusterNameTemplate   string   `json:"clusterNameTemplate,omitempty"`   // ARGOCD_CLUSTER_NAME_TEMPLATE
+	PropagatedLabels      []string `json:"propagatedLabels,omitempty"`      // ARGOCD_PROPAGATED_LABELS
+	PropagatedAnnotations []string `json:"propagatedAnnotations,omitempty"` // ARGOCD_PROPAGATED_ANNOTATIONS
+}
+
+// RetryConfig defines how the requests to the ArgoCD API which fail due to transient errors are retried
+type RetryConfig struct {
+	MaxAttempts    *int32           `json:"maxAttempts,omitempty"`    // ARGOCD_RETRY_MAX_ATTEMPTS
+	InitialBackoff *metav1.Duration `json:"initialBackoff,omitempty"` // ARGOCD_RETRY_INITIAL_BACKOFF
+	MaxBackoff     *metav1.Duration `json:"maxBackoff,omitempty"`     // ARGOCD_RETRY_MAX_BACKOFF
+	Jitter         *float64         `json:"jitter,omitempty"`         // ARGOCD_RETRY_JITTER
+	StatusCodes    []int            `json:"statusCodes,omitempty"`    // ARGOCD_RETRY_STATUS_CODES
+}
+
+// Load reads the configuration from the file informed and validates it. Unknown fields are rejected
+// so that a misspelled setting is not silently ignored.
+func Load(path string) (*OperatorConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the configuration file %s: %w", path, err)
+	}
+	config := &OperatorConfig{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("error parsing the configuration file %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Validate returns an error with all the settings which are not valid
+func (c *OperatorConfig) Validate() error {
+	var allErrs field.ErrorList
+	if c.APIVersion != APIVersion {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{APIVersion}))
+	}
+	if c.Kind != Kind {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{Kind}))
+	}
+	allErrs = append(allErrs, c.Manager.validate(field.NewPath("manager"))...)
+	allErrs = append(allErrs, c.ArgoCD.validate(field.NewPath("argocd"))...)
+	for gate := range c.FeatureGates {
+		if _, exists := featureGates[gate]; !exists {
+			allErrs = append(allErrs, field.NotSupported(field.NewPath("featureGates").Key(gate), gate,
+				supportedFeatureGates()))
+		}
+	}
+	return allErrs.ToAggregate()
+}
+
+// FeatureEnabled returns whether the feature gate informed is enabled, its default when it is not set
+func (c *OperatorConfig) FeatureEnabled(gate string) bool {
+	if enabled, exists := c.FeatureGates[gate]; exists {
+		return enabled
+	}
+	return featureGates[gate]
+}
+
+func supportedFeatureGates() []string {
+	gates := make([]string, 0, len(featureGates))
+	for gate := range featureGates {
+		gates = append(gates, gate)
+	}
+	return gates
+}
+
+func (c *ManagerConfig) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if c.MaxConcurrentReconciles != nil && *c.MaxConcurrentReconciles < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxConcurrentReconciles"),
+			*c.MaxConcurrentReconciles, "must be greater than 0"))
+	}
+	if c.KubeAPIQPS != nil && *c.KubeAPIQPS <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("kubeAPIQPS"), *c.KubeAPIQPS, "must be greater than 0"))
+	}
+	if c.KubeAPIBurst != nil && *c.KubeAPIBurst < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("kubeAPIBurst"), *c.KubeAPIBurst, "must be greater than 0"))
+	}
+	if c.FinalizerRetryBudget != nil && *c.FinalizerRetryBudget < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("finalizerRetryBudget"),
+			*c.FinalizerRetryBudget, "must be greater than 0"))
+	}
+	if c.PermanentFailureBudget != nil && *c.PermanentFailureBudget < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("permanentFailureBudget"),
+			*c.PermanentFailureBudget, "must be greater than 0"))
+	}
+	allErrs = append(allErrs, validateDuration(c.RateLimiterBaseDelay, fldPath.Child("rateLimiterBaseDelay"))...)
+	allErrs = append(allErrs, validateDuration(c.RateLimiterMaxDelay, fldPath.Child("rateLimiterMaxDelay"))...)
+	if c.EventBurst != nil && *c.EventBurst < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("eventBurst"), *c.EventBurst, "must be greater than 0"))
+	}
+	allErrs = append(allErrs, validateDuration(c.EventInterval, fldPath.Child("eventInterval"))...)
+	allErrs = append(allErrs, validateDuration(c.ArgoCDCacheTTL, fldPath.Child("argoCDCacheTTL"))...)
+	allErrs = append(allErrs, validateDuration(c.ArgoCDClusterListTTL, fldPath.Child("argoCDClusterListTTL"))...)
+	if c.BulkRegistrationWorkers != nil && *c.BulkRegistrationWorkers < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("bulkRegistrationWorkers"),
+			*c.BulkRegistrationWorkers, "must not be negative"))
+	}
+	if c.RegistrationWorkers != nil && *c.RegistrationWorkers < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("registrationWorkers"),
+			*c.RegistrationWorkers, "must not be negative"))
+	}
+	if c.ArgoCDCircuitBreakerThreshold != nil && *c.ArgoCDCircuitBreakerThreshold < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("argoCDCircuitBreakerThreshold"),
+			*c.ArgoCDCircuitBreakerThreshold, "must not be negative"))
+	}
+	allErrs = append(allErrs, validateDuration(c.ArgoCDCircuitBreakerCooldown,
+		fldPath.Child("argoCDCircuitBreakerCooldown"))...)
+	allErrs = append(allErrs, validateDuration(c.OrphanSweepInterval, fldPath.Child("orphanSweepInterval"))...)
+	allErrs = append(allErrs, validateDuration(c.ArgoCDOrphanSweepInterval,
+		fldPath.Child("argoCDOrphanSweepInterval"))...)
+	if c.PprofBindAddress != "" {
+		if err := ValidateLoopbackAddress(c.PprofBindAddress); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("pprofBindAddress"), c.PprofBindAddress, err.Error()))
+		}
+	}
+	for i, namespace := range c.WatchNamespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("watchNamespaces").Index(i), namespace, msg))
+		}
+	}
+	if _, err := labels.Parse(c.WatchNamespaceSelector); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("watchNamespaceSelector"), c.WatchNamespaceSelector,
+			err.Error()))
+	}
+	if _, err := labels.Parse(c.SecretLabelSelector); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("secretLabelSelector"), c.SecretLabelSelector,
+			err.Error()))
+	}
+	return allErrs
+}
+
+func (c *ArgoCDConfig) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if c.Endpoint != "" {
+		if endpoint, err := url.Parse(c.Endpoint); err != nil || endpoint.Host == "" ||
+			(endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("endpoint"), c.Endpoint,
+				"must be an http or https URL, i.e. https://argocd-server.argocd.svc"))
+		} else if endpoint.Scheme == "http" && (c.AllowInsecureEndpoint == nil || !*c.AllowInsecureEndpoint) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("endpoint"), c.Endpoint,
+				"must be an https URL since the credentials of the clusters are sent to ArgoCD, "+
+					"unless allowInsecureEndpoint is set"))
+		}
+	}
+	if c.ProxyURL != "" {
+		if proxyURL, err := url.Parse(c.ProxyURL); err != nil || proxyURL.Host == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("proxyURL"), c.ProxyURL,
+				"must be a URL with a host, i.e. http://proxy:3128"))
+		}
+	}
+	switch argocdv1beta1.RegistrationMode(c.RegistrationMode) {
+	case "", argocdv1beta1.RegistrationModeAPI, argocdv1beta1.RegistrationModeDeclarative:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("registrationMode"), c.RegistrationMode,
+			[]string{string(argocdv1beta1.RegistrationModeAPI), string(argocdv1beta1.RegistrationModeDeclarative)}))
+	}
+	switch c.EndpointDiscovery {
+	case "", argocd.EndpointDiscoveryService, argocd.EndpointDiscoveryLoadBalancer, argocd.EndpointDiscoveryIngress,
+		argocd.EndpointDiscoveryRoute:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("endpointDiscovery"), c.EndpointDiscovery,
+			[]string{argocd.EndpointDiscoveryService, argocd.EndpointDiscoveryLoadBalancer,
+				argocd.EndpointDiscoveryIngress, argocd.EndpointDiscoveryRoute}))
+	}
+	switch c.CredentialsProvider {
+	case "", argocd.CredentialsProviderSecret, argocd.CredentialsProviderVault, argocd.CredentialsProviderFile:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("credentialsProvider"), c.CredentialsProvider,
+			[]string{argocd.CredentialsProviderSecret, argocd.CredentialsProviderVault, argocd.CredentialsProviderFile}))
+	}
+	if c.RequestTimeout != nil && c.RequestTimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("requestTimeout"), c.RequestTimeout.Duration.String(),
+			"must be greater than 0"))
+	}
+	if c.ClusterNameTemplate != "" {
+		if _, err := argocd.RenderClusterName(c.ClusterNameTemplate, &clusterapiv1.Cluster{}); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("clusterNameTemplate"), c.ClusterNameTemplate,
+				err.Error()))
+		}
+	}
+
+	retryPath := fldPath.Child("retry")
+	if c.Retry.MaxAttempts != nil && *c.Retry.MaxAttempts < 1 {
+		allErrs = append(allErrs, field.Invalid(retryPath.Child("maxAttempts"), *c.Retry.MaxAttempts,
+			"must be greater than 0, 1 disables the retries"))
+	}
+	allErrs = append(allErrs, validateDuration(c.Retry.InitialBackoff, retryPath.Child("initialBackoff"))...)
+	allErrs = append(allErrs, validateDuration(c.Retry.MaxBackoff, retryPath.Child("maxBackoff"))...)
+	if c.Retry.Jitter != nil && *c.Retry.Jitter < 0 {
+		allErrs = append(allErrs, field.Invalid(retryPath.Child("jitter"), *c.Retry.Jitter, "must not be negative"))
+	}
+	for i, code := range c.Retry.StatusCodes {
+		if http.StatusText(code) == "" {
+			allErrs = append(allErrs, field.Invalid(retryPath.Child("statusCodes").Index(i), code,
+				"must be a valid HTTP status code"))
+		}
+	}
+	return allErrs
+}
+
+func validateDuration(duration *metav1.Duration, fldPath *field.Path) field.ErrorList {
+	if duration != nil && duration.Duration < 0 {
+		return field.ErrorList{field.Invalid(fldPath, duration.Duration.String(), "must not be negative")}
+	}
+	return nil
+}
+
+// ValidateLoopbackAddress returns an error when the address informed does not bind to the loopback interface.
+// The pprof endpoint is not authenticated and its profiles expose the memory of the Manager, i.e. the
+// credentials of the clusters, therefore, it is only reachable from the Pod, i.e. via kubectl port-forward.
+func ValidateLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("must bind to the loopback interface, i.e. %s", DefaultPprofBindAddress)
+	}
+	return nil
+}
+
+// Flags returns the value of the flags of the Manager defined by the settings informed
+func (c *ManagerConfig) Flags() map[string]string {
+	flags := map[string]string{}
+	setString := func(name, value string) {
+		if value != "" {
+			flags[name] = value
+		}
+	}
+	setString("metrics-bind-address", c.MetricsBindAddress)
+	setString("health-probe-bind-address", c.HealthProbeBindAddress)
+	setString("argocd-instance", c.ArgoCDInstance)
+	setString("argocd-token-file", c.ArgoCDTokenFile)
+	setString("management-cluster-name", c.ManagementClusterName)
+	setString("pprof-bind-address", c.PprofBindAddress)
+	setString("allowed-secret-namespaces", strings.Join(c.AllowedSecretNamespaces, ","))
+	setString("watch-namespaces", strings.Join(c.WatchNamespaces, ","))
+	setString("watch-namespace-selector", c.WatchNamespaceSelector)
+	setString("secret-label-selector", c.SecretLabelSelector)
+	if c.LeaderElect != nil {
+		flags["leader-elect"] = strconv.FormatBool(*c.LeaderElect)
+	}
+	if c.RegisterManagementCluster != nil {
+		flags["register-management-cluster"] = strconv.FormatBool(*c.RegisterManagementCluster)
+	}
+	if c.EnableTracing != nil {
+		flags["enable-tracing"] = strconv.FormatBool(*c.EnableTracing)
+	}
+	if c.EnablePprof != nil {
+		flags["enable-pprof"] = strconv.FormatBool(*c.EnablePprof)
+	}
+	if c.ArgoCDReadinessCheck != nil {
+		flags["argocd-readiness-check"] = strconv.FormatBool(*c.ArgoCDReadinessCheck)
+	}
+	if c.MaxConcurrentReconciles != nil {
+		flags["max-concurrent-reconciles"] = strconv.Itoa(int(*c.MaxConcurrentReconciles))
+	}
+	if c.KubeAPIQPS != nil {
+		flags["kube-api-qps"] = strconv.FormatFloat(*c.KubeAPIQPS, 'f', -1, 64)
+	}
+	if c.KubeAPIBurst != nil {
+		flags["kube-api-burst"] = strconv.Itoa(int(*c.KubeAPIBurst))
+	}
+	if c.FinalizerRetryBudget != nil {
+		flags["finalizer-retry-budget"] = strconv.Itoa(int(*c.FinalizerRetryBudget))
+	}
+	if c.PermanentFailureBudget != nil {
+		flags["permanent-failure-budget"] = strconv.Itoa(int(*c.PermanentFailureBudget))
+	}
+	if c.RateLimiterBaseDelay != nil {
+		flags["rate-limiter-base-delay"] = c.RateLimiterBaseDelay.Duration.String()
+	}
+	if c.RateLimiterMaxDelay != nil {
+		flags["rate-limiter-max-delay"] = c.RateLimiterMaxDelay.Duration.String()
+	}
+	if c.EventBurst != nil {
+		flags["event-burst"] = strconv.Itoa(int(*c.EventBurst))
+	}
+	if c.EventInterval != nil {
+		flags["event-interval"] = c.EventInterval.Duration.String()
+	}
+	if c.ArgoCDCacheTTL != nil {
+		flags["argocd-cache-ttl"] = c.ArgoCDCacheTTL.Duration.String()
+	}
+	if c.ArgoCDClusterListTTL != nil {
+		flags["argocd-cluster-list-ttl"] = c.ArgoCDClusterListTTL.Duration.String()
+	}
+	if c.BulkRegistrationWorkers != nil {
+		flags["bulk-registration-workers"] = strconv.Itoa(int(*c.BulkRegistrationWorkers))
+	}
+	if c.RegistrationWorkers != nil {
+		flags["registration-workers"] = strconv.Itoa(int(*c.RegistrationWorkers))
+	}
+	if c.ArgoCDCircuitBreakerThreshold != nil {
+		flags["argocd-circuit-breaker-threshold"] = strconv.Itoa(int(*c.ArgoCDCircuitBreakerThreshold))
+	}
+	if c.ArgoCDCircuitBreakerCooldown != nil {
+		flags["argocd-circuit-breaker-cooldown"] = c.ArgoCDCircuitBreakerCooldown.Duration.String()
+	}
+	if c.OrphanSweepInterval != nil {
+		flags["orphan-sweep-interval"] = c.OrphanSweepInterval.Duration.String()
+	}
+	if c.ArgoCDOrphanSweepInterval != nil {
+		flags["argocd-orphan-sweep-interval"] = c.ArgoCDOrphanSweepInterval.Duration.String()
+	}
+	if c.AdoptArgoCDClusters != nil {
+		flags["adopt-argocd-clusters"] = strconv.FormatBool(*c.AdoptArgoCDClusters)
+	}
+	return flags
+}
+
+// Env returns the value of the env vars of the Manager defined by the settings informed
+func (c *ArgoCDConfig) Env() map[string]string {
+	env := map[string]string{}
+	setString := func(name, value string) {
+		if value != "" {
+			env[name] = value
+		}
+	}
+	setString(argocd.APIEndpointEnvVar, c.Endpoint)
+	setString(argocd.EndpointDiscoveryEnvVar, c.EndpointDiscovery)
+	setString(argocd.NamespaceEnvVar, c.Namespace)
+	setString(argocd.SecretNameEnvVar, c.SecretName)
+	setString(argocd.CredentialsProviderEnvVar, c.CredentialsProvider)
+	setString(argocd.RegistrationModeEnvVar, c.RegistrationMode)
+	setString(argocd.CAConfigMapNameEnvVar, c.CAConfigMapName)
+	setString(argocd.CASecretNameEnvVar, c.CASecretName)
+	setString(argocd.ClientCertSecretNameEnvVar, c.ClientCertSecretName)
+	setString(argocd.ProxyURLEnvVar, c.ProxyURL)
+	setString(argocd.ClusterNameTemplateEnvVar, c.ClusterNameTemplate)
+	if c.CoreMode != nil {
+		env[argocd.CoreModeEnvVar] = strconv.FormatBool(*c.CoreMode)
+	}
+	if c.InsecureSkipVerify != nil {
+		env[argocd.InsecureSkipVerifyEnvVar] = strconv.FormatBool(*c.InsecureSkipVerify)
+	}
+	if c.AllowInsecureEndpoint != nil {
+		env[argocd.AllowInsecureEndpointEnvVar] = strconv.FormatBool(*c.AllowInsecureEndpoint)
+	}
+	if c.RequestTimeout != nil {
+		env[argocd.RequestTimeoutEnvVar] = c.RequestTimeout.Duration.String()
+	}
+	if c.PropagatedLabels != nil {
+		env[argocd.PropagatedLabelsEnvVar] = strings.Join(c.PropagatedLabels, ",")
+	}
+	if c.PropagatedAnnotations != nil {
+		env[argocd.PropagatedAnnotationsEnvVar] = strings.Join(c.PropagatedAnnotations, ",")
+	}
+	if c.Retry.MaxAttempts != nil {
+		env[argocd.RetryMaxAttemptsEnvVar] = strconv.Itoa(int(*c.Retry.MaxAttempts))
+	}
+	if c.Retry.InitialBackoff != nil {
+		env[argocd.RetryInitialBackoffEnvVar] = c.Retry.InitialBackoff.Duration.String()
+	}
+	if c.Retry.MaxBackoff != nil {
+		env[argocd.RetryMaxBackoffEnvVar] = c.Retry.MaxBackoff.Duration.String()
+	}
+	if c.Retry.Jitter != nil {
+		env[argocd.RetryJitterEnvVar] = strconv.FormatFloat(*c.Retry.Jitter, 'f', -1, 64)
+	}
+	if c.Retry.StatusCodes != nil {
+		codes := make([]string, 0, len(c.Retry.StatusCodes))
+		for _, code := range c.Retry.StatusCodes {
+			codes = append(codes, strconv.Itoa(code))
+		}
+		env[argocd.RetryStatusCodesEnvVar] = strings.Join(codes, ",")
+	}
+	return env
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/workload-operator/internal/argocd"
+)
+
+const validConfig = `apiVersion: config.workload.com/v1beta1
+kind: OperatorConfig
+manager:
+  leaderElect: true
+  maxConcurrentReconciles: 10
+  kubeAPIQPS: 50
+  rateLimiterMaxDelay: 5m
+  permanentFailureBudget: 3
+  allowedSecretNamespaces:
+  - capi-system
+  - fleet
+  secretLabelSelector: cluster.x-k8s.io/cluster-name
+  argoCDCacheTTL: 10m
+  argoCDClusterListTTL: 1m
+  bulkRegistrationWorkers: 50
+  registrationWorkers: 10
+  argoCDCircuitBreakerThreshold: 3
+  argoCDCircuitBreakerCooldown: 1m
+  orphanSweepInterval: 30m
+  argoCDOrphanSweepInterval: 1h
+  adoptArgoCDClusters: true
+argocd:
+  endpoint: https://argocd-server.argocd.svc
+  namespace: gitops
+  requestTimeout: 1m
+  propagatedLabels: []
+  retry:
+    maxAttempts: 2
+    statusCodes:
+    - 502
+    - 503
+featureGates:
+  Webhooks: false
+`
+
+var _ = Describe("OperatorConfig", func() {
+	var path string
+
+	writeConfig := func(content string) {
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+	})
+
+	It("should load the settings of the Manager as its flags", func() {
+		writeConfig(validConfig)
+		config, err := Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Manager.Flags()).To(Equal(map[string]string{
+			"leader-elect":                     "true",
+			"max-concurrent-reconciles":        "10",
+			"kube-api-qps":                     "50",
+			"rate-limiter-max-delay":           "5m0s",
+			"permanent-failure-budget":         "3",
+			"allowed-secret-namespaces":        "capi-system,fleet",
+			"secret-label-selector":            "cluster.x-k8s.io/cluster-name",
+			"argocd-cache-ttl":                 "10m0s",
+			"argocd-cluster-list-ttl":          "1m0s",
+			"bulk-registration-workers":        "50",
+			"registration-workers":             "10",
+			"argocd-circuit-breaker-threshold": "3",
+			"argocd-circuit-breaker-cooldown":  "1m0s",
+			"orphan-sweep-interval":            "30m0s",
+			"argocd-orphan-sweep-interval":     "1h0m0s",
+			"adopt-argocd-clusters":            "true",
+		}))
+		Expect(config.FeatureEnabled(FeatureWebhooks)).To(BeFalse())
+	})
+
+	It("should load the settings of ArgoCD as its env vars", func() {
+		writeConfig(validConfig)
+		config, err := Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.ArgoCD.Env()).To(Equal(map[string]string{
+			argocd.APIEndpointEnvVar:      "https://argocd-server.argocd.svc",
+			argocd.NamespaceEnvVar:        "gitops",
+			argocd.RequestTimeoutEnvVar:   "1m0s",
+			argocd.PropagatedLabelsEnvVar: "",
+			argocd.RetryMaxAttemptsEnvVar: "2",
+			argocd.RetryStatusCodesEnvVar: "502,503",
+		}))
+	})
+
+	It("should reject the invalid settings", func() {
+		writeConfig(`apiVersion: config.workload.com/v1beta1
+kind: OperatorConfig
+manager:
+  maxConcurrentReconciles: 0
+  pprofBindAddress: ":6060"
+  watchNamespaces:
+  - Tenant_A
+  watchNamespaceSelector: "workload.com/managed in (true"
+  secretLabelSelector: "!"
+  argoCDCacheTTL: -1m
+  permanentFailureBudget: 0
+  bulkRegistrationWorkers: -1
+  registrationWorkers: -1
+  argoCDCircuitBreakerThreshold: -1
+  argoCDCircuitBreakerCooldown: -30s
+  orphanSweepInterval: -10m
+  argoCDOrphanSweepInterval: -1h
+argocd:
+  endpoint: argocd-server
+  registrationMode: Manual
+  retry:
+    statusCodes:
+    - 999
+featureGates:
+  Unknown: true
+`)
+		_, err := Load(path)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("manager.maxConcurrentReconciles"))
+		Expect(err.Error()).To(ContainSubstring("manager.pprofBindAddress"))
+		Expect(err.Error()).To(ContainSubstring("manager.watchNamespaces[0]"))
+		Expect(err.Error()).To(ContainSubstring("manager.watchNamespaceSelector"))
+		Expect(err.Error()).To(ContainSubstring("manager.secretLabelSelector"))
+		Expect(err.Error()).To(ContainSubstring("manager.argoCDCacheTTL"))
+		Expect(err.Error()).To(ContainSubstring("manager.permanentFailureBudget"))
+		Expect(err.Error()).To(ContainSubstring("manager.bulkRegistrationWorkers"))
+		Expect(err.Error()).To(ContainSubstring("manager.registrationWorkers"))
+		Expect(err.Error()).To(ContainSubstring("manager.argoCDCircuitBreakerThreshold"))
+		Expect(err.Error()).To(ContainSubstring("manager.argoCDCircuitBreakerCooldown"))
+		Expect(err.Error()).To(ContainSubstring("manager.orphanSweepInterval"))
+		Expect(err.Error()).To(ContainSubstring("manager.argoCDOrphanSweepInterval"))
+		Expect(err.Error()).To(ContainSubstring("argocd.endpoint"))
+		Expect(err.Error()).To(ContainSubstring("argocd.registrationMode"))
+		Expect(err.Error()).To(ContainSubstring("argocd.retry.statusCodes[0]"))
+		Expect(err.Error()).To(ContainSubstring("featureGates[Unknown]"))
+	})
+
+	It("should reject an http endpoint of ArgoCD unless it is allowed", func() {
+		writeConfig(`apiVersion: config.workload.com/v1beta1
+kind: OperatorConfig
+argocd:
+  endpoint: http://argocd-server.argocd.svc
+`)
+		_, err := Load(path)
+		Expect(err).To(MatchError(ContainSubstring("allowInsecureEndpoint")))
+
+		writeConfig(`apiVersion: config.workload.com/v1beta1
+kind: OperatorConfig
+argocd:
+  endpoint: http://argocd-server.argocd.svc
+  allowInsecureEndpoint: true
+`)
+		cfg, err := Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.ArgoCD.Env()).To(HaveKeyWithValue(argocd.AllowInsecureEndpointEnvVar, "true"))
+	})
+
+	It("should reject the unknown settings", func() {
+		writeConfig(`apiVersion: config.workload.com/v1beta1
+kind: OperatorConfig
+argocd:
+  endpont: https://argocd-server.argocd.svc
+`)
+		_, err := Load(path)
+		Expect(err).To(MatchError(ContainSubstring("endpont")))
+	})
+
+	It("should restore the env vars once the settings of ArgoCD are removed", func() {
+		Expect(os.Setenv(argocd.NamespaceEnvVar, "argocd")).To(Succeed())
+		Expect(os.Unsetenv(argocd.APIEndpointEnvVar)).To(Succeed())
+		DeferCleanup(func() {
+			_ = os.Unsetenv(argocd.NamespaceEnvVar)
+		})
+
+		Expect(ApplyArgoCDSettings(&ArgoCDConfig{Endpoint: "https://argocd-server.gitops.svc",
+			Namespace: "gitops"})).To(Succeed())
+		Expect(os.Getenv(argocd.APIEndpointEnvVar)).To(Equal("https://argocd-server.gitops.svc"))
+		Expect(os.Getenv(argocd.NamespaceEnvVar)).To(Equal("gitops"))
+
+		Expect(ApplyArgoCDSettings(&ArgoCDConfig{})).To(Succeed())
+		_, exists := os.LookupEnv(argocd.APIEndpointEnvVar)
+		Expect(exists).To(BeFalse())
+		Expect(os.Getenv(argocd.NamespaceEnvVar)).To(Equal("argocd"))
+	})
+
+	It("should reload the settings of ArgoCD when the file changes", func() {
+		writeConfig(validConfig)
+		config, err := Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ApplyArgoCDSettings(&config.ArgoCD)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(ApplyArgoCDSettings(&ArgoCDConfig{})).To(Succeed())
+		})
+		watcher := NewWatcher(path, config, logr.Discard())
+
+		By("Keeping the previous configuration when the file is not valid")
+		writeConfig("kind: Unknown")
+		watcher.reload()
+		Expect(os.Getenv(argocd.NamespaceEnvVar)).To(Equal("gitops"))
+
+		By("Applying the new settings of ArgoCD")
+		writeConfig(`apiVersion: config.workload.com/v1beta1
+kind: OperatorConfig
+argocd:
+  namespace: argocd-prod
+`)
+		watcher.reload()
+		Expect(os.Getenv(argocd.NamespaceEnvVar)).To(Equal("argocd-prod"))
+		_, exists := os.LookupEnv(argocd.RequestTimeoutEnvVar)
+		Expect(exists).To(BeFalse())
+	})
+})
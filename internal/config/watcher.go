@@ -0,0 +1,137 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// DefaultReloadInterval is the interval to check whether the configuration file changed
+const DefaultReloadInterval = 30 * time.Second
+
+// argoCDEnv records the value of the env vars before they were overridden by the settings of ArgoCD, so
+// that they are restored when the setting is removed from the configuration file
+var argoCDEnv = struct {
+	mu       sync.Mutex
+	original map[string]*string
+}{original: map[string]*string{}}
+
+// ApplyArgoCDSettings overrides the env vars of the Manager with the settings of ArgoCD informed. They
+// are read on every reconciliation, therefore, they are applied without restarting the Manager.
+func ApplyArgoCDSettings(config *ArgoCDConfig) error {
+	argoCDEnv.mu.Lock()
+	defer argoCDEnv.mu.Unlock()
+
+	env := config.Env()
+	for name, original := range argoCDEnv.original {
+		if _, exists := env[name]; exists {
+			continue
+		}
+		var err error
+		if original == nil {
+			err = os.Unsetenv(name)
+		} else {
+			err = os.Setenv(name, *original)
+		}
+		if err != nil {
+			return err
+		}
+		delete(argoCDEnv.original, name)
+	}
+	for name, value := range env {
+		if _, recorded := argoCDEnv.original[name]; !recorded {
+			if original, exists := os.LookupEnv(name); exists {
+				argoCDEnv.original[name] = &original
+			} else {
+				argoCDEnv.original[name] = nil
+			}
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watcher reloads the configuration file when it changes, i.e. when the ConfigMap mounted is updated.
+// The settings of ArgoCD are applied right away while the ones of the Manager and the feature gates are
+// only applied once the Manager is restarted, therefore, their changes are only reported.
+type Watcher struct {
+	Path string
+	Log  logr.Logger
+
+	// Interval to check whether the file changed, DefaultReloadInterval when it is not informed
+	Interval time.Duration
+
+	// current is the configuration applied
+	current *OperatorConfig
+}
+
+var _ manager.Runnable = &Watcher{}
+var _ manager.LeaderElectionRunnable = &Watcher{}
+
+// NewWatcher returns the Watcher of the configuration file which was loaded when the Manager started
+func NewWatcher(path string, current *OperatorConfig, log logr.Logger) *Watcher {
+	return &Watcher{Path: path, Log: log, current: current}
+}
+
+// Start checks whether the configuration file changed periodically until the Manager is stopped
+func (w *Watcher) Start(ctx context.Context) error {
+	interval := w.Interval
+	if interval == 0 {
+		interval = DefaultReloadInterval
+	}
+	wait.UntilWithContext(ctx, func(_ context.Context) { w.reload() }, interval)
+	return nil
+}
+
+// NeedLeaderElection returns false since every replica of the Manager must apply the configuration
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// reload applies the settings of ArgoCD when the configuration file changed. The previous configuration
+// is kept when the file is no longer valid.
+func (w *Watcher) reload() {
+	config, err := Load(w.Path)
+	if err != nil {
+		w.Log.Error(err, "Failed to reload the configuration file, the previous configuration is kept")
+		return
+	}
+	if reflect.DeepEqual(config, w.current) {
+		return
+	}
+	if !reflect.DeepEqual(config.Manager, w.current.Manager) ||
+		!reflect.DeepEqual(config.FeatureGates, w.current.FeatureGates) {
+		w.Log.Info("The settings of the Manager or the feature gates changed, they are applied once it is restarted",
+			"path", w.Path)
+	}
+	if err := ApplyArgoCDSettings(&config.ArgoCD); err != nil {
+		w.Log.Error(err, "Failed to apply the settings of ArgoCD")
+		return
+	}
+	w.current = config
+	w.Log.Info("Configuration file reloaded", "path", w.Path)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/status"
+)
+
+// handleAdoption looks for a cluster registered within ArgoCD with the server of the Cluster which is not managed
+// by this project, i.e. registered via the ArgoCD CLI, so that the Register takes over its cluster entry rather
+// than registering the Cluster again. The name, the project and the namespaces of the cluster entry are kept in
+// the spec of the Register, unless they are informed, so that the Applications which target it are not affected.
+// It returns the server of the cluster entry adopted, which can differ from the one of the Cluster in its scheme,
+// or the server informed when there is none.
+func (r *RegisterReconciler) handleAdoption(ctx context.Context, req ctrl.Request, RegisterCR *argocdv1beta1.Register,
+	server string, options *argocd.ClusterOptions) (string, error) {
+	registered, err := argocd.UnmanagedCluster(ctx, r.Client, r.Log, options.Instance, server)
+	if err != nil {
+		r.Log.Error(err, "Failed to look for the cluster registered within ArgoCD to be adopted")
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return "", err
+		}
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+			Message: fmt.Sprintf("Unable to look for the cluster registered within ArgoCD to be adopted: %s", err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return "", err
+		}
+		return "", err
+	}
+	if registered == nil {
+		return server, nil
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return "", err
+	}
+	adopted := RegisterCR.DeepCopy()
+	if adopted.Spec.Name == "" {
+		adopted.Spec.Name = registered.Name
+	}
+	if adopted.Spec.Project == "" {
+		adopted.Spec.Project = registered.Project
+	}
+	if len(adopted.Spec.Namespaces) == 0 {
+		adopted.Spec.Namespaces = registered.Namespaces
+		adopted.Spec.ClusterResources = registered.ClusterResources
+	}
+	if !equality.Semantic.DeepEqual(adopted.Spec, RegisterCR.Spec) {
+		if err := r.Update(ctx, adopted); err != nil {
+			r.Log.Error(err, "Failed to update Register with the cluster registered within ArgoCD")
+			return "", err
+		}
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to re-fetch RegisterCR")
+			return "", err
+		}
+	}
+	options.Name = RegisterCR.Spec.Name
+	options.Project = RegisterCR.Spec.Project
+	options.Namespaces = RegisterCR.Spec.Namespaces
+	options.ClusterResources = RegisterCR.Spec.ClusterResources
+
+	r.Log.Info("Adopting the cluster registered within ArgoCD", "server", registered.Server, "name", registered.Name)
+	if r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Normal", "Adopted",
+			fmt.Sprintf("Cluster %s registered within ArgoCD with the server %s was adopted", registered.Name,
+				registered.Server))
+	}
+	return registered.Server, nil
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/status"
+)
+
+// argoCDInstanceCheckInterval is the interval to check again that the API of an ArgoCDInstance is reachable
+const argoCDInstanceCheckInterval = 5 * time.Minute
+
+// credentialKeys are the keys of the Secrets and ConfigMaps which store the credentials of the ArgoCD account,
+// the CA bundles and the client certificates used to connect to ArgoCD
+var credentialKeys = []string{argocd.UsernameSecretKey, argocd.PasswordSecretKey, argocd.TokenSecretKey,
+	argocd.CABundleKey, corev1.TLSCertKey, corev1.TLSPrivateKeyKey}
+
+// credentialsUpdated filters out the updates of the Secrets and ConfigMaps which do not change the credentials
+// stored in them, i.e. the resyncs, the changes of their labels and annotations and the keys written by ArgoCD
+// itself, so that the sessions and the connections to ArgoCD are only dropped when the credentials change
+var credentialsUpdated = predicate.Funcs{UpdateFunc: func(e event.UpdateEvent) bool {
+	previous, current := credentialData(e.ObjectOld), credentialData(e.ObjectNew)
+	for _, key := range credentialKeys {
+		if !bytes.Equal(previous[key], current[key]) {
+			return true
+		}
+	}
+	return false
+}}
+
+// credentialData returns the data of the Secret or of the ConfigMap informed
+func credentialData(obj client.Object) map[string][]byte {
+	switch obj := obj.(type) {
+	case *corev1.Secret:
+		return obj.Data
+	case *corev1.ConfigMap:
+		data := make(map[string][]byte, len(obj.Data))
+		for key, value := range obj.Data {
+			data[key] = []byte(value)
+		}
+		return data
+	}
+	return nil
+}
+
+// ArgoCDInstanceReconciler reconciles an ArgoCDInstance object
+type ArgoCDInstanceReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// CheckInstance returns the version of the ArgoCD instance and fails when its API is not reachable
+	// with the credentials configured. When it is not informed argocd.CheckInstance is used.
+	CheckInstance func(ctx context.Context, client client.Client, log logr.Logger,
+		instance *argocd.Instance) (string, error)
+}
+
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=argocdinstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=argocdinstances/status,verbs=get;update;patch
+
+// Reconcile checks periodically that the API of the ArgoCDInstance is reachable with the credentials
+// configured and reports it in its status, so that a misconfiguration is observable before it makes
+// the registration of the Clusters fail.
+func (r *ArgoCDInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	instance := &argocdv1beta1.ArgoCDInstance{}
+	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("ArgoCDInstance resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get ArgoCDInstance")
+		return ctrl.Result{}, err
+	}
+
+	checkInstance := r.CheckInstance
+	if checkInstance == nil {
+		checkInstance = argocd.CheckInstance
+	}
+	version, checkErr := checkInstance(ctx, r.Client, logger, newInstance(instance))
+
+	now := metav1.Now()
+	instance.Status.LastCheckedTime = &now
+	if checkErr != nil {
+		logger.Error(checkErr, "Failed to reach the ArgoCD API", "endpoint", instance.Spec.Endpoint)
+		message := fmt.Sprintf("Unable to reach the ArgoCD API %s: %s", instance.Spec.Endpoint, checkErr)
+		if r.Recorder != nil {
+			r.Recorder.Event(instance, "Warning", "Unavailable", message)
+		}
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+			Status: metav1.ConditionFalse, Reason: argocd.ErrorReason(checkErr), Message: message})
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(checkErr), Message: message})
+	} else {
+		instance.Status.Version = version
+		message := fmt.Sprintf("The ArgoCD API %s is reachable with the credentials configured", instance.Spec.Endpoint)
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+			Status: metav1.ConditionTrue, Reason: status.ReasonReachable, Message: message})
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionFalse, Reason: status.ReasonReachable, Message: message})
+	}
+	if err := r.updateStatus(ctx, instance); err != nil {
+		logger.Error(err, "Failed to update ArgoCDInstance status")
+		return ctrl.Result{}, err
+	}
+	if checkErr != nil {
+		return ctrl.Result{}, checkErr
+	}
+	return ctrl.Result{RequeueAfter: argoCDInstanceCheckInterval}, nil
+}
+
+// updateStatus updates the status of the ArgoCDInstance recording the generation observed in it and in its
+// conditions, so that the consumers can tell whether they reflect the latest spec
+func (r *ArgoCDInstanceReconciler) updateStatus(ctx context.Context, instance *argocdv1beta1.ArgoCDInstance) error {
+	instance.Status.ObservedGeneration = instance.Generation
+	status.SetObservedGeneration(instance.Status.Conditions, instance.Generation)
+	redactConditions(instance.Status.Conditions)
+	return r.Status().Update(ctx, instance)
+}
+
+// newInstance returns how to connect to the ArgoCD instance defined by the ArgoCDInstance
+func newInstance(instance *argocdv1beta1.ArgoCDInstance) *argocd.Instance {
+	newInstance := &argocd.Instance{
+		Name:                  instance.Name,
+		Endpoint:              instance.Spec.Endpoint,
+		Namespace:             instance.Spec.Namespace,
+		InsecureSkipVerify:    instance.Spec.InsecureSkipVerify,
+		AllowInsecureEndpoint: instance.Spec.AllowInsecureEndpoint,
+		Project:               instance.Spec.DefaultProject,
+		Shard:                 instance.Spec.DefaultShard,
+	}
+	if ref := instance.Spec.CredentialsSecretRef; ref != nil {
+		newInstance.CredentialsSecretName = ref.Name
+	}
+	if ref := instance.Spec.ClientCertSecretRef; ref != nil {
+		newInstance.ClientCertSecretName = ref.Name
+	}
+	if ref := instance.Spec.CABundleRef; ref != nil {
+		if ref.Kind == argocdv1beta1.CABundleKindSecret {
+			newInstance.CASecretName = ref.Name
+		} else {
+			newInstance.CAConfigMapName = ref.Name
+		}
+	}
+	return newInstance
+}
+
+// argoCDInstance returns how to connect to the ArgoCDInstance with the name informed. It returns nil
+// when no name is informed, so that the ArgoCD instance configured via Manager ENV VAR is used.
+func argoCDInstance(ctx context.Context, c client.Reader, name string) (*argocd.Instance, error) {
+	if name == "" {
+		return nil, nil
+	}
+	instance := &argocdv1beta1.ArgoCDInstance{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, instance); err != nil {
+		return nil, fmt.Errorf("error fetching the ArgoCDInstance %s: %w", name, err)
+	}
+	return newInstance(instance), nil
+}
+
+// isCredentialsSecret returns true when the object is the Secret which stores the credentials of the
+// ArgoCD account, the one referenced by the ArgoCDInstance with the name informed, if any
+func isCredentialsSecret(ctx context.Context, c client.Reader, instanceName string, obj client.Object) bool {
+	if instanceName == "" {
+		return argocd.IsCredentialsSecret(obj)
+	}
+	instance, err := argoCDInstance(ctx, c, instanceName)
+	if err != nil {
+		return false
+	}
+	return instance.IsCredentialsSecret(obj)
+}
+
+// secretToRequests maps the Secrets and ConfigMaps to the ArgoCDInstances which reference them, so
+// that the rotation of the credentials or of the CA bundle is checked right away, and the cached
+// connections to ArgoCD are configured again with them
+func (r *ArgoCDInstanceReconciler) secretToRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	instances := &argocdv1beta1.ArgoCDInstanceList{}
+	if err := r.List(ctx, instances); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ArgoCDInstances")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range instances.Items {
+		instance := newInstance(&instances.Items[i])
+		if instance.Namespace != obj.GetNamespace() {
+			continue
+		}
+		var names []string
+		switch obj.(type) {
+		case *corev1.ConfigMap:
+			names = []string{instance.CAConfigMapName}
+		default:
+			names = []string{instance.CASecretName, instance.ClientCertSecretName}
+			if instance.IsCredentialsSecret(obj) {
+				names = append(names, obj.GetName())
+			}
+		}
+		for _, name := range names {
+			if name == obj.GetName() {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(&instances.Items[i])})
+				break
+			}
+		}
+	}
+	if len(requests) > 0 {
+		argocd.InvalidateAPIManagers()
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ArgoCDInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&argocdv1beta1.ArgoCDInstance{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretToRequests),
+			builder.WithPredicates(credentialsUpdated)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.secretToRequests),
+			builder.WithPredicates(credentialsUpdated)).
+		Complete(r)
+}
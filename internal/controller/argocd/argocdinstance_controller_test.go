@@ -0,0 +1,181 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/status"
+)
+
+var _ = Describe("ArgoCDInstance controller", func() {
+	ctx := context.Background()
+	typeNamespaceName := types.NamespacedName{Name: "production"}
+	instance := &argocdv1beta1.ArgoCDInstance{}
+
+	BeforeEach(func() {
+		By("Creating the ArgoCDInstance")
+		Expect(k8sClient.Create(ctx, &argocdv1beta1.ArgoCDInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: typeNamespaceName.Name},
+			Spec: argocdv1beta1.ArgoCDInstanceSpec{
+				Endpoint:             "https://argocd-server.gitops.svc",
+				Namespace:            "gitops",
+				CredentialsSecretRef: &corev1.LocalObjectReference{Name: "argocd-operator-token"},
+				CABundleRef: &argocdv1beta1.CABundleReference{
+					Kind: argocdv1beta1.CABundleKindSecret, Name: "argocd-ca"},
+				DefaultProject: "fleet",
+			},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		By("Removing the ArgoCDInstance")
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &argocdv1beta1.ArgoCDInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: typeNamespaceName.Name}}))).To(Succeed())
+	})
+
+	It("should report the version of ArgoCD when its API is reachable", func() {
+		var checked *argocd.Instance
+		reconciler := &ArgoCDInstanceReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			CheckInstance: func(_ context.Context, _ client.Client, _ logr.Logger,
+				instance *argocd.Instance) (string, error) {
+				checked = instance
+				return "v2.8.4+c279299", nil
+			},
+		}
+
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespaceName})
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(result.RequeueAfter).To(Equal(argoCDInstanceCheckInterval))
+		Expect(checked).To(Equal(&argocd.Instance{
+			Name:                  typeNamespaceName.Name,
+			Endpoint:              "https://argocd-server.gitops.svc",
+			Namespace:             "gitops",
+			CredentialsSecretName: "argocd-operator-token",
+			CASecretName:          "argocd-ca",
+			Project:               "fleet",
+		}))
+
+		Expect(k8sClient.Get(ctx, typeNamespaceName, instance)).To(Succeed())
+		Expect(instance.Status.Version).To(Equal("v2.8.4+c279299"))
+		Expect(instance.Status.LastCheckedTime).NotTo(BeNil())
+		Expect(instance.Status.ObservedGeneration).To(Equal(instance.Generation))
+		Expect(meta.IsStatusConditionTrue(instance.Status.Conditions, status.ConditionAvailable)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(instance.Status.Conditions, status.ConditionDegraded)).To(BeTrue())
+	})
+
+	It("should report when the ArgoCD API is not reachable", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &ArgoCDInstanceReconciler{
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Recorder: recorder,
+			CheckInstance: func(_ context.Context, _ client.Client, _ logr.Logger, _ *argocd.Instance) (string, error) {
+				return "", errors.New("connection refused")
+			},
+		}
+
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespaceName})
+		Expect(err).To(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring("connection refused")))
+
+		Expect(k8sClient.Get(ctx, typeNamespaceName, instance)).To(Succeed())
+		Expect(meta.IsStatusConditionFalse(instance.Status.Conditions, status.ConditionAvailable)).To(BeTrue())
+		condition := meta.FindStatusCondition(instance.Status.Conditions, status.ConditionDegraded)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("connection refused"))
+	})
+
+	It("should map the Secrets referenced to the ArgoCDInstance", func() {
+		reconciler := &ArgoCDInstanceReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		request := reconcile.Request{NamespacedName: typeNamespaceName}
+
+		Expect(reconciler.secretToRequests(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "argocd-operator-token", Namespace: "gitops"}})).To(ContainElement(request))
+		Expect(reconciler.secretToRequests(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "argocd-ca", Namespace: "gitops"}})).To(ContainElement(request))
+		Expect(reconciler.secretToRequests(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: "argocd-operator-token", Namespace: "argocd"}})).NotTo(ContainElement(request))
+		Expect(reconciler.secretToRequests(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: "argocd-ca", Namespace: "gitops"}})).NotTo(ContainElement(request))
+	})
+
+	It("should only map the Secrets and ConfigMaps whose credentials change", func() {
+		old := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "argocd-secret", Namespace: "argocd"},
+			Data: map[string][]byte{argocd.PasswordSecretKey: []byte("password"), "server.secretkey": []byte("key")}}
+		updated := func(mutate func(secret *corev1.Secret)) event.UpdateEvent {
+			secret := old.DeepCopy()
+			mutate(secret)
+			return event.UpdateEvent{ObjectOld: old, ObjectNew: secret}
+		}
+
+		By("Filtering out the resyncs, the changes of the metadata and of the keys written by ArgoCD")
+		Expect(credentialsUpdated.Update(updated(func(*corev1.Secret) {}))).To(BeFalse())
+		Expect(credentialsUpdated.Update(updated(func(secret *corev1.Secret) {
+			secret.Labels = map[string]string{"app.kubernetes.io/part-of": "argocd"}
+		}))).To(BeFalse())
+		Expect(credentialsUpdated.Update(updated(func(secret *corev1.Secret) {
+			secret.Data["server.secretkey"] = []byte("rotated")
+		}))).To(BeFalse())
+
+		By("Mapping the changes of the credentials, the CA bundles and the client certificates")
+		Expect(credentialsUpdated.Update(updated(func(secret *corev1.Secret) {
+			secret.Data[argocd.PasswordSecretKey] = []byte("rotated")
+		}))).To(BeTrue())
+		Expect(credentialsUpdated.Update(updated(func(secret *corev1.Secret) {
+			secret.Data[corev1.TLSCertKey] = []byte("certificate")
+		}))).To(BeTrue())
+		Expect(credentialsUpdated.Update(event.UpdateEvent{
+			ObjectOld: &corev1.ConfigMap{Data: map[string]string{argocd.CABundleKey: "ca"}},
+			ObjectNew: &corev1.ConfigMap{Data: map[string]string{argocd.CABundleKey: "rotated"}},
+		})).To(BeTrue())
+	})
+
+	It("should gather the ArgoCDInstance used by the controllers", func() {
+		instance, err := argoCDInstance(ctx, k8sClient, typeNamespaceName.Name)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(instance.Endpoint).To(Equal("https://argocd-server.gitops.svc"))
+		Expect(instance.Project).To(Equal("fleet"))
+
+		By("Using the ArgoCD instance configured via Manager ENV VAR when no ArgoCDInstance is informed")
+		instance, err = argoCDInstance(ctx, k8sClient, "")
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(instance).To(BeNil())
+
+		By("Failing when the ArgoCDInstance does not exist")
+		_, err = argoCDInstance(ctx, k8sClient, "staging")
+		Expect(err).To(MatchError(ContainSubstring("error fetching the ArgoCDInstance staging")))
+	})
+})
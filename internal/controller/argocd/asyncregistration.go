@@ -0,0 +1,170 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/workload-operator/internal/tracing"
+)
+
+// registrationFunc performs the calls to ArgoCD of the reconciliation of a Cluster and returns its result
+type registrationFunc func(ctx context.Context) (ctrl.Result, error)
+
+// registrationJob is the registration of a Cluster queued, or performed, by the registrationWorkers
+type registrationJob struct {
+	run  registrationFunc
+	done bool
+
+	// generation is the generation of the Register registered
+	generation int64
+
+	// dirty is true when the Cluster was reconciled again while its registration was in progress, therefore,
+	// the result of the registration is stale and it is performed again
+	dirty bool
+
+	result ctrl.Result
+	err    error
+}
+
+// registrationWorkers performs the calls to ArgoCD of the reconciliations of the Clusters in a pool of workers,
+// so that the workers of the controller are not blocked while ArgoCD is slow to respond and keep reconciling
+// the events of the Clusters. Once a registration is performed its Cluster is reconciled again, via the channel
+// watched by the controller, and the reconciliation returns its result so that it is requeued as it would be
+// if it was performed by the controller, unless the Register changed since. Each Cluster has a single
+// registration in progress.
+type registrationWorkers struct {
+	workers int
+	log     logr.Logger
+	queue   workqueue.Interface
+	events  chan event.GenericEvent
+
+	mu   sync.Mutex
+	jobs map[client.ObjectKey]*registrationJob
+}
+
+var _ manager.Runnable = &registrationWorkers{}
+var _ manager.LeaderElectionRunnable = &registrationWorkers{}
+
+func newRegistrationWorkers(workers int, log logr.Logger) *registrationWorkers {
+	return &registrationWorkers{
+		workers: workers,
+		log:     log,
+		queue:   workqueue.New(),
+		events:  make(chan event.GenericEvent),
+		jobs:    map[client.ObjectKey]*registrationJob{},
+	}
+}
+
+// Start runs the workers until the context is cancelled
+func (w *registrationWorkers) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for w.processNext(ctx) {
+			}
+		}()
+	}
+	<-ctx.Done()
+	w.queue.ShutDown()
+	wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection returns true since the registrations are submitted by the controller, which only runs
+// in the leader
+func (w *registrationWorkers) NeedLeaderElection() bool {
+	return true
+}
+
+// take returns the registration of the Cluster once it is performed, and whether it is in progress. The
+// registration in progress is marked as dirty, since the Cluster may have changed, so that the result of the
+// registration is dropped once it is performed and the Cluster is registered again.
+func (w *registrationWorkers) take(key client.ObjectKey) (*registrationJob, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	job, ok := w.jobs[key]
+	switch {
+	case !ok:
+		return nil, false
+	case !job.done:
+		job.dirty = true
+		return nil, true
+	}
+	delete(w.jobs, key)
+	if job.dirty {
+		return nil, false
+	}
+	return job, false
+}
+
+// submit queues the registration of the generation of the Register of the Cluster, unless one is already
+// in progress
+func (w *registrationWorkers) submit(key client.ObjectKey, generation int64, run registrationFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.jobs[key]; ok {
+		return
+	}
+	w.jobs[key] = &registrationJob{run: run, generation: generation}
+	w.queue.Add(key)
+}
+
+// processNext performs the next registration queued and reconciles its Cluster again, it returns false once
+// the queue is shut down
+func (w *registrationWorkers) processNext(ctx context.Context) bool {
+	item, shutdown := w.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer w.queue.Done(item)
+	key := item.(client.ObjectKey)
+
+	w.mu.Lock()
+	job := w.jobs[key]
+	w.mu.Unlock()
+
+	jobCtx, span := tracing.Start(log.IntoContext(ctx, w.log.WithValues("Cluster", key)), "Register Cluster",
+		tracing.ClusterAttributes(key.Name, key.Namespace)...)
+	result, err := job.run(jobCtx)
+	_ = tracing.RecordError(span, err)
+	span.End()
+
+	w.mu.Lock()
+	job.done, job.result, job.err = true, result, err
+	w.mu.Unlock()
+
+	// The Cluster is reconciled again so that the result is returned to the controller
+	select {
+	case w.events <- event.GenericEvent{Object: &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}}:
+	case <-ctx.Done():
+	}
+	return true
+}
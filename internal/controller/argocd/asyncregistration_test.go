@@ -0,0 +1,97 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("Registration workers", func() {
+	key := client.ObjectKey{Name: "slow", Namespace: "fleet"}
+	var (
+		workers *registrationWorkers
+		cancel  context.CancelFunc
+		release chan struct{}
+	)
+
+	// slowRegistration blocks until it is released, as a registration while ArgoCD is slow to respond
+	slowRegistration := func(result ctrl.Result, err error) registrationFunc {
+		return func(ctx context.Context) (ctrl.Result, error) {
+			<-release
+			return result, err
+		}
+	}
+
+	BeforeEach(func() {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		release = make(chan struct{})
+		workers = newRegistrationWorkers(2, logr.Discard())
+		go func() {
+			defer GinkgoRecover()
+			Expect(workers.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should return the result of the registration once it is performed", func() {
+		workers.submit(key, 1, slowRegistration(ctrl.Result{RequeueAfter: time.Minute}, errors.New("unavailable")))
+
+		By("checking that the Cluster is not registered again while its registration is in progress")
+		workers.submit(key, 1, func(ctx context.Context) (ctrl.Result, error) {
+			Fail("the Cluster was registered concurrently")
+			return ctrl.Result{}, nil
+		})
+		_, inProgress := workers.take(key)
+		Expect(inProgress).To(BeTrue())
+
+		By("reconciling the Cluster again once it is registered")
+		close(release)
+		var reconciled event.GenericEvent
+		Eventually(workers.events).Should(Receive(&reconciled))
+		Expect(client.ObjectKeyFromObject(reconciled.Object)).To(Equal(key))
+
+		By("dropping the result since the Cluster was reconciled while it was registered")
+		job, inProgress := workers.take(key)
+		Expect(inProgress).To(BeFalse())
+		Expect(job).To(BeNil())
+
+		By("returning the result of a registration performed without changes of the Cluster")
+		workers.submit(key, 2, slowRegistration(ctrl.Result{RequeueAfter: time.Minute}, errors.New("unavailable")))
+		Eventually(workers.events).Should(Receive())
+		job, inProgress = workers.take(key)
+		Expect(inProgress).To(BeFalse())
+		Expect(job.generation).To(Equal(int64(2)))
+		Expect(job.result).To(Equal(ctrl.Result{RequeueAfter: time.Minute}))
+		Expect(job.err).To(MatchError("unavailable"))
+
+		job, _ = workers.take(key)
+		Expect(job).To(BeNil())
+	})
+})
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+// DefaultBulkRegistrationWorkers is the number of Clusters registered concurrently by the BulkRegistrar
+const DefaultBulkRegistrationWorkers = 20
+
+// BulkRegistrar registers, once the Manager starts, the Clusters which are not registered within ArgoCD, i.e.
+// when the operator is first deployed into a fleet or the management cluster is restored. The Clusters are
+// diffed against the clusters registered, listed once per ArgoCD instance, and only the ones missing or whose
+// Register changed are reconciled, concurrently, rather than waiting behind the whole fleet in the workqueue.
+type BulkRegistrar struct {
+	Reconciler *RegisterReconciler
+	Log        logr.Logger
+
+	// Workers is the number of Clusters registered concurrently, DefaultBulkRegistrationWorkers when it is
+	// not informed
+	Workers int
+}
+
+var _ manager.Runnable = &BulkRegistrar{}
+var _ manager.LeaderElectionRunnable = &BulkRegistrar{}
+
+// Start reconciles the Clusters which are not registered within ArgoCD. The ones which fail are left to the
+// controller, which reconciles every Cluster once it starts.
+func (b *BulkRegistrar) Start(ctx context.Context) error {
+	requests, err := b.pendingRequests(ctx)
+	if err != nil {
+		b.Log.Error(err, "Failed to diff the Clusters against ArgoCD, they are registered by the controller")
+		return nil
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+
+	workers := b.Workers
+	if workers <= 0 {
+		workers = DefaultBulkRegistrationWorkers
+	}
+	b.Log.Info("Registering the Clusters within ArgoCD in bulk", "clusters", len(requests), "workers", workers)
+	var failed int32
+	workqueue.ParallelizeUntil(ctx, workers, len(requests), func(i int) {
+		ctx := log.IntoContext(ctx, b.Log.WithValues("Cluster", requests[i].NamespacedName))
+		if _, err := b.Reconciler.Reconcile(ctx, requests[i]); err != nil {
+			atomic.AddInt32(&failed, 1)
+		}
+	})
+	b.Log.Info("Clusters registered within ArgoCD in bulk", "clusters", len(requests), "failed", failed)
+	return nil
+}
+
+// NeedLeaderElection returns true so that only the leader registers the Clusters
+func (b *BulkRegistrar) NeedLeaderElection() bool {
+	return true
+}
+
+// pendingRequests returns the Clusters without a Register, or whose Register changed since it was reconciled
+// or whose server is not registered within its ArgoCD instance. The Registers which are deleted, registered
+// declaratively or within an ArgoCD instance whose clusters cannot be listed are left to the controller.
+func (b *BulkRegistrar) pendingRequests(ctx context.Context) ([]reconcile.Request, error) {
+	r := b.Reconciler
+	clusters := &unstructured.UnstructuredList{}
+	clusters.SetGroupVersionKind(r.clusterGVK().GroupVersion().WithKind(r.clusterGVK().Kind + "List"))
+	if err := r.List(ctx, clusters); err != nil {
+		return nil, err
+	}
+	registers, err := r.listRegisters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registersByCluster := make(map[client.ObjectKey]*argocdv1beta1.Register, len(registers))
+	for i := range registers {
+		registersByCluster[client.ObjectKeyFromObject(&registers[i])] = &registers[i]
+	}
+
+	serversByInstance := map[string]sets.Set[string]{}
+	var requests []reconcile.Request
+	for i := range clusters.Items {
+		key := client.ObjectKeyFromObject(&clusters.Items[i])
+		register, exists := registersByCluster[key]
+		if exists {
+			if !register.DeletionTimestamp.IsZero() ||
+				r.registrationMode(register) != argocdv1beta1.RegistrationModeAPI {
+				continue
+			}
+			if register.Status.Server != "" && register.Status.ObservedGeneration == register.Generation {
+				instanceName := r.instanceName(register)
+				servers, listed := serversByInstance[instanceName]
+				if !listed {
+					if servers, err = b.registeredServers(ctx, instanceName); err != nil {
+						b.Log.Error(err, "Failed to list the clusters registered within ArgoCD, "+
+							"its Clusters are registered by the controller", "instance", instanceName)
+					}
+					serversByInstance[instanceName] = servers
+				}
+				if servers == nil || servers.Has(register.Status.Server) {
+					continue
+				}
+			}
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: key})
+	}
+	return requests, nil
+}
+
+// registeredServers returns the servers of the clusters registered within the ArgoCD instance
+func (b *BulkRegistrar) registeredServers(ctx context.Context, instanceName string) (sets.Set[string], error) {
+	instance, err := argoCDInstance(ctx, b.Reconciler.Client, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	servers, err := argocd.RegisteredServers(ctx, b.Reconciler.Client, b.Log, instance)
+	if err != nil {
+		return nil, err
+	}
+	return sets.New(servers...), nil
+}
+
+// keyedLocks serializes the work by key
+type keyedLocks struct {
+	mu    sync.Mutex
+	locks map[client.ObjectKey]*sync.Mutex
+}
+
+// lock locks the key and returns the func which unlocks it
+func (k *keyedLocks) lock(key client.ObjectKey) func() {
+	k.mu.Lock()
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		k.locks[key] = lock
+	}
+	k.mu.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+// clusterLocks serializes the reconciliations of each Cluster, since they are run by the BulkRegistrar
+// besides the workqueue of the controller, which never reconciles the same Cluster concurrently
+var clusterLocks = &keyedLocks{locks: map[client.ObjectKey]*sync.Mutex{}}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+var _ = Describe("Bulk registration", func() {
+	ctx := context.Background()
+
+	newRegister := func(name string, generation, observedGeneration int64) *argocdv1beta1.Register {
+		register := newFleetRegister(name)
+		register.Generation = generation
+		register.Status = argocdv1beta1.RegisterStatus{Server: name + ":6443", ObservedGeneration: observedGeneration}
+		return register
+	}
+
+	It("should only reconcile the Clusters which are not registered or whose Register changed", func() {
+		deleted := newRegister("deleted", 1, 1)
+		deleted.Finalizers = []string{registerCRFinalizer}
+		deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		declarative := newRegister("declarative", 2, 1)
+		declarative.Spec.RegistrationMode = argocdv1beta1.RegistrationModeDeclarative
+		unlisted := newRegister("unlisted", 1, 1)
+		unlisted.Spec.InstanceRef = &corev1.LocalObjectReference{Name: "missing"}
+
+		reconciler := &RegisterReconciler{Client: newFleetClient(
+			newFleetCluster("new"), newFleetCluster("changed"), newFleetCluster("deleted"),
+			newFleetCluster("declarative"), newFleetCluster("unlisted"),
+			newRegister("changed", 2, 1), deleted, declarative, unlisted,
+		)}
+		registrar := &BulkRegistrar{Reconciler: reconciler, Log: logr.Discard()}
+
+		By("leaving the Registers of an ArgoCD instance whose clusters cannot be listed to the controller")
+		requests, err := registrar.pendingRequests(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(ConsistOf(fleetRequest("new"), fleetRequest("changed")))
+	})
+
+	It("should serialize the reconciliations of each Cluster", func() {
+		locks := &keyedLocks{locks: map[client.ObjectKey]*sync.Mutex{}}
+		unlock := locks.lock(fleetRequest("first").NamespacedName)
+
+		By("locking another Cluster while the first one is locked")
+		locks.lock(fleetRequest("second").NamespacedName)()
+
+		locked := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			locks.lock(fleetRequest("first").NamespacedName)()
+			close(locked)
+		}()
+		Consistently(locked, 100*time.Millisecond).ShouldNot(BeClosed())
+		unlock()
+		Eventually(locked).Should(BeClosed())
+	})
+})
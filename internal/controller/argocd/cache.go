@@ -0,0 +1,112 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewCacheOptions returns the options of the cache of the Manager, which watches the namespaces informed, or
+// all of them when none is, and only the Secrets which match the selector when it is informed. The objects
+// are trimmed before they are stored, so that the memory of the Manager does not grow with the fields which
+// it never reads.
+func NewCacheOptions(namespaces []string, secretSelector labels.Selector) cache.Options {
+	return cache.Options{
+		Namespaces:       namespaces,
+		DefaultTransform: TrimManagedFields,
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Secret{}:        {Label: secretSelector, Transform: TrimReadOnlyObject},
+			&clusterapiv1.Cluster{}: {Transform: TrimReadOnlyObject},
+		},
+	}
+}
+
+// TrimManagedFields removes the managedFields of the object cached. They are not sent back when the object
+// is updated, which keeps them unchanged in the kube-apiserver.
+func TrimManagedFields(obj interface{}) (interface{}, error) {
+	// The objects which have no metadata, i.e. the tombstones of the deleted ones, are kept as they are
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+// TrimReadOnlyObject removes the managedFields and the last applied configuration of kubectl, which holds
+// a whole copy of the object, i.e. of the data of the Secrets. It is only used for the objects which the
+// Manager never updates, since an update would remove the annotation.
+func TrimReadOnlyObject(obj interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+		if annotations := accessor.GetAnnotations(); annotations != nil {
+			delete(annotations, corev1.LastAppliedConfigAnnotation)
+			accessor.SetAnnotations(annotations)
+		}
+	}
+	return obj, nil
+}
+
+// NewClient returns the func which creates the client of the Manager. When the Secrets cached are scoped by
+// the selector informed, the ones which do not match it, i.e. the credentials of ArgoCD or the kubeconfig
+// Secrets referenced by the Registers, are read from the kube-apiserver rather than reported as not found.
+func NewClient(secretSelector labels.Selector) client.NewClientFunc {
+	return func(config *rest.Config, options client.Options) (client.Client, error) {
+		c, err := client.New(config, options)
+		if err != nil || secretSelector == nil {
+			return c, err
+		}
+		options.Cache = nil
+		apiReader, err := client.New(config, options)
+		if err != nil {
+			return nil, err
+		}
+		return &scopedSecretsClient{Client: c, apiReader: apiReader}, nil
+	}
+}
+
+// scopedSecretsClient reads the Secrets which are not cached from the kube-apiserver
+type scopedSecretsClient struct {
+	client.Client
+
+	apiReader client.Reader
+}
+
+// Get reads the Secret from the kube-apiserver when it is not found in the cache
+func (c *scopedSecretsClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object,
+	opts ...client.GetOption) error {
+	err := c.Client.Get(ctx, key, obj, opts...)
+	if _, isSecret := obj.(*corev1.Secret); isSecret && apierrors.IsNotFound(err) {
+		return c.apiReader.Get(ctx, key, obj, opts...)
+	}
+	return err
+}
+
+// List lists the Secrets from the kube-apiserver, since the cache only has the ones which match the selector
+func (c *scopedSecretsClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, isSecretList := list.(*corev1.SecretList); isSecretList {
+		return c.apiReader.List(ctx, list, opts...)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
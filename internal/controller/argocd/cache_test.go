@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+var _ = Describe("Cache", func() {
+	ctx := context.Background()
+
+	newSecret := func(name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:          name,
+			Namespace:     "tenant",
+			Annotations:   map[string]string{corev1.LastAppliedConfigAnnotation: "{}", "team": "fleet"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		}}
+	}
+
+	It("should trim the fields which the Manager never reads", func() {
+		By("removing only the managedFields of the objects which the Manager updates")
+		register := &argocdv1beta1.Register{ObjectMeta: metav1.ObjectMeta{
+			Annotations:   map[string]string{corev1.LastAppliedConfigAnnotation: "{}"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		}}
+		_, err := TrimManagedFields(register)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(register.ManagedFields).To(BeNil())
+		Expect(register.Annotations).To(HaveKey(corev1.LastAppliedConfigAnnotation))
+
+		By("removing the last applied configuration of the objects which the Manager only reads")
+		secret := newSecret("kubeconfig")
+		_, err = TrimReadOnlyObject(secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.ManagedFields).To(BeNil())
+		Expect(secret.Annotations).To(Equal(map[string]string{"team": "fleet"}))
+
+		By("keeping the objects without metadata as they are")
+		Expect(TrimReadOnlyObject("tombstone")).To(Equal("tombstone"))
+	})
+
+	It("should read the Secrets which are not cached from the kube-apiserver", func() {
+		cached := fake.NewClientBuilder().WithScheme(k8sClient.Scheme()).WithObjects(newSecret("cached")).Build()
+		apiReader := fake.NewClientBuilder().WithScheme(k8sClient.Scheme()).
+			WithObjects(newSecret("cached"), newSecret("not-cached")).Build()
+		c := &scopedSecretsClient{Client: cached, apiReader: apiReader}
+
+		Expect(c.Get(ctx, client.ObjectKey{Name: "not-cached", Namespace: "tenant"}, &corev1.Secret{})).To(Succeed())
+		secrets := &corev1.SecretList{}
+		Expect(c.List(ctx, secrets, client.InNamespace("tenant"))).To(Succeed())
+		Expect(secrets.Items).To(HaveLen(2))
+
+		By("reporting the other objects which are not cached as not found")
+		err := c.Get(ctx, client.ObjectKey{Name: "not-cached", Namespace: "tenant"}, &corev1.ConfigMap{})
+		Expect(err).To(HaveOccurred())
+	})
+})
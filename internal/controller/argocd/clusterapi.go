@@ -0,0 +1,141 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterAPIVersionEnvVar store the name of the envvar used to provide the version of the Cluster API
+// used to read the Clusters, i.e. v1beta2. When it is not provided the preferred version served by the
+// management cluster is used.
+const ClusterAPIVersionEnvVar = "CLUSTER_API_VERSION"
+
+// clusterGroupKind is the GroupKind of the Cluster API Clusters which is the same across the API versions
+var clusterGroupKind = schema.GroupKind{Group: clusterapiv1.GroupVersion.Group, Kind: "Cluster"}
+
+// discoverClusterGVK returns the GroupVersionKind used to read the Clusters. It allows the controller to
+// work against management clusters running different versions of Cluster API without a rebuild.
+func discoverClusterGVK(mapper meta.RESTMapper) (schema.GroupVersionKind, error) {
+	if version := os.Getenv(ClusterAPIVersionEnvVar); version != "" {
+		return clusterGroupKind.WithVersion(version), nil
+	}
+	mapping, err := mapper.RESTMapping(clusterGroupKind)
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("error discovering the version of the Cluster API: %w", err)
+	}
+	return mapping.GroupVersionKind, nil
+}
+
+// clusterGVK returns the GroupVersionKind used to read the Clusters, v1beta1 when it was not discovered
+func (r *RegisterReconciler) clusterGVK() schema.GroupVersionKind {
+	if r.ClusterGVK.Empty() {
+		return clusterapiv1.GroupVersion.WithKind(clusterGroupKind.Kind)
+	}
+	return r.ClusterGVK
+}
+
+// newClusterObject returns an empty Cluster of the version used to read the Clusters
+func (r *RegisterReconciler) newClusterObject() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(r.clusterGVK())
+	return obj
+}
+
+// getCluster reads the Cluster in the version used by the controller and converts it to the v1beta1 one
+func (r *RegisterReconciler) getCluster(ctx context.Context, key client.ObjectKey,
+	clusterAPI *clusterapiv1.Cluster) error {
+	obj := r.newClusterObject()
+	if err := r.Get(ctx, key, obj); err != nil {
+		return err
+	}
+	return clusterFromUnstructured(obj, clusterAPI)
+}
+
+// clusterFromUnstructured converts the Cluster read in any version of the Cluster API to the v1beta1 one.
+// The v1beta1 Clusters are fully converted, otherwise, only the metadata and the fields of the spec which
+// are part of the Cluster API contract used by the controller (controlPlaneEndpoint, controlPlaneRef and
+// paused) are.
+func clusterFromUnstructured(obj *unstructured.Unstructured, clusterAPI *clusterapiv1.Cluster) error {
+	if obj.GroupVersionKind().GroupVersion() == clusterapiv1.GroupVersion {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, clusterAPI); err != nil {
+			return fmt.Errorf("error converting the Cluster: %w", err)
+		}
+		return nil
+	}
+
+	metadata, _, err := unstructured.NestedMap(obj.Object, "metadata")
+	if err != nil {
+		return fmt.Errorf("error reading the metadata of the Cluster: %w", err)
+	}
+	objectMeta := metav1.ObjectMeta{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(metadata, &objectMeta); err != nil {
+		return fmt.Errorf("error converting the metadata of the Cluster: %w", err)
+	}
+	host, _, err := unstructured.NestedString(obj.Object, "spec", "controlPlaneEndpoint", "host")
+	if err != nil {
+		return fmt.Errorf("error reading the control plane endpoint of the Cluster: %w", err)
+	}
+	port, _, err := unstructured.NestedInt64(obj.Object, "spec", "controlPlaneEndpoint", "port")
+	if err != nil {
+		return fmt.Errorf("error reading the control plane endpoint of the Cluster: %w", err)
+	}
+	paused, _, err := unstructured.NestedBool(obj.Object, "spec", "paused")
+	if err != nil {
+		return fmt.Errorf("error reading if the Cluster is paused: %w", err)
+	}
+	var controlPlaneRef *corev1.ObjectReference
+	if ref, found, err := unstructured.NestedMap(obj.Object, "spec", "controlPlaneRef"); err != nil {
+		return fmt.Errorf("error reading the control plane reference of the Cluster: %w", err)
+	} else if found {
+		controlPlaneRef = &corev1.ObjectReference{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(ref, controlPlaneRef); err != nil {
+			return fmt.Errorf("error converting the control plane reference of the Cluster: %w", err)
+		}
+	}
+
+	*clusterAPI = clusterapiv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: obj.GetAPIVersion(), Kind: obj.GetKind()},
+		ObjectMeta: objectMeta,
+		Spec: clusterapiv1.ClusterSpec{
+			Paused:               paused,
+			ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: host, Port: int32(port)},
+			ControlPlaneRef:      controlPlaneRef,
+		},
+	}
+	return nil
+}
+
+// clusterOwner returns the Cluster as owner of the resources created for it, keeping the version which
+// the Cluster was read with, so that the owner references are resolvable by the garbage collector.
+func (r *RegisterReconciler) clusterOwner(clusterAPI *clusterapiv1.Cluster) client.Object {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: r.clusterGVK().GroupVersion().String(), Kind: r.clusterGVK().Kind},
+		ObjectMeta: clusterAPI.ObjectMeta,
+	}
+}
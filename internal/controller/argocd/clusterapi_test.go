@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+var _ = Describe("Cluster API compatibility", func() {
+	AfterEach(func() {
+		_ = os.Unsetenv(ClusterAPIVersionEnvVar)
+	})
+
+	It("should discover the version of the Cluster API served by the management cluster", func() {
+		httpClient, err := rest.HTTPClientFor(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		mapper, err := apiutil.NewDynamicRESTMapper(cfg, httpClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		clusterGVK, err := discoverClusterGVK(mapper)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusterGVK).To(Equal(clusterapiv1.GroupVersion.WithKind("Cluster")))
+
+		By("using the version informed via the Manager ENV VAR")
+		Expect(os.Setenv(ClusterAPIVersionEnvVar, "v1beta2")).To(Succeed())
+		clusterGVK, err = discoverClusterGVK(mapper)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusterGVK.Version).To(Equal("v1beta2"))
+	})
+
+	It("should convert the Clusters of newer versions of the Cluster API", func() {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "cluster.x-k8s.io/v1beta2",
+			"kind":       "Cluster",
+			"metadata": map[string]interface{}{
+				"name":      "workload",
+				"namespace": "default",
+				"labels":    map[string]interface{}{"env": "prod"},
+			},
+			"spec": map[string]interface{}{
+				"paused":               true,
+				"controlPlaneEndpoint": map[string]interface{}{"host": "workload.example.com", "port": int64(6443)},
+				"topology":             map[string]interface{}{"classRef": map[string]interface{}{"name": "quick-start"}},
+			},
+			"status": map[string]interface{}{
+				"failureDomains": []interface{}{map[string]interface{}{"name": "az-1"}},
+			},
+		}}
+
+		clusterAPI := &clusterapiv1.Cluster{}
+		Expect(clusterFromUnstructured(obj, clusterAPI)).To(Succeed())
+		Expect(clusterAPI.Name).To(Equal("workload"))
+		Expect(clusterAPI.Labels).To(HaveKeyWithValue("env", "prod"))
+		Expect(clusterAPI.Spec.Paused).To(BeTrue())
+		Expect(clusterAPI.Spec.ControlPlaneEndpoint).To(Equal(clusterapiv1.APIEndpoint{
+			Host: "workload.example.com", Port: 6443}))
+
+		By("owning the Register with the version which the Cluster was read with")
+		registerReconciler := &RegisterReconciler{
+			Scheme:     k8sClient.Scheme(),
+			ClusterGVK: obj.GroupVersionKind(),
+		}
+		register := &argocdv1beta1.Register{ObjectMeta: metav1.ObjectMeta{Name: "workload", Namespace: "default"}}
+		Expect(controllerutil.SetOwnerReference(registerReconciler.clusterOwner(clusterAPI), register,
+			registerReconciler.Scheme)).To(Succeed())
+		Expect(register.OwnerReferences).To(HaveLen(1))
+		Expect(register.OwnerReferences[0].APIVersion).To(Equal("cluster.x-k8s.io/v1beta2"))
+		Expect(register.OwnerReferences[0].Kind).To(Equal("Cluster"))
+	})
+})
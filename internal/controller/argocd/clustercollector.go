@@ -0,0 +1,182 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+// ArgoCDClusterCollector periodically removes from ArgoCD the clusters labeled as managed by this installation
+// of the project whose Register, ExternalCluster or Cluster no longer exists, i.e. when they were deleted while
+// the operator was down or restored from a backup without them, so that ArgoCD does not accumulate dead clusters.
+// The registrations retained by the deletionPolicy of their Register are no longer labeled as managed, therefore,
+// they are kept, as well as the ones of the other installations which share the ArgoCD instance. The ArgoCD
+// instances running in core mode are skipped since they have no API.
+type ArgoCDClusterCollector struct {
+	Reconciler *RegisterReconciler
+	Log        logr.Logger
+
+	// Interval between the sweeps, DefaultOrphanSweepInterval when it is not informed
+	Interval time.Duration
+
+	// candidates are the servers found orphaned by the previous sweep, by ArgoCD instance. A cluster is only
+	// removed once it is found orphaned by two sweeps in a row, so that the ones being registered, whose
+	// server is not recorded in the status of their Register yet, are not removed.
+	candidates map[string]sets.Set[string]
+}
+
+var _ manager.Runnable = &ArgoCDClusterCollector{}
+var _ manager.LeaderElectionRunnable = &ArgoCDClusterCollector{}
+
+// Start sweeps the orphaned clusters within ArgoCD periodically until the Manager is stopped
+func (c *ArgoCDClusterCollector) Start(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultOrphanSweepInterval
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.sweep(ctx); err != nil {
+			c.Log.Error(err, "Failed to sweep the orphaned clusters within ArgoCD")
+		}
+	}, interval)
+	return nil
+}
+
+// NeedLeaderElection returns true so that only the leader removes the orphaned clusters
+func (c *ArgoCDClusterCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// sweep lists the clusters managed within each ArgoCD instance and removes the ones which were already orphaned
+// in the previous sweep. The instances whose clusters cannot be listed are swept again next time.
+func (c *ArgoCDClusterCollector) sweep(ctx context.Context) error {
+	protected, err := c.protectedServers(ctx)
+	if err != nil {
+		return err
+	}
+	instanceNames, err := c.instanceNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, instanceName := range sets.List(instanceNames) {
+		log := c.Log.WithValues("instance", instanceName)
+		instance, err := argoCDInstance(ctx, c.Reconciler.Client, instanceName)
+		if err != nil {
+			log.Error(err, "Failed to sweep the orphaned clusters of the ArgoCD instance")
+			delete(c.candidates, instanceName)
+			continue
+		}
+		// The clusters of the ArgoCD instances without API, i.e. running in core mode, cannot be listed
+		hasAPI, err := argocd.HasAPI(ctx, log, instance)
+		if err != nil {
+			log.Error(err, "Failed to check whether the ArgoCD instance has an API")
+			delete(c.candidates, instanceName)
+			continue
+		}
+		if !hasAPI {
+			log.V(1).Info("Skipping the ArgoCD instance since it runs in core mode without API")
+			delete(c.candidates, instanceName)
+			continue
+		}
+		managed, err := argocd.ManagedServers(ctx, c.Reconciler.Client, log, instance)
+		if err != nil {
+			log.Error(err, "Failed to list the clusters managed within the ArgoCD instance")
+			delete(c.candidates, instanceName)
+			continue
+		}
+		for _, server := range c.orphaned(instanceName, managed, protected) {
+			if err := c.remove(ctx, log, instance, server); err != nil {
+				log.Error(err, "Failed to remove the orphaned cluster from ArgoCD", "server", server)
+				continue
+			}
+			log.Info("Orphaned cluster was removed from ArgoCD", "server", server)
+		}
+	}
+	return nil
+}
+
+// orphaned returns the servers managed within the ArgoCD instance which are not protected and were already
+// orphaned in the previous sweep, and records the ones orphaned now as the candidates of the next sweep
+func (c *ArgoCDClusterCollector) orphaned(instanceName string, managed []string,
+	protected sets.Set[string]) []string {
+	orphaned := sets.New(managed...).Difference(protected)
+	previous := c.candidates[instanceName]
+	if c.candidates == nil {
+		c.candidates = map[string]sets.Set[string]{}
+	}
+	c.candidates[instanceName] = orphaned
+	return sets.List(orphaned.Intersection(previous))
+}
+
+// protectedServers returns the servers registered by the Registers, the ClusterRegisters and the ExternalClusters,
+// including the ones being deleted, and by the management cluster
+func (c *ArgoCDClusterCollector) protectedServers(ctx context.Context) (sets.Set[string], error) {
+	protected := sets.New(argocd.InClusterServer)
+	registers, err := c.Reconciler.listRegisters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range registers {
+		protected.Insert(registers[i].Status.Server)
+	}
+	externalClusters := &argocdv1beta1.ExternalClusterList{}
+	if err := c.Reconciler.List(ctx, externalClusters); err != nil {
+		return nil, err
+	}
+	for i := range externalClusters.Items {
+		protected.Insert(externalClusters.Items[i].Status.Server)
+	}
+	protected.Delete("")
+	return protected, nil
+}
+
+// instanceNames returns the names of the ArgoCDInstances, and the one of the ArgoCD instance where the Clusters
+// are registered by default, which is empty when it is configured via Manager ENV VAR
+func (c *ArgoCDClusterCollector) instanceNames(ctx context.Context) (sets.Set[string], error) {
+	instances := &argocdv1beta1.ArgoCDInstanceList{}
+	if err := c.Reconciler.List(ctx, instances); err != nil {
+		return nil, err
+	}
+	names := sets.New(c.Reconciler.ArgoCDInstance)
+	for i := range instances.Items {
+		names.Insert(instances.Items[i].Name)
+	}
+	return names, nil
+}
+
+// remove unregisters the server from the ArgoCD instance
+func (c *ArgoCDClusterCollector) remove(ctx context.Context, log logr.Logger, instance *argocd.Instance,
+	server string) error {
+	options := argocd.ClusterOptions{Server: server, Instance: instance}
+	argoCDManager, err := c.Reconciler.registrarFactory()(ctx, c.Reconciler.Client, log,
+		argocdv1beta1.RegistrationModeAPI, &clusterapiv1.Cluster{}, nil, options)
+	if err != nil {
+		return err
+	}
+	return argoCDManager.UnRegisterCluster(ctx)
+}
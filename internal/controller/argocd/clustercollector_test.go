@@ -0,0 +1,122 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+var _ = Describe("ArgoCD cluster collector", func() {
+	ctx := context.Background()
+
+	It("should protect the servers registered by the operator", func() {
+		register := &argocdv1beta1.Register{
+			ObjectMeta: metav1.ObjectMeta{Name: "register", Namespace: "fleet"},
+			Status:     argocdv1beta1.RegisterStatus{Server: "register:6443"},
+		}
+		clusterRegister := &argocdv1beta1.ClusterRegister{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-register"},
+			Spec: argocdv1beta1.ClusterRegisterSpec{
+				ClusterRef: argocdv1beta1.ClusterReference{Name: "cluster-register", Namespace: "fleet"},
+			},
+			Status: argocdv1beta1.RegisterStatus{Server: "cluster-register:6443"},
+		}
+		externalCluster := &argocdv1beta1.ExternalCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "fleet"},
+			Status:     argocdv1beta1.ExternalClusterStatus{Server: "external:6443"},
+		}
+		pending := &argocdv1beta1.Register{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "fleet"}}
+		instance := &argocdv1beta1.ArgoCDInstance{ObjectMeta: metav1.ObjectMeta{Name: "secondary"}}
+
+		collector := &ArgoCDClusterCollector{Log: logr.Discard(), Reconciler: &RegisterReconciler{
+			Client: fake.NewClientBuilder().WithScheme(k8sClient.Scheme()).WithObjects(
+				register, clusterRegister, externalCluster, pending, instance).Build(),
+		}}
+		protected, err := collector.protectedServers(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(protected.UnsortedList()).To(ConsistOf(argocd.InClusterServer, "register:6443",
+			"cluster-register:6443", "external:6443"))
+
+		By("sweeping the ArgoCD instance configured via Manager ENV VAR and the ArgoCDInstances")
+		instanceNames, err := collector.instanceNames(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instanceNames.UnsortedList()).To(ConsistOf("", "secondary"))
+	})
+
+	It("should only remove the clusters found orphaned by two sweeps in a row", func() {
+		collector := &ArgoCDClusterCollector{Log: logr.Discard(), Reconciler: &RegisterReconciler{}}
+		protected := sets.New(argocd.InClusterServer, "registered:6443")
+		managed := []string{argocd.InClusterServer, "registered:6443", "orphaned:6443", "registering:6443"}
+
+		Expect(collector.orphaned("", managed, protected)).To(BeEmpty())
+
+		By("protecting the clusters registered meanwhile")
+		protected.Insert("registering:6443")
+		Expect(collector.orphaned("", managed, protected)).To(ConsistOf("orphaned:6443"))
+
+		By("confirming the clusters orphaned again in the next sweep")
+		Expect(collector.orphaned("", managed[:2], protected)).To(BeEmpty())
+		Expect(collector.orphaned("", managed, protected)).To(BeEmpty())
+	})
+
+	It("should remove the orphaned clusters from the ArgoCD instance", func() {
+		registrar := &fakeRegistrar{}
+		collector := &ArgoCDClusterCollector{Log: logr.Discard(), Reconciler: &RegisterReconciler{
+			NewRegistrar: registrar.factory,
+		}}
+		Expect(collector.remove(ctx, logr.Discard(), nil, "orphaned:6443")).To(Succeed())
+		Expect(registrar.unregistered).To(ConsistOf("orphaned:6443"))
+	})
+
+	It("should skip the ArgoCD instance which runs in core mode", func() {
+		Expect(os.Setenv(argocd.NamespaceEnvVar, "argocd")).To(Succeed())
+		Expect(os.Setenv(argocd.CoreModeEnvVar, "true")).To(Succeed())
+		DeferCleanup(func() {
+			Expect(os.Unsetenv(argocd.NamespaceEnvVar)).To(Succeed())
+			Expect(os.Unsetenv(argocd.CoreModeEnvVar)).To(Succeed())
+		})
+
+		var errorsLogged []string
+		log := funcr.New(func(_, args string) {
+			if strings.Contains(args, `"error"`) {
+				errorsLogged = append(errorsLogged, args)
+			}
+		}, funcr.Options{})
+		registrar := &fakeRegistrar{}
+		collector := &ArgoCDClusterCollector{Log: log, Reconciler: &RegisterReconciler{
+			Client:       fake.NewClientBuilder().WithScheme(k8sClient.Scheme()).Build(),
+			NewRegistrar: registrar.factory,
+		}, candidates: map[string]sets.Set[string]{"": sets.New("orphaned:6443")}}
+		Expect(collector.sweep(ctx)).To(Succeed())
+		Expect(errorsLogged).To(BeEmpty())
+		Expect(registrar.unregistered).To(BeEmpty())
+		Expect(collector.candidates).NotTo(HaveKey(""))
+	})
+})
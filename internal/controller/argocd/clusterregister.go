@@ -0,0 +1,245 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=clusterregisters,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=clusterregisters/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=clusterregisters/finalizers,verbs=update
+
+// useClusterRegister makes the reconciliation of the Cluster use the ClusterRegister which references it,
+// when the Cluster has no Register. The ClusterRegister is read and written as the Register of the Cluster,
+// so that it is handled exactly as a Register, without ever creating a Register in the namespace of the Cluster.
+func (r *RegisterReconciler) useClusterRegister(ctx context.Context, req reconcile.Request) error {
+	// The Register of the Cluster, when it exists, takes precedence
+	err := r.Get(ctx, req.NamespacedName, &argocdv1beta1.Register{})
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	clusterRegister, err := r.clusterRegisterOf(ctx, req.NamespacedName)
+	if err != nil || clusterRegister == nil {
+		return err
+	}
+
+	r.clusterRegister = clusterRegister.Name
+	r.Client = &clusterRegisterClient{Client: r.Client, name: clusterRegister.Name, cluster: req.NamespacedName}
+	if r.Recorder != nil {
+		r.Recorder = &clusterRegisterRecorder{EventRecorder: r.Recorder, clusterRegister: clusterRegister}
+	}
+	r.Log = r.Log.WithValues("clusterRegister", clusterRegister.Name)
+	return nil
+}
+
+// clusterRegisterOf returns the ClusterRegister which references the Cluster, or nil when there is none.
+// When several ClusterRegisters reference it the oldest one is used.
+func (r *RegisterReconciler) clusterRegisterOf(ctx context.Context,
+	cluster client.ObjectKey) (*argocdv1beta1.ClusterRegister, error) {
+	found, err := r.clusterRegistersIndexed(ctx, cluster)
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].CreationTimestamp.Equal(&found[j].CreationTimestamp) {
+			return found[i].CreationTimestamp.Before(&found[j].CreationTimestamp)
+		}
+		return found[i].Name < found[j].Name
+	})
+	return &found[0], nil
+}
+
+// clusterRegisterCluster returns the key of the Cluster referenced by the ClusterRegister
+func clusterRegisterCluster(clusterRegister *argocdv1beta1.ClusterRegister) client.ObjectKey {
+	ref := clusterRegister.Spec.ClusterRef
+	return client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}
+}
+
+// clusterRegisterToRegister fills the Register with the ClusterRegister, as if it was the Register of the
+// Cluster, named as the Cluster in its namespace
+func clusterRegisterToRegister(clusterRegister *argocdv1beta1.ClusterRegister, register *argocdv1beta1.Register) {
+	clusterRegister.ObjectMeta.DeepCopyInto(&register.ObjectMeta)
+	register.Namespace = clusterRegister.Spec.ClusterRef.Namespace
+	register.Name = clusterRegister.Spec.ClusterRef.Name
+	clusterRegister.Spec.RegisterSpec.DeepCopyInto(&register.Spec)
+	clusterRegister.Status.DeepCopyInto(&register.Status)
+}
+
+// listRegisters returns the Registers and, as Registers of their Cluster, the ClusterRegisters, so that
+// the Secrets and the ArgoCDInstances are mapped to the Clusters registered via both
+func (r *RegisterReconciler) listRegisters(ctx context.Context) ([]argocdv1beta1.Register, error) {
+	registers := &argocdv1beta1.RegisterList{}
+	if err := r.List(ctx, registers); err != nil {
+		return nil, err
+	}
+	clusterRegisters := &argocdv1beta1.ClusterRegisterList{}
+	if err := r.List(ctx, clusterRegisters); err != nil {
+		return nil, err
+	}
+	items := registers.Items
+	for i := range clusterRegisters.Items {
+		register := argocdv1beta1.Register{}
+		clusterRegisterToRegister(&clusterRegisters.Items[i], &register)
+		items = append(items, register)
+	}
+	return items, nil
+}
+
+// clusterRegisterToRequests maps the ClusterRegister to the Cluster which it references
+func (r *RegisterReconciler) clusterRegisterToRequests(_ context.Context, obj client.Object) []reconcile.Request {
+	clusterRegister, ok := obj.(*argocdv1beta1.ClusterRegister)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: clusterRegisterCluster(clusterRegister)}}
+}
+
+// clusterRegisterClient reads and writes the ClusterRegister when the Register of the Cluster is read or
+// written, the other objects are read and written as they are
+type clusterRegisterClient struct {
+	client.Client
+
+	// name of the ClusterRegister
+	name string
+	// cluster is the key of the Cluster referenced by the ClusterRegister
+	cluster client.ObjectKey
+}
+
+// register returns the Register when the object is the Register of the Cluster
+func (c *clusterRegisterClient) register(obj client.Object) (*argocdv1beta1.Register, bool) {
+	register, ok := obj.(*argocdv1beta1.Register)
+	return register, ok && client.ObjectKeyFromObject(register) == c.cluster
+}
+
+// clusterRegister returns the ClusterRegister with the metadata, spec and status of the Register
+func (c *clusterRegisterClient) clusterRegister(register *argocdv1beta1.Register) *argocdv1beta1.ClusterRegister {
+	clusterRegister := &argocdv1beta1.ClusterRegister{}
+	register.ObjectMeta.DeepCopyInto(&clusterRegister.ObjectMeta)
+	clusterRegister.Namespace, clusterRegister.Name = "", c.name
+	clusterRegister.Spec.ClusterRef = argocdv1beta1.ClusterReference{Namespace: c.cluster.Namespace, Name: c.cluster.Name}
+	register.Spec.DeepCopyInto(&clusterRegister.Spec.RegisterSpec)
+	register.Status.DeepCopyInto(&clusterRegister.Status)
+	return clusterRegister
+}
+
+// Get reads the ClusterRegister when the Register of the Cluster is read
+func (c *clusterRegisterClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object,
+	opts ...client.GetOption) error {
+	register, ok := obj.(*argocdv1beta1.Register)
+	if !ok || key != c.cluster {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+	clusterRegister := &argocdv1beta1.ClusterRegister{}
+	if err := c.Client.Get(ctx, client.ObjectKey{Name: c.name}, clusterRegister, opts...); err != nil {
+		return err
+	}
+	clusterRegisterToRegister(clusterRegister, register)
+	return nil
+}
+
+// Update updates the ClusterRegister when the Register of the Cluster is updated
+func (c *clusterRegisterClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	register, ok := c.register(obj)
+	if !ok {
+		return c.Client.Update(ctx, obj, opts...)
+	}
+	clusterRegister := c.clusterRegister(register)
+	if err := c.Client.Update(ctx, clusterRegister, opts...); err != nil {
+		return err
+	}
+	clusterRegisterToRegister(clusterRegister, register)
+	return nil
+}
+
+// Delete deletes the ClusterRegister when the Register of the Cluster is deleted
+func (c *clusterRegisterClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	register, ok := c.register(obj)
+	if !ok {
+		return c.Client.Delete(ctx, obj, opts...)
+	}
+	return c.Client.Delete(ctx, c.clusterRegister(register), opts...)
+}
+
+// Status returns the writer of the status which updates the one of the ClusterRegister
+func (c *clusterRegisterClient) Status() client.SubResourceWriter {
+	return &clusterRegisterStatusWriter{SubResourceWriter: c.Client.Status(), client: c}
+}
+
+// clusterRegisterStatusWriter updates the status of the ClusterRegister when the one of the Register of the
+// Cluster is updated
+type clusterRegisterStatusWriter struct {
+	client.SubResourceWriter
+
+	client *clusterRegisterClient
+}
+
+// Update updates the status of the ClusterRegister when the one of the Register of the Cluster is updated
+func (w *clusterRegisterStatusWriter) Update(ctx context.Context, obj client.Object,
+	opts ...client.SubResourceUpdateOption) error {
+	register, ok := w.client.register(obj)
+	if !ok {
+		return w.SubResourceWriter.Update(ctx, obj, opts...)
+	}
+	clusterRegister := w.client.clusterRegister(register)
+	if err := w.SubResourceWriter.Update(ctx, clusterRegister, opts...); err != nil {
+		return err
+	}
+	clusterRegisterToRegister(clusterRegister, register)
+	return nil
+}
+
+// clusterRegisterRecorder records the events of the Register of the Cluster on the ClusterRegister
+type clusterRegisterRecorder struct {
+	record.EventRecorder
+
+	clusterRegister *argocdv1beta1.ClusterRegister
+}
+
+// object returns the ClusterRegister when the object is a Register
+func (r *clusterRegisterRecorder) object(obj runtime.Object) runtime.Object {
+	if _, ok := obj.(*argocdv1beta1.Register); ok {
+		return r.clusterRegister
+	}
+	return obj
+}
+
+// Event records the event on the ClusterRegister when it is recorded on the Register of the Cluster
+func (r *clusterRegisterRecorder) Event(obj runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.Event(r.object(obj), eventtype, reason, message)
+}
+
+// Eventf records the event on the ClusterRegister when it is recorded on the Register of the Cluster
+func (r *clusterRegisterRecorder) Eventf(obj runtime.Object, eventtype, reason, messageFmt string,
+	args ...interface{}) {
+	r.EventRecorder.Eventf(r.object(obj), eventtype, reason, messageFmt, args...)
+}
+
+// AnnotatedEventf records the event on the ClusterRegister when it is recorded on the Register of the Cluster
+func (r *clusterRegisterRecorder) AnnotatedEventf(obj runtime.Object, annotations map[string]string,
+	eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(r.object(obj), annotations, eventtype, reason, messageFmt, args...)
+}
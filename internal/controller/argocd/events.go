@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// DefaultEventBurst is the number of events of the same type and reason an object can raise before
+	// they are throttled
+	DefaultEventBurst = 10
+	// DefaultEventInterval is the interval at which an object can raise again an event of the same type and
+	// reason once its burst is exhausted
+	DefaultEventInterval = 5 * time.Minute
+)
+
+// eventAggregateMaxEvents is the number of events of an object with the same reason, but with different
+// messages, which are raised before the following ones are aggregated into a single event
+const eventAggregateMaxEvents = 5
+
+// NewEventBroadcaster returns the broadcaster of the events raised by the controllers. The identical events
+// are correlated into a single one with its count, the ones with the same reason are aggregated, and the
+// events of each object are throttled by type and reason, the defaults are used for the burst and the
+// interval not informed. It keeps a failure raised on every reconciliation, i.e. while ArgoCD is down, from
+// flooding etcd, without suppressing the events of the lifecycle transitions of the object.
+func NewEventBroadcaster(burst int, interval time.Duration) record.EventBroadcaster {
+	if burst <= 0 {
+		burst = DefaultEventBurst
+	}
+	if interval <= 0 {
+		interval = DefaultEventInterval
+	}
+	return record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+		BurstSize:   burst,
+		QPS:         float32(1 / interval.Seconds()),
+		MaxEvents:   eventAggregateMaxEvents,
+		SpamKeyFunc: eventSpamKey,
+	})
+}
+
+// eventSpamKey returns the key the events are throttled by, which is the object, the type and the reason
+// of the event, while the default one of client-go throttles all the events of the object together
+func eventSpamKey(event *corev1.Event) string {
+	return strings.Join([]string{
+		event.Source.Component,
+		event.Source.Host,
+		event.InvolvedObject.APIVersion,
+		event.InvolvedObject.Kind,
+		event.InvolvedObject.Namespace,
+		event.InvolvedObject.Name,
+		string(event.InvolvedObject.UID),
+		event.Type,
+		event.Reason,
+	}, "/")
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// fakeEventSink records the events which would be created or updated in the Kubernetes API
+type fakeEventSink struct {
+	mu     sync.Mutex
+	events []*corev1.Event
+}
+
+func (s *fakeEventSink) record(event *corev1.Event) (*corev1.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return event, nil
+}
+
+func (s *fakeEventSink) Create(event *corev1.Event) (*corev1.Event, error) { return s.record(event) }
+
+func (s *fakeEventSink) Update(event *corev1.Event) (*corev1.Event, error) { return s.record(event) }
+
+func (s *fakeEventSink) Patch(event *corev1.Event, _ []byte) (*corev1.Event, error) {
+	return s.record(event)
+}
+
+func (s *fakeEventSink) reasons() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reasons []string
+	for _, event := range s.events {
+		reasons = append(reasons, event.Reason)
+	}
+	return reasons
+}
+
+var _ = Describe("Event broadcaster", func() {
+	It("should throttle the events of each object by type and reason", func() {
+		broadcaster := NewEventBroadcaster(2, time.Hour)
+		defer broadcaster.Shutdown()
+		sink := &fakeEventSink{}
+		broadcaster.StartRecordingToSink(sink)
+		recorder := broadcaster.NewRecorder(clientgoscheme.Scheme, corev1.EventSource{Component: "test"})
+
+		object := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "uid"}}
+		for i := 0; i < 5; i++ {
+			recorder.Event(object, corev1.EventTypeWarning, "Unavailable", "Unable to reach the ArgoCD API")
+		}
+		Eventually(sink.reasons).Should(Equal([]string{"Unavailable", "Unavailable"}))
+		Consistently(sink.reasons, time.Second).Should(HaveLen(2))
+
+		By("Checking that the events with other reasons are not suppressed")
+		recorder.Event(object, corev1.EventTypeNormal, "Registered", "Cluster test was registered within ArgoCD")
+		Eventually(sink.reasons).Should(Equal([]string{"Unavailable", "Unavailable", "Registered"}))
+	})
+})
@@ -0,0 +1,523 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	capisecret "sigs.k8s.io/cluster-api/util/secret"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/status"
+	"github.com/workload-operator/internal/tracing"
+)
+
+// ExternalClusterReconciler reconciles a ExternalCluster object
+type ExternalClusterReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Log      logr.Logger
+
+	// NewRegistrar returns the Registrar used to register the cluster within ArgoCD.
+	// When it is not informed argocd.NewRegistrar is used.
+	NewRegistrar argocd.RegistrarFactory
+
+	// MaxConcurrentReconciles is the maximum number of ExternalClusters reconciled concurrently,
+	// 1 when it is not informed
+	MaxConcurrentReconciles int
+
+	// RateLimiter requeues the failed requests. When it is not informed the default of controller-runtime is used.
+	RateLimiter ratelimiter.RateLimiter
+
+	// ArgoCDInstance is the name of the ArgoCDInstance where the clusters are registered. When it is not
+	// informed the ArgoCD instance is configured via Manager ENV VAR.
+	ArgoCDInstance string
+}
+
+const externalClusterFinalizer = "argocd.externalcluster.workload.com/finalizer"
+
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=externalclusters,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=externalclusters/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=externalclusters/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+// Reconcile will register within ArgoCD the clusters which are not managed by Cluster API, i.e.
+// provisioned by other tools or by cloud providers, with the kubeconfig stored in the Secret
+// referenced in the ExternalCluster CR, and remove their registration when the CR is deleted.
+func (r *ExternalClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Start(ctx, "Reconcile ExternalCluster", tracing.ClusterAttributes(req.Name, req.Namespace)...)
+	defer span.End()
+	result, err := r.reconcile(ctx, req)
+	return result, tracing.RecordError(span, err)
+}
+
+// reconcile performs the reconciliation of the ExternalCluster traced by Reconcile
+func (r *ExternalClusterReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	// Each reconciliation uses its own copy of the reconciler so that the logger is not shared
+	reconciler := *r
+	r = &reconciler
+	r.Log = log.FromContext(ctx)
+
+	externalCluster := &argocdv1beta1.ExternalCluster{}
+	if err := r.Get(ctx, req.NamespacedName, externalCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Log.Info("ExternalCluster resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		r.Log.Error(err, "Failed to get ExternalCluster")
+		return ctrl.Result{}, err
+	}
+
+	if annotations.HasPaused(externalCluster) {
+		r.Log.Info("Reconciliation is paused for the ExternalCluster")
+		return ctrl.Result{}, nil
+	}
+
+	// Check if the ExternalCluster is marked to be deleted, if yes then handle finalization
+	if isMarkedToBeDeleted := externalCluster.GetDeletionTimestamp() != nil; isMarkedToBeDeleted {
+		if err := r.handleFinalizer(ctx, req, externalCluster); err != nil {
+			return requeueWhenRateLimited(err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(externalCluster, externalClusterFinalizer) {
+		r.Log.Info("Adding Finalizer for ExternalCluster")
+		controllerutil.AddFinalizer(externalCluster, externalClusterFinalizer)
+		if err := r.Update(ctx, externalCluster); err != nil {
+			r.Log.Error(err, "Failed to update ExternalCluster to add finalizer")
+			return ctrl.Result{}, err
+		}
+		if err := r.Get(ctx, req.NamespacedName, externalCluster); err != nil {
+			r.Log.Error(err, "Failed to re-fetch ExternalCluster")
+			return ctrl.Result{}, err
+		}
+	}
+
+	argoCDManager, err := r.handleIntegrationWithArgoCD(ctx, req, externalCluster)
+	if err != nil {
+		return requeueWhenRateLimited(err)
+	}
+
+	connectIn, err := r.handleClusterRegistration(ctx, req, argoCDManager, externalCluster)
+	if err != nil {
+		return requeueWhenRateLimited(err)
+	}
+	return ctrl.Result{RequeueAfter: connectIn}, nil
+}
+
+// handleIntegrationWithArgoCD reads the kubeconfig of the cluster and creates the Registrar to register
+// it within ArgoCD. When the server of the kubeconfig changes the registration of the previous one,
+// recorded in the status, is removed.
+func (r *ExternalClusterReconciler) handleIntegrationWithArgoCD(ctx context.Context, req ctrl.Request,
+	externalCluster *argocdv1beta1.ExternalCluster) (argocd.Registrar, error) {
+	kubeconfigContent, err := r.getKubeConfigFromSecret(ctx, externalCluster)
+	if err != nil {
+		r.Log.Error(err, "Failed to get KubeConfigFromSecret")
+		return nil, r.handleError(ctx, req, externalCluster, status.ReasonKubeconfigNotFound,
+			fmt.Sprintf("Unable to gathering kubeConfig: %s", err), err)
+	}
+
+	server, err := argocd.KubeConfigServer(kubeconfigContent)
+	if err != nil {
+		r.Log.Error(err, "Failed to get the server of the cluster from the kubeConfig")
+		return nil, r.handleError(ctx, req, externalCluster, status.ReasonKubeconfigInvalid,
+			fmt.Sprintf("Unable to get the server of the cluster from the kubeConfig: %s", err), err)
+	}
+
+	options, err := externalClusterOptions(externalCluster)
+	if err != nil {
+		r.Log.Error(err, "Failed to render the name of the cluster within ArgoCD")
+		return nil, r.handleError(ctx, req, externalCluster, status.ReasonInvalidNameTemplate,
+			fmt.Sprintf("Unable to render the name of the cluster within ArgoCD: %s", err), err)
+	}
+
+	if externalCluster.Status.Server != "" && externalCluster.Status.Server != server {
+		if err := r.handleServerChange(ctx, req, externalCluster, server, options); err != nil {
+			return nil, err
+		}
+	}
+	if externalCluster.Status.ClusterName != options.Name || externalCluster.Status.Server != server {
+		if err := r.Get(ctx, req.NamespacedName, externalCluster); err != nil {
+			r.Log.Error(err, "Failed to get ExternalCluster")
+			return nil, err
+		}
+		externalCluster.Status.ClusterName = options.Name
+		externalCluster.Status.Server = server
+		if err := r.updateStatus(ctx, externalCluster); err != nil {
+			r.Log.Error(err, "Failed to update ExternalCluster status")
+			return nil, err
+		}
+	}
+
+	options.Server = server
+	argoCDManager, err := r.newRegistrar(ctx, externalCluster, kubeconfigContent, options)
+	if err != nil {
+		r.Log.Error(err, "Failed to gathering pre-requirements to connect with ArgoCD")
+		return nil, r.handleError(ctx, req, externalCluster, status.ReasonArgoCDSetupFailed,
+			fmt.Sprintf("Unable to gathering pre-requirements to connect with ArgoCD: %s", err), err)
+	}
+	return argoCDManager, nil
+}
+
+// handleServerChange will remove the registration of the previous server of the cluster, recorded
+// in the status, so that no dead registration is left behind within ArgoCD
+func (r *ExternalClusterReconciler) handleServerChange(ctx context.Context, req ctrl.Request,
+	externalCluster *argocdv1beta1.ExternalCluster, server string, options argocd.ClusterOptions) error {
+	previousServer := externalCluster.Status.Server
+	r.Log.Info("Server of the cluster changed, removing the registration of the previous one",
+		"previousServer", previousServer, "server", server)
+
+	options.Server = previousServer
+	staleRegistrar, err := r.newRegistrar(ctx, externalCluster, nil, options)
+	if err == nil {
+		err = staleRegistrar.UnRegisterCluster(ctx)
+	}
+	if err != nil {
+		r.Log.Error(err, "Failed to remove the registration of the previous server")
+		return r.handleError(ctx, req, externalCluster, argocd.ErrorReason(err),
+			fmt.Sprintf("Unable to remove the registration of the previous server %s: %s", previousServer, err), err)
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to get ExternalCluster")
+		return err
+	}
+	message := fmt.Sprintf("Server of the cluster changed from %s to %s, "+
+		"the registration of the previous one was removed", previousServer, server)
+	if r.Recorder != nil {
+		r.Recorder.Event(externalCluster, "Normal", "EndpointChanged", message)
+	}
+	meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+		Status: metav1.ConditionFalse, Reason: status.ReasonEndpointChanged, Message: message})
+	externalCluster.Status.Server = server
+	if err := r.updateStatus(ctx, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to update ExternalCluster status")
+		return err
+	}
+	return nil
+}
+
+// handleClusterRegistration will verify if the cluster is or not registered, if not register it.
+// It returns when the connection state must be checked again, zero when ArgoCD is connected to the cluster.
+func (r *ExternalClusterReconciler) handleClusterRegistration(ctx context.Context, req ctrl.Request,
+	argoCDManager argocd.Registrar, externalCluster *argocdv1beta1.ExternalCluster) (time.Duration, error) {
+	isClusterRegistered, err := argoCDManager.IsClusterRegistered(ctx)
+	if err != nil {
+		r.Log.Error(err, "Failed to Check Cluster Registration")
+		return 0, r.handleError(ctx, req, externalCluster, argocd.ErrorReason(err),
+			fmt.Sprintf("Unable to verify Cluster Registration: %s", err), err)
+	}
+
+	if isClusterRegistered {
+		if _, err := argoCDManager.SyncCluster(ctx); err != nil {
+			r.Log.Error(err, "Failed to Sync Cluster Registration")
+			return 0, r.handleError(ctx, req, externalCluster, argocd.ErrorReason(err),
+				fmt.Sprintf("Unable to sync Cluster Registration: %s", err), err)
+		}
+	} else {
+		if err := argoCDManager.RegisterCluster(ctx); err != nil {
+			r.Log.Error(err, "Failed to Register Cluster into ArgoCD")
+			return 0, r.handleError(ctx, req, externalCluster, argocd.ErrorReason(err),
+				fmt.Sprintf("Unable to register Cluster into ArgoCD: %s", err), err)
+		}
+		if r.Recorder != nil {
+			r.Recorder.Event(externalCluster, "Normal", "Registered",
+				fmt.Sprintf("Cluster %s was registered within ArgoCD", externalCluster.Status.ClusterName))
+		}
+	}
+
+	// Verify the registration so that we are able to distinguish when the cluster is registered
+	// from when it is registered but ArgoCD is unable to connect to it
+	var connErr *argocd.ConnectionError
+	verifyErr := argoCDManager.Verify(ctx)
+	if verifyErr != nil && !errors.As(verifyErr, &connErr) {
+		r.Log.Error(verifyErr, "Failed to Check Cluster Registration")
+		return 0, r.handleError(ctx, req, externalCluster, argocd.ErrorReason(verifyErr),
+			fmt.Sprintf("Unable to verify Cluster Registration: %s", verifyErr), verifyErr)
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to get ExternalCluster")
+		return 0, err
+	}
+	meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{Type: status.ConditionRegistered,
+		Status: metav1.ConditionTrue, Reason: status.ReasonRegistered,
+		Message: fmt.Sprintf("Cluster is registered within ArgoCD as %s", externalCluster.Status.ClusterName)})
+	connectIn := time.Duration(0)
+	switch {
+	case connErr != nil && connErr.Status == argocd.ConnectionStatusFailed:
+		r.Log.Info("Cluster is Registered but ArgoCD is unable to connect to it", "message", connErr.Message)
+		meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+			Status: metav1.ConditionFalse, Reason: status.ReasonConnectionFailed,
+			Message: fmt.Sprintf("Cluster is Registered but ArgoCD is unable to connect to it: %s", connErr.Message)})
+		connectIn = connectionPollInterval
+	case connErr != nil:
+		r.Log.Info("Cluster is Registered but ArgoCD is not connected to it yet", "status", connErr.Status)
+		meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+			Status: metav1.ConditionFalse, Reason: status.ReasonWaitingForConnection,
+			Message: fmt.Sprintf("Cluster is Registered but ArgoCD is not connected to it yet (status: %s)",
+				connErr.Status)})
+		connectIn = connectionPollInterval
+	default:
+		meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+			Status: metav1.ConditionTrue, Reason: status.ReasonRegistrationSucceeded, Message: "Cluster is Registered"})
+	}
+	meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+		Status: metav1.ConditionFalse, Reason: status.ReasonRegistrationSucceeded,
+		Message: "Cluster registration is up to date"})
+	if err := r.updateStatus(ctx, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to update ExternalCluster status")
+		return 0, err
+	}
+	return connectIn, nil
+}
+
+// handleFinalizer will remove the registration of the cluster from ArgoCD before allowing the
+// Kubernetes API to delete the ExternalCluster CR. The kubeconfig is not required to do so, therefore,
+// the registration is removed even when its Secret was already deleted.
+func (r *ExternalClusterReconciler) handleFinalizer(ctx context.Context, req ctrl.Request,
+	externalCluster *argocdv1beta1.ExternalCluster) error {
+	if !controllerutil.ContainsFinalizer(externalCluster, externalClusterFinalizer) {
+		return nil
+	}
+
+	r.Log.Info("Performing Finalizer Operations for ExternalCluster before delete CR")
+	if externalCluster.Status.Server != "" {
+		options, err := externalClusterOptions(externalCluster)
+		if err == nil {
+			options.Name = externalCluster.Status.ClusterName
+			options.Server = externalCluster.Status.Server
+			var argoCDManager argocd.Registrar
+			if argoCDManager, err = r.newRegistrar(ctx, externalCluster, nil, options); err == nil {
+				err = argoCDManager.UnRegisterCluster(ctx)
+			}
+		}
+		if err != nil {
+			r.Log.Error(err, "Failed to Unregister Cluster from ArgoCD")
+			if err := r.Get(ctx, req.NamespacedName, externalCluster); err != nil {
+				r.Log.Error(err, "Failed to get ExternalCluster")
+				return err
+			}
+			var rateLimitedErr *argocd.RateLimitedError
+			if errors.As(err, &rateLimitedErr) {
+				return r.handleRateLimited(ctx, externalCluster, rateLimitedErr)
+			}
+			meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{
+				Type: status.ConditionDegraded, Status: metav1.ConditionUnknown, Reason: status.ReasonFinalizing,
+				Message: fmt.Sprintf("Error to perform required operations: %s", err)})
+			if err := r.updateStatus(ctx, externalCluster); err != nil {
+				r.Log.Error(err, "Failed to update ExternalCluster status")
+				return err
+			}
+			return err
+		}
+		if r.Recorder != nil {
+			r.Recorder.Event(externalCluster, "Normal", "Unregistered",
+				fmt.Sprintf("Cluster %s was unregistered from ArgoCD", externalCluster.Status.ClusterName))
+		}
+	}
+
+	r.Log.Info("Removing Finalizer for ExternalCluster after successfully perform the operations")
+	if err := r.Get(ctx, req.NamespacedName, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to re-fetch ExternalCluster")
+		return err
+	}
+	controllerutil.RemoveFinalizer(externalCluster, externalClusterFinalizer)
+	if err := r.Update(ctx, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to update ExternalCluster to remove finalizer")
+		return err
+	}
+	return nil
+}
+
+// handleError will report the error via the Degraded condition and returns it so that the
+// reconciliation is requeued, when the requests were rate limited it is reported as progressing instead
+func (r *ExternalClusterReconciler) handleError(ctx context.Context, req ctrl.Request,
+	externalCluster *argocdv1beta1.ExternalCluster, reason, message string, err error) error {
+	if err := r.Get(ctx, req.NamespacedName, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to get ExternalCluster")
+		return err
+	}
+	var rateLimitedErr *argocd.RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return r.handleRateLimited(ctx, externalCluster, rateLimitedErr)
+	}
+	meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+		Status: metav1.ConditionTrue, Reason: reason, Message: message})
+	if err := r.updateStatus(ctx, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to update ExternalCluster status")
+		return err
+	}
+	return err
+}
+
+// handleRateLimited will report that the requests were rate limited by ArgoCD and returns the error
+// so that the reconciliation is requeued after the delay informed
+func (r *ExternalClusterReconciler) handleRateLimited(ctx context.Context,
+	externalCluster *argocdv1beta1.ExternalCluster, rateLimitedErr *argocd.RateLimitedError) error {
+	r.Log.Info("ArgoCD API is rate limiting the requests", "retryAfter", rateLimitedErr.RetryAfter.String())
+	meta.SetStatusCondition(&externalCluster.Status.Conditions, metav1.Condition{Type: status.ConditionProgressing,
+		Status: metav1.ConditionTrue, Reason: status.ReasonRateLimited,
+		Message: fmt.Sprintf("ArgoCD API is rate limiting the requests, retrying in %s", rateLimitedErr.RetryAfter)})
+	if err := r.updateStatus(ctx, externalCluster); err != nil {
+		r.Log.Error(err, "Failed to update ExternalCluster status")
+		return err
+	}
+	return rateLimitedErr
+}
+
+// updateStatus updates the status of the ExternalCluster recording the generation which was observed
+// in it and in its conditions, so that the consumers can tell whether they reflect the latest spec
+func (r *ExternalClusterReconciler) updateStatus(ctx context.Context,
+	externalCluster *argocdv1beta1.ExternalCluster) error {
+	externalCluster.Status.ObservedGeneration = externalCluster.Generation
+	status.SetObservedGeneration(externalCluster.Status.Conditions, externalCluster.Generation)
+	redactConditions(externalCluster.Status.Conditions)
+	return r.Status().Update(ctx, externalCluster)
+}
+
+// newRegistrar returns the Registrar used to register the cluster within ArgoCD. Since there is no
+// Cluster API Cluster, the metadata of the ExternalCluster is used to build the cluster entry.
+func (r *ExternalClusterReconciler) newRegistrar(ctx context.Context,
+	externalCluster *argocdv1beta1.ExternalCluster, kubeConfig []byte, options argocd.ClusterOptions) (
+	argocd.Registrar, error) {
+	newRegistrar := r.NewRegistrar
+	if newRegistrar == nil {
+		newRegistrar = argocd.NewRegistrar
+	}
+	instance, err := argoCDInstance(ctx, r.Client, r.ArgoCDInstance)
+	if err != nil {
+		return nil, err
+	}
+	options.Instance = instance
+	return newRegistrar(ctx, r.Client, r.Log, registrationMode(externalCluster.Spec.RegistrationMode),
+		externalClusterObject(externalCluster), kubeConfig, options)
+}
+
+// externalClusterObject returns the Cluster which represents the ExternalCluster so that the same
+// registration machinery used for the Cluster API Clusters can be used
+func externalClusterObject(externalCluster *argocdv1beta1.ExternalCluster) *clusterapiv1.Cluster {
+	return &clusterapiv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        externalCluster.Name,
+			Namespace:   externalCluster.Namespace,
+			Labels:      externalCluster.Labels,
+			Annotations: externalCluster.Annotations,
+		},
+	}
+}
+
+// externalClusterOptions returns the settings of the cluster entry. The name informed in the
+// ExternalCluster is used as is, otherwise, it is rendered as the name of the Cluster API Clusters.
+func externalClusterOptions(externalCluster *argocdv1beta1.ExternalCluster) (argocd.ClusterOptions, error) {
+	options := argocd.ClusterOptions{
+		Name:             externalCluster.Spec.Name,
+		Namespaces:       externalCluster.Spec.Namespaces,
+		ClusterResources: externalCluster.Spec.ClusterResources,
+		Project:          externalCluster.Spec.Project,
+	}
+	if options.Name != "" {
+		return options, nil
+	}
+	name, err := argocd.ClusterName(externalClusterObject(externalCluster))
+	options.Name = name
+	return options, err
+}
+
+// getKubeConfigFromSecret returns the kubeconfig stored in the Secret referenced in the ExternalCluster
+func (r *ExternalClusterReconciler) getKubeConfigFromSecret(ctx context.Context,
+	externalCluster *argocdv1beta1.ExternalCluster) ([]byte, error) {
+	secretKey := externalClusterSecretKey(externalCluster)
+	ctx, span := tracing.Start(ctx, "Fetch kubeconfig Secret",
+		tracing.ClusterAttributes(externalCluster.Name, externalCluster.Namespace)...)
+	defer span.End()
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, secretKey, secret); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+	key := externalCluster.Spec.KubeConfigSecretRef.Key
+	if key == "" {
+		key = capisecret.KubeconfigDataName
+	}
+	kubeconfig, exists := secret.Data[key]
+	if !exists {
+		return nil, tracing.RecordError(span, fmt.Errorf("%s not found in secret %s", key, secretKey))
+	}
+	return kubeconfig, nil
+}
+
+// externalClusterSecretKey returns the key of the Secret which stores the kubeconfig of the cluster,
+// which is in the namespace of the ExternalCluster when its namespace is not informed
+func externalClusterSecretKey(externalCluster *argocdv1beta1.ExternalCluster) client.ObjectKey {
+	ref := externalCluster.Spec.KubeConfigSecretRef
+	if ref.Namespace == "" {
+		return client.ObjectKey{Namespace: externalCluster.Namespace, Name: ref.Name}
+	}
+	return client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}
+}
+
+// secretToRequests maps the Secrets to the ExternalClusters which reference them so that the changes
+// of the kubeconfig, i.e. a new server, are reconciled
+func (r *ExternalClusterReconciler) secretToRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	externalClusters := &argocdv1beta1.ExternalClusterList{}
+	if err := r.List(ctx, externalClusters); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ExternalClusters")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(externalClusters.Items))
+	for i := range externalClusters.Items {
+		externalCluster := &externalClusters.Items[i]
+		if externalClusterSecretKey(externalCluster) == client.ObjectKeyFromObject(obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(externalCluster)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ExternalClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter: r.RateLimiter}).
+		For(&argocdv1beta1.ExternalCluster{}, builder.WithPredicates(specOrMetadataChanged)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretToRequests)).
+		Complete(r)
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/argocd/mocks"
+	"github.com/workload-operator/internal/status"
+)
+
+var _ = Describe("ExternalCluster controller", func() {
+	const ExternalClusterNamespace = "mocks-externalcluster"
+
+	ctx := context.Background()
+	typeNamespaceName := types.NamespacedName{Name: "production-eks", Namespace: ExternalClusterNamespace}
+	secretName := types.NamespacedName{Name: "production-eks-kubeconfig", Namespace: ExternalClusterNamespace}
+	externalCluster := &argocdv1beta1.ExternalCluster{}
+
+	reconcileExternalCluster := func(reconciler *ExternalClusterReconciler) (reconcile.Result, error) {
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespaceName})
+		Expect(k8sClient.Get(ctx, typeNamespaceName, externalCluster)).To(Succeed())
+		return result, err
+	}
+
+	BeforeEach(func() {
+		By("Creating the Namespace to perform the tests")
+		err := k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ExternalClusterNamespace}})
+		Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
+
+		By("Creating the Secret with the kubeconfig of the cluster")
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: secretName.Name, Namespace: secretName.Namespace},
+			Data:       map[string][]byte{"value": []byte(mocks.MockKubeConfig)},
+		})).To(Succeed())
+
+		By("Creating the ExternalCluster")
+		Expect(k8sClient.Create(ctx, &argocdv1beta1.ExternalCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: typeNamespaceName.Name, Namespace: typeNamespaceName.Namespace},
+			Spec: argocdv1beta1.ExternalClusterSpec{
+				KubeConfigSecretRef: argocdv1beta1.KubeConfigSecretReference{Name: secretName.Name},
+				Namespaces:          []string{"apps"},
+			},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		By("Removing the ExternalCluster and the Secret")
+		found := &argocdv1beta1.ExternalCluster{}
+		if err := k8sClient.Get(ctx, typeNamespaceName, found); err == nil {
+			found.Finalizers = nil
+			Expect(k8sClient.Update(ctx, found)).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, found))).To(Succeed())
+		}
+		_ = k8sClient.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: secretName.Name, Namespace: secretName.Namespace}})
+	})
+
+	It("should register the cluster and remove its registration when it is deleted", func() {
+		registrar := &fakeRegistrar{}
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &ExternalClusterReconciler{
+			Client:       k8sClient,
+			Scheme:       k8sClient.Scheme(),
+			Recorder:     recorder,
+			NewRegistrar: registrar.factory,
+		}
+
+		_, err := reconcileExternalCluster(reconciler)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(registrar.registered).To(BeTrue())
+		Expect(string(registrar.kubeConfig)).To(Equal(mocks.MockKubeConfig))
+		Expect(registrar.options.Name).To(Equal(typeNamespaceName.Name))
+		Expect(registrar.options.Server).To(Equal("https://your-cluster-server-here"))
+		Expect(registrar.options.Namespaces).To(Equal([]string{"apps"}))
+		Expect(recorder.Events).To(Receive(ContainSubstring("Registered")))
+
+		By("Checking that the ExternalCluster is Available")
+		Expect(externalCluster.Finalizers).To(ContainElement(externalClusterFinalizer))
+		Expect(meta.IsStatusConditionTrue(externalCluster.Status.Conditions, status.ConditionAvailable)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(externalCluster.Status.Conditions, status.ConditionDegraded)).To(BeTrue())
+		Expect(externalCluster.Status.ClusterName).To(Equal(typeNamespaceName.Name))
+		Expect(externalCluster.Status.Server).To(Equal("https://your-cluster-server-here"))
+
+		By("Removing the registration when the ExternalCluster is deleted")
+		Expect(k8sClient.Delete(ctx, externalCluster)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespaceName})
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(registrar.unregistered).To(Equal([]string{"https://your-cluster-server-here"}))
+		err = k8sClient.Get(ctx, typeNamespaceName, externalCluster)
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should remove the registration of the previous server when the kubeconfig changes", func() {
+		registrar := &fakeRegistrar{}
+		reconciler := &ExternalClusterReconciler{
+			Client:       k8sClient,
+			Scheme:       k8sClient.Scheme(),
+			NewRegistrar: registrar.factory,
+		}
+		_, err := reconcileExternalCluster(reconciler)
+		Expect(err).To(Not(HaveOccurred()))
+
+		By("Pointing the kubeconfig to a new server")
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, secretName, secret)).To(Succeed())
+		secret.Data["value"] = []byte(strings.ReplaceAll(mocks.MockKubeConfig,
+			"https://your-cluster-server-here", "https://new-server:6443"))
+		Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+
+		By("Checking that the Secret is mapped to the ExternalCluster")
+		Expect(reconciler.secretToRequests(ctx, secret)).To(Equal([]reconcile.Request{
+			{NamespacedName: typeNamespaceName}}))
+
+		_, err = reconcileExternalCluster(reconciler)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(registrar.unregistered).To(Equal([]string{"https://your-cluster-server-here"}))
+		Expect(registrar.options.Server).To(Equal("https://new-server:6443"))
+		Expect(externalCluster.Status.Server).To(Equal("https://new-server:6443"))
+	})
+
+	It("should report when ArgoCD is unable to connect to the cluster", func() {
+		registrar := &fakeRegistrar{
+			verifyErr: &argocd.ConnectionError{Status: argocd.ConnectionStatusFailed, Message: "i/o timeout"},
+		}
+		reconciler := &ExternalClusterReconciler{
+			Client:       k8sClient,
+			Scheme:       k8sClient.Scheme(),
+			NewRegistrar: registrar.factory,
+		}
+
+		result, err := reconcileExternalCluster(reconciler)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(result.RequeueAfter).To(Equal(connectionPollInterval))
+		condition := meta.FindStatusCondition(externalCluster.Status.Conditions, status.ConditionAvailable)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("ConnectionFailed"))
+		Expect(meta.IsStatusConditionTrue(externalCluster.Status.Conditions, status.ConditionRegistered)).To(BeTrue())
+	})
+
+	It("should report when the kubeconfig Secret is not found", func() {
+		Expect(k8sClient.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: secretName.Name, Namespace: secretName.Namespace}})).To(Succeed())
+
+		registrar := &fakeRegistrar{}
+		reconciler := &ExternalClusterReconciler{
+			Client:       k8sClient,
+			Scheme:       k8sClient.Scheme(),
+			NewRegistrar: registrar.factory,
+		}
+		_, err := reconcileExternalCluster(reconciler)
+		Expect(err).To(HaveOccurred())
+		Expect(registrar.registered).To(BeFalse())
+		condition := meta.FindStatusCondition(externalCluster.Status.Conditions, status.ConditionDegraded)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("Unable to gathering kubeConfig"))
+	})
+
+	It("should register the cluster within the ArgoCDInstance configured", func() {
+		By("Creating the ArgoCDInstance")
+		shard := int64(2)
+		argoCDInstance := &argocdv1beta1.ArgoCDInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "external"},
+			Spec: argocdv1beta1.ArgoCDInstanceSpec{
+				Endpoint:     "https://argocd-server.gitops.svc",
+				Namespace:    "gitops",
+				DefaultShard: &shard,
+			},
+		}
+		Expect(k8sClient.Create(ctx, argoCDInstance)).To(Succeed())
+		defer func() {
+			Expect(k8sClient.Delete(ctx, argoCDInstance)).To(Succeed())
+		}()
+
+		registrar := &fakeRegistrar{}
+		reconciler := &ExternalClusterReconciler{
+			Client:         k8sClient,
+			Scheme:         k8sClient.Scheme(),
+			NewRegistrar:   registrar.factory,
+			ArgoCDInstance: argoCDInstance.Name,
+		}
+		_, err := reconcileExternalCluster(reconciler)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(registrar.registered).To(BeTrue())
+		Expect(registrar.options.Instance).NotTo(BeNil())
+		Expect(registrar.options.Instance.Endpoint).To(Equal("https://argocd-server.gitops.svc"))
+		Expect(registrar.options.Instance.Namespace).To(Equal("gitops"))
+		Expect(registrar.options.Instance.Shard).To(Equal(&shard))
+	})
+})
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+const (
+	// kubeConfigSecretRefIndex indexes the Registers by the key of the kubeconfig Secret which they reference
+	kubeConfigSecretRefIndex = "spec.kubeconfigSecretRef"
+	// serverIndex indexes the Registers by the server which they registered within ArgoCD
+	serverIndex = "status.server"
+	// clusterRefIndex indexes the ClusterRegisters by the key of the Cluster which they reference
+	clusterRefIndex = "spec.clusterRef"
+	// providerKubeConfigSecretIndex indexes the Clusters by the name of the Secret where their control plane
+	// provider stores the kubeconfig
+	providerKubeConfigSecretIndex = "spec.controlPlaneRef.kubeconfigSecret"
+)
+
+// registerIndexes are the field indexes of the Registers. The ClusterRegisters are indexed by the same
+// fields, as Registers of their Cluster, so that both are looked up together.
+var registerIndexes = map[string]func(register *argocdv1beta1.Register) []string{
+	kubeConfigSecretRefIndex: func(register *argocdv1beta1.Register) []string {
+		if register.Spec.KubeConfigSecretRef == nil {
+			return nil
+		}
+		return []string{kubeConfigSecretRefKey(register).String()}
+	},
+	serverIndex: func(register *argocdv1beta1.Register) []string {
+		if register.Status.Server == "" {
+			return nil
+		}
+		return []string{register.Status.Server}
+	},
+}
+
+// indexFields registers the field indexes of the Registers and the ClusterRegisters in the cache of the
+// Manager, so that the mapping of the Secrets and the lookups made by the reconciliation do not list all
+// the Registers of the fleet
+func (r *RegisterReconciler) indexFields(ctx context.Context, indexer client.FieldIndexer) error {
+	for field, extract := range registerIndexes {
+		extract := extract
+		if err := indexer.IndexField(ctx, &argocdv1beta1.Register{}, field, func(obj client.Object) []string {
+			register, ok := obj.(*argocdv1beta1.Register)
+			if !ok {
+				return nil
+			}
+			return extract(register)
+		}); err != nil {
+			return err
+		}
+		if err := indexer.IndexField(ctx, &argocdv1beta1.ClusterRegister{}, field, func(obj client.Object) []string {
+			clusterRegister, ok := obj.(*argocdv1beta1.ClusterRegister)
+			if !ok {
+				return nil
+			}
+			register := &argocdv1beta1.Register{}
+			clusterRegisterToRegister(clusterRegister, register)
+			return extract(register)
+		}); err != nil {
+			return err
+		}
+	}
+	if err := indexer.IndexField(ctx, &argocdv1beta1.ClusterRegister{}, clusterRefIndex,
+		func(obj client.Object) []string {
+			clusterRegister, ok := obj.(*argocdv1beta1.ClusterRegister)
+			if !ok {
+				return nil
+			}
+			return []string{clusterRegisterCluster(clusterRegister).String()}
+		}); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, r.newClusterObject(), providerKubeConfigSecretIndex,
+		providerKubeConfigSecretName); err != nil {
+		return err
+	}
+	r.fieldIndexed = true
+	return nil
+}
+
+// providerKubeConfigSecretName returns the name of the Secret where the control plane provider of the Cluster
+// stores its kubeconfig, if any
+func providerKubeConfigSecretName(obj client.Object) []string {
+	cluster, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	clusterAPI := &clusterapiv1.Cluster{}
+	if err := clusterFromUnstructured(cluster, clusterAPI); err != nil {
+		return nil
+	}
+	name, _, found, err := argocd.ProviderKubeConfigSecret(clusterAPI)
+	if err != nil || !found {
+		return nil
+	}
+	return []string{name}
+}
+
+// registersIndexed returns the Registers, including the ClusterRegisters as Registers of their Cluster,
+// whose field index has the value informed. The indexes of the cache are used once they are registered,
+// otherwise, i.e. when the client does not read from the cache of the Manager, all of them are filtered.
+func (r *RegisterReconciler) registersIndexed(ctx context.Context, index, value string) ([]argocdv1beta1.Register,
+	error) {
+	if !r.fieldIndexed {
+		registers, err := r.listRegisters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var matching []argocdv1beta1.Register
+		for i := range registers {
+			for _, indexed := range registerIndexes[index](&registers[i]) {
+				if indexed == value {
+					matching = append(matching, registers[i])
+					break
+				}
+			}
+		}
+		return matching, nil
+	}
+
+	registers := &argocdv1beta1.RegisterList{}
+	if err := r.List(ctx, registers, client.MatchingFields{index: value}); err != nil {
+		return nil, err
+	}
+	clusterRegisters := &argocdv1beta1.ClusterRegisterList{}
+	if err := r.List(ctx, clusterRegisters, client.MatchingFields{index: value}); err != nil {
+		return nil, err
+	}
+	items := registers.Items
+	for i := range clusterRegisters.Items {
+		register := argocdv1beta1.Register{}
+		clusterRegisterToRegister(&clusterRegisters.Items[i], &register)
+		items = append(items, register)
+	}
+	return items, nil
+}
+
+// clusterRegistersIndexed returns the ClusterRegisters which reference the Cluster
+func (r *RegisterReconciler) clusterRegistersIndexed(ctx context.Context,
+	cluster client.ObjectKey) ([]argocdv1beta1.ClusterRegister, error) {
+	clusterRegisters := &argocdv1beta1.ClusterRegisterList{}
+	if r.fieldIndexed {
+		err := r.List(ctx, clusterRegisters, client.MatchingFields{clusterRefIndex: cluster.String()})
+		return clusterRegisters.Items, err
+	}
+	if err := r.List(ctx, clusterRegisters); err != nil {
+		return nil, err
+	}
+	var matching []argocdv1beta1.ClusterRegister
+	for i := range clusterRegisters.Items {
+		if clusterRegisterCluster(&clusterRegisters.Items[i]) == cluster {
+			matching = append(matching, clusterRegisters.Items[i])
+		}
+	}
+	return matching, nil
+}
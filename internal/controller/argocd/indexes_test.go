@@ -0,0 +1,123 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+// builderIndexer registers the field indexes in the fake client built, as the cache of the Manager does
+type builderIndexer struct {
+	builder *fake.ClientBuilder
+}
+
+func (b builderIndexer) IndexField(_ context.Context, obj client.Object, field string,
+	extractValue client.IndexerFunc) error {
+	b.builder.WithIndex(obj, field, extractValue)
+	return nil
+}
+
+var _ = Describe("Register field indexes", func() {
+	ctx := context.Background()
+
+	It("should look up the Registers and the ClusterRegisters via the field indexes", func() {
+		builder := fake.NewClientBuilder().WithScheme(k8sClient.Scheme()).WithObjects(
+			&argocdv1beta1.Register{
+				ObjectMeta: metav1.ObjectMeta{Name: "referencing", Namespace: "tenant"},
+				Spec: argocdv1beta1.RegisterSpec{
+					KubeConfigSecretRef: &argocdv1beta1.KubeConfigSecretReference{Name: "shared-credentials"},
+				},
+				Status: argocdv1beta1.RegisterStatus{Server: "referencing:443"},
+			},
+			&argocdv1beta1.Register{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "tenant"}},
+			&argocdv1beta1.ClusterRegister{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-scoped"},
+				Spec: argocdv1beta1.ClusterRegisterSpec{
+					ClusterRef: argocdv1beta1.ClusterReference{Namespace: "tenant-b", Name: "workload"},
+				},
+				Status: argocdv1beta1.RegisterStatus{Server: "workload:443"},
+			},
+		)
+		reconciler := &RegisterReconciler{}
+		Expect(reconciler.indexFields(ctx, builderIndexer{builder: builder})).To(Succeed())
+		Expect(reconciler.fieldIndexed).To(BeTrue())
+		reconciler.Client = builder.Build()
+
+		By("mapping the kubeconfig Secret to the Registers which reference it")
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "shared-credentials", Namespace: "tenant"},
+			Data: map[string][]byte{"value": []byte("kubeconfig")}}
+		Expect(reconciler.secretToRequests(ctx, secret)).To(ConsistOf(reconcile.Request{
+			NamespacedName: client.ObjectKey{Name: "referencing", Namespace: "tenant"}}))
+
+		By("finding the Registers and the ClusterRegisters of a server")
+		registers, err := reconciler.registersIndexed(ctx, serverIndex, "workload:443")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registers).To(HaveLen(1))
+		Expect(client.ObjectKeyFromObject(&registers[0])).To(Equal(client.ObjectKey{Name: "workload",
+			Namespace: "tenant-b"}))
+
+		By("finding the ClusterRegister of a Cluster")
+		clusterRegister, err := reconciler.clusterRegisterOf(ctx, client.ObjectKey{Name: "workload",
+			Namespace: "tenant-b"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusterRegister).NotTo(BeNil())
+		Expect(clusterRegister.Name).To(Equal("cluster-scoped"))
+	})
+
+	It("should look up the Clusters via the kubeconfig Secret of their control plane provider", func() {
+		newCluster := func(name, controlPlane string) *unstructured.Unstructured {
+			cluster := &clusterapiv1.Cluster{
+				TypeMeta:   metav1.TypeMeta{APIVersion: clusterapiv1.GroupVersion.String(), Kind: "Cluster"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tenant"},
+				Spec: clusterapiv1.ClusterSpec{ControlPlaneRef: &corev1.ObjectReference{
+					APIVersion: "controlplane.cluster.x-k8s.io/v1alpha1", Kind: "KamajiControlPlane",
+					Name: controlPlane}},
+			}
+			obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cluster)
+			Expect(err).NotTo(HaveOccurred())
+			return &unstructured.Unstructured{Object: obj}
+		}
+		builder := fake.NewClientBuilder().WithScheme(k8sClient.Scheme()).WithObjects(
+			newCluster("workload", "workload-cp"), newCluster("other", "other-cp"))
+		reconciler := &RegisterReconciler{}
+		Expect(reconciler.indexFields(ctx, builderIndexer{builder: builder})).To(Succeed())
+		reconciler.Client = builder.Build()
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "workload-cp-admin-kubeconfig",
+			Namespace: "tenant"}, Data: map[string][]byte{"admin.conf": []byte("kubeconfig")}}
+		Expect(reconciler.providerKubeConfigSecretRequests(ctx, secret)).To(ConsistOf(reconcile.Request{
+			NamespacedName: client.ObjectKey{Name: "workload", Namespace: "tenant"}}))
+
+		By("ignoring the Secrets which are not the kubeconfig of any control plane")
+		token := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "default-token", Namespace: "tenant"},
+			Data: map[string][]byte{corev1.ServiceAccountTokenKey: []byte("token")}}
+		Expect(reconciler.providerKubeConfigSecretRequests(ctx, token)).To(BeEmpty())
+	})
+})
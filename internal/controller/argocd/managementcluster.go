@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/workload-operator/internal/argocd"
+)
+
+// DefaultManagementClusterName is the name used by ArgoCD for the cluster where it is running
+const DefaultManagementClusterName = "in-cluster"
+
+// managementClusterSyncInterval is the interval to check again the registration of the management
+// cluster, so that it is restored when it was removed or edited out-of-band
+const managementClusterSyncInterval = 5 * time.Minute
+
+// ManagementClusterRegistrar registers the management cluster itself within ArgoCD, so that the whole
+// fleet including the hub is registered consistently. ArgoCD connects to it with its own ServiceAccount,
+// as it does for the in-cluster entry, therefore, no kubeconfig is required.
+type ManagementClusterRegistrar struct {
+	Client client.Client
+	Log    logr.Logger
+
+	// Name of the cluster within ArgoCD, DefaultManagementClusterName when it is not informed
+	Name string
+
+	// NewRegistrar returns the Registrar used to register the cluster within ArgoCD.
+	// When it is not informed argocd.NewRegistrar is used.
+	NewRegistrar argocd.RegistrarFactory
+
+	// ArgoCDInstance is the name of the ArgoCDInstance where the cluster is registered. When it is not
+	// informed the ArgoCD instance is configured via Manager ENV VAR.
+	ArgoCDInstance string
+}
+
+var _ manager.Runnable = &ManagementClusterRegistrar{}
+var _ manager.LeaderElectionRunnable = &ManagementClusterRegistrar{}
+
+// Start registers the management cluster and checks its registration periodically until the
+// Manager is stopped. The registration is kept when the Manager stops.
+func (m *ManagementClusterRegistrar) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := m.register(ctx); err != nil {
+			m.Log.Error(err, "Failed to register the management cluster into ArgoCD")
+		}
+	}, managementClusterSyncInterval)
+	return nil
+}
+
+// NeedLeaderElection returns true so that only the leader registers the management cluster
+func (m *ManagementClusterRegistrar) NeedLeaderElection() bool {
+	return true
+}
+
+// register will register the management cluster within ArgoCD when it is not registered yet,
+// otherwise, its registration is updated when it drifted from the desired state
+func (m *ManagementClusterRegistrar) register(ctx context.Context) error {
+	name := m.Name
+	if name == "" {
+		name = DefaultManagementClusterName
+	}
+	newRegistrar := m.NewRegistrar
+	if newRegistrar == nil {
+		newRegistrar = argocd.NewRegistrar
+	}
+
+	instance, err := argoCDInstance(ctx, m.Client, m.ArgoCDInstance)
+	if err != nil {
+		return err
+	}
+	options := argocd.ClusterOptions{Name: name, Server: argocd.InClusterServer, InCluster: true, Instance: instance}
+	argoCDManager, err := newRegistrar(ctx, m.Client, m.Log, registrationMode(""),
+		&clusterapiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil, options)
+	if err != nil {
+		return err
+	}
+
+	isClusterRegistered, err := argoCDManager.IsClusterRegistered(ctx)
+	if err != nil {
+		return err
+	}
+	if isClusterRegistered {
+		if _, err := argoCDManager.SyncCluster(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+	if err := argoCDManager.RegisterCluster(ctx); err != nil {
+		return err
+	}
+	m.Log.Info("Management cluster was registered within ArgoCD", "name", name, "server", argocd.InClusterServer)
+	return nil
+}
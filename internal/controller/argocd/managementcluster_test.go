@@ -0,0 +1,56 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/workload-operator/internal/argocd"
+)
+
+var _ = Describe("Management cluster registration", func() {
+	ctx := context.Background()
+
+	It("should register the management cluster with the ServiceAccount of ArgoCD", func() {
+		registrar := &fakeRegistrar{}
+		managementCluster := &ManagementClusterRegistrar{
+			Client:       k8sClient,
+			Log:          logr.Discard(),
+			NewRegistrar: registrar.factory,
+		}
+
+		Expect(managementCluster.register(ctx)).To(Succeed())
+		Expect(registrar.registrations).To(Equal(1))
+		Expect(registrar.kubeConfig).To(BeNil())
+		Expect(registrar.options).To(Equal(argocd.ClusterOptions{Name: DefaultManagementClusterName,
+			Server: argocd.InClusterServer, InCluster: true}))
+
+		By("keeping the registration when it is already registered")
+		Expect(managementCluster.register(ctx)).To(Succeed())
+		Expect(registrar.registrations).To(Equal(1))
+
+		By("using the name informed")
+		registrar.registered = false
+		managementCluster.Name = "hub"
+		Expect(managementCluster.register(ctx)).To(Succeed())
+		Expect(registrar.options.Name).To(Equal("hub"))
+	})
+})
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+// metricsCollectTimeout limits how long the Registers are listed from the cache when the metrics are scraped
+const metricsCollectTimeout = 10 * time.Second
+
+// registerConditionDesc describes the gauge which reports the status of the conditions of the Registers
+var registerConditionDesc = prometheus.NewDesc("workload_register_condition",
+	"The status of the conditions of the Registers, 1 for the current status of each condition type.",
+	[]string{"name", "namespace", "type", "status"}, nil)
+
+// RegisterConditionCollector reports the status of the conditions of the Registers, so that the alerting
+// rules can fire on the Clusters which are stuck, i.e. Degraded, without querying the Kubernetes API. The
+// Registers are listed from the cache of the Manager when the metrics are scraped, therefore, the series
+// of the Registers deleted are not reported anymore.
+type RegisterConditionCollector struct {
+	Client client.Reader
+}
+
+var _ prometheus.Collector = &RegisterConditionCollector{}
+
+// Describe sends the descriptor of the gauge reported
+func (c *RegisterConditionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- registerConditionDesc
+}
+
+// Collect sends, for each condition of the Registers, a series per status with 1 for the current one
+func (c *RegisterConditionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsCollectTimeout)
+	defer cancel()
+
+	registers := &argocdv1beta1.RegisterList{}
+	if err := c.Client.List(ctx, registers); err != nil {
+		ctrllog.Log.WithName("metrics").Error(err, "Failed to list Registers")
+		return
+	}
+	for _, register := range registers.Items {
+		for _, condition := range register.Status.Conditions {
+			for _, conditionStatus := range []metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionFalse,
+				metav1.ConditionUnknown} {
+				value := 0.0
+				if condition.Status == conditionStatus {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(registerConditionDesc, prometheus.GaugeValue, value,
+					register.Name, register.Namespace, condition.Type, string(conditionStatus))
+			}
+		}
+	}
+}
+
+// registerMetrics registers the collector of the conditions of the Registers in the registry of the metrics
+// served by the Manager. The collector of a previous Manager, i.e. the one stopped when the namespaces
+// watched changed, is replaced so that the Registers are listed from the cache of the current one.
+func registerMetrics(c client.Reader) error {
+	collector := &RegisterConditionCollector{Client: c}
+	metrics.Registry.Unregister(collector)
+	return metrics.Registry.Register(collector)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/status"
+)
+
+var _ = Describe("Register condition metrics", func() {
+	ctx := context.Background()
+	const namespace = "register-metrics"
+
+	register := &argocdv1beta1.Register{ObjectMeta: metav1.ObjectMeta{Name: "degraded", Namespace: namespace}}
+
+	BeforeEach(func() {
+		By("Creating a Register which is Degraded")
+		err := k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+		Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
+		Expect(k8sClient.Create(ctx, register.DeepCopy())).To(Succeed())
+
+		created := &argocdv1beta1.Register{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(register), created)).To(Succeed())
+		created.Status.Conditions = []metav1.Condition{
+			{Type: status.ConditionDegraded, Status: metav1.ConditionTrue, Reason: "Reconciling",
+				Message: "Failed to register the cluster", LastTransitionTime: metav1.Now()},
+		}
+		Expect(k8sClient.Status().Update(ctx, created)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, register.DeepCopy()))).To(Succeed())
+	})
+
+	It("should report a series per status of the conditions of the Registers", func() {
+		ch := make(chan prometheus.Metric, 100)
+		(&RegisterConditionCollector{Client: k8sClient}).Collect(ch)
+		close(ch)
+
+		values := map[string]float64{}
+		for metric := range ch {
+			written := &dto.Metric{}
+			Expect(metric.Write(written)).To(Succeed())
+			labels := map[string]string{}
+			for _, label := range written.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["namespace"] != namespace {
+				continue
+			}
+			Expect(labels).To(HaveKeyWithValue("name", "degraded"))
+			Expect(labels).To(HaveKeyWithValue("type", status.ConditionDegraded))
+			values[labels["status"]] = written.GetGauge().GetValue()
+		}
+		Expect(values).To(Equal(map[string]float64{"True": 1, "False": 0, "Unknown": 0}))
+	})
+})
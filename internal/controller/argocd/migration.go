@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/status"
+)
+
+// handleRegistrationModeChange migrates the registration of the Cluster to the registration mode selected,
+// i.e. when the spec.registrationMode of the Register or the one provided via the Manager ENV VAR changes, so
+// that a fleet can switch between the API and the Declarative modes without registering its Clusters by hand.
+// ArgoCD stores the clusters registered via its API as cluster Secrets named like the ones of the Declarative
+// mode, therefore, the Cluster is registered again with the new mode, which takes over its cluster entry in
+// place, and the registration of the previous mode is not removed since it is the same cluster entry. Thereby,
+// the Applications which target the Cluster are not affected by the migration.
+func (r *RegisterReconciler) handleRegistrationModeChange(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, argoCDManager argocd.Registrar) error {
+	previousMode, mode := RegisterCR.Status.RegistrationMode, r.registrationMode(RegisterCR)
+	r.Log.Info("Registration mode of the Cluster changed, migrating its registration",
+		"previousMode", previousMode, "mode", mode)
+
+	err := argoCDManager.RegisterCluster(ctx)
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return err
+	}
+	if err != nil {
+		var rateLimitedErr *argocd.RateLimitedError
+		if errors.As(err, &rateLimitedErr) {
+			return r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+		}
+		r.Log.Error(err, "Failed to migrate the registration to the registration mode selected")
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+			Message: fmt.Sprintf("Unable to migrate the registration from the %s mode to the %s mode: %s",
+				previousMode, mode, err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return err
+		}
+		return err
+	}
+
+	message := fmt.Sprintf("Registration mode of the Cluster changed from %s to %s, "+
+		"the registration was migrated", previousMode, mode)
+	if r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Normal", "RegistrationModeChanged", message)
+	}
+	// The Cluster remains Available since its cluster entry was kept while it was migrated
+	if meta.IsStatusConditionTrue(RegisterCR.Status.Conditions, status.ConditionAvailable) {
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+			Status: metav1.ConditionTrue, Reason: status.ReasonRegistrationModeChanged,
+			Message: fmt.Sprintf("Cluster is Registered. %s", message)})
+	}
+	RegisterCR.Status.RegistrationMode = mode
+	RegisterCR.Status.LastRegistrationTime = &metav1.Time{Time: time.Now()}
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// SelectedNamespaces returns, sorted, the names of the Namespaces which match the label selector informed
+func SelectedNamespaces(ctx context.Context, c client.Reader, selector labels.Selector) ([]string, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		names = append(names, namespace.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// NamespaceSelectionReconciler watches the Namespaces and calls OnChange once the Namespaces selected by
+// the label selector are no longer the ones watched by the cache of the Manager, i.e. when a tenant
+// Namespace is labeled, so that the Manager is created again with a cache which watches them.
+type NamespaceSelectionReconciler struct {
+	client.Client
+
+	// Selector selects the Namespaces watched by the cache of the Manager
+	Selector labels.Selector
+	// Namespaces are the Namespaces selected when the Manager was created
+	Namespaces []string
+	// OnChange is called, once, when the Namespaces selected changed
+	OnChange func()
+
+	changed sync.Once
+}
+
+// Reconcile compares the Namespaces selected with the ones watched by the cache of the Manager
+func (r *NamespaceSelectionReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	selected, err := SelectedNamespaces(ctx, r.Client, r.Selector)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if equalNamespaces(selected, r.Namespaces) {
+		return ctrl.Result{}, nil
+	}
+	r.changed.Do(func() {
+		log.FromContext(ctx).Info("The namespaces selected changed, the cache of the Manager is rebuilt",
+			"selector", r.Selector.String(), "previous", r.Namespaces, "selected", selected)
+		r.OnChange()
+	})
+	return ctrl.Result{}, nil
+}
+
+// equalNamespaces returns true when both sorted lists have the same Namespaces
+func equalNamespaces(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SetupWithManager sets up the controller with the Manager. It runs in every replica, since each one
+// has its own cache, rather than only in the leader.
+func (r *NamespaceSelectionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	needLeaderElection := false
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("namespaceselection").
+		For(&corev1.Namespace{}).
+		WithOptions(controller.Options{NeedLeaderElection: &needLeaderElection}).
+		Complete(r)
+}
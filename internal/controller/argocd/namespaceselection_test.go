@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Namespace selection", func() {
+	ctx := context.Background()
+	selector := labels.SelectorFromSet(labels.Set{"workload.com/managed-test": "true"})
+
+	tenant := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-selected",
+		Labels: map[string]string{"workload.com/managed-test": "true"}}}
+
+	BeforeEach(func() {
+		err := k8sClient.Create(ctx, tenant.DeepCopy())
+		Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
+	})
+
+	It("should list the namespaces which match the selector", func() {
+		Expect(SelectedNamespaces(ctx, k8sClient, selector)).To(Equal([]string{"tenant-selected"}))
+	})
+
+	It("should report once that the namespaces selected changed", func() {
+		changes := 0
+		reconciler := &NamespaceSelectionReconciler{Client: k8sClient, Selector: selector,
+			Namespaces: []string{"tenant-selected"}, OnChange: func() { changes++ }}
+
+		By("checking that nothing is reported while the namespaces selected are the ones watched")
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(Equal(0))
+
+		By("removing the label of the namespace")
+		namespace := &corev1.Namespace{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), namespace)).To(Succeed())
+		delete(namespace.Labels, "workload.com/managed-test")
+		Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+
+		for i := 0; i < 2; i++ {
+			_, err = reconciler.Reconcile(ctx, ctrl.Request{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(changes).To(Equal(1))
+
+		namespace.Labels = tenant.Labels
+		Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+	})
+})
@@ -0,0 +1,135 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+// DefaultOrphanSweepInterval is the interval between the sweeps of the OrphanCollector
+const DefaultOrphanSweepInterval = 10 * time.Minute
+
+// OrphanCollector periodically finalizes and deletes the Registers whose Cluster no longer exists. The Registers
+// are deleted by the garbage collector with the Cluster which owns them, and by the controller when the Cluster
+// is deleted first, however, the Registers without owner, i.e. created by hand or restored from a backup, and the
+// ClusterRegisters are never reconciled again when their Cluster was deleted while the operator was down.
+type OrphanCollector struct {
+	Reconciler *RegisterReconciler
+	Log        logr.Logger
+
+	// Interval between the sweeps, DefaultOrphanSweepInterval when it is not informed
+	Interval time.Duration
+}
+
+var _ manager.Runnable = &OrphanCollector{}
+var _ manager.LeaderElectionRunnable = &OrphanCollector{}
+
+// Start sweeps the orphaned Registers once the Manager starts and then periodically until it is stopped
+func (o *OrphanCollector) Start(ctx context.Context) error {
+	interval := o.Interval
+	if interval <= 0 {
+		interval = DefaultOrphanSweepInterval
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := o.sweep(ctx); err != nil {
+			o.Log.Error(err, "Failed to sweep the orphaned Registers")
+		}
+	}, interval)
+	return nil
+}
+
+// NeedLeaderElection returns true so that only the leader deletes the orphaned Registers
+func (o *OrphanCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// sweep reconciles the orphaned Registers, which are deleted and, according to their deletionPolicy, removed
+// from ArgoCD by the controller as when their Cluster is deleted. The ones which fail are swept again next time.
+func (o *OrphanCollector) sweep(ctx context.Context) error {
+	requests, err := o.orphanedRequests(ctx)
+	if err != nil {
+		return err
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+
+	o.Log.Info("Deleting the Registers whose Cluster no longer exists", "registers", len(requests))
+	var failed int
+	for _, request := range requests {
+		ctx := log.IntoContext(ctx, o.Log.WithValues("Cluster", request.NamespacedName))
+		if _, err := o.Reconciler.Reconcile(ctx, request); err != nil {
+			failed++
+		}
+	}
+	o.Log.Info("Orphaned Registers swept", "registers", len(requests), "failed", failed)
+	return nil
+}
+
+// orphanedRequests returns the Clusters which no longer exist but still have a Register. The ClusterRegisters
+// which were never reconciled are waiting for their Cluster to be created, and the paused Registers are being
+// moved by `clusterctl move`, therefore, both are kept.
+func (o *OrphanCollector) orphanedRequests(ctx context.Context) ([]reconcile.Request, error) {
+	r := o.Reconciler
+	clusters := &unstructured.UnstructuredList{}
+	clusters.SetGroupVersionKind(r.clusterGVK().GroupVersion().WithKind(r.clusterGVK().Kind + "List"))
+	if err := r.List(ctx, clusters); err != nil {
+		return nil, err
+	}
+	existing := sets.New[client.ObjectKey]()
+	for i := range clusters.Items {
+		existing.Insert(client.ObjectKeyFromObject(&clusters.Items[i]))
+	}
+	registers := &argocdv1beta1.RegisterList{}
+	if err := r.List(ctx, registers); err != nil {
+		return nil, err
+	}
+	clusterRegisters := &argocdv1beta1.ClusterRegisterList{}
+	if err := r.List(ctx, clusterRegisters); err != nil {
+		return nil, err
+	}
+
+	var requests []reconcile.Request
+	orphaned := func(key client.ObjectKey, obj client.Object) {
+		if !existing.Has(key) && !annotations.HasPaused(obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: key})
+		}
+	}
+	for i := range registers.Items {
+		orphaned(client.ObjectKeyFromObject(&registers.Items[i]), &registers.Items[i])
+	}
+	for i := range clusterRegisters.Items {
+		if controllerutil.ContainsFinalizer(&clusterRegisters.Items[i], registerCRFinalizer) {
+			orphaned(clusterRegisterCluster(&clusterRegisters.Items[i]), &clusterRegisters.Items[i])
+		}
+	}
+	return requests, nil
+}
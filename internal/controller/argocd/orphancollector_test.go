@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+)
+
+var _ = Describe("Orphan collector", func() {
+	ctx := context.Background()
+
+	newClusterRegister := func(name string, finalizers ...string) *argocdv1beta1.ClusterRegister {
+		return &argocdv1beta1.ClusterRegister{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Finalizers: finalizers},
+			Spec: argocdv1beta1.ClusterRegisterSpec{
+				ClusterRef: argocdv1beta1.ClusterReference{Name: name, Namespace: fleetNamespace},
+			},
+		}
+	}
+
+	It("should only sweep the Registers whose Cluster no longer exists", func() {
+		paused := newFleetRegister("paused")
+		paused.Annotations = map[string]string{clusterapiv1.PausedAnnotation: ""}
+
+		reconciler := &RegisterReconciler{Client: newFleetClient(
+			newFleetCluster("existing"), newFleetRegister("existing"), newFleetRegister("orphaned"), paused,
+			newClusterRegister("reconciled", registerCRFinalizer), newClusterRegister("pending"),
+		)}
+		collector := &OrphanCollector{Reconciler: reconciler, Log: logr.Discard()}
+
+		By("keeping the ClusterRegisters which wait for their Cluster to be created")
+		requests, err := collector.orphanedRequests(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(ConsistOf(fleetRequest("orphaned"), fleetRequest("reconciled")))
+	})
+
+	It("should delete the orphaned Registers", func() {
+		reconciler := &RegisterReconciler{Client: newFleetClient(
+			newFleetCluster("existing"), newFleetRegister("existing"), newFleetRegister("orphaned"),
+		)}
+		collector := &OrphanCollector{Reconciler: reconciler, Log: logr.Discard()}
+		Expect(collector.sweep(ctx)).To(Succeed())
+
+		err := reconciler.Get(ctx, fleetRequest("orphaned").NamespacedName, &argocdv1beta1.Register{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(reconciler.Get(ctx, fleetRequest("existing").NamespacedName, &argocdv1beta1.Register{})).To(Succeed())
+	})
+})
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/status"
+)
+
+// permanentError is returned when the reconciliation failed with an error which is not solved by retrying,
+// i.e. when the kubeconfig cannot be decrypted, with the reason reported in the Degraded condition
+type permanentError struct {
+	reason string
+	err    error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// permanentFailure returns the reason of the error when it is not solved by retrying, that is, when ArgoCD
+// denies the operation or rejects the cluster entry. Unauthorized is not permanent since the credentials of the
+// ArgoCD account are read again once they are fixed.
+func permanentFailure(err error) (string, bool) {
+	var permanentErr *permanentError
+	if errors.As(err, &permanentErr) {
+		return permanentErr.reason, true
+	}
+	var apiErr *argocd.APIError
+	if errors.As(err, &apiErr) {
+		switch reason := apiErr.Reason(); reason {
+		case argocd.ReasonPermissionDenied, argocd.ReasonInvalidSpec:
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// permanentFailureBudget returns the number of permanent failures tolerated before the Register Failed
+func (r *RegisterReconciler) permanentFailureBudget() int32 {
+	if r.PermanentFailureBudget <= 0 {
+		return DefaultPermanentFailureBudget
+	}
+	return r.PermanentFailureBudget
+}
+
+// handlePermanentFailure records the failure which is not solved by retrying. The reconciliation is requeued
+// with the controller backoff until the budget is exhausted, then the Register is reported as Failed and it
+// is no longer requeued, rather than calling ArgoCD in a loop which never succeeds.
+func (r *RegisterReconciler) handlePermanentFailure(ctx context.Context, req ctrl.Request, reason string,
+	failure error) (ctrl.Result, error) {
+	RegisterCR := &argocdv1beta1.Register{}
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return ctrl.Result{}, err
+	}
+	RegisterCR.Status.PermanentFailures++
+	if RegisterCR.Status.PermanentFailures < r.permanentFailureBudget() {
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, failure
+	}
+
+	message := fmt.Sprintf("Registration failed after %d attempts: %s. It is retried once the spec of the "+
+		"Register changes or the annotation %s is set", RegisterCR.Status.PermanentFailures, failure,
+		argocdv1beta1.RefreshAnnotation)
+	r.Log.Error(failure, "Registration failed, the Register is no longer reconciled",
+		"failures", RegisterCR.Status.PermanentFailures)
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionFailed,
+		Status: metav1.ConditionTrue, Reason: reason, Message: message})
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return ctrl.Result{}, err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Warning", "Failed", message)
+	}
+	return ctrl.Result{}, nil
+}
+
+// handleFailed returns true while the Register Failed, so that no calls are made to ArgoCD. The reconciliation
+// is restarted when the spec of the Register changed since it Failed, or when it has the RefreshAnnotation,
+// which is removed.
+func (r *RegisterReconciler) handleFailed(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register) (bool, error) {
+	_, refresh := RegisterCR.GetAnnotations()[argocdv1beta1.RefreshAnnotation]
+	if refresh {
+		delete(RegisterCR.Annotations, argocdv1beta1.RefreshAnnotation)
+		if err := r.Update(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register to remove the refresh annotation")
+			return false, err
+		}
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to re-fetch RegisterCR")
+			return false, err
+		}
+	}
+
+	failed := meta.FindStatusCondition(RegisterCR.Status.Conditions, status.ConditionFailed)
+	if failed == nil {
+		return false, nil
+	}
+	specChanged := RegisterCR.Generation != RegisterCR.Status.ObservedGeneration
+	if !refresh && !specChanged {
+		r.Log.V(1).Info("Registration failed, waiting for the spec to change or the Register to be refreshed")
+		return true, nil
+	}
+
+	r.Log.Info("Restarting the reconciliation of the Register which failed", "refreshed", refresh,
+		"specChanged", specChanged)
+	if r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Normal", status.ReasonRefreshed,
+			"The reconciliation of the Register which failed was restarted")
+	}
+	meta.RemoveStatusCondition(&RegisterCR.Status.Conditions, status.ConditionFailed)
+	RegisterCR.Status.PermanentFailures = 0
+	// The status is updated without recording the generation observed, so that the changes of the spec
+	// are still reported as an update by the reconciliation
+	RegisterCR.Status.Phase = registerPhase(RegisterCR)
+	if err := r.Status().Update(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+	"github.com/workload-operator/internal/status"
+)
+
+var _ = Describe("Permanent failures", func() {
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKey{Name: "denied", Namespace: "fleet"}}
+	denied := &argocd.APIError{Operation: "registering cluster", StatusCode: http.StatusForbidden}
+
+	It("should only consider the errors which are not solved by retrying as permanent", func() {
+		reason, permanent := permanentFailure(fmt.Errorf("registering: %w", denied))
+		Expect(permanent).To(BeTrue())
+		Expect(reason).To(Equal(argocd.ReasonPermissionDenied))
+
+		_, permanent = permanentFailure(&argocd.APIError{StatusCode: http.StatusUnauthorized})
+		Expect(permanent).To(BeFalse())
+		_, permanent = permanentFailure(errors.New("the object has been modified"))
+		Expect(permanent).To(BeFalse())
+	})
+
+	It("should stop reconciling the Register once the budget is exhausted until it is refreshed", func() {
+		register := &argocdv1beta1.Register{ObjectMeta: metav1.ObjectMeta{Name: "denied", Namespace: "fleet",
+			Generation: 1}}
+		reconciler := &RegisterReconciler{Log: logr.Discard(), PermanentFailureBudget: 2,
+			Client: fake.NewClientBuilder().WithScheme(k8sClient.Scheme()).WithObjects(register).
+				WithStatusSubresource(register).Build()}
+
+		By("requeueing the reconciliation while the budget is not exhausted")
+		_, err := reconciler.requeueOnError(ctx, req, denied)
+		Expect(err).To(MatchError(denied))
+
+		By("reporting the Register as Failed once the budget is exhausted")
+		result, err := reconciler.requeueOnError(ctx, req, denied)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(reconciler.Get(ctx, req.NamespacedName, register)).To(Succeed())
+		Expect(register.Status.PermanentFailures).To(Equal(int32(2)))
+		Expect(register.Status.Phase).To(Equal(argocdv1beta1.RegisterPhaseFailed))
+		condition := meta.FindStatusCondition(register.Status.Conditions, status.ConditionFailed)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(argocd.ReasonPermissionDenied))
+
+		failed, err := reconciler.handleFailed(ctx, req, register)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failed).To(BeTrue())
+
+		By("restarting the reconciliation once the Register is refreshed")
+		register.Annotations = map[string]string{argocdv1beta1.RefreshAnnotation: "true"}
+		Expect(reconciler.Update(ctx, register)).To(Succeed())
+		failed, err = reconciler.handleFailed(ctx, req, register)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failed).To(BeFalse())
+		Expect(reconciler.Get(ctx, req.NamespacedName, register)).To(Succeed())
+		Expect(register.Annotations).NotTo(HaveKey(argocdv1beta1.RefreshAnnotation))
+		Expect(register.Status.PermanentFailures).To(BeZero())
+		Expect(meta.FindStatusCondition(register.Status.Conditions, status.ConditionFailed)).To(BeNil())
+	})
+})
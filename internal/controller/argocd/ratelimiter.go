@@ -0,0 +1,50 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+)
+
+const (
+	// DefaultRateLimiterBaseDelay is the delay to requeue a request after its first failure, as the
+	// default rate limiter of controller-runtime
+	DefaultRateLimiterBaseDelay = 5 * time.Millisecond
+	// DefaultRateLimiterMaxDelay is the maximum delay to requeue a request which keeps failing, as the
+	// default rate limiter of controller-runtime
+	DefaultRateLimiterMaxDelay = 1000 * time.Second
+)
+
+// NewRateLimiter returns the rate limiter of the workqueue of the controllers which requeues the failed
+// requests with an exponential backoff from the base delay up to the max delay, the defaults are used for
+// the ones not informed. It returns nil when none is informed so that the default rate limiter of
+// controller-runtime, which also limits the overall rate of the requests, is kept.
+func NewRateLimiter(baseDelay, maxDelay time.Duration) ratelimiter.RateLimiter {
+	if baseDelay <= 0 && maxDelay <= 0 {
+		return nil
+	}
+	if baseDelay <= 0 {
+		baseDelay = DefaultRateLimiterBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRateLimiterMaxDelay
+	}
+	return workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay)
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rate limiter", func() {
+	It("should keep the default rate limiter of controller-runtime when no delay is informed", func() {
+		Expect(NewRateLimiter(0, 0)).To(BeNil())
+	})
+
+	It("should requeue the failed requests with an exponential backoff up to the max delay", func() {
+		rateLimiter := NewRateLimiter(time.Second, 3*time.Second)
+		Expect(rateLimiter.When("cluster")).To(Equal(time.Second))
+		Expect(rateLimiter.When("cluster")).To(Equal(2 * time.Second))
+		Expect(rateLimiter.When("cluster")).To(Equal(3 * time.Second))
+		Expect(rateLimiter.NumRequeues("cluster")).To(Equal(3))
+
+		By("Restarting the backoff once the request succeeds")
+		rateLimiter.Forget("cluster")
+		Expect(rateLimiter.When("cluster")).To(Equal(time.Second))
+	})
+
+	It("should use the default of the delay which is not informed", func() {
+		rateLimiter := NewRateLimiter(0, time.Minute)
+		Expect(rateLimiter.When("cluster")).To(Equal(DefaultRateLimiterBaseDelay))
+	})
+})
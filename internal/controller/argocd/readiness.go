@@ -0,0 +1,143 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+const (
+	// readinessCheckInterval is the interval to ping again the ArgoCD instances. The readiness probes
+	// report the result of the last ping, so that they are not delayed by the calls to ArgoCD.
+	readinessCheckInterval = 30 * time.Second
+
+	// readinessCheckTimeout limits how long each ArgoCD instance is pinged
+	readinessCheckTimeout = 10 * time.Second
+)
+
+// errNotCheckedYet is reported by the readiness probes until the ArgoCD instances are pinged once
+var errNotCheckedYet = errors.New("the connectivity to ArgoCD was not checked yet")
+
+// ArgoCDReadinessChecker pings the API of each ArgoCD instance configured, the one configured via Manager
+// ENV VAR and the ArgoCDInstances, so that the Manager is not reported as ready while it cannot reach
+// ArgoCD, i.e. due to a misconfiguration of the endpoint, and the issue is noticed right after the rollout.
+type ArgoCDReadinessChecker struct {
+	Client client.Client
+	Log    logr.Logger
+
+	// ArgoCDInstance is the name of the ArgoCDInstance where the clusters are registered by default.
+	// When it is informed the ArgoCD instance configured via Manager ENV VAR is not pinged.
+	ArgoCDInstance string
+
+	// Ping checks that the API of an ArgoCD instance is reachable. When it is not informed argocd.Ping is used.
+	Ping func(ctx context.Context, client client.Client, log logr.Logger, instance *argocd.Instance) error
+
+	mu      sync.Mutex
+	checked bool
+	err     error
+}
+
+var _ manager.Runnable = &ArgoCDReadinessChecker{}
+var _ manager.LeaderElectionRunnable = &ArgoCDReadinessChecker{}
+var _ healthz.Checker = (&ArgoCDReadinessChecker{}).Check
+
+// Start pings the ArgoCD instances periodically until the Manager is stopped
+func (c *ArgoCDReadinessChecker) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := c.ping(ctx)
+		if err != nil {
+			c.Log.Error(err, "Unable to reach ArgoCD, the Manager is reported as not ready")
+		}
+		c.mu.Lock()
+		c.checked, c.err = true, err
+		c.mu.Unlock()
+	}, readinessCheckInterval)
+	return nil
+}
+
+// NeedLeaderElection returns false so that every replica reports whether it can reach ArgoCD
+func (c *ArgoCDReadinessChecker) NeedLeaderElection() bool {
+	return false
+}
+
+// Check returns the result of the last ping of the ArgoCD instances, it is the checker of the readiness probe
+func (c *ArgoCDReadinessChecker) Check(_ *http.Request) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checked {
+		return errNotCheckedYet
+	}
+	return c.err
+}
+
+// ping pings the ArgoCD instances and returns the errors of the ones which are not reachable
+func (c *ArgoCDReadinessChecker) ping(ctx context.Context) error {
+	ping := c.Ping
+	if ping == nil {
+		ping = argocd.Ping
+	}
+
+	instances := map[string]*argocd.Instance{}
+	// The API is not used to register the clusters declaratively, therefore, the endpoint may not be configured
+	if c.ArgoCDInstance == "" && registrationMode("") != argocdv1beta1.RegistrationModeDeclarative {
+		instances[""] = nil
+	}
+	list := &argocdv1beta1.ArgoCDInstanceList{}
+	if err := c.Client.List(ctx, list); err != nil {
+		return fmt.Errorf("error listing the ArgoCDInstances: %w", err)
+	}
+	for i := range list.Items {
+		instances[list.Items[i].Name] = newInstance(&list.Items[i])
+	}
+
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		pingCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+		err := ping(pingCtx, c.Client, c.Log, instances[name])
+		cancel()
+		if err == nil {
+			continue
+		}
+		if name == "" {
+			errs = append(errs, fmt.Errorf("the ArgoCD API configured via env vars is not reachable: %w", err))
+		} else {
+			errs = append(errs, fmt.Errorf("the ArgoCD API of the ArgoCDInstance %s is not reachable: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
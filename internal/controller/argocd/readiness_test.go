@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+var _ = Describe("ArgoCD readiness check", func() {
+	ctx := context.Background()
+
+	instance := &argocdv1beta1.ArgoCDInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "readiness"},
+		Spec:       argocdv1beta1.ArgoCDInstanceSpec{Endpoint: "https://argocd-server.readiness.svc"},
+	}
+
+	BeforeEach(func() {
+		Expect(k8sClient.Create(ctx, instance.DeepCopy())).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, instance.DeepCopy()))).To(Succeed())
+	})
+
+	It("should not be ready until the ArgoCD instances are pinged", func() {
+		checker := &ArgoCDReadinessChecker{Client: k8sClient, Log: logr.Discard()}
+		Expect(checker.Check(nil)).To(MatchError(errNotCheckedYet))
+	})
+
+	It("should ping the instance configured via env vars and each ArgoCDInstance", func() {
+		var pinged []string
+		checker := &ArgoCDReadinessChecker{Client: k8sClient, Log: logr.Discard(),
+			Ping: func(_ context.Context, _ client.Client, _ logr.Logger, instance *argocd.Instance) error {
+				if instance == nil {
+					pinged = append(pinged, "")
+					return nil
+				}
+				pinged = append(pinged, instance.Name)
+				if instance.Name == "readiness" {
+					return errors.New("connection refused")
+				}
+				return nil
+			}}
+
+		err := checker.ping(ctx)
+		Expect(pinged).To(ContainElements("", "readiness"))
+		Expect(err).To(MatchError(ContainSubstring("the ArgoCD API of the ArgoCDInstance readiness is not reachable")))
+		Expect(err).NotTo(MatchError(ContainSubstring("configured via env vars")))
+
+		By("checking that the instance configured via env vars is not pinged when an ArgoCDInstance is selected")
+		pinged = nil
+		checker.ArgoCDInstance = "readiness"
+		_ = checker.ping(ctx)
+		Expect(pinged).NotTo(ContainElement(""))
+	})
+})
@@ -20,8 +20,13 @@ package argocd
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -29,17 +34,31 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/record"
 	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	capisecret "sigs.k8s.io/cluster-api/util/secret"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
 	"github.com/workload-operator/internal/argocd"
 	"github.com/workload-operator/internal/status"
+	"github.com/workload-operator/internal/tracing"
 )
 
 // RegisterReconciler reconciles a Register object
@@ -48,26 +67,140 @@ type RegisterReconciler struct {
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
 	Log      logr.Logger
+
+	// NewRegistrar returns the Registrar used to register the Cluster within ArgoCD.
+	// When it is not informed argocd.NewRegistrar is used.
+	NewRegistrar argocd.RegistrarFactory
+
+	// ServerVersion returns the version of the API server of the Cluster reached with its kubeconfig.
+	// When it is not informed argocd.ServerVersion is used.
+	ServerVersion func(ctx context.Context, kubeConfig []byte) (string, error)
+
+	// ServiceAccountCredentials returns the kubeconfig which authenticates with the token of the ServiceAccount
+	// used by ArgoCD. When it is not informed argocd.GetServiceAccountCredentials is used.
+	ServiceAccountCredentials func(ctx context.Context, kubeConfig []byte, spec *argocdv1beta1.ServiceAccountSpec,
+		reconcileRBAC bool) (*argocd.ServiceAccountCredentials, error)
+
+	// ClusterGVK is the version of the Cluster API used to read the Clusters. It is discovered from
+	// the management cluster when the controller is set up, v1beta1 is used when it is not informed.
+	ClusterGVK schema.GroupVersionKind
+
+	// MaxConcurrentReconciles is the maximum number of Clusters reconciled concurrently, 1 when it is not
+	// informed. It allows to register the new Clusters without waiting behind the whole fleet.
+	MaxConcurrentReconciles int
+
+	// RateLimiter requeues the failed requests. When it is not informed the default of controller-runtime is used.
+	RateLimiter ratelimiter.RateLimiter
+
+	// FinalizerRetryBudget is the number of failed attempts to remove the registration from ArgoCD
+	// before the finalization is reported as failed. DefaultFinalizerRetryBudget is used when it is not informed.
+	FinalizerRetryBudget int32
+
+	// PermanentFailureBudget is the number of consecutive reconciliations failed with an error which is not
+	// solved by retrying before the Register is reported as Failed and no longer reconciled.
+	// DefaultPermanentFailureBudget is used when it is not informed.
+	PermanentFailureBudget int32
+
+	// ArgoCDInstance is the name of the ArgoCDInstance where the Clusters are registered. When it is not
+	// informed the ArgoCD instance is configured via Manager ENV VAR.
+	ArgoCDInstance string
+
+	// AdoptClusters allows the Registers to adopt the clusters already registered within ArgoCD with the server
+	// of their Cluster, i.e. via the ArgoCD CLI, rather than registering them again
+	AdoptClusters bool
+
+	// clusterRegister is the name of the ClusterRegister used as the Register of the Cluster reconciled
+	clusterRegister string
+
+	// RegistrationWorkers is the number of workers which perform the calls to ArgoCD of the reconciliations,
+	// so that the workers of the controller are not blocked while ArgoCD is slow to respond. The calls are
+	// performed by the reconciliations when it is not informed.
+	RegistrationWorkers int
+
+	// registrations performs the calls to ArgoCD when the RegistrationWorkers are informed
+	registrations *registrationWorkers
+
+	// fieldIndexed is true once the field indexes of the Registers are registered in the cache of the Manager
+	fieldIndexed bool
 }
 
 const registerCRFinalizer = "argocd.register.workload.com/finalizer"
 
+// DefaultFinalizerRetryBudget is the number of failed attempts to remove the registration from ArgoCD
+// before the finalization of a Register is reported as failed
+const DefaultFinalizerRetryBudget = 10
+
+// DefaultPermanentFailureBudget is the number of consecutive reconciliations failed with an error which is not
+// solved by retrying before the Register is reported as Failed
+const DefaultPermanentFailureBudget = 5
+
+// specOrMetadataChanged filters out the updates of the status, which are mostly made by the controller itself
+// and would re-trigger the reconciliation, so that only the changes of the spec, the deletion, the labels and
+// the annotations, i.e. the paused one, are reconciled
+var specOrMetadataChanged = predicate.Or(predicate.GenerationChangedPredicate{},
+	predicate.LabelChangedPredicate{}, predicate.AnnotationChangedPredicate{})
+
+const (
+	// transientBackoffBase is the delay to requeue the reconciliation after the first failure to reach ArgoCD
+	transientBackoffBase = 5 * time.Second
+	// transientBackoffCap is the maximum delay to requeue the reconciliation while ArgoCD is unavailable
+	transientBackoffCap = 5 * time.Minute
+)
+
+// connectionPollInterval is the interval to check again the connection state of a Cluster registered
+// within ArgoCD until ArgoCD is able to connect to it
+const connectionPollInterval = 30 * time.Second
+
 //+kubebuilder:rbac:groups=argocd.workload.com,resources=instances,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=argocd.workload.com,resources=instances/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=argocd.workload.com,resources=instances/finalizers,verbs=update
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch
 
 // Reconcile will reconcile Clusters resources from the API clusters.cluster.x-k8s.io since
 // then represent a Workload Cluster and either Register Instances created and managed into
 // this reconciliation due to the fact its purpose is to ensure the Workload Cluster registration
 // within ArgoCD in the Management Cluster.
 func (r *RegisterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Start(ctx, "Reconcile Cluster", tracing.ClusterAttributes(req.Name, req.Namespace)...)
+	defer span.End()
+	defer clusterLocks.lock(req.NamespacedName)()
+	result, err := r.reconcile(ctx, req)
+	return result, tracing.RecordError(span, err)
+}
+
+// reconcile performs the reconciliation of the Cluster traced by Reconcile
+func (r *RegisterReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	// The Clusters can be reconciled concurrently, therefore, each reconciliation uses its own copy of the
+	// reconciler so that the logger with the values of its request is not shared
+	reconciler := *r
+	r = &reconciler
 	r.Log = log.FromContext(ctx)
 
+	// While the Cluster is registered by the registrationWorkers it is not reconciled, so that it is never
+	// changed concurrently. Once it is registered the result of the registration is returned below.
+	var registration *registrationJob
+	if r.registrations != nil {
+		var inProgress bool
+		if registration, inProgress = r.registrations.take(req.NamespacedName); inProgress {
+			r.Log.V(1).Info("Cluster registration is in progress, it is reconciled again once it completes")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if err := r.useClusterRegister(ctx, req); err != nil {
+		r.Log.Error(err, "Failed to find the ClusterRegister of the Cluster")
+		return ctrl.Result{}, err
+	}
+
 	clusterAPI := &clusterapiv1.Cluster{}
 	RegisterCR := &argocdv1beta1.Register{}
-	if err := r.Get(ctx, req.NamespacedName, clusterAPI); err != nil {
+	if err := r.getCluster(ctx, req.NamespacedName, clusterAPI); err != nil {
 		if !apierrors.IsNotFound(err) {
 			r.Log.Error(err, "Failed to get Cluster CR")
 			return ctrl.Result{}, err
@@ -85,24 +218,58 @@ func (r *RegisterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			return ctrl.Result{}, err
 		}
 
-		// If Register CR exist and is not marked to be deleted then we will mark it
+		// The ClusterRegister can be created before the Cluster, therefore, it is only deleted with the
+		// Cluster once it was reconciled
+		if r.clusterRegister != "" && !controllerutil.ContainsFinalizer(RegisterCR, registerCRFinalizer) {
+			r.Log.Info("Cluster referenced by the ClusterRegister not found, waiting for it to be created")
+			return ctrl.Result{}, nil
+		}
+
+		// If Register CR exist and is not marked to be deleted then we will delete it, i.e. when the
+		// Cluster was removed before the garbage collector deleted it. The deletion timestamp can only
+		// be set by the API server, therefore, the Register is re-fetched so that its finalizer is handled
+		// below according to its deletionPolicy.
 		if isMarkedToBeDeleted := RegisterCR.GetDeletionTimestamp() != nil; !isMarkedToBeDeleted {
-			RegisterCR.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
-			err := r.Client.Update(ctx, RegisterCR)
-			if err != nil {
-				r.Log.Error(err, "Failed to set Deletion Timestamp on Register")
+			r.Log.Info("Cluster was deleted, deleting its Register")
+			if err := r.Delete(ctx, RegisterCR); client.IgnoreNotFound(err) != nil {
+				r.Log.Error(err, "Failed to delete Register")
+				return ctrl.Result{}, err
+			}
+			if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+				if apierrors.IsNotFound(err) {
+					// The Register had no finalizer, therefore, it was removed right away
+					return ctrl.Result{}, nil
+				}
+				r.Log.Error(err, "Failed to re-fetch RegisterCR")
 				return ctrl.Result{}, err
 			}
 		}
 	}
 
+	// Mirroring the Cluster API controllers, the reconciliation is skipped while the Cluster is paused,
+	// i.e. during `clusterctl move`, so that the Cluster is not unregistered during the pivot
+	if annotations.IsPaused(clusterAPI, clusterAPI) {
+		r.Log.Info("Reconciliation is paused for the Cluster")
+		return ctrl.Result{}, nil
+	}
+
 	// Check if Register exist, if not create
 	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
 		if !apierrors.IsNotFound(err) {
 			r.Log.Error(err, "Failed to fetch Register for ArgoCD")
 			return ctrl.Result{}, err
 		}
-		if err = r.createRegisterCR(ctx, clusterAPI, RegisterCR); err != nil {
+		// Only the Clusters selected by the RegistrationPolicies, if any, are registered
+		policy, selected, err := r.registrationPolicy(ctx, clusterAPI)
+		if err != nil {
+			r.Log.Error(err, "Failed to check the RegistrationPolicies which select the Cluster")
+			return ctrl.Result{}, err
+		}
+		if !selected {
+			r.Log.Info("Cluster is not selected by any RegistrationPolicy, it is not registered")
+			return ctrl.Result{}, nil
+		}
+		if err = r.createRegisterCR(ctx, clusterAPI, policy, RegisterCR); err != nil {
 			r.Log.Error(err, "Failed to create Register Instance CR")
 			return ctrl.Result{}, err
 		}
@@ -116,241 +283,1805 @@ func (r *RegisterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 	}
 
-	// Gathering the data, validate and create a argoCDAPIManager to allow us to perform operations
-	// using ArgoCD API
-	argoCDAPIManager, err := r.handleIntegrationWithArgoCDAPI(ctx, req, RegisterCR, clusterAPI)
-	if err != nil {
-		return ctrl.Result{}, err
+	// The Register can be paused as well, i.e. when the Cluster was already removed by `clusterctl move`
+	if annotations.HasPaused(RegisterCR) {
+		r.Log.Info("Reconciliation is paused for the Register")
+		return ctrl.Result{}, nil
 	}
 
-	// Check if RegisterCR is marked to be deleted, if yes then handle finalization
+	// Check if RegisterCR is marked to be deleted, if yes then handle finalization. The kubeconfig is not
+	// required to remove the registration, therefore, it is handled even when its Secret was deleted
 	if isMarkedToBeDeleted := RegisterCR.GetDeletionTimestamp() != nil; isMarkedToBeDeleted {
-		if err := r.handleFinalizer(ctx, RegisterCR, req, argoCDAPIManager); err != nil {
-			return ctrl.Result{}, err
+		if err := r.handleFinalizer(ctx, RegisterCR, req, clusterAPI); err != nil {
+			return r.requeueOnError(ctx, req, err)
 		}
 		// Finalize reconciliation since the Register was marked to be deleted and
 		// all required operations to allow to do so were completed successfully
 		return ctrl.Result{}, nil
 	}
 
-	if err := r.handleClusterRegistration(ctx, req, argoCDAPIManager, RegisterCR); err != nil {
+	if !controllerutil.ContainsFinalizer(RegisterCR, registerCRFinalizer) {
+		r.Log.Info("Adding Finalizer for RegisterCR")
+		controllerutil.AddFinalizer(RegisterCR, registerCRFinalizer)
+		if err := r.Update(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register to add finalizer")
+			return ctrl.Result{}, err
+		}
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to re-fetch RegisterCR")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// When the Register is suspended no calls are made to ArgoCD until it is resumed, unless it is deleted
+	if suspended, err := r.handleSuspend(ctx, req, RegisterCR); err != nil || suspended {
+		return ctrl.Result{}, err
+	}
+
+	// Once the Register Failed no calls are made to ArgoCD until its spec changes or it is refreshed
+	if failed, err := r.handleFailed(ctx, req, RegisterCR); err != nil || failed {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	if r.registrations != nil {
+		if registration != nil && registration.generation == RegisterCR.Generation {
+			return registration.result, registration.err
+		}
+		r.registrations.submit(req.NamespacedName, RegisterCR.Generation,
+			func(ctx context.Context) (ctrl.Result, error) {
+				return r.reconcileRegistration(ctx, req, RegisterCR, clusterAPI)
+			})
+		return ctrl.Result{}, nil
+	}
+	return r.reconcileRegistration(ctx, req, RegisterCR, clusterAPI)
 }
 
-func (r *RegisterReconciler) handleIntegrationWithArgoCDAPI(ctx context.Context, req ctrl.Request,
-	RegisterCR *argocdv1beta1.Register, clusterAPI *clusterapiv1.Cluster) (*argocd.APIManager, error) {
-	kubeconfigContent, err := r.getClusterKubeConfigFromSecret(ctx, req)
+// reconcileRegistration performs the calls to ArgoCD which register the Cluster, update its registration and
+// verify it, and returns when the registration must be verified again
+func (r *RegisterReconciler) reconcileRegistration(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, clusterAPI *clusterapiv1.Cluster) (ctrl.Result, error) {
+	// The spec of the Register changed since the last reconciliation, therefore, the changes of the cluster
+	// entry are reported as an update rather than as a drift. It is checked before the status is updated.
+	specChanged := RegisterCR.Generation != RegisterCR.Status.ObservedGeneration
+
+	// Gathering the data, validate and create a argoCDAPIManager to allow us to perform operations
+	// using ArgoCD API or its cluster Secrets
+	argoCDAPIManager, tokenExpiry, err := r.handleIntegrationWithArgoCDAPI(ctx, req, RegisterCR, clusterAPI)
 	if err != nil {
-		r.Log.Error(err, "Failed to get KubeConfigFromSecret")
-		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
-			r.Log.Error(err, "Failed to get RegisterCR")
-			return nil, err
-		}
-		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
-			Status: metav1.ConditionTrue, Reason: "Error",
-			Message: fmt.Sprintf("Unable to gathering kubeConfig: %s", err)})
-		if err := r.Status().Update(ctx, RegisterCR); err != nil {
-			r.Log.Error(err, "Failed to update Register status")
-			return nil, err
-		}
-		return nil, err
+		return r.requeueOnError(ctx, req, err)
 	}
 
-	// Create the APIManager so that is possible to interact with ArgoCD API
-	argoCDAPIManager, err := argocd.NewAPIManagerWithCluster(ctx, r.Client, r.Log, clusterAPI, kubeconfigContent)
+	connectIn, err := r.handleClusterRegistration(ctx, req, argoCDAPIManager, RegisterCR, specChanged,
+		tokenExpiry)
 	if err != nil {
-		r.Log.Error(err, "Failed to gathering pre-requirements to connect with ArgoCD")
-		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
-			r.Log.Error(err, "Failed to get RegisterCR")
-			return nil, err
-		}
-		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
-			Status: metav1.ConditionTrue, Reason: "Error",
-			Message: fmt.Sprintf("Unable to gathering pre-requirements to connect with ArgoCD: %s", err)})
-		if err := r.Status().Update(ctx, RegisterCR); err != nil {
-			r.Log.Error(err, "Failed to update Register status")
-			return nil, err
+		return r.requeueOnError(ctx, req, err)
+	}
+
+	if err := r.handleKubeConfigRotation(ctx, req, argoCDAPIManager, RegisterCR, clusterAPI); err != nil {
+		return r.requeueOnError(ctx, req, err)
+	}
+
+	rotateIn, err := r.handleTokenRotation(ctx, req, argoCDAPIManager, RegisterCR, tokenExpiry)
+	if err != nil {
+		return r.requeueOnError(ctx, req, err)
+	}
+
+	// The registration is verified again at the verifyInterval, the reconciliation checks that it exists,
+	// has not drifted and that ArgoCD is able to connect to the Cluster, and repairs it otherwise
+	var verifyIn time.Duration
+	if RegisterCR.Spec.VerifyInterval != nil {
+		verifyIn = RegisterCR.Spec.VerifyInterval.Duration
+	}
+	if err := r.resetFailures(ctx, req, RegisterCR); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: earliestRequeue(connectIn, rotateIn, verifyIn)}, nil
+}
+
+// earliestRequeue returns the shortest of the intervals informed, ignoring the ones which are not
+// positive, so that the reconciliation is requeued for the first which is due. It returns zero
+// when none is positive.
+func earliestRequeue(intervals ...time.Duration) time.Duration {
+	var earliest time.Duration
+	for _, interval := range intervals {
+		if interval > 0 && (earliest == 0 || interval < earliest) {
+			earliest = interval
 		}
 	}
-	return argoCDAPIManager, nil
+	return earliest
 }
 
-// handleClusterRegistration  will verify if the Cluster is or not registered, if not register it
-func (r *RegisterReconciler) handleClusterRegistration(ctx context.Context, req ctrl.Request,
-	argoCDManager *argocd.APIManager, RegisterCR *argocdv1beta1.Register) error {
+// handleSuspend will report via the Progressing condition when the Register is suspended, and remove it
+// once the Register is resumed. It returns true when the reconciliation must stop.
+func (r *RegisterReconciler) handleSuspend(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register) (bool, error) {
+	suspended := RegisterCR.Spec.Suspend && RegisterCR.GetDeletionTimestamp() == nil
+	condition := meta.FindStatusCondition(RegisterCR.Status.Conditions, status.ConditionProgressing)
+	wasSuspended := condition != nil && condition.Reason == status.ReasonSuspended
+	if suspended == wasSuspended {
+		if suspended {
+			r.Log.Info("Reconciliation is suspended for the Register")
+		}
+		return suspended, nil
+	}
 
-	isClusterRegistered, err := argoCDManager.IsClusterRegistered()
 	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
 		r.Log.Error(err, "Failed to get RegisterCR")
-		return err
+		return false, err
 	}
-	if err != nil {
-		r.Log.Error(err, "Failed to Check Cluster Registration")
-		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
-			Status: metav1.ConditionTrue, Reason: "Error",
-			Message: fmt.Sprintf("Unable to verify Cluster Registration: %s", err)})
-		if err := r.Status().Update(ctx, RegisterCR); err != nil {
-			r.Log.Error(err, "Failed to update Register status")
-			return err
+	if suspended {
+		r.Log.Info("Reconciliation is suspended for the Register")
+		if r.Recorder != nil {
+			r.Recorder.Event(RegisterCR, "Normal", "Suspended", "Reconciliation of the Register was suspended")
 		}
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionProgressing,
+			Status: metav1.ConditionFalse, Reason: status.ReasonSuspended,
+			Message: "Reconciliation is suspended, no calls are made to ArgoCD until it is resumed"})
+	} else {
+		meta.RemoveStatusCondition(&RegisterCR.Status.Conditions, status.ConditionProgressing)
 	}
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return false, err
+	}
+	return suspended, nil
+}
 
-	if !isClusterRegistered {
-		if err := argoCDManager.RegisterCluster(); err != nil {
-			r.Log.Error(err, "Failed to Register Cluster into ArgoCD")
-			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
-				Status: metav1.ConditionTrue, Reason: "Error",
-				Message: fmt.Sprintf("Unable to register Cluster into ArgoCD: %s", err)})
-			if err := r.Status().Update(ctx, RegisterCR); err != nil {
-				r.Log.Error(err, "Failed to update Register status")
-				return err
-			}
-		}
+// requeueOnError requeues the reconciliation after the delay informed by ArgoCD when the requests were
+// rate limited, and with an exponential backoff, reported via the Progressing condition, while ArgoCD is
+// unavailable, instead of treating them as failures which would flood the logs. Otherwise, the error
+// is returned.
+func (r *RegisterReconciler) requeueOnError(ctx context.Context, req ctrl.Request, err error) (ctrl.Result, error) {
+	var circuitOpenErr *argocd.CircuitOpenError
+	if errors.As(err, &circuitOpenErr) {
+		return r.handleArgoCDUnavailable(ctx, req, circuitOpenErr)
+	}
+	if reason, permanent := permanentFailure(err); permanent {
+		return r.handlePermanentFailure(ctx, req, reason, err)
+	}
+	if !argocd.IsTransient(err) {
+		return requeueWhenRateLimited(err)
 	}
 
-	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
-		Status: metav1.ConditionTrue, Reason: "Reconciling",
-		Message: "Cluster is Registered"})
-	if err := r.Status().Update(ctx, RegisterCR); err != nil {
+	RegisterCR := &argocdv1beta1.Register{}
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return ctrl.Result{}, err
+	}
+	RegisterCR.Status.TransientFailures++
+	delay := transientBackoff(RegisterCR.Status.TransientFailures)
+	r.Log.Info("ArgoCD is unavailable, backing off", "retryIn", delay.String(),
+		"failures", RegisterCR.Status.TransientFailures, "reason", err.Error())
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionProgressing,
+		Status: metav1.ConditionTrue, Reason: status.ReasonBackoff,
+		Message: fmt.Sprintf("ArgoCD is unavailable, retrying in %s after %d failed attempts: %s",
+			delay, RegisterCR.Status.TransientFailures, err)})
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// transientBackoff returns the delay to requeue the reconciliation after the failures informed, doubled
+// after each one from transientBackoffBase up to transientBackoffCap
+func transientBackoff(failures int32) time.Duration {
+	delay := transientBackoffBase
+	for i := int32(1); i < failures && delay < transientBackoffCap; i++ {
+		delay *= 2
+	}
+	if delay > transientBackoffCap {
+		return transientBackoffCap
+	}
+	return delay
+}
+
+// handleArgoCDUnavailable will report that the requests to ArgoCD are failed fast since it is down, and
+// requeues the reconciliation once it is probed again. The requeues are spread with a jitter so that the
+// Registers do not all hit ArgoCD at once when it is back.
+func (r *RegisterReconciler) handleArgoCDUnavailable(ctx context.Context, req ctrl.Request,
+	circuitOpenErr *argocd.CircuitOpenError) (ctrl.Result, error) {
+	RegisterCR := &argocdv1beta1.Register{}
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return ctrl.Result{}, err
+	}
+	r.Log.Info("ArgoCD is unavailable, failing fast", "retryIn", circuitOpenErr.RetryAfter.String())
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{
+		Type: status.ConditionArgoCDUnavailable, Status: metav1.ConditionTrue, Reason: status.ReasonCircuitOpen,
+		Message: circuitOpenErr.Error()})
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: wait.Jitter(circuitOpenErr.RetryAfter, 0.2)}, nil
+}
+
+// resetFailures clears the backoff once the reconciliation succeeds, so that the next time ArgoCD is
+// unavailable the backoff starts again from transientBackoffBase, reports that ArgoCD is available again
+// and restarts the budget of the permanent failures
+func (r *RegisterReconciler) resetFailures(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register) error {
+	if RegisterCR.Status.TransientFailures == 0 && RegisterCR.Status.PermanentFailures == 0 &&
+		meta.FindStatusCondition(RegisterCR.Status.Conditions, status.ConditionArgoCDUnavailable) == nil {
+		return nil
+	}
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return err
+	}
+	RegisterCR.Status.TransientFailures = 0
+	RegisterCR.Status.PermanentFailures = 0
+	condition := meta.FindStatusCondition(RegisterCR.Status.Conditions, status.ConditionProgressing)
+	if condition != nil && condition.Reason == status.ReasonBackoff {
+		meta.RemoveStatusCondition(&RegisterCR.Status.Conditions, status.ConditionProgressing)
+	}
+	meta.RemoveStatusCondition(&RegisterCR.Status.Conditions, status.ConditionArgoCDUnavailable)
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
 		r.Log.Error(err, "Failed to update Register status")
 		return err
 	}
 	return nil
 }
 
-func (r *RegisterReconciler) createRegisterCR(ctx context.Context, clusterAPI *clusterapiv1.Cluster,
-	RegisterCR *argocdv1beta1.Register) error {
-	// Create the Register which will represent the registration with ArgoCD in the cluster
-	newRegister, err := r.generateRegisterCR(clusterAPI)
-	if err != nil {
-		return fmt.Errorf("failed to generate Register CR: %w", err)
+// requeueWhenRateLimited requeues the reconciliation after the delay informed by ArgoCD when the
+// requests were rate limited instead of treating it as a failure
+func requeueWhenRateLimited(err error) (ctrl.Result, error) {
+	var rateLimitedErr *argocd.RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return ctrl.Result{RequeueAfter: rateLimitedErr.RetryAfter}, nil
 	}
+	return ctrl.Result{}, err
+}
 
-	// Let's add here a status "Downgrade" to define that this resource begin its process to be terminated.
+// handleRateLimited will report that the requests were rate limited by ArgoCD and returns the error
+// so that the reconciliation is requeued after the delay informed
+func (r *RegisterReconciler) handleRateLimited(ctx context.Context, RegisterCR *argocdv1beta1.Register,
+	rateLimitedErr *argocd.RateLimitedError) error {
+	r.Log.Info("ArgoCD API is rate limiting the requests", "retryAfter", rateLimitedErr.RetryAfter.String())
 	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionProgressing,
-		Status: metav1.ConditionTrue, Reason: "Creating Register",
-		Message: "Preparing to Register Cluster with ArgoCD"})
-
-	// Create the Register CR in the cluster
-	if err := r.Client.Create(ctx, newRegister); err != nil {
-		return fmt.Errorf("failed to create Register CR: %w", err)
+		Status: metav1.ConditionTrue, Reason: status.ReasonRateLimited,
+		Message: fmt.Sprintf("ArgoCD API is rate limiting the requests, retrying in %s", rateLimitedErr.RetryAfter)})
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return err
 	}
-	return nil
+	return rateLimitedErr
 }
 
-// handleFinalizer will handle the finalization of the Register CR to allow kubernetes API delete it
-func (r *RegisterReconciler) handleFinalizer(ctx context.Context, RegisterCR *argocdv1beta1.Register, req ctrl.Request,
-	argoCDManager *argocd.APIManager) error {
-	if controllerutil.ContainsFinalizer(RegisterCR, registerCRFinalizer) {
-		r.Log.Info("Performing Finalizer Operations for RegisterCR before delete CR")
+// handleIntegrationWithArgoCDAPI creates the Registrar to interact with ArgoCD and returns when the
+// token used by ArgoCD to connect to the Cluster expires, zero when it is long-lived
+func (r *RegisterReconciler) handleIntegrationWithArgoCDAPI(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, clusterAPI *clusterapiv1.Cluster) (argocd.Registrar, time.Time, error) {
+	kubeconfigContent, err := r.getClusterKubeConfigFromSecret(ctx, req, RegisterCR, clusterAPI)
+	if err != nil {
+		r.Log.Error(err, "Failed to get KubeConfigFromSecret")
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return nil, time.Time{}, err
+		}
 		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
-			Status: metav1.ConditionTrue, Reason: "Finalizing",
-			Message: "Performing finalizer operations to delete Register"})
-		if err := r.Status().Update(ctx, RegisterCR); err != nil {
+			Status: metav1.ConditionTrue, Reason: status.ReasonKubeconfigNotFound,
+			Message: fmt.Sprintf("Unable to gathering kubeConfig: %s", err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
 			r.Log.Error(err, "Failed to update Register status")
-			return err
+			return nil, time.Time{}, err
 		}
-		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
-			r.Log.Error(err, "Failed to re-fetch RegisterCR")
-			return err
+		return nil, time.Time{}, err
+	}
+
+	if RegisterCR.Spec.Decryption != nil {
+		kubeconfigContent, err = r.decryptKubeConfig(ctx, RegisterCR, kubeconfigContent)
+		if err != nil {
+			r.Log.Error(err, "Failed to decrypt the kubeConfig")
+			if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to get RegisterCR")
+				return nil, time.Time{}, err
+			}
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+				Status: metav1.ConditionTrue, Reason: status.ReasonKubeconfigInvalid,
+				Message: fmt.Sprintf("Unable to decrypt the kubeConfig: %s", err)})
+			if err := r.updateStatus(ctx, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to update Register status")
+				return nil, time.Time{}, err
+			}
+			return nil, time.Time{}, &permanentError{reason: status.ReasonKubeconfigInvalid, err: err}
 		}
+	}
 
-		// Perform all operations required before remove the finalizer and allow
-		// the Kubernetes API to remove the custom resource.
-		if err := r.doFinalizerOperations(RegisterCR, argoCDManager); err != nil {
+	if RegisterCR.Spec.ValidateConnectivity {
+		if err := r.validateConnectivity(ctx, req, RegisterCR, kubeconfigContent); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	// When the ServiceAccountToken strategy is used ArgoCD connects to the Cluster with the token
+	// of the ServiceAccount instead of the credentials of the kubeconfig
+	var tokenExpiry time.Time
+	if authStrategy(RegisterCR) == argocdv1beta1.AuthStrategyServiceAccountToken {
+		// The ServiceAccount and its RBAC are only reconciled when the spec.serviceAccount changed
+		serviceAccountHash := serviceAccountHash(RegisterCR.Spec.ServiceAccount)
+		reconcileRBAC := RegisterCR.Status.ServiceAccountHash != serviceAccountHash
+		credentials, err := r.serviceAccountCredentials(ctx, req, RegisterCR, kubeconfigContent, reconcileRBAC)
+		if err != nil {
+			r.Log.Error(err, "Failed to gathering the ServiceAccount token from the Cluster")
+			if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to get RegisterCR")
+				return nil, time.Time{}, err
+			}
+			// The RBAC is reconciled again, i.e. when the ServiceAccount was deleted out-of-band
+			RegisterCR.Status.ServiceAccountHash = ""
 			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
-				Status: metav1.ConditionUnknown, Reason: "Finalizing",
-				Message: fmt.Sprintf("Error to perform required operations: %s", err)})
-			if err := r.Status().Update(ctx, RegisterCR); err != nil {
+				Status: metav1.ConditionTrue, Reason: status.ReasonServiceAccountTokenFailed,
+				Message: fmt.Sprintf("Unable to gathering the ServiceAccount token from the Cluster: %s", err)})
+			if err := r.updateStatus(ctx, RegisterCR); err != nil {
 				r.Log.Error(err, "Failed to update Register status")
-				return err
+				return nil, time.Time{}, err
+			}
+			return nil, time.Time{}, err
+		}
+		kubeconfigContent, tokenExpiry = credentials.KubeConfig, credentials.ExpiresAt
+		if reconcileRBAC {
+			if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to get RegisterCR")
+				return nil, time.Time{}, err
+			}
+			RegisterCR.Status.ServiceAccountHash = serviceAccountHash
+			if err := r.updateStatus(ctx, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to update Register status")
+				return nil, time.Time{}, err
 			}
-			return err
 		}
+	} else if RegisterCR.Status.ServiceAccountHash != "" {
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return nil, time.Time{}, err
+		}
+		RegisterCR.Status.ServiceAccountHash = ""
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return nil, time.Time{}, err
+		}
+	}
 
+	// Render the name of the cluster within ArgoCD and record it so that it can be found in ArgoCD
+	options := clusterOptions(RegisterCR)
+	options.Name = RegisterCR.Spec.Name
+	if options.Name == "" {
+		options.Name, err = argocd.ClusterName(clusterAPI)
+	}
+	if err != nil {
+		r.Log.Error(err, "Failed to render the name of the Cluster within ArgoCD")
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return nil, time.Time{}, err
+		}
 		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
-			Status: metav1.ConditionTrue, Reason: "Finalizing",
-			Message: "Cluster is unregister successfully accomplished"})
-		if err := r.Status().Update(ctx, RegisterCR); err != nil {
+			Status: metav1.ConditionTrue, Reason: status.ReasonInvalidNameTemplate,
+			Message: fmt.Sprintf("Unable to render the name of the Cluster within ArgoCD: %s", err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
 			r.Log.Error(err, "Failed to update Register status")
-			return err
+			return nil, time.Time{}, err
 		}
+		return nil, time.Time{}, &permanentError{reason: status.ReasonInvalidNameTemplate, err: err}
+	}
 
-		r.Log.Info("Removing Finalizer for RegisterCR after successfully perform the operations")
+	instanceName := r.instanceName(RegisterCR)
+	options.Instance, err = argoCDInstance(ctx, r.Client, instanceName)
+	if err != nil {
+		r.Log.Error(err, "Failed to gathering the ArgoCDInstance")
 		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
-			r.Log.Error(err, "Failed to re-fetch RegisterCR")
-			return err
-		}
-		if ok := controllerutil.RemoveFinalizer(RegisterCR, registerCRFinalizer); !ok {
-			r.Log.Error(errors.New("failed to remove finalizer from Register CR"), "Unable to finalize:")
-			return nil
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return nil, time.Time{}, err
 		}
-		if err := r.Update(ctx, RegisterCR); err != nil {
-			r.Log.Error(err, "Failed to update Register to remove finalizer")
-			return err
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: status.ReasonArgoCDSetupFailed,
+			Message: fmt.Sprintf("Unable to gathering the ArgoCDInstance %s: %s", instanceName, err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return nil, time.Time{}, err
 		}
+		return nil, time.Time{}, err
 	}
-	return nil
-}
 
-// generateRegisterCR will return the Register Instance to represent on cluster the registration within the ArgoCD API
-func (r *RegisterReconciler) generateRegisterCR(clusterAPI *clusterapiv1.Cluster) (*argocdv1beta1.Register, error) {
-	// Define the Register Resource
-	newRegister := &argocdv1beta1.Register{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      clusterAPI.Name,
-			Namespace: clusterAPI.Namespace,
-		},
+	// The Cluster is registered within the ArgoCDInstance selected and its registration is removed
+	// from the previous one, if any, so that it is not registered within many instances
+	if RegisterCR.Status.Server != "" && RegisterCR.Status.Instance != instanceName {
+		if err := r.handleInstanceChange(ctx, req, RegisterCR, clusterAPI, options); err != nil {
+			return nil, time.Time{}, err
+		}
 	}
 
-	// Set the owner reference for garbage collection if needed
-	return newRegister, controllerutil.SetOwnerReference(clusterAPI, newRegister, r.Scheme)
-}
-
-// getClusterKubeConfigFromSecret will retrieve the kubeConfig stored in the secret of the current
-// namespace. The Cluster Workload kubeconfig is stored in a secret into the namespace
-// therefore we will retrieve it within the assumption that each namespace has only one secret.
-// However, if that is not true, then we must filter ideally by labels or by name
-func (r *RegisterReconciler) getClusterKubeConfigFromSecret(ctx context.Context, req ctrl.Request) ([]byte, error) {
-	// Fetch the associated kubeconfig secret
-	secret := &corev1.Secret{}
-	if err := r.Get(ctx, req.NamespacedName, secret); err != nil {
-		return nil, err
+	// ArgoCD identifies the clusters by their server, therefore, each server is registered by a single Register
+	server := argocd.ServerURL(clusterAPI)
+	if r.AdoptClusters && RegisterCR.Status.Server == "" &&
+		r.registrationMode(RegisterCR) == argocdv1beta1.RegistrationModeAPI {
+		if server, err = r.handleAdoption(ctx, req, RegisterCR, server, &options); err != nil {
+			return nil, time.Time{}, err
+		}
 	}
-
-	// Extract the kubeconfig
-	kubeconfig, exists := secret.Data["kubeconfig"] // or "kubeconfig", depending on the actual key
-	if !exists {
-		return nil, fmt.Errorf("kubeconfig not found in secret")
+	// The server of the cluster entry adopted is kept, it can differ from the one of the Cluster in its scheme
+	if argocd.SameServer(RegisterCR.Status.Server, server) {
+		server = RegisterCR.Status.Server
 	}
-	return kubeconfig, nil
-}
-
-// doFinalizerOperations will perform the required operations before delete the CR.
-func (r *RegisterReconciler) doFinalizerOperations(cr *argocdv1beta1.Register,
-	argoCDManager *argocd.APIManager) error {
-	if err := argoCDManager.UnRegisterCluster(); err != nil {
-		r.Log.Error(err, "Failed to Unregister Cluster from ArgoCD")
-		return err
+	options.Server = server
+	if err := r.handleDuplicateServer(ctx, req, RegisterCR, server, instanceName); err != nil {
+		return nil, time.Time{}, err
 	}
 
-	// The following implementation will raise an event
-	r.Recorder.Event(cr, "Warning", "Deleting",
-		fmt.Sprintf("Register CR %s from the namespace %s will be deleted.",
-			cr.Namespace,
-			cr.Name,
-		))
-
-	return nil
-}
-
-// SetupWithManager sets up the controller with the Manager.
-func (r *RegisterReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).Owns(&argocdv1beta1.Register{}).
-		For(&clusterapiv1.Cluster{}).
-		Owns(&argocdv1beta1.Register{}).
-		Complete(r)
+	// When the control plane endpoint changes the Cluster is registered again and the registration of the
+	// previous one is removed
+	if RegisterCR.Status.Server != "" && RegisterCR.Status.Server != server {
+		if err := r.handleEndpointChange(ctx, req, RegisterCR, clusterAPI, kubeconfigContent, options); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+	if RegisterCR.Status.ClusterName != options.Name || RegisterCR.Status.Server != server ||
+		RegisterCR.Status.Instance != instanceName || RegisterCR.Status.RegistrationMode == "" {
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return nil, time.Time{}, err
+		}
+		RegisterCR.Status.ClusterName = options.Name
+		RegisterCR.Status.Server = server
+		RegisterCR.Status.Instance = instanceName
+		// The mode is recorded once the Cluster is registered, changing it afterwards migrates the registration
+		if RegisterCR.Status.RegistrationMode == "" {
+			RegisterCR.Status.RegistrationMode = r.registrationMode(RegisterCR)
+		}
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return nil, time.Time{}, err
+		}
+	}
+
+	// Create the Registrar so that is possible to interact with ArgoCD
+	argoCDAPIManager, err := r.registrarFactory()(ctx, r.Client, r.Log, r.registrationMode(RegisterCR), clusterAPI,
+		kubeconfigContent, options)
+	if err != nil {
+		r.Log.Error(err, "Failed to gathering pre-requirements to connect with ArgoCD")
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return nil, time.Time{}, err
+		}
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: status.ReasonArgoCDSetupFailed,
+			Message: fmt.Sprintf("Unable to gathering pre-requirements to connect with ArgoCD: %s", err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return nil, time.Time{}, err
+		}
+		return nil, time.Time{}, err
+	}
+
+	// The registration is migrated to the registration mode selected when it changed
+	if RegisterCR.Status.RegistrationMode != r.registrationMode(RegisterCR) {
+		if err := r.handleRegistrationModeChange(ctx, req, RegisterCR, argoCDAPIManager); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	if err := r.handleInsecureSkipVerify(ctx, req, RegisterCR, options.Instance); err != nil {
+		return nil, time.Time{}, err
+	}
+	return argoCDAPIManager, tokenExpiry, nil
+}
+
+// handleDuplicateServer reports the Register as Degraded when the server of the Cluster is already registered
+// within the same ArgoCD instance by another Register, since both would fight over the same cluster entry.
+// The Register which registered it first keeps it, or the oldest one when both did, i.e. when they were
+// reconciled concurrently, and the other one forgets it so that its deletion does not remove the registration.
+func (r *RegisterReconciler) handleDuplicateServer(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, server, instanceName string) error {
+	owner, err := r.serverOwner(ctx, req, RegisterCR, server, instanceName)
+	if err != nil {
+		r.Log.Error(err, "Failed to check the Registers of the server")
+		return err
+	}
+	if owner == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("the server %s is already registered within ArgoCD by the Register of the Cluster %s",
+		server, client.ObjectKeyFromObject(owner))
+	r.Log.Error(err, "Failed to Register Cluster into ArgoCD")
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return err
+	}
+	if RegisterCR.Status.Server == server {
+		RegisterCR.Status.Server = ""
+		meta.RemoveStatusCondition(&RegisterCR.Status.Conditions, status.ConditionRegistered)
+		meta.RemoveStatusCondition(&RegisterCR.Status.Conditions, status.ConditionAvailable)
+	}
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+		Status: metav1.ConditionTrue, Reason: status.ReasonDuplicateServer,
+		Message: fmt.Sprintf("Unable to register Cluster into ArgoCD: %s", err)})
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return err
+	}
+	return err
+}
+
+// serverOwner returns the other Register, if any, which keeps the registration of the server within the
+// ArgoCD instance, including the ClusterRegisters and the ones being deleted, which still remove it
+func (r *RegisterReconciler) serverOwner(ctx context.Context, req ctrl.Request, RegisterCR *argocdv1beta1.Register,
+	server, instanceName string) (*argocdv1beta1.Register, error) {
+	registers, err := r.registersIndexed(ctx, serverIndex, server)
+	if err != nil {
+		return nil, err
+	}
+	for i := range registers {
+		other := &registers[i]
+		if client.ObjectKeyFromObject(other) == req.NamespacedName || other.Status.Instance != instanceName {
+			continue
+		}
+		if RegisterCR.Status.Server != server || registeredBefore(other, RegisterCR) {
+			return other, nil
+		}
+	}
+	return nil, nil
+}
+
+// registeredBefore returns true when the first Register is older than the second one, or precedes it
+// alphabetically when they were created at the same time
+func registeredBefore(a, b *argocdv1beta1.Register) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return client.ObjectKeyFromObject(a).String() < client.ObjectKeyFromObject(b).String()
+}
+
+// validateConnectivity reaches the Cluster with its kubeconfig before it is registered within ArgoCD
+// and records the version of its API server, so that unreachable endpoints or rejected credentials are
+// reported instead of being registered
+func (r *RegisterReconciler) validateConnectivity(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, kubeConfig []byte) error {
+	serverVersion := r.ServerVersion
+	if serverVersion == nil {
+		serverVersion = argocd.ServerVersion
+	}
+
+	version, err := serverVersion(ctx, kubeConfig)
+	if err != nil {
+		r.Log.Error(err, "Failed to connect to the Cluster with the kubeConfig")
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return err
+		}
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: status.ReasonUnreachable,
+			Message: fmt.Sprintf("Unable to connect to the Cluster with the kubeConfig: %s", err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return err
+		}
+		return err
+	}
+
+	if RegisterCR.Status.KubernetesVersion != version {
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return err
+		}
+		RegisterCR.Status.KubernetesVersion = version
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return err
+		}
+	}
+	return nil
+}
+
+// registrarFactory returns the factory used to create the Registrar, argocd.NewRegistrar by default
+func (r *RegisterReconciler) registrarFactory() argocd.RegistrarFactory {
+	if r.NewRegistrar == nil {
+		return argocd.NewRegistrar
+	}
+	return r.NewRegistrar
+}
+
+// handleEndpointChange will remove the registration of the previous control plane endpoint of the
+// Cluster, recorded in the status, so that no dead registration is left behind within ArgoCD
+func (r *RegisterReconciler) handleEndpointChange(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, clusterAPI *clusterapiv1.Cluster, kubeConfig []byte,
+	options argocd.ClusterOptions) error {
+	previousServer, server := RegisterCR.Status.Server, argocd.ServerURL(clusterAPI)
+	r.Log.Info("Control plane endpoint of the Cluster changed, removing the registration of the previous one",
+		"previousServer", previousServer, "server", server)
+
+	options.Server = previousServer
+	staleRegistrar, err := r.registrarFactory()(ctx, r.Client, r.Log, r.registrationMode(RegisterCR), clusterAPI,
+		kubeConfig, options)
+	if err == nil {
+		err = staleRegistrar.UnRegisterCluster(ctx)
+	}
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return err
+	}
+	if err != nil {
+		var rateLimitedErr *argocd.RateLimitedError
+		if errors.As(err, &rateLimitedErr) {
+			return r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+		}
+		r.Log.Error(err, "Failed to remove the registration of the previous control plane endpoint")
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+			Message: fmt.Sprintf("Unable to remove the registration of the previous control plane endpoint %s: %s",
+				previousServer, err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return err
+		}
+		return err
+	}
+
+	message := fmt.Sprintf("Control plane endpoint of the Cluster changed from %s to %s, "+
+		"the registration of the previous one was removed", previousServer, server)
+	if r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Normal", "EndpointChanged", message)
+	}
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+		Status: metav1.ConditionFalse, Reason: status.ReasonEndpointChanged, Message: message})
+	RegisterCR.Status.Server = server
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return err
+	}
+	return nil
+}
+
+// handleInstanceChange removes the registration of the Cluster from the ArgoCDInstance where it was
+// registered, so that it is registered within the one selected afterwards. The registration is left
+// behind when the previous ArgoCDInstance no longer exists since its ArgoCD cannot be reached.
+func (r *RegisterReconciler) handleInstanceChange(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, clusterAPI *clusterapiv1.Cluster, options argocd.ClusterOptions) error {
+	previousInstance, instance := RegisterCR.Status.Instance, r.instanceName(RegisterCR)
+	r.Log.Info("ArgoCDInstance of the Cluster changed, removing the registration from the previous one",
+		"previousInstance", previousInstance, "instance", instance)
+
+	options.Name = RegisterCR.Status.ClusterName
+	options.Server = RegisterCR.Status.Server
+	var err error
+	options.Instance, err = argoCDInstance(ctx, r.Client, previousInstance)
+	if err == nil {
+		var staleRegistrar argocd.Registrar
+		staleRegistrar, err = r.registrarFactory()(ctx, r.Client, r.Log, r.registrationMode(RegisterCR), clusterAPI,
+			nil, options)
+		if err == nil {
+			err = staleRegistrar.UnRegisterCluster(ctx)
+		}
+	} else if apierrors.IsNotFound(err) {
+		r.Log.Info("Previous ArgoCDInstance not found, its registration of the Cluster is left behind",
+			"previousInstance", previousInstance)
+		err = nil
+	}
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return err
+	}
+	if err != nil {
+		var rateLimitedErr *argocd.RateLimitedError
+		if errors.As(err, &rateLimitedErr) {
+			return r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+		}
+		r.Log.Error(err, "Failed to remove the registration from the previous ArgoCDInstance")
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+			Message: fmt.Sprintf("Unable to remove the registration from the previous ArgoCDInstance %s: %s",
+				previousInstance, err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return err
+		}
+		return err
+	}
+
+	message := fmt.Sprintf("ArgoCDInstance of the Cluster changed from %q to %q, "+
+		"the registration within the previous one was removed", previousInstance, instance)
+	if r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Normal", "InstanceChanged", message)
+	}
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+		Status: metav1.ConditionFalse, Reason: status.ReasonInstanceChanged, Message: message})
+	RegisterCR.Status.Instance = instance
+	RegisterCR.Status.Server = argocd.ServerURL(clusterAPI)
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return err
+	}
+	return nil
+}
+
+// instanceName returns the name of the ArgoCDInstance where the Cluster is registered. The one referenced
+// by the Register CR takes precedence over the one selected via the Manager flag, when none is informed
+// the ArgoCD instance configured via Manager ENV VAR is used.
+func (r *RegisterReconciler) instanceName(RegisterCR *argocdv1beta1.Register) string {
+	if RegisterCR.Spec.InstanceRef != nil && RegisterCR.Spec.InstanceRef.Name != "" {
+		return RegisterCR.Spec.InstanceRef.Name
+	}
+	return r.ArgoCDInstance
+}
+
+// authStrategy returns how ArgoCD authenticates to the Cluster. When the strategy is not defined
+// in the Register CR it is defaulted from the credentials informed, and when none are informed
+// the credentials of the kubeconfig are embedded.
+func authStrategy(RegisterCR *argocdv1beta1.Register) argocdv1beta1.AuthStrategy {
+	switch {
+	case RegisterCR.Spec.AuthStrategy != "":
+		return RegisterCR.Spec.AuthStrategy
+	case RegisterCR.Spec.ServiceAccount != nil:
+		return argocdv1beta1.AuthStrategyServiceAccountToken
+	case RegisterCR.Spec.AWSAuth != nil:
+		return argocdv1beta1.AuthStrategyAWSAuth
+	case RegisterCR.Spec.ExecProvider != nil:
+		return argocdv1beta1.AuthStrategyExecProvider
+	default:
+		return argocdv1beta1.AuthStrategyEmbeddedTLSConfig
+	}
+}
+
+// serviceAccountCredentials returns the kubeconfig which authenticates with the token of the ServiceAccount used
+// by ArgoCD. The tokens bound to the TokenExpiration are only requested when there is none issued for the Register,
+// when the one held by ArgoCD must be rotated or when the kubeconfig or the spec.serviceAccount changed, otherwise,
+// the credentials in use are returned so that the cluster entry is not rewritten by every reconciliation.
+func (r *RegisterReconciler) serviceAccountCredentials(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, kubeConfig []byte, reconcileRBAC bool) (*argocd.ServiceAccountCredentials, error) {
+	getCredentials := r.ServiceAccountCredentials
+	if getCredentials == nil {
+		getCredentials = argocd.GetServiceAccountCredentials
+	}
+	spec := RegisterCR.Spec.ServiceAccount
+	if spec == nil || spec.TokenExpiration == nil {
+		return getCredentials(ctx, kubeConfig, spec, reconcileRBAC)
+	}
+
+	hash := sha256.New()
+	_, _ = hash.Write(kubeConfig)
+	_, _ = hash.Write([]byte(serviceAccountHash(spec)))
+	source := fmt.Sprintf("%x", hash.Sum(nil))
+	if credentials := issuedTokens.current(req.NamespacedName, source, RegisterCR); credentials != nil {
+		return credentials, nil
+	}
+	credentials, err := getCredentials(ctx, kubeConfig, spec, reconcileRBAC)
+	if err != nil {
+		issuedTokens.forget(req.NamespacedName)
+		return nil, err
+	}
+	issuedTokens.store(req.NamespacedName, source, credentials)
+	return credentials, nil
+}
+
+// serviceAccountHash returns the hash of the spec of the ServiceAccount used by ArgoCD, which identifies the
+// ServiceAccount and the RBAC created in the workload Cluster
+func serviceAccountHash(spec *argocdv1beta1.ServiceAccountSpec) string {
+	data, _ := json.Marshal(spec)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// clusterOptions returns how ArgoCD connects to the Cluster when it should not use the settings
+// of the kubeconfig, and the settings of the cluster entry
+func clusterOptions(RegisterCR *argocdv1beta1.Register) argocd.ClusterOptions {
+	options := argocd.ClusterOptions{
+		Namespaces:       RegisterCR.Spec.Namespaces,
+		ClusterResources: RegisterCR.Spec.ClusterResources,
+		Project:          RegisterCR.Spec.Project,
+		ProxyURL:         RegisterCR.Spec.ProxyURL,
+	}
+	switch authStrategy(RegisterCR) {
+	case argocdv1beta1.AuthStrategyAWSAuth:
+		options.AWSAuthConfig = &argocd.AWSAuthConfig{
+			ClusterName: RegisterCR.Spec.AWSAuth.ClusterName,
+			RoleARN:     RegisterCR.Spec.AWSAuth.RoleARN,
+		}
+	case argocdv1beta1.AuthStrategyExecProvider:
+		options.ExecProviderConfig = argocd.NewExecProviderConfig(RegisterCR.Spec.ExecProvider)
+	}
+	return options
+}
+
+// handleInsecureSkipVerify will warn, via event and status condition, when the verification of the
+// ArgoCD API certificate is disabled so that it cannot be enabled silently in production. The setting of
+// the ArgoCDInstance takes precedence over the one provided via the Manager ENV VAR.
+func (r *RegisterReconciler) handleInsecureSkipVerify(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, instance *argocd.Instance) error {
+	insecureSkipVerify, _ := argocd.InsecureSkipVerify()
+	source := argocd.InsecureSkipVerifyEnvVar
+	if instance != nil {
+		insecureSkipVerify = instance.InsecureSkipVerify
+		source = "the ArgoCDInstance " + instance.Name
+	}
+	if r.registrationMode(RegisterCR) != argocdv1beta1.RegistrationModeAPI {
+		insecureSkipVerify = false
+	}
+	if !insecureSkipVerify && meta.FindStatusCondition(RegisterCR.Status.Conditions, status.ConditionInsecure) == nil {
+		return nil
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return err
+	}
+	if insecureSkipVerify {
+		message := fmt.Sprintf("The verification of the ArgoCD API certificate is disabled via %s. "+
+			"This option must not be used in production", source)
+		if r.Recorder != nil {
+			r.Recorder.Event(RegisterCR, "Warning", "InsecureSkipVerify", message)
+		}
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionInsecure,
+			Status: metav1.ConditionTrue, Reason: status.ReasonInsecureSkipVerify, Message: message})
+	} else {
+		meta.RemoveStatusCondition(&RegisterCR.Status.Conditions, status.ConditionInsecure)
+	}
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return err
+	}
+	return nil
+}
+
+// registrationMode returns the mode used to register the Cluster within ArgoCD. The mode defined
+// in the Register CR takes precedence over the one provided via the Manager ENV VAR.
+func (r *RegisterReconciler) registrationMode(RegisterCR *argocdv1beta1.Register) argocdv1beta1.RegistrationMode {
+	return registrationMode(RegisterCR.Spec.RegistrationMode)
+}
+
+// registrationMode returns the mode informed or, when it is not informed, the one provided via the
+// Manager ENV VAR which defaults to API
+func registrationMode(mode argocdv1beta1.RegistrationMode) argocdv1beta1.RegistrationMode {
+	if mode != "" {
+		return mode
+	}
+	if mode, exists := os.LookupEnv(argocd.RegistrationModeEnvVar); exists {
+		return argocdv1beta1.RegistrationMode(mode)
+	}
+	return argocdv1beta1.RegistrationModeAPI
+}
+
+// handleClusterRegistration  will verify if the Cluster is or not registered, if not register it.
+// It returns when the connection state must be checked again, zero when ArgoCD is connected to the Cluster.
+// The changes of the cluster entry are reported as an update when the spec of the Register changed. The expiry
+// of the ServiceAccount token which the Cluster is registered with is recorded along with its registration.
+func (r *RegisterReconciler) handleClusterRegistration(ctx context.Context, req ctrl.Request,
+	argoCDManager argocd.Registrar, RegisterCR *argocdv1beta1.Register, specChanged bool,
+	tokenExpiry time.Time) (time.Duration, error) {
+
+	isClusterRegistered, err := argoCDManager.IsClusterRegistered(ctx)
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return 0, err
+	}
+	var rateLimitedErr *argocd.RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return 0, r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+	}
+	if err != nil {
+		r.Log.Error(err, "Failed to Check Cluster Registration")
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+			Message: fmt.Sprintf("Unable to verify Cluster Registration: %s", err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return 0, err
+		}
+		// The registration is unknown, therefore, it must not be reported as removed out-of-band
+		return 0, err
+	}
+
+	// driftCorrected is true when the registration was changed or removed out-of-band and restored, while
+	// updated is true when the cluster entry was changed with the spec of the Register
+	driftCorrected, updated := false, false
+	if isClusterRegistered {
+		driftCorrected, err = argoCDManager.SyncCluster(ctx)
+		if errors.As(err, &rateLimitedErr) {
+			return 0, r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+		}
+		if err != nil {
+			r.Log.Error(err, "Failed to Sync Cluster Registration")
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+				Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+				Message: fmt.Sprintf("Unable to sync Cluster Registration: %s", err)})
+			if err := r.updateStatus(ctx, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to update Register status")
+				return 0, err
+			}
+			return 0, err
+		}
+		updated, driftCorrected = driftCorrected && specChanged, driftCorrected && !specChanged
+	}
+
+	if !isClusterRegistered {
+		// The Cluster was already registered, therefore, its registration was removed out-of-band
+		driftCorrected = meta.IsStatusConditionTrue(RegisterCR.Status.Conditions, status.ConditionAvailable)
+		if err := argoCDManager.RegisterCluster(ctx); err != nil {
+			driftCorrected = false
+			if errors.As(err, &rateLimitedErr) {
+				return 0, r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+			}
+			r.Log.Error(err, "Failed to Register Cluster into ArgoCD")
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+				Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+				Message: fmt.Sprintf("Unable to register Cluster into ArgoCD: %s", err)})
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionRegistered,
+				Status: metav1.ConditionFalse, Reason: argocd.ErrorReason(err),
+				Message: fmt.Sprintf("Unable to register Cluster into ArgoCD: %s", err)})
+			if err := r.updateStatus(ctx, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to update Register status")
+				return 0, err
+			}
+			return 0, err
+		}
+		if !driftCorrected && r.Recorder != nil {
+			r.Recorder.Event(RegisterCR, "Normal", "Registered",
+				fmt.Sprintf("Cluster %s was registered within ArgoCD", RegisterCR.Status.ClusterName))
+		}
+		// ArgoCD holds the token just issued, therefore, it must not be pushed again as if it was rotated
+		if !tokenExpiry.IsZero() {
+			RegisterCR.Status.TokenExpiry = &metav1.Time{Time: tokenExpiry}
+		}
+	}
+	if !isClusterRegistered || driftCorrected || updated {
+		RegisterCR.Status.LastRegistrationTime = &metav1.Time{Time: time.Now()}
+	}
+	if updated && r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Normal", "Updated",
+			fmt.Sprintf("Cluster %s was updated within ArgoCD with the changes of the Register",
+				RegisterCR.Status.ClusterName))
+	}
+
+	// Verify the registration so that we are able to distinguish when the Cluster is registered
+	// from when it is registered but ArgoCD is unable to connect to it
+	if err := argoCDManager.Verify(ctx); err != nil {
+		if errors.As(err, &rateLimitedErr) {
+			return 0, r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+		}
+		var connErr *argocd.ConnectionError
+		if !errors.As(err, &connErr) {
+			r.Log.Error(err, "Failed to Check Cluster Registration")
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+				Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+				Message: fmt.Sprintf("Unable to verify Cluster Registration: %s", err)})
+			if err := r.updateStatus(ctx, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to update Register status")
+				return 0, err
+			}
+			return 0, err
+		}
+		r.recordVerification(ctx, argoCDManager, RegisterCR)
+
+		// The Cluster is only Available once ArgoCD reports that it is able to connect to it, therefore,
+		// the connection state is polled until then
+		if connErr.Status == argocd.ConnectionStatusFailed {
+			r.Log.Info("Cluster is Registered but ArgoCD is unable to connect to it", "message", connErr.Message)
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+				Status: metav1.ConditionFalse, Reason: status.ReasonConnectionFailed,
+				Message: fmt.Sprintf("Cluster is Registered but ArgoCD is unable to connect to it: %s", connErr.Message)})
+		} else {
+			r.Log.Info("Cluster is Registered but ArgoCD is not connected to it yet", "status", connErr.Status)
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+				Status: metav1.ConditionFalse, Reason: status.ReasonWaitingForConnection,
+				Message: fmt.Sprintf("Cluster is Registered but ArgoCD is not connected to it yet (status: %s)",
+					connErr.Status)})
+		}
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return 0, err
+		}
+		return connectionPollInterval, nil
+	}
+	r.recordVerification(ctx, argoCDManager, RegisterCR)
+
+	// The event is raised once ArgoCD connects to the Cluster, and not on every verification which follows
+	if !meta.IsStatusConditionTrue(RegisterCR.Status.Conditions, status.ConditionAvailable) && r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Normal", "Verified",
+			fmt.Sprintf("ArgoCD is connected to the Cluster %s", RegisterCR.Status.ClusterName))
+	}
+	if driftCorrected {
+		message := "Cluster registration drifted from the desired state and it was corrected"
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(RegisterCR, "Normal", "DriftCorrected", message)
+		}
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+			Status: metav1.ConditionTrue, Reason: status.ReasonDriftCorrected,
+			Message: fmt.Sprintf("Cluster is Registered. %s", message)})
+	} else {
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionAvailable,
+			Status: metav1.ConditionTrue, Reason: status.ReasonRegistrationSucceeded,
+			Message: "Cluster is Registered"})
+	}
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return 0, err
+	}
+	return 0, nil
+}
+
+// recordVerification records in the status that the cluster entry exists within ArgoCD, when it was verified
+// and how the Cluster is registered, so that it can be audited without querying ArgoCD. Failing to describe
+// the registration is not fatal, the values previously recorded are kept.
+func (r *RegisterReconciler) recordVerification(ctx context.Context, argoCDManager argocd.Registrar,
+	RegisterCR *argocdv1beta1.Register) {
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionRegistered,
+		Status: metav1.ConditionTrue, Reason: status.ReasonRegistered,
+		Message: fmt.Sprintf("Cluster is registered within ArgoCD as %s", RegisterCR.Status.ClusterName)})
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+		Status: metav1.ConditionFalse, Reason: status.ReasonRegistrationSucceeded,
+		Message: "Cluster registration is up to date"})
+	RegisterCR.Status.LastVerifiedTime = &metav1.Time{Time: time.Now()}
+	info, err := argoCDManager.Describe(ctx)
+	if err != nil {
+		r.Log.Error(err, "Failed to describe the Cluster Registration")
+		return
+	}
+	RegisterCR.Status.ArgoCDClusterID = info.ClusterID
+	RegisterCR.Status.ArgoCDServerVersion = info.ServerVersion
+}
+
+// handleKubeConfigRotation will push the new credentials to ArgoCD when the kubeconfig of the Cluster
+// was rotated, i.e. by Cluster API, so that ArgoCD does not lose the access to the Cluster. The rotation
+// is detected by comparing the hash of the kubeconfig with the one recorded in the status.
+func (r *RegisterReconciler) handleKubeConfigRotation(ctx context.Context, req ctrl.Request,
+	argoCDManager argocd.Registrar, RegisterCR *argocdv1beta1.Register, clusterAPI *clusterapiv1.Cluster) error {
+	kubeconfigContent, err := r.getClusterKubeConfigFromSecret(ctx, req, RegisterCR, clusterAPI)
+	if err != nil {
+		r.Log.Error(err, "Failed to get KubeConfigFromSecret")
+		return err
+	}
+	kubeConfigHash := fmt.Sprintf("%x", sha256.Sum256(kubeconfigContent))
+	if RegisterCR.Status.KubeConfigHash == kubeConfigHash {
+		return nil
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return err
+	}
+	// The hash is only recorded when the Cluster is registered for the first time since the
+	// registration was performed with the current kubeconfig
+	if RegisterCR.Status.KubeConfigHash != "" {
+		if err := argoCDManager.RegisterCluster(ctx); err != nil {
+			var rateLimitedErr *argocd.RateLimitedError
+			if errors.As(err, &rateLimitedErr) {
+				return r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+			}
+			r.Log.Error(err, "Failed to push the rotated kubeconfig credentials to ArgoCD")
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+				Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+				Message: fmt.Sprintf("Unable to push the rotated kubeconfig credentials to ArgoCD: %s", err)})
+			if err := r.updateStatus(ctx, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to update Register status")
+				return err
+			}
+			return err
+		}
+
+		message := "Kubeconfig of the Cluster was rotated, the new credentials were pushed to ArgoCD"
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(RegisterCR, "Normal", "CredentialsRotated", message)
+		}
+	}
+	RegisterCR.Status.KubeConfigHash = kubeConfigHash
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return err
+	}
+	return nil
+}
+
+// handleTokenRotation will rotate the token used by ArgoCD to connect to the Cluster before it expires
+// by updating the registration with the new token issued. It returns the duration until the next
+// rotation, zero when the token is long-lived.
+func (r *RegisterReconciler) handleTokenRotation(ctx context.Context, req ctrl.Request,
+	argoCDManager argocd.Registrar, RegisterCR *argocdv1beta1.Register, tokenExpiry time.Time) (time.Duration, error) {
+	if tokenExpiry.IsZero() {
+		if RegisterCR.Status.TokenExpiry == nil {
+			return 0, nil
+		}
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to get RegisterCR")
+			return 0, err
+		}
+		RegisterCR.Status.TokenExpiry = nil
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return 0, err
+		}
+		return 0, nil
+	}
+
+	// The token is rotated when 80% of its lifetime has elapsed, when a new token is issued. Until then the token
+	// in use is the one recorded in the status, which is held by ArgoCD.
+	refreshWindow := RegisterCR.Spec.ServiceAccount.TokenExpiration.Duration / 5
+	if sameExpiry(RegisterCR.Status.TokenExpiry, tokenExpiry) {
+		return time.Until(tokenExpiry.Add(-refreshWindow)), nil
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to get RegisterCR")
+		return 0, err
+	}
+	if err := argoCDManager.RegisterCluster(ctx); err != nil {
+		var rateLimitedErr *argocd.RateLimitedError
+		if errors.As(err, &rateLimitedErr) {
+			return 0, r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+		}
+		r.Log.Error(err, "Failed to rotate the ServiceAccount token within ArgoCD")
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: argocd.ErrorReason(err),
+			Message: fmt.Sprintf("Unable to rotate the ServiceAccount token within ArgoCD: %s", err)})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return 0, err
+		}
+		return 0, err
+	}
+
+	message := fmt.Sprintf("ServiceAccount token used by ArgoCD was rotated, it expires at %s",
+		tokenExpiry.UTC().Format(time.RFC3339))
+	r.Log.Info(message)
+	if r.Recorder != nil {
+		r.Recorder.Event(RegisterCR, "Normal", "CredentialsRotated", message)
+	}
+	RegisterCR.Status.TokenExpiry = &metav1.Time{Time: tokenExpiry}
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return 0, err
+	}
+	return time.Until(tokenExpiry.Add(-refreshWindow)), nil
+}
+
+func (r *RegisterReconciler) createRegisterCR(ctx context.Context, clusterAPI *clusterapiv1.Cluster,
+	policy *argocdv1beta1.RegistrationPolicy, RegisterCR *argocdv1beta1.Register) error {
+	// Create the Register which will represent the registration with ArgoCD in the cluster
+	newRegister, err := r.generateRegisterCR(clusterAPI)
+	if err != nil {
+		return fmt.Errorf("failed to generate Register CR: %w", err)
+	}
+	if policy != nil {
+		if err := applyRegistrationPolicy(newRegister, policy, clusterAPI); err != nil {
+			return fmt.Errorf("failed to generate Register CR: %w", err)
+		}
+	}
+
+	// Report that the Register is being created to represent the registration of the Cluster
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionProgressing,
+		Status: metav1.ConditionTrue, Reason: status.ReasonCreating,
+		Message: "Preparing to Register Cluster with ArgoCD"})
+
+	// Create the Register CR in the cluster
+	if err := r.Client.Create(ctx, newRegister); err != nil {
+		return fmt.Errorf("failed to create Register CR: %w", err)
+	}
+	return nil
+}
+
+// handleFinalizer will handle the finalization of the Register CR to allow kubernetes API delete it
+func (r *RegisterReconciler) handleFinalizer(ctx context.Context, RegisterCR *argocdv1beta1.Register, req ctrl.Request,
+	clusterAPI *clusterapiv1.Cluster) error {
+	if controllerutil.ContainsFinalizer(RegisterCR, registerCRFinalizer) {
+		r.Log.Info("Performing Finalizer Operations for RegisterCR before delete CR")
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: status.ReasonFinalizing,
+			Message: "Performing finalizer operations to delete Register"})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return err
+		}
+		if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to re-fetch RegisterCR")
+			return err
+		}
+
+		// Perform all operations required before remove the finalizer and allow
+		// the Kubernetes API to remove the custom resource.
+		if err := r.doFinalizerOperations(ctx, RegisterCR, clusterAPI); err != nil {
+			var rateLimitedErr *argocd.RateLimitedError
+			if errors.As(err, &rateLimitedErr) {
+				return r.handleRateLimited(ctx, RegisterCR, rateLimitedErr)
+			}
+			// The requests failed fast while ArgoCD is down were not sent, therefore, they are not failures
+			var circuitOpenErr *argocd.CircuitOpenError
+			if errors.As(err, &circuitOpenErr) {
+				return err
+			}
+			// The failures are recorded so that the ForceUnregisterAnnotation can skip the ArgoCD call
+			// once they are reached, i.e. when ArgoCD no longer exists
+			RegisterCR.Status.UnregisterFailures++
+			if RegisterCR.Status.UnregisterFailures >= r.finalizerRetryBudget() {
+				return r.handleFinalizationFailed(ctx, req, RegisterCR, err)
+			}
+			meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+				Status: metav1.ConditionUnknown, Reason: status.ReasonFinalizing,
+				Message: fmt.Sprintf("Error to perform required operations: %s. When ArgoCD is no longer reachable "+
+					"the annotation %s allows to delete the Register without removing the registration",
+					err, argocdv1beta1.ForceUnregisterAnnotation)})
+			if err := r.updateStatus(ctx, RegisterCR); err != nil {
+				r.Log.Error(err, "Failed to update Register status")
+				return err
+			}
+			return err
+		}
+
+		meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+			Status: metav1.ConditionTrue, Reason: status.ReasonFinalizing,
+			Message: "Cluster is unregister successfully accomplished"})
+		if err := r.updateStatus(ctx, RegisterCR); err != nil {
+			r.Log.Error(err, "Failed to update Register status")
+			return err
+		}
+
+		r.Log.Info("Removing Finalizer for RegisterCR after successfully perform the operations")
+		return r.removeFinalizer(ctx, req, RegisterCR)
+	}
+	return nil
+}
+
+// handleFinalizationFailed reports that the retry budget to remove the registration from ArgoCD was
+// exhausted. The finalizer is released when the finalizerFailurePolicy of the Register is Release, leaving
+// the registration behind, otherwise, the finalization keeps being retried with the controller backoff.
+func (r *RegisterReconciler) handleFinalizationFailed(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, finalizerErr error) error {
+	release := RegisterCR.Spec.FinalizerFailurePolicy == argocdv1beta1.FinalizerFailurePolicyRelease
+	message := fmt.Sprintf("Unable to remove the registration from ArgoCD after %d attempts: %s",
+		RegisterCR.Status.UnregisterFailures, finalizerErr)
+	if release {
+		message += ". The finalizer was released and the registration may be left behind within ArgoCD"
+	}
+	r.Log.Error(finalizerErr, "Finalization of the Register failed", "failures", RegisterCR.Status.UnregisterFailures)
+
+	meta.SetStatusCondition(&RegisterCR.Status.Conditions, metav1.Condition{Type: status.ConditionDegraded,
+		Status: metav1.ConditionTrue, Reason: status.ReasonFinalizationFailed, Message: message})
+	if err := r.updateStatus(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register status")
+		return err
+	}
+	// The event is raised once the budget is exhausted, and not on every retry which follows
+	if r.Recorder != nil && (release || RegisterCR.Status.UnregisterFailures == r.finalizerRetryBudget()) {
+		r.Recorder.Event(RegisterCR, "Warning", "FinalizationFailed", message)
+	}
+
+	if !release {
+		return finalizerErr
+	}
+	r.Log.Info("Removing Finalizer for RegisterCR since the finalizerFailurePolicy is Release")
+	return r.removeFinalizer(ctx, req, RegisterCR)
+}
+
+// removeFinalizer removes the finalizer of the Register to allow kubernetes API delete it
+func (r *RegisterReconciler) removeFinalizer(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register) error {
+	if err := r.Get(ctx, req.NamespacedName, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to re-fetch RegisterCR")
+		return err
+	}
+	if ok := controllerutil.RemoveFinalizer(RegisterCR, registerCRFinalizer); !ok {
+		r.Log.Error(errors.New("failed to remove finalizer from Register CR"), "Unable to finalize:")
+		return nil
+	}
+	if err := r.Update(ctx, RegisterCR); err != nil {
+		r.Log.Error(err, "Failed to update Register to remove finalizer")
+		return err
+	}
+	issuedTokens.forget(req.NamespacedName)
+	return nil
+}
+
+// finalizerRetryBudget returns the number of failed attempts to remove the registration from ArgoCD
+// tolerated before the finalization is reported as failed
+func (r *RegisterReconciler) finalizerRetryBudget() int32 {
+	if r.FinalizerRetryBudget <= 0 {
+		return DefaultFinalizerRetryBudget
+	}
+	return r.FinalizerRetryBudget
+}
+
+// updateStatus updates the status of the Register recording the generation which was observed
+// in it and in its conditions, so that the consumers can tell whether they reflect the latest spec,
+// and the phase which summarizes the conditions
+func (r *RegisterReconciler) updateStatus(ctx context.Context, RegisterCR *argocdv1beta1.Register) error {
+	RegisterCR.Status.ObservedGeneration = RegisterCR.Generation
+	status.SetObservedGeneration(RegisterCR.Status.Conditions, RegisterCR.Generation)
+	redactConditions(RegisterCR.Status.Conditions)
+	RegisterCR.Status.Phase = registerPhase(RegisterCR)
+	return r.Status().Update(ctx, RegisterCR)
+}
+
+// redactConditions redacts the sensitive values from the messages of the conditions, which are mostly built
+// from the errors, so that the ArgoCD tokens and the credentials of the kubeconfigs are never reported
+func redactConditions(conditions []metav1.Condition) {
+	for i := range conditions {
+		conditions[i].Message = argocd.Redact(conditions[i].Message)
+	}
+}
+
+// registerPhase returns the phase which summarizes the conditions of the Register
+func registerPhase(RegisterCR *argocdv1beta1.Register) argocdv1beta1.RegisterPhase {
+	conditions := RegisterCR.Status.Conditions
+	switch {
+	case !RegisterCR.GetDeletionTimestamp().IsZero():
+		return argocdv1beta1.RegisterPhaseDeleting
+	case meta.IsStatusConditionTrue(conditions, status.ConditionDegraded) ||
+		meta.IsStatusConditionTrue(conditions, status.ConditionFailed):
+		return argocdv1beta1.RegisterPhaseFailed
+	case meta.IsStatusConditionTrue(conditions, status.ConditionRegistered):
+		return argocdv1beta1.RegisterPhaseRegistered
+	case meta.IsStatusConditionTrue(conditions, status.ConditionProgressing) ||
+		meta.FindStatusCondition(conditions, status.ConditionAvailable) != nil:
+		return argocdv1beta1.RegisterPhaseRegistering
+	default:
+		return argocdv1beta1.RegisterPhasePending
+	}
+}
+
+// generateRegisterCR will return the Register Instance to represent on cluster the registration within the ArgoCD API
+func (r *RegisterReconciler) generateRegisterCR(clusterAPI *clusterapiv1.Cluster) (*argocdv1beta1.Register, error) {
+	// Define the Register Resource
+	newRegister := &argocdv1beta1.Register{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterAPI.Name,
+			Namespace: clusterAPI.Namespace,
+		},
+	}
+
+	// Set the owner reference for garbage collection if needed
+	return newRegister, controllerutil.SetOwnerReference(r.clusterOwner(clusterAPI), newRegister, r.Scheme)
+}
+
+// getClusterKubeConfigFromSecret will retrieve the kubeConfig of the Cluster Workload from the secret
+// referenced in the Register CR when it is informed. Otherwise, Cluster API stores it in the
+// <cluster-name>-kubeconfig secret under the value key. When that secret does not exist the kubeConfig
+// is retrieved from the secret defined by the convention of the control plane provider of the Cluster,
+// i.e. for hosted control planes, then discovered from the secrets labeled with the name of the Cluster
+// and, at last, it is retrieved from the secret with the same name of the Cluster under the kubeconfig key.
+func (r *RegisterReconciler) getClusterKubeConfigFromSecret(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, clusterAPI *clusterapiv1.Cluster) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "Fetch kubeconfig Secret", tracing.ClusterAttributes(req.Name, req.Namespace)...)
+	defer span.End()
+	kubeconfig, err := r.fetchClusterKubeConfig(ctx, req, RegisterCR, clusterAPI)
+	return kubeconfig, tracing.RecordError(span, err)
+}
+
+// fetchClusterKubeConfig retrieves the kubeConfig of the Cluster Workload as described in
+// getClusterKubeConfigFromSecret
+func (r *RegisterReconciler) fetchClusterKubeConfig(ctx context.Context, req ctrl.Request,
+	RegisterCR *argocdv1beta1.Register, clusterAPI *clusterapiv1.Cluster) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if ref := RegisterCR.Spec.KubeConfigSecretRef; ref != nil {
+		secretKey := kubeConfigSecretRefKey(RegisterCR)
+		if err := r.Get(ctx, secretKey, secret); err != nil {
+			return nil, err
+		}
+		key := ref.Key
+		if key == "" {
+			key = capisecret.KubeconfigDataName
+		}
+		kubeconfig, exists := secret.Data[key]
+		if !exists {
+			return nil, fmt.Errorf("%s not found in secret %s", key, secretKey)
+		}
+		return kubeconfig, nil
+	}
+
+	// Fetch the kubeconfig secret created by Cluster API
+	secretName := capisecret.Name(req.Name, capisecret.Kubeconfig)
+	err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: secretName}, secret)
+	if err == nil {
+		kubeconfig, exists := secret.Data[capisecret.KubeconfigDataName]
+		if !exists {
+			return nil, fmt.Errorf("%s not found in secret %s", capisecret.KubeconfigDataName, secretName)
+		}
+		return kubeconfig, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	// Fetch the kubeconfig secret by the convention of the control plane provider
+	providerSecretName, providerKey, found, err := argocd.ProviderKubeConfigSecret(clusterAPI)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: providerSecretName}, secret)
+		if err == nil {
+			kubeconfig, exists := secret.Data[providerKey]
+			if !exists {
+				return nil, fmt.Errorf("%s not found in secret %s", providerKey, providerSecretName)
+			}
+			return kubeconfig, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	kubeconfig, err := r.discoverKubeConfig(ctx, req)
+	if err != nil || kubeconfig != nil {
+		return kubeconfig, err
+	}
+
+	// Fallback to the secret with the same name of the Cluster
+	if err := r.Get(ctx, req.NamespacedName, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("kubeconfig secret not found: neither %s nor %s exist and no secret labeled "+
+				"with %s=%s stores it: %w", secretName, req.Name, clusterapiv1.ClusterNameLabel, req.Name, err)
+		}
+		return nil, err
+	}
+	kubeconfig, exists := secret.Data["kubeconfig"]
+	if !exists {
+		return nil, fmt.Errorf("kubeconfig not found in secret")
+	}
+	return kubeconfig, nil
+}
+
+// doFinalizerOperations will perform the required operations before delete the CR. The registration
+// recorded in the status is removed from ArgoCD unless the deletionPolicy of the Register is Retain.
+func (r *RegisterReconciler) doFinalizerOperations(ctx context.Context, cr *argocdv1beta1.Register,
+	clusterAPI *clusterapiv1.Cluster) error {
+	if cr.Spec.DeletionPolicy == argocdv1beta1.DeletionPolicyRetain {
+		r.Log.Info("Retaining the registration of the Cluster within ArgoCD", "server", cr.Status.Server)
+		// The registration is released so that it is not removed from ArgoCD as orphaned once the Register is
+		// deleted, unless ArgoCD is no longer reachable and the removal of the registration is forced
+		if cr.Status.Server != "" && !forceUnregister(cr) {
+			argoCDManager, err := r.finalizerRegistrar(ctx, cr, clusterAPI)
+			if err == nil {
+				err = argoCDManager.ReleaseCluster(ctx)
+			}
+			if err != nil {
+				r.Log.Error(err, "Failed to release the registration of the Cluster within ArgoCD")
+				return err
+			}
+		}
+		if r.Recorder != nil {
+			r.Recorder.Event(cr, "Normal", "Retained",
+				fmt.Sprintf("Cluster %s is kept registered within ArgoCD since the deletionPolicy is Retain",
+					cr.Status.ClusterName))
+		}
+		return nil
+	}
+
+	if forceUnregister(cr) {
+		r.Log.Info("Skipping the removal of the registration from ArgoCD since it is forced",
+			"server", cr.Status.Server, "failures", cr.Status.UnregisterFailures)
+		if r.Recorder != nil {
+			r.Recorder.Event(cr, "Warning", "ForceUnregistered",
+				fmt.Sprintf("Cluster %s may still be registered within ArgoCD since its removal was skipped after "+
+					"%d failed attempts", cr.Status.ClusterName, cr.Status.UnregisterFailures))
+		}
+		return nil
+	}
+
+	// Nothing was registered when the status has no server, i.e. when the registration never succeeded
+	if cr.Status.Server != "" {
+		argoCDManager, err := r.finalizerRegistrar(ctx, cr, clusterAPI)
+		if err == nil {
+			err = argoCDManager.UnRegisterCluster(ctx)
+		}
+		if err != nil {
+			r.Log.Error(err, "Failed to Unregister Cluster from ArgoCD")
+			return err
+		}
+		if r.Recorder != nil {
+			r.Recorder.Event(cr, "Normal", "Unregistered",
+				fmt.Sprintf("Cluster %s was unregistered from ArgoCD", cr.Status.ClusterName))
+		}
+	}
+	return nil
+}
+
+// finalizerRegistrar returns the Registrar of the registration recorded in the status of the Register. The
+// kubeconfig is not required to remove or release the registration, therefore, it is not gathered. The
+// Registrar targets the ArgoCDInstance where the Cluster was registered, with the mode used to register it.
+func (r *RegisterReconciler) finalizerRegistrar(ctx context.Context, cr *argocdv1beta1.Register,
+	clusterAPI *clusterapiv1.Cluster) (argocd.Registrar, error) {
+	options := clusterOptions(cr)
+	options.Name = cr.Status.ClusterName
+	options.Server = cr.Status.Server
+	instance, err := argoCDInstance(ctx, r.Client, cr.Status.Instance)
+	if err != nil {
+		return nil, err
+	}
+	options.Instance = instance
+	mode := cr.Status.RegistrationMode
+	if mode == "" {
+		mode = r.registrationMode(cr)
+	}
+	return r.registrarFactory()(ctx, r.Client, r.Log, mode, clusterAPI, nil, options)
+}
+
+// forceUnregister returns true when the Register has the ForceUnregisterAnnotation and the failed attempts
+// to remove its registration from ArgoCD reached the number tolerated by it
+func forceUnregister(cr *argocdv1beta1.Register) bool {
+	value, exists := cr.GetAnnotations()[argocdv1beta1.ForceUnregisterAnnotation]
+	if !exists {
+		return false
+	}
+	tolerated, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || tolerated < 0 {
+		return false
+	}
+	return int64(cr.Status.UnregisterFailures) >= tolerated
+}
+
+// decryptKubeConfig returns the kubeConfig decrypted with the provider informed in the Register CR
+// using the keys stored in the secret referenced
+func (r *RegisterReconciler) decryptKubeConfig(ctx context.Context, RegisterCR *argocdv1beta1.Register,
+	kubeConfig []byte) ([]byte, error) {
+	decryption := RegisterCR.Spec.Decryption
+	switch decryption.Provider {
+	case argocdv1beta1.DecryptionProviderSOPS, "":
+	default:
+		return nil, fmt.Errorf("unknown decryption provider %q", decryption.Provider)
+	}
+
+	secret := &corev1.Secret{}
+	secretKey := client.ObjectKey{Namespace: RegisterCR.Namespace, Name: decryption.SecretRef.Name}
+	if err := r.Get(ctx, secretKey, secret); err != nil {
+		return nil, err
+	}
+	var ageKeys []string
+	for key, value := range secret.Data {
+		if strings.HasSuffix(key, argocd.AgeKeySuffix) {
+			ageKeys = append(ageKeys, string(value))
+		}
+	}
+	if len(ageKeys) == 0 {
+		return nil, fmt.Errorf("no age key found in secret %s, the keys must have the %s suffix",
+			secretKey, argocd.AgeKeySuffix)
+	}
+	return argocd.DecryptSOPS(ctx, kubeConfig, ageKeys)
+}
+
+// kubeConfigSecretKeys are the keys under which the kubeConfig is stored in the secrets discovered
+var kubeConfigSecretKeys = []string{capisecret.KubeconfigDataName, "kubeconfig"}
+
+// discoverKubeConfig returns the kubeConfig stored in the only secret labeled with the name of the
+// Cluster which has one of the kubeConfigSecretKeys. It returns nil when no secret is found, and an
+// error when multiple secrets are found since it is not possible to determine which one should be used.
+func (r *RegisterReconciler) discoverKubeConfig(ctx context.Context, req ctrl.Request) ([]byte, error) {
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.InNamespace(req.Namespace),
+		client.MatchingLabels{clusterapiv1.ClusterNameLabel: req.Name}); err != nil {
+		return nil, err
+	}
+
+	var kubeconfig []byte
+	var candidates []string
+	for i := range secrets.Items {
+		if data, exists := kubeConfigSecretData(&secrets.Items[i]); exists {
+			kubeconfig = data
+			candidates = append(candidates, secrets.Items[i].Name)
+		}
+	}
+	if len(candidates) > 1 {
+		return nil, fmt.Errorf("multiple secrets labeled with %s=%s store a kubeconfig (%s), "+
+			"inform the kubeconfigSecretRef of the Register to select one", clusterapiv1.ClusterNameLabel, req.Name,
+			strings.Join(candidates, ", "))
+	}
+	return kubeconfig, nil
+}
+
+// kubeConfigSecretData returns the kubeConfig stored in the secret under one of the kubeConfigSecretKeys
+func kubeConfigSecretData(secret *corev1.Secret) ([]byte, bool) {
+	for _, key := range kubeConfigSecretKeys {
+		if data, exists := secret.Data[key]; exists {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// kubeConfigSecretRefKey returns the key of the secret referenced in the Register CR to read the
+// kubeConfig, which is in the namespace of the Register CR when its namespace is not informed
+func kubeConfigSecretRefKey(RegisterCR *argocdv1beta1.Register) client.ObjectKey {
+	ref := RegisterCR.Spec.KubeConfigSecretRef
+	if ref.Namespace == "" {
+		return client.ObjectKey{Namespace: RegisterCR.Namespace, Name: ref.Name}
+	}
+	return client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}
+}
+
+// kubeConfigSecretCluster returns the Cluster whose kubeconfig is stored in the Secret, either the
+// <cluster-name>-kubeconfig Secret created by Cluster API, a Secret labeled with the name of the
+// Cluster or the one with the same name of the Cluster.
+func kubeConfigSecretCluster(obj client.Object) (client.ObjectKey, bool) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return client.ObjectKey{}, false
+	}
+	if clusterName := secret.Labels[clusterapiv1.ClusterNameLabel]; clusterName != "" {
+		if _, exists := kubeConfigSecretData(secret); exists {
+			return client.ObjectKey{Namespace: secret.Namespace, Name: clusterName}, true
+		}
+	}
+	suffix := "-" + string(capisecret.Kubeconfig)
+	if _, exists := secret.Data[capisecret.KubeconfigDataName]; exists && strings.HasSuffix(secret.Name, suffix) {
+		return client.ObjectKey{Namespace: secret.Namespace, Name: strings.TrimSuffix(secret.Name, suffix)}, true
+	}
+	if _, exists := secret.Data["kubeconfig"]; exists {
+		return client.ObjectKeyFromObject(secret), true
+	}
+	return client.ObjectKey{}, false
+}
+
+// providerKubeConfigSecretRequests maps the Secret to the Clusters of its namespace whose kubeconfig is
+// stored in it by the convention of their control plane provider, since its name is not derived from the
+// name of the Cluster, i.e. the Kamaji <control plane>-admin-kubeconfig Secret. The Clusters are looked up
+// via the field index of the cache once it is registered, so that the Clusters are not listed by every Secret.
+func (r *RegisterReconciler) providerKubeConfigSecretRequests(ctx context.Context,
+	obj client.Object) []reconcile.Request {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok || len(secret.Data) == 0 {
+		return nil
+	}
+	clusters := &unstructured.UnstructuredList{}
+	clusters.SetGroupVersionKind(r.clusterGVK().GroupVersion().WithKind(r.clusterGVK().Kind + "List"))
+	opts := []client.ListOption{client.InNamespace(secret.Namespace)}
+	if r.fieldIndexed {
+		opts = append(opts, client.MatchingFields{providerKubeConfigSecretIndex: secret.Name})
+	}
+	if err := r.List(ctx, clusters, opts...); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Clusters")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range clusters.Items {
+		clusterAPI := &clusterapiv1.Cluster{}
+		if err := clusterFromUnstructured(&clusters.Items[i], clusterAPI); err != nil {
+			continue
+		}
+		name, key, found, err := argocd.ProviderKubeConfigSecret(clusterAPI)
+		if err != nil || !found || name != secret.Name {
+			continue
+		}
+		if _, exists := secret.Data[key]; exists {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(clusterAPI)})
+		}
+	}
+	return requests
+}
+
+// secretToRequests maps the Secrets to the Clusters which should be reconciled. The kubeconfig Secrets,
+// found by the naming conventions, including the ones of the control plane providers, or referenced in
+// the Register CRs, are mapped to their Cluster so that the Clusters are registered as soon as their
+// kubeconfig is created and the rotated credentials are pushed to ArgoCD.
+func (r *RegisterReconciler) secretToRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := r.providerKubeConfigSecretRequests(ctx, obj)
+	if cluster, ok := kubeConfigSecretCluster(obj); ok {
+		return append(requests, reconcile.Request{NamespacedName: cluster})
+	}
+
+	referencing, err := r.registersIndexed(ctx, kubeConfigSecretRefIndex, client.ObjectKeyFromObject(obj).String())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Registers")
+		return nil
+	}
+	for i := range referencing {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&referencing[i])})
+	}
+	return requests
+}
+
+// credentialsSecretToRequests maps the Secrets with the credentials of the ArgoCD account of an ArgoCD
+// instance to the Clusters registered within it via the ArgoCD API. The cached sessions are dropped and the
+// Clusters are reconciled instead of using the stale credentials. It is only called when the credentials
+// stored in the Secret change.
+func (r *RegisterReconciler) credentialsSecretToRequests(ctx context.Context,
+	obj client.Object) []reconcile.Request {
+	// Each ArgoCD instance has its own credentials, therefore, the Secret is checked once per instance, and
+	// the Registers are only listed when it holds the credentials of one of them
+	changedInstances := r.credentialsChanged(ctx, obj)
+	if len(changedInstances) == 0 {
+		return nil
+	}
+	log.FromContext(ctx).Info("Credentials of the ArgoCD account changed, invalidating the cached sessions "+
+		"and connections", "secret", obj.GetName(), "namespace", obj.GetNamespace())
+	argocd.InvalidateSessions()
+	argocd.InvalidateAPIManagers()
+	registers, err := r.listRegisters(ctx)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Registers")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range registers {
+		register := &registers[i]
+		if changedInstances.Has(r.instanceName(register)) &&
+			r.registrationMode(register) == argocdv1beta1.RegistrationModeAPI {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(register)})
+		}
+	}
+	return requests
+}
+
+// credentialsChanged returns the names of the ArgoCD instances whose credentials are stored in the Secret,
+// the ArgoCD instance configured via Manager ENV VAR is named as the ArgoCDInstance selected, if any
+func (r *RegisterReconciler) credentialsChanged(ctx context.Context, obj client.Object) sets.Set[string] {
+	names := sets.New(r.ArgoCDInstance)
+	instances := &argocdv1beta1.ArgoCDInstanceList{}
+	if err := r.List(ctx, instances); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ArgoCDInstances")
+	}
+	for i := range instances.Items {
+		names.Insert(instances.Items[i].Name)
+	}
+	changed := sets.New[string]()
+	for name := range names {
+		if isCredentialsSecret(ctx, r.Client, name, obj) {
+			changed.Insert(name)
+		}
+	}
+	return changed
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RegisterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.ClusterGVK.Empty() {
+		clusterGVK, err := discoverClusterGVK(mgr.GetRESTMapper())
+		if err != nil {
+			return err
+		}
+		r.ClusterGVK = clusterGVK
+	}
+	if err := registerMetrics(mgr.GetClient()); err != nil {
+		return err
+	}
+	if r.RegistrationWorkers > 0 {
+		r.registrations = newRegistrationWorkers(r.RegistrationWorkers, mgr.GetLogger().WithName("registration"))
+		if err := mgr.Add(r.registrations); err != nil {
+			return err
+		}
+	}
+	if err := r.indexFields(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		return err
+	}
+
+	// The Cluster is set as an owner but not as the controller of the Register, therefore, every owner
+	// is matched so that the changes of the Register, i.e. its deletion, are reconciled. The ClusterRegisters
+	// are mapped to the Cluster which they reference, since they are reconciled as its Register.
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter: r.RateLimiter}).
+		For(r.newClusterObject(), builder.WithPredicates(specOrMetadataChanged)).
+		Owns(&argocdv1beta1.Register{}, builder.MatchEveryOwner,
+			builder.WithPredicates(specOrMetadataChanged)).
+		Watches(&argocdv1beta1.ClusterRegister{}, handler.EnqueueRequestsFromMapFunc(r.clusterRegisterToRequests),
+			builder.WithPredicates(specOrMetadataChanged)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretToRequests)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.credentialsSecretToRequests),
+			builder.WithPredicates(credentialsUpdated)).
+		Watches(&argocdv1beta1.RegistrationPolicy{}, handler.EnqueueRequestsFromMapFunc(r.registrationPolicyToRequests)).
+		Watches(&argocdv1beta1.ArgoCDInstance{}, handler.EnqueueRequestsFromMapFunc(r.argoCDInstanceToRequests),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	if r.registrations != nil {
+		// The Clusters are reconciled again once their registration is performed
+		bldr = bldr.WatchesRawSource(&source.Channel{Source: r.registrations.events}, &handler.EnqueueRequestForObject{})
+	}
+	return bldr.Complete(r)
+}
+
+// argoCDInstanceToRequests maps the ArgoCDInstances to the Clusters registered within them, so that they
+// are registered again once its configuration, i.e. the default project or shard, changes. The one selected
+// via the Manager flag is mapped to all Clusters since it is used by the Clusters without a Register yet.
+func (r *RegisterReconciler) argoCDInstanceToRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetName() == r.ArgoCDInstance {
+		return r.registrationPolicyToRequests(ctx, obj)
+	}
+	registers, err := r.listRegisters(ctx)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Registers")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range registers {
+		if r.instanceName(&registers[i]) == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&registers[i])})
+		}
+	}
+	return requests
 }
@@ -19,17 +19,27 @@ package argocd
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"time"
 
+	"github.com/go-logr/logr"
+	"github.com/workload-operator/internal/argocd"
 	"github.com/workload-operator/internal/argocd/mocks"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
@@ -53,10 +63,27 @@ var _ = Describe("Register controller", func() {
 		typeNamespaceName := types.NamespacedName{Name: RegisterNamespace, Namespace: RegisterNamespace}
 		registerCR := &argocdv1beta1.Register{}
 
+		// argoServer fakes the ArgoCD API so that the cluster registration can be performed
+		var argoServer *httptest.Server
+
 		BeforeEach(func() {
+			By("Starting a fake ArgoCD API")
+			argoServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v1/session" {
+					_, _ = fmt.Fprint(w, `{"token":"session-token"}`)
+					return
+				}
+				if r.Method == http.MethodGet {
+					_, _ = fmt.Fprintf(w, `{"server":"mocks:80","name":%q,"info":{"connectionState":{"status":%q}}}`,
+						RegisterNamespace, argocd.ConnectionStatusSuccessful)
+				}
+			}))
+			Expect(os.Setenv(argocd.APIEndpointEnvVar, argoServer.URL)).To(Succeed())
+			Expect(os.Setenv(argocd.AllowInsecureEndpointEnvVar, "true")).To(Succeed())
+
 			By("Creating the Namespace to perform the tests")
 			err := k8sClient.Create(ctx, namespace)
-			Expect(err).To(Not(HaveOccurred()))
+			Expect(client.IgnoreAlreadyExists(err)).To(Not(HaveOccurred()))
 
 			By("creating the custom resource for the Cluster to emulate values in the namespace")
 			err = k8sClient.Get(ctx, typeNamespaceName, registerCR)
@@ -89,20 +116,56 @@ var _ = Describe("Register controller", func() {
 
 			err = k8sClient.Create(ctx, secret)
 			Expect(err).To(Not(HaveOccurred()))
+
+			By("creating the secret with the credentials of the ArgoCD account")
+			Expect(os.Setenv(argocd.NamespaceEnvVar, RegisterNamespace)).To(Succeed())
+			Expect(os.Setenv(argocd.SecretNameEnvVar, "argocd-credentials")).To(Succeed())
+			argoSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "argocd-credentials",
+					Namespace: RegisterNamespace,
+				},
+				Data: map[string][]byte{
+					argocd.PasswordSecretKey: []byte("password-test"),
+				},
+			}
+			err = k8sClient.Create(ctx, argoSecret)
+			Expect(err).To(Not(HaveOccurred()))
 		})
 
 		AfterEach(func() {
 			By("removing the custom resource for the Cluster")
 			found := &clusterapiv1.Cluster{}
 			err := k8sClient.Get(ctx, typeNamespaceName, found)
-			Expect(err).To(Not(HaveOccurred()))
+			Expect(client.IgnoreNotFound(err)).To(Not(HaveOccurred()))
 
-			Eventually(func() error {
-				return k8sClient.Delete(ctx, found)
-			}, 2*time.Minute, time.Second).Should(Succeed())
+			// The Cluster is already removed by the tests which check its deletion
+			if err == nil {
+				Eventually(func() error {
+					return k8sClient.Delete(ctx, found)
+				}, 2*time.Minute, time.Second).Should(Succeed())
+			}
+
+			// The Namespace is not deleted since envtest does not run the namespace controller
+			// therefore it would be kept as terminating and the resources could not be re-created
+			By("removing the Register and Secrets created in the Namespace")
+			register := &argocdv1beta1.Register{}
+			if err := k8sClient.Get(ctx, typeNamespaceName, register); err == nil {
+				register.Finalizers = nil
+				Expect(k8sClient.Update(ctx, register)).To(Succeed())
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, register))).To(Succeed())
+			}
+			_ = k8sClient.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name: RegisterNamespace, Namespace: RegisterNamespace}})
+			_ = k8sClient.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name: "argocd-credentials", Namespace: RegisterNamespace}})
 
-			By("Deleting the Namespace to perform the tests")
-			_ = k8sClient.Delete(ctx, namespace)
+			By("Stopping the fake ArgoCD API")
+			argoServer.Close()
+			_ = os.Unsetenv(argocd.APIEndpointEnvVar)
+			_ = os.Unsetenv(argocd.AllowInsecureEndpointEnvVar)
+			_ = os.Unsetenv(argocd.NamespaceEnvVar)
+			_ = os.Unsetenv(argocd.SecretNameEnvVar)
 		})
 
 		It("should successfully reconcile a custom resource for Cluster", func() {
@@ -125,6 +188,9 @@ var _ = Describe("Register controller", func() {
 
 			By("Checking the latest Status Condition added to the Register instance")
 			Eventually(func() error {
+				if err := k8sClient.Get(ctx, typeNamespaceName, registerCR); err != nil {
+					return err
+				}
 				if registerCR.Status.Conditions != nil && len(registerCR.Status.Conditions) != 0 {
 					latestStatusCondition := registerCR.Status.Conditions[len(registerCR.Status.Conditions)-1]
 					if latestStatusCondition.Type != status.ConditionAvailable {
@@ -134,5 +200,1739 @@ var _ = Describe("Register controller", func() {
 				return nil
 			}, time.Minute, time.Second).Should(Succeed())
 		})
-	})
-})
+
+		It("should register the Cluster using the Registrar", func() {
+			registrar := &fakeRegistrar{}
+
+			By("Reconciling the custom resource created")
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registered).To(BeTrue())
+
+			By("Checking that the Register instance is Available")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(registerCR.Status.Conditions, status.ConditionAvailable)).To(BeTrue())
+			Expect(registerCR.Status.ClusterName).To(Equal(RegisterNamespace))
+
+			By("Checking that the registration is recorded in the status")
+			Expect(registerCR.Status.LastRegistrationTime).NotTo(BeNil())
+			Expect(registerCR.Status.LastVerifiedTime).NotTo(BeNil())
+			Expect(registerCR.Status.ArgoCDClusterID).To(Equal("mocks-cluster-id"))
+			Expect(registerCR.Status.ArgoCDServerVersion).To(Equal("v2.8.4+c279299"))
+			lastRegistrationTime := registerCR.Status.LastRegistrationTime
+
+			By("Checking that the registration time is kept when the Cluster is already registered")
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.LastRegistrationTime.Equal(lastRegistrationTime)).To(BeTrue())
+		})
+
+		It("should record the generation observed in the status and conditions", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.ObservedGeneration).To(Equal(registerCR.Generation))
+
+			By("Changing the spec of the Register")
+			registerCR.Spec.Project = "team-a"
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			Expect(registerCR.Status.ObservedGeneration).To(BeNumerically("<", registerCR.Generation))
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.ObservedGeneration).To(Equal(registerCR.Generation))
+			Expect(registerCR.Status.Phase).To(Equal(argocdv1beta1.RegisterPhaseRegistered))
+			for _, condition := range registerCR.Status.Conditions {
+				Expect(condition.ObservedGeneration).To(Equal(registerCR.Generation), condition.Type)
+			}
+		})
+
+		It("should register the EKS Cluster authenticating via IAM", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.options.AWSAuthConfig).To(BeNil())
+
+			By("Informing the AWS auth config in the Register")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.AWSAuth = &argocdv1beta1.AWSAuthSpec{ClusterName: "eks-cluster",
+				RoleARN: "arn:aws:iam::123456789012:role/argocd"}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.options.AWSAuthConfig).To(Equal(&argocd.AWSAuthConfig{ClusterName: "eks-cluster",
+				RoleARN: "arn:aws:iam::123456789012:role/argocd"}))
+		})
+
+		It("should report when ArgoCD is unable to connect to the Cluster", func() {
+			registrar := &fakeRegistrar{
+				verifyErr: &argocd.ConnectionError{Status: argocd.ConnectionStatusFailed, Message: "i/o timeout"},
+			}
+
+			By("Reconciling the custom resource created")
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+
+			result, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(result.RequeueAfter).To(Equal(connectionPollInterval))
+
+			By("Checking that the Register instance is not Available")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionAvailable)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("ConnectionFailed"))
+			Expect(condition.Message).To(ContainSubstring("i/o timeout"))
+
+			By("Checking that the Register instance is Registered even though ArgoCD is unable to connect")
+			Expect(meta.IsStatusConditionTrue(registerCR.Status.Conditions, status.ConditionRegistered)).To(BeTrue())
+
+			By("Checking that the Register instance is not Available until ArgoCD is connected")
+			registrar.verifyErr = &argocd.ConnectionError{Status: argocd.ConnectionStatusUnknown}
+			result, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(result.RequeueAfter).To(Equal(connectionPollInterval))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition = meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionAvailable)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("WaitingForConnection"))
+
+			By("Checking that the Register instance is Available once ArgoCD is connected")
+			registrar.verifyErr = nil
+			result, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(registerCR.Status.Conditions, status.ConditionAvailable)).To(BeTrue())
+		})
+
+		It("should correct the drift of the Cluster registration", func() {
+			registrar := &fakeRegistrar{}
+
+			By("Reconciling the custom resource created")
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			reconcileRegister := func() *metav1.Condition {
+				_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespaceName,
+				})
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+				return meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionAvailable)
+			}
+			Expect(reconcileRegister().Reason).To(Equal(status.ReasonRegistrationSucceeded))
+			Expect(recorder.Events).To(Receive(ContainSubstring("Normal Registered")))
+			Expect(recorder.Events).To(Receive(ContainSubstring("Normal Verified")))
+			Expect(recorder.Events).NotTo(Receive())
+
+			By("Checking that the changes of the Register are reported as an update rather than as a drift")
+			registerCR.Spec.VerifyInterval = &metav1.Duration{Duration: time.Hour}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			registrar.drifted = true
+			Expect(reconcileRegister().Reason).To(Equal(status.ReasonRegistrationSucceeded))
+			Expect(recorder.Events).To(Receive(ContainSubstring("Normal Updated")))
+			Expect(recorder.Events).NotTo(Receive())
+
+			By("Checking that the drift is reported when the registration was edited out-of-band")
+			registrar.drifted = true
+			Expect(reconcileRegister().Reason).To(Equal("DriftCorrected"))
+			Expect(recorder.Events).To(Receive(ContainSubstring("DriftCorrected")))
+
+			By("Checking that the drift is reported when the registration was removed out-of-band")
+			registrar.registered = false
+			Expect(reconcileRegister().Reason).To(Equal("DriftCorrected"))
+			Expect(registrar.registered).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring("DriftCorrected")))
+
+			By("Checking that the drift is no longer reported once corrected")
+			Expect(reconcileRegister().Reason).To(Equal(status.ReasonRegistrationSucceeded))
+		})
+
+		It("should verify the registration again at the verifyInterval", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			result, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(result.RequeueAfter).To(BeZero())
+
+			By("Informing the verifyInterval")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.VerifyInterval = &metav1.Duration{Duration: 10 * time.Minute}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+
+			result, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+
+			By("Checking that the connection state is checked first while ArgoCD is unable to connect")
+			registrar.verifyErr = &argocd.ConnectionError{Status: argocd.ConnectionStatusFailed, Message: "i/o timeout"}
+			result, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(result.RequeueAfter).To(Equal(connectionPollInterval))
+
+			By("Rejecting the intervals shorter than 1m")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.VerifyInterval = &metav1.Duration{Duration: 30 * time.Second}
+			err = k8sClient.Update(ctx, registerCR)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("verifyInterval must be at least 1m"))
+		})
+
+		It("should remove the registration of the previous control plane endpoint when it changes", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.Server).To(Equal("mocks:80"))
+
+			By("Changing the control plane endpoint of the Cluster")
+			cluster := &clusterapiv1.Cluster{}
+			Expect(k8sClient.Get(ctx, typeNamespaceName, cluster)).To(Succeed())
+			cluster.Spec.ControlPlaneEndpoint = clusterapiv1.APIEndpoint{Host: "mocks-lb", Port: 6443}
+			Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.unregistered).To(Equal([]string{"mocks:80"}))
+			Expect(registrar.options.Server).To(BeEmpty())
+			Expect(registrar.registered).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring("EndpointChanged")))
+
+			By("Checking that the new control plane endpoint is recorded")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.Server).To(Equal("mocks-lb:6443"))
+			Expect(meta.IsStatusConditionTrue(registerCR.Status.Conditions, status.ConditionAvailable)).To(BeTrue())
+		})
+
+		It("should move the registration to the ArgoCDInstance referenced by the Register", func() {
+			By("Creating the ArgoCDInstance")
+			instance := &argocdv1beta1.ArgoCDInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: "mocks-staging"},
+				Spec:       argocdv1beta1.ArgoCDInstanceSpec{Endpoint: "https://argocd-server.staging.svc"},
+			}
+			Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, instance)).To(Succeed())
+			})
+
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.options.Instance).To(BeNil())
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.Instance).To(BeEmpty())
+
+			By("Referencing the ArgoCDInstance in the Register")
+			registerCR.Spec.InstanceRef = &corev1.LocalObjectReference{Name: instance.Name}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			Expect(registerReconciler.argoCDInstanceToRequests(ctx, instance)).To(Equal(
+				[]reconcile.Request{{NamespacedName: typeNamespaceName}}))
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.unregistered).To(Equal([]string{"mocks:80"}))
+			Expect(registrar.registered).To(BeTrue())
+			Expect(registrar.options.Instance).NotTo(BeNil())
+			Expect(registrar.options.Instance.Endpoint).To(Equal("https://argocd-server.staging.svc"))
+			Expect(recorder.Events).To(Receive(ContainSubstring("InstanceChanged")))
+
+			By("Checking that the ArgoCDInstance is recorded")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.Instance).To(Equal(instance.Name))
+			Expect(registerCR.Status.Server).To(Equal("mocks:80"))
+		})
+
+		It("should report when the ArgoCDInstance does not exist", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				NewRegistrar:   registrar.factory,
+				ArgoCDInstance: "mocks-missing",
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(registrar.registered).To(BeFalse())
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionDegraded)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(status.ReasonArgoCDSetupFailed))
+			Expect(condition.Message).To(ContainSubstring("mocks-missing"))
+		})
+
+		It("should push the new credentials to ArgoCD when the kubeconfig is rotated", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			kubeConfigHash := registerCR.Status.KubeConfigHash
+			Expect(kubeConfigHash).NotTo(BeEmpty())
+			Expect(recorder.Events).To(Receive(ContainSubstring("Registered")))
+			Expect(recorder.Events).To(Receive(ContainSubstring("Verified")))
+			Expect(recorder.Events).NotTo(Receive())
+
+			By("Checking that the credentials are not pushed again when the kubeconfig did not change")
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+
+			By("Rotating the kubeconfig of the Cluster")
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, typeNamespaceName, secret)).To(Succeed())
+			secret.Data["kubeconfig"] = []byte(mocks.MockKubeConfig + "\n# rotated\n")
+			Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(2))
+			Expect(recorder.Events).To(Receive(ContainSubstring("CredentialsRotated")))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.KubeConfigHash).NotTo(Equal(kubeConfigHash))
+		})
+
+		It("should use the kubeconfig Secret created by Cluster API when it exists", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.kubeConfig).To(Equal([]byte(mocks.MockKubeConfig)))
+
+			By("Creating the kubeconfig Secret following the Cluster API convention")
+			capiKubeConfig := []byte(mocks.MockKubeConfig + "\n# cluster-api\n")
+			capiSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      RegisterNamespace + "-kubeconfig",
+					Namespace: RegisterNamespace,
+				},
+				Data: map[string][]byte{"value": capiKubeConfig},
+			}
+			Expect(k8sClient.Create(ctx, capiSecret)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, capiSecret)).To(Succeed()) }()
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.kubeConfig).To(Equal(capiKubeConfig))
+
+			By("Mapping the kubeconfig Secret created by Cluster API to its Cluster")
+			Expect(registerReconciler.secretToRequests(ctx, capiSecret)).To(Equal(
+				[]reconcile.Request{{NamespacedName: typeNamespaceName}}))
+		})
+
+		It("should use the kubeconfig Secret of the hosted control plane provider", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+
+			By("Referencing a Kamaji control plane in the Cluster")
+			cluster := &clusterapiv1.Cluster{}
+			Expect(k8sClient.Get(ctx, typeNamespaceName, cluster)).To(Succeed())
+			cluster.Spec.ControlPlaneRef = &corev1.ObjectReference{
+				APIVersion: "controlplane.cluster.x-k8s.io/v1alpha1", Kind: "KamajiControlPlane", Name: "tenant"}
+			Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+
+			By("Creating the kubeconfig Secret following the Kamaji convention")
+			kamajiKubeConfig := []byte(mocks.MockKubeConfig + "\n# kamaji\n")
+			kamajiSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-admin-kubeconfig", Namespace: RegisterNamespace},
+				Data:       map[string][]byte{"admin.conf": kamajiKubeConfig},
+			}
+			Expect(k8sClient.Create(ctx, kamajiSecret)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, kamajiSecret)).To(Succeed()) }()
+
+			By("Checking that the Secret is mapped to the Cluster")
+			Expect(registerReconciler.secretToRequests(ctx, kamajiSecret)).To(Equal([]reconcile.Request{
+				{NamespacedName: typeNamespaceName}}))
+
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.kubeConfig).To(Equal(kamajiKubeConfig))
+		})
+
+		It("should discover the kubeconfig Secret labeled with the name of the Cluster", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			newLabeledSecret := func(name string, kubeConfig []byte) *corev1.Secret {
+				return &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: RegisterNamespace,
+						Labels: map[string]string{clusterapiv1.ClusterNameLabel: RegisterNamespace}},
+					Data: map[string][]byte{"value": kubeConfig},
+				}
+			}
+
+			By("Creating a labeled Secret which does not store a kubeconfig")
+			caSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "labeled-ca", Namespace: RegisterNamespace,
+					Labels: map[string]string{clusterapiv1.ClusterNameLabel: RegisterNamespace}},
+				Data: map[string][]byte{"tls.crt": []byte("cert")},
+			}
+			Expect(k8sClient.Create(ctx, caSecret)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, caSecret)).To(Succeed()) }()
+			Expect(registerReconciler.secretToRequests(ctx, caSecret)).To(BeEmpty())
+
+			By("Creating the labeled Secret which stores the kubeconfig")
+			discoveredKubeConfig := []byte(mocks.MockKubeConfig + "\n# discovered\n")
+			discovered := newLabeledSecret("discovered", discoveredKubeConfig)
+			Expect(k8sClient.Create(ctx, discovered)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, discovered)).To(Succeed()) }()
+
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.kubeConfig).To(Equal(discoveredKubeConfig))
+			Expect(registerReconciler.secretToRequests(ctx, discovered)).To(Equal(
+				[]reconcile.Request{{NamespacedName: typeNamespaceName}}))
+
+			By("Checking that an error is reported when multiple labeled Secrets store a kubeconfig")
+			other := newLabeledSecret("other", []byte(mocks.MockKubeConfig))
+			Expect(k8sClient.Create(ctx, other)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, other)).To(Succeed()) }()
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("multiple secrets"))
+			Expect(err.Error()).To(ContainSubstring("discovered, other"))
+		})
+
+		It("should use the kubeconfig Secret referenced in the Register", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+
+			By("Referencing a kubeconfig Secret with a non-standard layout")
+			customKubeConfig := []byte(mocks.MockKubeConfig + "\n# custom\n")
+			customSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom-credentials", Namespace: RegisterNamespace},
+				Data:       map[string][]byte{"config": customKubeConfig},
+			}
+			Expect(k8sClient.Create(ctx, customSecret)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, customSecret)).To(Succeed()) }()
+
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.KubeConfigSecretRef = &argocdv1beta1.KubeConfigSecretReference{Name: "custom-credentials",
+				Key: "config"}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.kubeConfig).To(Equal(customKubeConfig))
+
+			By("Mapping the referenced Secret to the Cluster")
+			Expect(registerReconciler.secretToRequests(ctx, customSecret)).To(Equal(
+				[]reconcile.Request{{NamespacedName: typeNamespaceName}}))
+
+			By("Checking that the Register is Degraded when the key is not found in the Secret")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.KubeConfigSecretRef.Key = "missing"
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionDegraded)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Message).To(ContainSubstring("missing not found in secret"))
+		})
+
+		It("should report when the kubeconfig cannot be decrypted", func() {
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: (&fakeRegistrar{}).factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+
+			By("Referencing a Secret without age keys to decrypt the kubeconfig")
+			keysSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "sops-age", Namespace: RegisterNamespace},
+				Data:       map[string][]byte{"age.txt": []byte("AGE-SECRET-KEY-1TEST")},
+			}
+			Expect(k8sClient.Create(ctx, keysSecret)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, keysSecret)).To(Succeed()) }()
+
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.Decryption = &argocdv1beta1.DecryptionSpec{
+				SecretRef: corev1.LocalObjectReference{Name: "sops-age"}}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			Expect(registerCR.Spec.Decryption.Provider).To(Equal(argocdv1beta1.DecryptionProviderSOPS))
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionDegraded)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Message).To(ContainSubstring("no age key found in secret"))
+		})
+
+		It("should reach the Cluster with its kubeconfig before registering it", func() {
+			registrar := &fakeRegistrar{}
+			var serverVersionErr error
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+				ServerVersion: func(_ context.Context, kubeConfig []byte) (string, error) {
+					Expect(kubeConfig).To(Equal([]byte(mocks.MockKubeConfig)))
+					return "v1.27.2", serverVersionErr
+				},
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.KubernetesVersion).To(BeEmpty())
+
+			By("Enabling the validation of the connectivity")
+			registerCR.Spec.ValidateConnectivity = true
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.KubernetesVersion).To(Equal("v1.27.2"))
+
+			By("Reporting when the Cluster is unreachable")
+			serverVersionErr = fmt.Errorf("connection refused")
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionDegraded)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("Unreachable"))
+			Expect(condition.Message).To(ContainSubstring("connection refused"))
+		})
+
+		It("should skip the reconciliation while the Cluster or the Register are paused", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+
+			By("Pausing the Cluster")
+			cluster := &clusterapiv1.Cluster{}
+			Expect(k8sClient.Get(ctx, typeNamespaceName, cluster)).To(Succeed())
+			cluster.Spec.Paused = true
+			Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(BeZero())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespaceName, registerCR))).To(BeTrue())
+
+			By("Resuming the Cluster")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, cluster)).To(Succeed())
+			cluster.Spec.Paused = false
+			Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+
+			By("Pausing the Register")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Annotations = map[string]string{clusterapiv1.PausedAnnotation: ""}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+
+			registrar.registered = false
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+		})
+
+		It("should not call ArgoCD while the Register is suspended", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+
+			By("Suspending the Register")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.Suspend = true
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+
+			registrar.registered = false
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionProgressing)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("Suspended"))
+
+			By("Resuming the Register")
+			registerCR.Spec.Suspend = false
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(2))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionProgressing)).To(BeNil())
+		})
+
+		It("should remove the registration from ArgoCD when the Register is deleted", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Finalizers).To(ContainElement(registerCRFinalizer))
+			Expect(registerCR.Spec.DeletionPolicy).To(Equal(argocdv1beta1.DeletionPolicyDelete))
+
+			By("Deleting the Register")
+			Expect(k8sClient.Delete(ctx, registerCR)).To(Succeed())
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.unregistered).To(Equal([]string{"mocks:80"}))
+			Eventually(recorder.Events).Should(Receive(ContainSubstring("Normal Unregistered")))
+			err = k8sClient.Get(ctx, typeNamespaceName, registerCR)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should delete the Register and remove the registration when the Cluster is deleted", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registered).To(BeTrue())
+
+			By("Deleting the Cluster")
+			cluster := &clusterapiv1.Cluster{}
+			Expect(k8sClient.Get(ctx, typeNamespaceName, cluster)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, cluster)).To(Succeed())
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, typeNamespaceName, cluster))
+			}, time.Minute, time.Second).Should(BeTrue())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.unregistered).To(Equal([]string{"mocks:80"}))
+			err = k8sClient.Get(ctx, typeNamespaceName, registerCR)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should register the Cluster referenced by a ClusterRegister without creating a Register", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+
+			By("Creating the ClusterRegister which references the Cluster")
+			clusterRegister := &argocdv1beta1.ClusterRegister{
+				ObjectMeta: metav1.ObjectMeta{Name: "mocks-cluster-register"},
+				Spec: argocdv1beta1.ClusterRegisterSpec{
+					ClusterRef: argocdv1beta1.ClusterReference{Namespace: RegisterNamespace, Name: RegisterNamespace},
+				},
+			}
+			Expect(k8sClient.Create(ctx, clusterRegister)).To(Succeed())
+			DeferCleanup(func() {
+				found := &argocdv1beta1.ClusterRegister{}
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRegister), found); err == nil {
+					found.Finalizers = nil
+					Expect(k8sClient.Update(ctx, found)).To(Succeed())
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, found))).To(Succeed())
+				}
+			})
+
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registered).To(BeTrue())
+			Eventually(recorder.Events).Should(Receive(ContainSubstring("Normal Registered")))
+
+			By("Checking that the registration is reported in the ClusterRegister")
+			err = k8sClient.Get(ctx, typeNamespaceName, registerCR)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRegister), clusterRegister)).To(Succeed())
+			Expect(clusterRegister.Finalizers).To(ContainElement(registerCRFinalizer))
+			Expect(meta.IsStatusConditionTrue(clusterRegister.Status.Conditions, status.ConditionAvailable)).To(BeTrue())
+			Expect(clusterRegister.Status.ClusterName).To(Equal(RegisterNamespace))
+
+			By("Deleting the ClusterRegister")
+			Expect(k8sClient.Delete(ctx, clusterRegister)).To(Succeed())
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.unregistered).To(Equal([]string{"mocks:80"}))
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRegister), clusterRegister)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should not register the server of the Cluster already registered by another Register", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+
+			By("Creating another Cluster with the same control plane endpoint")
+			duplicateName := types.NamespacedName{Name: "mocks-duplicate", Namespace: RegisterNamespace}
+			Expect(k8sClient.Create(ctx, &clusterapiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: duplicateName.Name, Namespace: duplicateName.Namespace},
+				Spec: clusterapiv1.ClusterSpec{
+					ControlPlaneEndpoint: clusterapiv1.APIEndpoint{Host: "mocks", Port: 80},
+				},
+			})).To(Succeed())
+			Expect(k8sClient.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: duplicateName.Name, Namespace: duplicateName.Namespace},
+				Data:       map[string][]byte{"kubeconfig": []byte(mocks.MockKubeConfig)},
+			})).To(Succeed())
+			DeferCleanup(func() {
+				register := &argocdv1beta1.Register{}
+				if err := k8sClient.Get(ctx, duplicateName, register); err == nil {
+					register.Finalizers = nil
+					Expect(k8sClient.Update(ctx, register)).To(Succeed())
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, register))).To(Succeed())
+				}
+				_ = k8sClient.Delete(ctx, &clusterapiv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+					Name: duplicateName.Name, Namespace: duplicateName.Namespace}})
+				_ = k8sClient.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+					Name: duplicateName.Name, Namespace: duplicateName.Namespace}})
+			})
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: duplicateName})
+			Expect(err).To(MatchError(ContainSubstring("the server mocks:80 is already registered within ArgoCD")))
+			Expect(registrar.registrations).To(Equal(1))
+
+			By("Checking that the duplicate is reported as Degraded without recording the server")
+			duplicate := &argocdv1beta1.Register{}
+			Expect(k8sClient.Get(ctx, duplicateName, duplicate)).To(Succeed())
+			condition := meta.FindStatusCondition(duplicate.Status.Conditions, status.ConditionDegraded)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(status.ReasonDuplicateServer))
+			Expect(condition.Message).To(ContainSubstring(typeNamespaceName.String()))
+			Expect(duplicate.Status.Server).To(BeEmpty())
+
+			By("Checking that the Register which registered the server first keeps it")
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.Server).To(Equal("mocks:80"))
+		})
+
+		It("should retain the registration within ArgoCD when the deletionPolicy is Retain", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+
+			By("Retaining the registration and deleting the Register")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.DeletionPolicy = argocdv1beta1.DeletionPolicyRetain
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, registerCR)).To(Succeed())
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.unregistered).To(BeEmpty())
+			Expect(registrar.released).To(ConsistOf("mocks:80"))
+			Expect(registrar.registered).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring("Retained")))
+			err = k8sClient.Get(ctx, typeNamespaceName, registerCR)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should migrate the registration in place when the registration mode changes", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.RegistrationMode).To(Equal(argocdv1beta1.RegistrationModeAPI))
+			Expect(registrar.registrations).To(Equal(1))
+
+			By("Switching the Register to the Declarative mode")
+			registerCR.Spec.RegistrationMode = argocdv1beta1.RegistrationModeDeclarative
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.mode).To(Equal(argocdv1beta1.RegistrationModeDeclarative))
+			Expect(registrar.registrations).To(Equal(2))
+			Expect(registrar.unregistered).To(BeEmpty())
+			Expect(recorder.Events).To(Receive(ContainSubstring("RegistrationModeChanged")))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.RegistrationMode).To(Equal(argocdv1beta1.RegistrationModeDeclarative))
+
+			By("Removing the registration with the mode it was migrated to")
+			registrar.mode = ""
+			Expect(k8sClient.Delete(ctx, registerCR)).To(Succeed())
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.mode).To(Equal(argocdv1beta1.RegistrationModeDeclarative))
+			Expect(registrar.unregistered).To(ConsistOf("mocks:80"))
+		})
+
+		It("should adopt the cluster registered within ArgoCD with the server of the Cluster", func() {
+			argoServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v1/session":
+					_, _ = fmt.Fprint(w, `{"token":"session-token"}`)
+				case "/api/v1/clusters":
+					_, _ = fmt.Fprintf(w, `{"items":[{"server":"https://other:80","name":"other"},`+
+						`{"server":"https://mocks:80","name":"manual","project":"tenant-a"}]}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+			registrar := &fakeRegistrar{registered: true}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:        k8sClient,
+				Scheme:        k8sClient.Scheme(),
+				Recorder:      recorder,
+				NewRegistrar:  registrar.factory,
+				AdoptClusters: true,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+
+			By("Keeping the server, the name and the project of the cluster entry")
+			Expect(registrar.registrations).To(BeZero())
+			Expect(registrar.options.Server).To(Equal("https://mocks:80"))
+			Expect(registrar.options.Name).To(Equal("manual"))
+			Expect(registrar.options.Project).To(Equal("tenant-a"))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Spec.Name).To(Equal("manual"))
+			Expect(registerCR.Spec.Project).To(Equal("tenant-a"))
+			Expect(registerCR.Status.Server).To(Equal("https://mocks:80"))
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Adopted")))
+
+			By("Reconciling the Register adopted without registering the Cluster again")
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(BeZero())
+			Expect(registrar.options.Server).To(Equal("https://mocks:80"))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.Server).To(Equal("https://mocks:80"))
+		})
+
+		It("should keep the cluster-scoped resources of the cluster entry adopted", func() {
+			argoServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v1/session":
+					_, _ = fmt.Fprint(w, `{"token":"session-token"}`)
+				case "/api/v1/clusters":
+					_, _ = fmt.Fprint(w, `{"items":[{"server":"https://mocks:80","clusterResources":true}]}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+			registrar := &fakeRegistrar{registered: true}
+			registerReconciler := &RegisterReconciler{
+				Client:        k8sClient,
+				Scheme:        k8sClient.Scheme(),
+				NewRegistrar:  registrar.factory,
+				AdoptClusters: true,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(BeZero())
+			Expect(registrar.options.ClusterResources).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Spec.ClusterResources).To(BeTrue())
+			Expect(registerCR.Status.Server).To(Equal("https://mocks:80"))
+		})
+
+		It("should skip the removal of the registration once the failures tolerated are reached", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+
+			By("Deleting the Register while ArgoCD is unreachable")
+			registrar.unregisterErr = fmt.Errorf("dial tcp: lookup argocd-server: no such host")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Annotations = map[string]string{argocdv1beta1.ForceUnregisterAnnotation: "2"}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, registerCR)).To(Succeed())
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+
+			for attempt := 1; attempt <= 2; attempt++ {
+				_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespaceName,
+				})
+				Expect(err).To(HaveOccurred())
+				Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+				Expect(registerCR.Status.UnregisterFailures).To(Equal(int32(attempt)))
+			}
+
+			By("Checking that the Register is deleted without calling ArgoCD")
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.unregistered).To(BeEmpty())
+			Expect(recorder.Events).To(Receive(ContainSubstring("ForceUnregistered")))
+			err = k8sClient.Get(ctx, typeNamespaceName, registerCR)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should report the finalization as failed once the retry budget is exhausted", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:               k8sClient,
+				Scheme:               k8sClient.Scheme(),
+				Recorder:             recorder,
+				NewRegistrar:         registrar.factory,
+				FinalizerRetryBudget: 2,
+			}
+			reconcileRegister := func() error {
+				_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespaceName,
+				})
+				return err
+			}
+			Expect(reconcileRegister()).To(Succeed())
+
+			By("Deleting the Register while ArgoCD is unreachable")
+			registrar.unregisterErr = fmt.Errorf("dial tcp: lookup argocd-server: no such host")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, registerCR)).To(Succeed())
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+
+			Expect(reconcileRegister()).To(HaveOccurred())
+			Expect(recorder.Events).NotTo(Receive())
+
+			By("Checking that the finalization is reported as failed and keeps being retried")
+			Expect(reconcileRegister()).To(HaveOccurred())
+			Expect(recorder.Events).To(Receive(ContainSubstring("FinalizationFailed")))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionDegraded)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("FinalizationFailed"))
+			Expect(reconcileRegister()).To(HaveOccurred())
+			Expect(recorder.Events).NotTo(Receive())
+
+			By("Releasing the finalizer when the finalizerFailurePolicy is Release")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.FinalizerFailurePolicy = argocdv1beta1.FinalizerFailurePolicyRelease
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			Expect(reconcileRegister()).To(Succeed())
+			Expect(recorder.Events).To(Receive(ContainSubstring("finalizer was released")))
+			Expect(registrar.unregistered).To(BeEmpty())
+			err := k8sClient.Get(ctx, typeNamespaceName, registerCR)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should only register the Clusters selected by the RegistrationPolicies", func() {
+			registrar := &fakeRegistrar{}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+
+			By("Creating a RegistrationPolicy which does not select the Cluster")
+			policy := &argocdv1beta1.RegistrationPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "mocks-policy"},
+				Spec: argocdv1beta1.RegistrationPolicySpec{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+						corev1.LabelMetadataName: RegisterNamespace}},
+					ClusterSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"argocd": "enabled"}},
+					RegistrationMode: argocdv1beta1.RegistrationModeDeclarative,
+					Project:          "platform",
+					NameTemplate:     "{{ .Namespace }}-{{ .Name }}",
+				},
+			}
+			Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+			})
+			Expect(registerReconciler.registrationPolicyToRequests(ctx, policy)).To(ContainElement(
+				reconcile.Request{NamespacedName: typeNamespaceName}))
+
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(BeZero())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespaceName, registerCR))).To(BeTrue())
+
+			By("Labeling the Cluster so that it is selected")
+			cluster := &clusterapiv1.Cluster{}
+			Expect(k8sClient.Get(ctx, typeNamespaceName, cluster)).To(Succeed())
+			cluster.Labels = map[string]string{"argocd": "enabled"}
+			Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+
+			By("Checking that the Register was created with the defaults of the RegistrationPolicy")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Spec.RegistrationMode).To(Equal(argocdv1beta1.RegistrationModeDeclarative))
+			Expect(registerCR.Spec.Project).To(Equal("platform"))
+			Expect(registerCR.Spec.Name).To(Equal(RegisterNamespace + "-" + RegisterNamespace))
+			Expect(registrar.options.Name).To(Equal(RegisterNamespace + "-" + RegisterNamespace))
+			Expect(registerCR.Status.ClusterName).To(Equal(RegisterNamespace + "-" + RegisterNamespace))
+		})
+
+		It("should reconcile the Clusters when the credentials of the ArgoCD account change", func() {
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: (&fakeRegistrar{}).factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+
+			By("Mapping the Secret with the credentials of the ArgoCD account to the Registers")
+			argoSecret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "argocd-credentials",
+				Namespace: RegisterNamespace}, argoSecret)).To(Succeed())
+			Expect(registerReconciler.credentialsSecretToRequests(ctx, argoSecret)).To(ContainElement(
+				reconcile.Request{NamespacedName: typeNamespaceName}))
+
+			By("Mapping the kubeconfig Secret to its Cluster")
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, typeNamespaceName, secret)).To(Succeed())
+			Expect(registerReconciler.secretToRequests(ctx, secret)).To(Equal(
+				[]reconcile.Request{{NamespacedName: typeNamespaceName}}))
+
+			By("Ignoring the other Secrets")
+			other := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: RegisterNamespace}}
+			Expect(registerReconciler.secretToRequests(ctx, other)).To(BeEmpty())
+			Expect(registerReconciler.credentialsSecretToRequests(ctx, other)).To(BeEmpty())
+		})
+
+		It("should report the reason when ArgoCD rejects the registration", func() {
+			registrar := &fakeRegistrar{
+				registerErr: &argocd.APIError{Operation: "registering cluster", StatusCode: http.StatusForbidden,
+					Code: 7, Message: "permission denied: clusters, create"},
+			}
+
+			By("Reconciling the custom resource created")
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(HaveOccurred())
+
+			By("Checking that the Register instance is Degraded with the reason")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionDegraded)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(argocd.ReasonPermissionDenied))
+			Expect(condition.Message).To(ContainSubstring("permission denied: clusters, create"))
+
+			By("Checking that the Register instance is not Registered")
+			condition = meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionRegistered)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(argocd.ReasonPermissionDenied))
+		})
+
+		It("should back off while ArgoCD is unavailable", func() {
+			registrar := &fakeRegistrar{
+				registerErr: &url.Error{Op: "Post", URL: "https://argocd-server/api/v1/clusters",
+					Err: fmt.Errorf("connect: connection refused")},
+			}
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+			reconcileRegister := func() reconcile.Result {
+				result, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespaceName,
+				})
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+				return result
+			}
+
+			By("Checking that the delay increases with the failures")
+			Expect(reconcileRegister().RequeueAfter).To(Equal(transientBackoffBase))
+			Expect(reconcileRegister().RequeueAfter).To(Equal(2 * transientBackoffBase))
+			Expect(registerCR.Status.TransientFailures).To(Equal(int32(2)))
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionProgressing)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("Backoff"))
+			Expect(condition.Message).To(ContainSubstring("connection refused"))
+
+			By("Resetting the backoff once ArgoCD is available")
+			registrar.registerErr = nil
+			Expect(reconcileRegister().RequeueAfter).To(BeZero())
+			Expect(registerCR.Status.TransientFailures).To(BeZero())
+			Expect(meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionProgressing)).To(BeNil())
+		})
+
+		It("should back off without registering again when the registration cannot be checked", func() {
+			registrar := &fakeRegistrar{}
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+
+			By("Failing to check the registration within ArgoCD")
+			registrar.registeredErr = &argocd.APIError{Operation: "fetching cluster", StatusCode: http.StatusServiceUnavailable}
+			result, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(result.RequeueAfter).To(Equal(transientBackoffBase))
+			Expect(registrar.registrations).To(Equal(1))
+			Expect(recorder.Events).NotTo(Receive(ContainSubstring("DriftCorrected")))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.TransientFailures).To(Equal(int32(1)))
+		})
+
+		It("should cap the delay while ArgoCD is unavailable", func() {
+			Expect(transientBackoff(1)).To(Equal(transientBackoffBase))
+			Expect(transientBackoff(3)).To(Equal(4 * transientBackoffBase))
+			Expect(transientBackoff(100)).To(Equal(transientBackoffCap))
+		})
+
+		It("should requeue the Register when ArgoCD rate limits the requests", func() {
+			registrar := &fakeRegistrar{
+				verifyErr: &argocd.RateLimitedError{RetryAfter: 7 * time.Second},
+			}
+
+			By("Reconciling the custom resource created")
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+			}
+
+			result, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(result.RequeueAfter).To(Equal(7 * time.Second))
+
+			By("Checking that the Register instance reports that it was rate limited")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			condition := meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionProgressing)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("RateLimited"))
+			Expect(meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionDegraded)).To(BeNil())
+		})
+
+		It("should rotate the ServiceAccount token before it expires", func() {
+			registrar := &fakeRegistrar{}
+
+			By("Reconciling the custom resource created")
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+			}
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.TokenExpiry).To(BeNil())
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+
+			By("Rotating the token when its expiry is unknown")
+			registrar.registered = false
+			registerCR.Spec.ServiceAccount = &argocdv1beta1.ServiceAccountSpec{
+				TokenExpiration: &metav1.Duration{Duration: time.Hour}}
+			tokenExpiry := time.Now().Add(time.Hour).Truncate(time.Second)
+			rotateIn, err := registerReconciler.handleTokenRotation(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName}, registrar, registerCR, tokenExpiry)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(rotateIn).To(BeNumerically("~", 48*time.Minute, time.Minute))
+			Expect(registrar.registered).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring("CredentialsRotated")))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.TokenExpiry.Time).To(BeTemporally("==", tokenExpiry))
+
+			By("Checking that the token held by ArgoCD is not pushed again before the refresh window")
+			registrar.registered = false
+			registerCR.Spec.ServiceAccount = &argocdv1beta1.ServiceAccountSpec{
+				TokenExpiration: &metav1.Duration{Duration: time.Hour}}
+			rotateIn, err = registerReconciler.handleTokenRotation(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName}, registrar, registerCR, tokenExpiry)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(rotateIn).To(BeNumerically("~", 48*time.Minute, time.Minute))
+			Expect(registrar.registered).To(BeFalse())
+			Expect(recorder.Events).NotTo(Receive())
+
+			By("Checking that the expiry is cleared when a long-lived token is used")
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(registerCR.Status.TokenExpiry).To(BeNil())
+		})
+
+		It("should only request a ServiceAccount token when it must be rotated", func() {
+			DeferCleanup(issuedTokens.forget, typeNamespaceName)
+			registrar := &fakeRegistrar{}
+			var tokenRequests, rbacReconciliations int
+			tokenExpiry := time.Now().Add(time.Hour)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				NewRegistrar: registrar.factory,
+				ServiceAccountCredentials: func(_ context.Context, _ []byte, _ *argocdv1beta1.ServiceAccountSpec,
+					reconcileRBAC bool) (*argocd.ServiceAccountCredentials, error) {
+					tokenRequests++
+					if reconcileRBAC {
+						rbacReconciliations++
+					}
+					return &argocd.ServiceAccountCredentials{
+						KubeConfig: []byte(fmt.Sprintf("token-%d", tokenRequests)), ExpiresAt: tokenExpiry}, nil
+				},
+			}
+			reconcileRegister := func() {
+				_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespaceName,
+				})
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			}
+			reconcileRegister()
+			registerCR.Spec.ServiceAccount = &argocdv1beta1.ServiceAccountSpec{
+				TokenExpiration: &metav1.Duration{Duration: time.Hour}}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+
+			By("Issuing a single token for the reconciliations which follow each other")
+			reconcileRegister()
+			registrations := registrar.registrations
+			reconcileRegister()
+			Expect(tokenRequests).To(Equal(1))
+			Expect(rbacReconciliations).To(Equal(1))
+			Expect(registrar.registrations).To(Equal(registrations))
+			Expect(registrar.kubeConfig).To(Equal([]byte("token-1")))
+			Expect(sameExpiry(registerCR.Status.TokenExpiry, tokenExpiry)).To(BeTrue())
+
+			By("Issuing a new token once the spec of the ServiceAccount changes")
+			registerCR.Spec.ServiceAccount.TokenExpiration = &metav1.Duration{Duration: 2 * time.Hour}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			reconcileRegister()
+			Expect(tokenRequests).To(Equal(2))
+			Expect(rbacReconciliations).To(Equal(2))
+		})
+
+		It("should register a Cluster with a ServiceAccount token only once", func() {
+			DeferCleanup(issuedTokens.forget, typeNamespaceName)
+			registrar := &fakeRegistrar{registerErr: fmt.Errorf("ArgoCD is unavailable")}
+			recorder := record.NewFakeRecorder(10)
+			tokenExpiry := time.Now().Add(time.Hour)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: registrar.factory,
+				ServiceAccountCredentials: func(_ context.Context, _ []byte, _ *argocdv1beta1.ServiceAccountSpec,
+					_ bool) (*argocd.ServiceAccountCredentials, error) {
+					return &argocd.ServiceAccountCredentials{KubeConfig: []byte("token"), ExpiresAt: tokenExpiry}, nil
+				},
+			}
+
+			By("Creating the Register without registering its Cluster")
+			_, _ = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(registrar.registrations).To(BeZero())
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			registerCR.Spec.ServiceAccount = &argocdv1beta1.ServiceAccountSpec{
+				TokenExpiration: &metav1.Duration{Duration: time.Hour}}
+			Expect(k8sClient.Update(ctx, registerCR)).To(Succeed())
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+
+			By("Registering the Cluster with the token issued")
+			registrar.registerErr = nil
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registrar.registrations).To(Equal(1))
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Registered")))
+			Expect(events).NotTo(ContainElement(ContainSubstring("CredentialsRotated")))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(sameExpiry(registerCR.Status.TokenExpiry, tokenExpiry)).To(BeTrue())
+		})
+
+		It("should warn when the verification of the ArgoCD API certificate is disabled", func() {
+			Expect(os.Setenv(argocd.InsecureSkipVerifyEnvVar, "true")).To(Succeed())
+			defer func() { _ = os.Unsetenv(argocd.InsecureSkipVerifyEnvVar) }()
+
+			By("Reconciling the custom resource created")
+			recorder := record.NewFakeRecorder(10)
+			registerReconciler := &RegisterReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				NewRegistrar: (&fakeRegistrar{}).factory,
+			}
+
+			_, err := registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+
+			By("Checking that a warning event was raised")
+			Expect(recorder.Events).To(Receive(ContainSubstring("InsecureSkipVerify")))
+
+			By("Checking that the Register instance reports the insecure configuration")
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(registerCR.Status.Conditions, status.ConditionInsecure)).To(BeTrue())
+
+			By("Checking that the condition is removed when the verification is enabled again")
+			Expect(os.Unsetenv(argocd.InsecureSkipVerifyEnvVar)).To(Succeed())
+			_, err = registerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespaceName,
+			})
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(k8sClient.Get(ctx, typeNamespaceName, registerCR)).To(Succeed())
+			Expect(meta.FindStatusCondition(registerCR.Status.Conditions, status.ConditionInsecure)).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("Register predicates", func() {
+	It("should only reconcile the changes of the spec, the deletion and the metadata", func() {
+		old := &argocdv1beta1.Register{ObjectMeta: metav1.ObjectMeta{Name: "register", Generation: 1}}
+		updated := func(mutate func(register *argocdv1beta1.Register)) event.UpdateEvent {
+			register := old.DeepCopy()
+			mutate(register)
+			return event.UpdateEvent{ObjectOld: old, ObjectNew: register}
+		}
+
+		By("Filtering out the updates of the status")
+		Expect(specOrMetadataChanged.Update(updated(func(register *argocdv1beta1.Register) {
+			register.Status.Server = "mocks:80"
+		}))).To(BeFalse())
+
+		By("Reconciling the changes of the spec and the deletion, which increase the generation")
+		Expect(specOrMetadataChanged.Update(updated(func(register *argocdv1beta1.Register) {
+			register.Generation = 2
+		}))).To(BeTrue())
+
+		By("Reconciling the changes of the labels and the annotations")
+		Expect(specOrMetadataChanged.Update(updated(func(register *argocdv1beta1.Register) {
+			register.Labels = map[string]string{"env": "prod"}
+		}))).To(BeTrue())
+		Expect(specOrMetadataChanged.Update(updated(func(register *argocdv1beta1.Register) {
+			register.Annotations = map[string]string{clusterapiv1.PausedAnnotation: ""}
+		}))).To(BeTrue())
+	})
+})
+
+var _ = Describe("Register phase", func() {
+	condition := func(conditionType string, conditionStatus metav1.ConditionStatus) metav1.Condition {
+		return metav1.Condition{Type: conditionType, Status: conditionStatus}
+	}
+
+	DescribeTable("should summarize the conditions",
+		func(deleting bool, conditions []metav1.Condition, expected argocdv1beta1.RegisterPhase) {
+			register := &argocdv1beta1.Register{Status: argocdv1beta1.RegisterStatus{Conditions: conditions}}
+			if deleting {
+				register.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+			Expect(registerPhase(register)).To(Equal(expected))
+		},
+		Entry("no conditions", false, nil, argocdv1beta1.RegisterPhasePending),
+		Entry("progressing", false, []metav1.Condition{condition(status.ConditionProgressing, metav1.ConditionTrue)},
+			argocdv1beta1.RegisterPhaseRegistering),
+		Entry("waiting for the registration", false,
+			[]metav1.Condition{condition(status.ConditionAvailable, metav1.ConditionFalse)},
+			argocdv1beta1.RegisterPhaseRegistering),
+		Entry("registered", false, []metav1.Condition{
+			condition(status.ConditionRegistered, metav1.ConditionTrue),
+			condition(status.ConditionAvailable, metav1.ConditionFalse),
+			condition(status.ConditionDegraded, metav1.ConditionFalse),
+		}, argocdv1beta1.RegisterPhaseRegistered),
+		Entry("degraded", false, []metav1.Condition{
+			condition(status.ConditionRegistered, metav1.ConditionTrue),
+			condition(status.ConditionDegraded, metav1.ConditionTrue),
+		}, argocdv1beta1.RegisterPhaseFailed),
+		Entry("deleting", true, []metav1.Condition{condition(status.ConditionDegraded, metav1.ConditionTrue)},
+			argocdv1beta1.RegisterPhaseDeleting),
+	)
+})
+
+var _ = Describe("Register auth strategy", func() {
+	DescribeTable("should default the auth strategy from the credentials informed",
+		func(spec argocdv1beta1.RegisterSpec, expected argocdv1beta1.AuthStrategy) {
+			Expect(authStrategy(&argocdv1beta1.Register{Spec: spec})).To(Equal(expected))
+		},
+		Entry("no credentials", argocdv1beta1.RegisterSpec{}, argocdv1beta1.AuthStrategyEmbeddedTLSConfig),
+		Entry("service account", argocdv1beta1.RegisterSpec{ServiceAccount: &argocdv1beta1.ServiceAccountSpec{}},
+			argocdv1beta1.AuthStrategyServiceAccountToken),
+		Entry("aws auth", argocdv1beta1.RegisterSpec{AWSAuth: &argocdv1beta1.AWSAuthSpec{ClusterName: "eks"}},
+			argocdv1beta1.AuthStrategyAWSAuth),
+		Entry("exec provider", argocdv1beta1.RegisterSpec{ExecProvider: &argocdv1beta1.ExecProviderSpec{
+			Command: "gke-gcloud-auth-plugin"}}, argocdv1beta1.AuthStrategyExecProvider),
+		Entry("informed", argocdv1beta1.RegisterSpec{AuthStrategy: argocdv1beta1.AuthStrategyServiceAccountToken},
+			argocdv1beta1.AuthStrategyServiceAccountToken),
+	)
+
+	It("should build the cluster options of the auth strategy", func() {
+		options := clusterOptions(&argocdv1beta1.Register{Spec: argocdv1beta1.RegisterSpec{
+			AuthStrategy: argocdv1beta1.AuthStrategyAWSAuth,
+			AWSAuth:      &argocdv1beta1.AWSAuthSpec{ClusterName: "eks"},
+		}})
+		Expect(options.AWSAuthConfig).To(Equal(&argocd.AWSAuthConfig{ClusterName: "eks"}))
+		Expect(options.ExecProviderConfig).To(BeNil())
+
+		options = clusterOptions(&argocdv1beta1.Register{Spec: argocdv1beta1.RegisterSpec{
+			AuthStrategy: argocdv1beta1.AuthStrategyEmbeddedTLSConfig,
+		}})
+		Expect(options).To(Equal(argocd.ClusterOptions{}))
+	})
+
+	It("should reject the credentials which do not match the auth strategy", func() {
+		ctx := context.Background()
+		register := &argocdv1beta1.Register{
+			ObjectMeta: metav1.ObjectMeta{Name: "auth-strategy", Namespace: "default"},
+			Spec: argocdv1beta1.RegisterSpec{
+				AuthStrategy: argocdv1beta1.AuthStrategyAWSAuth,
+			},
+		}
+		err := k8sClient.Create(ctx, register)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("awsAuth must be informed only when authStrategy is AWSAuth"))
+
+		register.Spec.AuthStrategy = argocdv1beta1.AuthStrategyEmbeddedTLSConfig
+		register.Spec.ServiceAccount = &argocdv1beta1.ServiceAccountSpec{}
+		err = k8sClient.Create(ctx, register)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("serviceAccount must be informed only when authStrategy"))
+	})
+})
+
+// fakeRegistrar allows to verify the reconciliation without interacting with ArgoCD
+type fakeRegistrar struct {
+	registered    bool
+	registrations int
+	drifted       bool
+	registerErr   error
+	unregisterErr error
+	registeredErr error
+	verifyErr     error
+	options       argocd.ClusterOptions
+	kubeConfig    []byte
+	mode          argocdv1beta1.RegistrationMode
+
+	// unregistered are the servers informed via the options when the clusters were unregistered
+	unregistered []string
+	// released are the servers informed via the options when the clusters were released
+	released []string
+}
+
+func (f *fakeRegistrar) factory(_ context.Context, _ client.Client, _ logr.Logger,
+	mode argocdv1beta1.RegistrationMode, _ *clusterapiv1.Cluster, kubeConfig []byte,
+	options argocd.ClusterOptions) (argocd.Registrar, error) {
+	f.mode = mode
+	f.kubeConfig = kubeConfig
+	f.options = options
+	return f, nil
+}
+
+func (f *fakeRegistrar) RegisterCluster(_ context.Context) error {
+	if f.registerErr != nil {
+		return f.registerErr
+	}
+	f.registered = true
+	f.registrations++
+	return nil
+}
+
+func (f *fakeRegistrar) UnRegisterCluster(_ context.Context) error {
+	if f.unregisterErr != nil {
+		return f.unregisterErr
+	}
+	f.unregistered = append(f.unregistered, f.options.Server)
+	f.registered = false
+	return nil
+}
+
+func (f *fakeRegistrar) ReleaseCluster(_ context.Context) error {
+	f.released = append(f.released, f.options.Server)
+	return nil
+}
+
+func (f *fakeRegistrar) IsClusterRegistered(_ context.Context) (bool, error) {
+	if f.registeredErr != nil {
+		return false, f.registeredErr
+	}
+	return f.registered, nil
+}
+
+func (f *fakeRegistrar) SyncCluster(_ context.Context) (bool, error) {
+	drifted := f.drifted
+	f.drifted = false
+	return drifted, nil
+}
+
+func (f *fakeRegistrar) Verify(_ context.Context) error {
+	return f.verifyErr
+}
+
+func (f *fakeRegistrar) Describe(_ context.Context) (*argocd.RegistrationInfo, error) {
+	return &argocd.RegistrationInfo{ClusterID: "mocks-cluster-id", ServerVersion: "v2.8.4+c279299"}, nil
+}
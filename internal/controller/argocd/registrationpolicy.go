@@ -0,0 +1,119 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	clusterapiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+//+kubebuilder:rbac:groups=argocd.workload.com,resources=registrationpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// registrationPolicy returns the RegistrationPolicy which selects the Cluster, the first one in alphabetical
+// order when many select it. It returns false when the Cluster must not be registered, which is when
+// RegistrationPolicies exist and none of them selects it. When no RegistrationPolicy exists all Clusters
+// are registered, therefore, it returns true without a RegistrationPolicy.
+func (r *RegisterReconciler) registrationPolicy(ctx context.Context,
+	clusterAPI *clusterapiv1.Cluster) (*argocdv1beta1.RegistrationPolicy, bool, error) {
+	policies := &argocdv1beta1.RegistrationPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return nil, false, err
+	}
+	if len(policies.Items) == 0 {
+		return nil, true, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: clusterAPI.Namespace}, namespace); err != nil {
+		return nil, false, err
+	}
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		selected, err := selects(policy.Spec.NamespaceSelector, namespace.Labels)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid namespaceSelector of the RegistrationPolicy %s: %w", policy.Name, err)
+		}
+		if !selected {
+			continue
+		}
+		if selected, err = selects(policy.Spec.ClusterSelector, clusterAPI.Labels); err != nil {
+			return nil, false, fmt.Errorf("invalid clusterSelector of the RegistrationPolicy %s: %w", policy.Name, err)
+		}
+		if selected {
+			return policy, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// selects returns true when the labels match the selector, any labels match it when it is not informed
+func selects(selector *metav1.LabelSelector, objLabels map[string]string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return labelSelector.Matches(labels.Set(objLabels)), nil
+}
+
+// applyRegistrationPolicy defaults the spec of the Register created for the Cluster with the one of the
+// RegistrationPolicy which selects it. The Register can be edited afterwards to override them.
+func applyRegistrationPolicy(register *argocdv1beta1.Register, policy *argocdv1beta1.RegistrationPolicy,
+	clusterAPI *clusterapiv1.Cluster) error {
+	register.Spec.RegistrationMode = policy.Spec.RegistrationMode
+	register.Spec.Project = policy.Spec.Project
+	register.Spec.InstanceRef = policy.Spec.InstanceRef.DeepCopy()
+	if policy.Spec.NameTemplate != "" {
+		name, err := argocd.RenderClusterName(policy.Spec.NameTemplate, clusterAPI)
+		if err != nil {
+			return fmt.Errorf("invalid nameTemplate of the RegistrationPolicy %s: %w", policy.Name, err)
+		}
+		register.Spec.Name = name
+	}
+	return nil
+}
+
+// registrationPolicyToRequests maps the RegistrationPolicies to all Clusters so that the Clusters
+// selected once a RegistrationPolicy is created or changed are registered
+func (r *RegisterReconciler) registrationPolicyToRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	clusters := &unstructured.UnstructuredList{}
+	clusters.SetGroupVersionKind(r.clusterGVK().GroupVersion().WithKind(r.clusterGVK().Kind + "List"))
+	if err := r.List(ctx, clusters); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Clusters")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(clusters.Items))
+	for i := range clusters.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&clusters.Items[i])})
+	}
+	return requests
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
+	"github.com/workload-operator/internal/argocd"
+)
+
+// issuedToken is the credentials issued with a token bound to the TokenExpiration of the ServiceAccount
+type issuedToken struct {
+	credentials *argocd.ServiceAccountCredentials
+	// source is the hash of the kubeconfig and the spec.serviceAccount which the token was issued with
+	source string
+}
+
+// serviceAccountTokens stores the tokens issued by Register, so that a new token is only requested when the
+// one held by ArgoCD must be rotated rather than by every reconciliation
+type serviceAccountTokens struct {
+	mu     sync.Mutex
+	tokens map[client.ObjectKey]issuedToken
+}
+
+// current returns the credentials issued for the Register when they can still be used: they were issued with the
+// same kubeconfig and spec.serviceAccount, their token is the one recorded in the status, therefore, held by
+// ArgoCD, and it is not due to be rotated. Otherwise, it returns nil and a new token must be requested.
+func (s *serviceAccountTokens) current(key client.ObjectKey, source string,
+	RegisterCR *argocdv1beta1.Register) *argocd.ServiceAccountCredentials {
+	s.mu.Lock()
+	issued, ok := s.tokens[key]
+	s.mu.Unlock()
+	if !ok || issued.source != source || !sameExpiry(RegisterCR.Status.TokenExpiry, issued.credentials.ExpiresAt) {
+		return nil
+	}
+	refreshWindow := RegisterCR.Spec.ServiceAccount.TokenExpiration.Duration / 5
+	if !time.Now().Before(issued.credentials.ExpiresAt.Add(-refreshWindow)) {
+		return nil
+	}
+	return issued.credentials
+}
+
+// store records the credentials issued for the Register
+func (s *serviceAccountTokens) store(key client.ObjectKey, source string,
+	credentials *argocd.ServiceAccountCredentials) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = issuedToken{credentials: credentials, source: source}
+}
+
+// forget removes the credentials issued for the Register, i.e. once it is deleted
+func (s *serviceAccountTokens) forget(key client.ObjectKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, key)
+}
+
+// issuedTokens are the tokens issued for the Registers whose ServiceAccount has a TokenExpiration
+var issuedTokens = &serviceAccountTokens{tokens: map[client.ObjectKey]issuedToken{}}
+
+// sameExpiry returns true when the expiry recorded is the one informed. The expiry recorded in the status is
+// truncated to the second when it is serialized.
+func sameExpiry(recorded *metav1.Time, expiresAt time.Time) bool {
+	return recorded != nil && recorded.Unix() == expiresAt.Unix()
+}
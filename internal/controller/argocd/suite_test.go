@@ -27,12 +27,15 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	argocdv1beta1 "github.com/workload-operator/api/argocd/v1beta1"
 	//+kubebuilder:scaffold:imports
@@ -91,3 +94,26 @@ var _ = AfterSuite(func() {
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
+
+// fleetNamespace is the namespace of the objects served by the fake clients of the tests
+const fleetNamespace = "fleet"
+
+// newFleetClient returns a fake client which serves the given objects
+func newFleetClient(objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(k8sClient.Scheme()).WithObjects(objs...).Build()
+}
+
+// newFleetCluster returns a Cluster of the fleet namespace
+func newFleetCluster(name string) *clusterapiv1.Cluster {
+	return &clusterapiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: fleetNamespace}}
+}
+
+// newFleetRegister returns the Register of the Cluster of the fleet namespace
+func newFleetRegister(name string) *argocdv1beta1.Register {
+	return &argocdv1beta1.Register{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: fleetNamespace}}
+}
+
+// fleetRequest returns the request to reconcile the Cluster of the fleet namespace
+func fleetRequest(name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: client.ObjectKey{Name: name, Namespace: fleetNamespace}}
+}
@@ -17,11 +17,17 @@ limitations under the License.
 // Package status defines the conditional status that will be used by this project
 package status
 
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 // ConditionAvailable indicates that the associated custom resource is available and operating as intended.
 // A resource is considered Available when the system's components are correctly configured
 // and ready to perform their tasks.
 const ConditionAvailable = "Available"
 
+// ConditionRegistered indicates whether the cluster entry exists within ArgoCD. Unlike ConditionAvailable
+// it does not take into account whether ArgoCD is able to connect to the cluster.
+const ConditionRegistered = "Registered"
+
 // ConditionDegraded indicates that the custom resource is in a degraded state.
 // This usually means that an error has occurred and the resource is not fully functional,
 // but it is not completely inoperative.
@@ -30,3 +36,104 @@ const ConditionDegraded = "Degraded"
 // ConditionProgressing indicates that the custom resource is currently being applied or updated.
 // This condition is set when changes to the configuration have been accepted but not yet completed.
 const ConditionProgressing = "Progressing"
+
+// ConditionInsecure indicates that the custom resource is operating with an insecure configuration.
+// For example, when the verification of the ArgoCD API certificate is disabled.
+const ConditionInsecure = "Insecure"
+
+// ConditionArgoCDUnavailable indicates that the ArgoCD API is down, therefore, the requests of all the custom
+// resources registered within it are failed fast until it is available again.
+const ConditionArgoCDUnavailable = "ArgoCDUnavailable"
+
+// ConditionFailed indicates that the custom resource failed with an error which is not solved by retrying,
+// therefore, it is no longer reconciled until its spec changes or it is refreshed.
+const ConditionFailed = "Failed"
+
+// SetObservedGeneration records in the conditions the generation of the custom resource which was observed
+// when they were reported, so that the consumers can tell whether they correspond to its latest spec.
+func SetObservedGeneration(conditions []metav1.Condition, generation int64) {
+	for i := range conditions {
+		conditions[i].ObservedGeneration = generation
+	}
+}
+
+// Reasons of the status conditions. They are CamelCase, as required by the Kubernetes API conventions,
+// and part of the API, therefore, consumers can rely on them to react to a specific state. The failures
+// reported by ArgoCD use the reasons returned by argocd.ErrorReason instead.
+const (
+	// ReasonCreating is used while the Register of the Cluster is created
+	ReasonCreating = "Creating"
+
+	// ReasonRegistrationSucceeded is used when the cluster is registered and ArgoCD is connected to it
+	ReasonRegistrationSucceeded = "RegistrationSucceeded"
+
+	// ReasonRegistered is used when the cluster entry exists within ArgoCD
+	ReasonRegistered = "Registered"
+
+	// ReasonDriftCorrected is used when the registration was edited or removed out-of-band and it was restored
+	ReasonDriftCorrected = "DriftCorrected"
+
+	// ReasonWaitingForConnection is used when the cluster is registered but ArgoCD did not connect to it yet
+	ReasonWaitingForConnection = "WaitingForConnection"
+
+	// ReasonConnectionFailed is used when the cluster is registered but ArgoCD is unable to connect to it
+	ReasonConnectionFailed = "ConnectionFailed"
+
+	// ReasonEndpointChanged is used when the cluster is registered again since its server changed
+	ReasonEndpointChanged = "EndpointChanged"
+
+	// ReasonInstanceChanged is used when the cluster is registered again since its ArgoCDInstance changed
+	ReasonInstanceChanged = "InstanceChanged"
+
+	// ReasonRegistrationModeChanged is used when the cluster registration is migrated since its registration
+	// mode changed
+	ReasonRegistrationModeChanged = "RegistrationModeChanged"
+
+	// ReasonKubeconfigNotFound is used when the kubeconfig of the cluster cannot be gathered
+	ReasonKubeconfigNotFound = "KubeconfigNotFound"
+
+	// ReasonKubeconfigInvalid is used when the kubeconfig of the cluster cannot be decrypted or parsed
+	ReasonKubeconfigInvalid = "KubeconfigInvalid"
+
+	// ReasonServiceAccountTokenFailed is used when the token of the ServiceAccount cannot be gathered
+	ReasonServiceAccountTokenFailed = "ServiceAccountTokenFailed"
+
+	// ReasonInvalidNameTemplate is used when the name of the cluster within ArgoCD cannot be rendered
+	ReasonInvalidNameTemplate = "InvalidNameTemplate"
+
+	// ReasonDuplicateServer is used when the server of the cluster is already registered by another resource
+	ReasonDuplicateServer = "DuplicateServer"
+
+	// ReasonArgoCDSetupFailed is used when the client of ArgoCD cannot be set up, i.e. due to the credentials
+	ReasonArgoCDSetupFailed = "ArgoCDSetupFailed"
+
+	// ReasonReachable is used when the ArgoCD API is reachable with the credentials configured
+	ReasonReachable = "Reachable"
+
+	// ReasonUnreachable is used when the cluster cannot be reached with its kubeconfig
+	ReasonUnreachable = "Unreachable"
+
+	// ReasonInsecureSkipVerify is used when the verification of the ArgoCD API certificate is disabled
+	ReasonInsecureSkipVerify = "InsecureSkipVerify"
+
+	// ReasonRateLimited is used while the ArgoCD API is rate limiting the requests
+	ReasonRateLimited = "RateLimited"
+
+	// ReasonBackoff is used while ArgoCD is unavailable and the reconciliation is retried with a backoff
+	ReasonBackoff = "Backoff"
+
+	// ReasonCircuitOpen is used while the requests to the ArgoCD API are failed fast since it is down
+	ReasonCircuitOpen = "CircuitOpen"
+
+	// ReasonSuspended is used while the reconciliation is suspended
+	ReasonSuspended = "Suspended"
+
+	// ReasonFinalizing is used while the registration is removed from ArgoCD before the deletion
+	ReasonFinalizing = "Finalizing"
+
+	// ReasonFinalizationFailed is used when the registration could not be removed within the retry budget
+	ReasonFinalizationFailed = "FinalizationFailed"
+
+	// ReasonRefreshed is used once the reconciliation of a custom resource which Failed is restarted
+	ReasonRefreshed = "Refreshed"
+)
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing provides the OpenTelemetry spans of the reconciliations and of the calls to ArgoCD, so
+// that the slow registrations can be debugged. The spans are only exported when the tracing is set up,
+// otherwise, they are discarded by the default no-op provider.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ServiceName is the name of the service which reports the spans, unless OTEL_SERVICE_NAME is set
+	ServiceName = "workload-operator"
+
+	// instrumentationName is the name of the tracer which creates the spans
+	instrumentationName = "github.com/workload-operator"
+)
+
+const (
+	// ClusterNameKey is the attribute with the name of the Cluster reconciled
+	ClusterNameKey = attribute.Key("cluster.name")
+	// ClusterNamespaceKey is the attribute with the namespace of the Cluster reconciled
+	ClusterNamespaceKey = attribute.Key("cluster.namespace")
+)
+
+// Setup exports the spans via OTLP over HTTP. The exporter is configured with the standard env vars of
+// OpenTelemetry, i.e. OTEL_EXPORTER_OTLP_ENDPOINT, and the trace context is propagated to ArgoCD in the
+// requests sent to its API. The function returned flushes the pending spans and stops the exporter.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("error creating the resource of the spans: %w", err)
+	}
+	// The env vars, i.e. OTEL_SERVICE_NAME, take precedence over the defaults
+	if res, err = resource.Merge(res, resource.Environment()); err != nil {
+		return nil, fmt.Errorf("error creating the resource of the spans: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{},
+		propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start creates a span, child of the one in the context when there is any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError records the error, if any, in the span and returns it
+func RecordError(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// Inject adds the trace context to the headers of a request, so that the spans of the server are linked
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// ClusterAttributes returns the attributes which identify the Cluster reconciled
+func ClusterAttributes(name, namespace string) []attribute.KeyValue {
+	return []attribute.KeyValue{ClusterNameKey.String(name), ClusterNamespaceKey.String(namespace)}
+}
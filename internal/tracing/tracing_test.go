@@ -0,0 +1,84 @@
+/*
+Copyright 2023 Camila Macedo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("Tracing", func() {
+	ctx := context.Background()
+	var recorder *tracetest.SpanRecorder
+
+	BeforeEach(func() {
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+
+	AfterEach(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	It("should create the spans of the calls as children of the reconciliation", func() {
+		ctx, reconcileSpan := Start(ctx, "Reconcile Cluster", ClusterAttributes("cluster", "default")...)
+		_, callSpan := Start(ctx, "ArgoCD GET")
+		callSpan.End()
+		reconcileSpan.End()
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name()).To(Equal("ArgoCD GET"))
+		Expect(spans[0].Parent().SpanID()).To(Equal(spans[1].SpanContext().SpanID()))
+		Expect(spans[1].Attributes()).To(ContainElements(ClusterNameKey.String("cluster"),
+			ClusterNamespaceKey.String("default")))
+	})
+
+	It("should record the errors in the spans", func() {
+		_, span := Start(ctx, "Fetch kubeconfig Secret")
+		Expect(RecordError(span, nil)).To(Succeed())
+		err := errors.New("secret not found")
+		Expect(RecordError(span, err)).To(Equal(err))
+		span.End()
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		Expect(spans[0].Status().Description).To(Equal("secret not found"))
+	})
+
+	It("should propagate the trace context in the headers of the requests", func() {
+		ctx, span := Start(ctx, "ArgoCD POST")
+		defer span.End()
+
+		header := http.Header{}
+		Inject(ctx, header)
+		Expect(header.Get("traceparent")).To(ContainSubstring(span.SpanContext().TraceID().String()))
+	})
+})